@@ -0,0 +1,672 @@
+// Package aodv implements a simplified AODV (RFC 3561) node, as a reactive baseline to compare OLSR against.
+//
+// Routes are discovered on demand with RREQ and RREP messages, and broken routes are reported with RERR messages.
+// Nodes which are part of an active route broadcast HELLO messages, so that their neighbors can detect link breaks.
+// Gratuitous replies, expanding ring search and local repair are not implemented.
+package aodv
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+// Params are the AODV protocol parameters of a Node, all in ticks unless stated otherwise.
+type Params struct {
+	// HelloInterval is how often a Node which is part of an active route sends a HELLO.
+	HelloInterval int `json:"helloInterval,omitempty"`
+
+	// AllowedHelloLoss is the number of HELLOs which may be missed before a link is considered broken.
+	AllowedHelloLoss int `json:"allowedHelloLoss,omitempty"`
+
+	// ActiveRouteTimeout is how long a route stays valid after it was last used.
+	ActiveRouteTimeout int `json:"activeRouteTimeout,omitempty"`
+
+	// NetTraversalTime is how long a Node waits for a RREP before retrying a route discovery. The wait doubles with
+	// each retry.
+	NetTraversalTime int `json:"netTraversalTime,omitempty"`
+
+	// RREQRetries is the number of times a route discovery is retried before the buffered data is dropped.
+	RREQRetries int `json:"rreqRetries,omitempty"`
+
+	// NetDiameter is the maximum number of hops a RREQ travels.
+	NetDiameter int `json:"netDiameter,omitempty"`
+}
+
+// DefaultParams returns the parameters used by a Node unless they are overridden.
+func DefaultParams() Params {
+	return Params{
+		HelloInterval:      5,
+		AllowedHelloLoss:   2,
+		ActiveRouteTimeout: 30,
+		NetTraversalTime:   20,
+		RREQRetries:        2,
+		NetDiameter:        35,
+	}
+}
+
+// Validate checks the parameters are usable.
+func (p Params) Validate() error {
+	switch {
+	case p.HelloInterval <= 0:
+		return fmt.Errorf("invalid params: hello interval must be positive: %d", p.HelloInterval)
+	case p.AllowedHelloLoss <= 0:
+		return fmt.Errorf("invalid params: allowed hello loss must be positive: %d", p.AllowedHelloLoss)
+	case p.ActiveRouteTimeout <= 0:
+		return fmt.Errorf("invalid params: active route timeout must be positive: %d", p.ActiveRouteTimeout)
+	case p.NetTraversalTime <= 0:
+		return fmt.Errorf("invalid params: net traversal time must be positive: %d", p.NetTraversalTime)
+	case p.RREQRetries < 0:
+		return fmt.Errorf("invalid params: rreq retries must not be negative: %d", p.RREQRetries)
+	case p.NetDiameter <= 0:
+		return fmt.Errorf("invalid params: net diameter must be positive: %d", p.NetDiameter)
+	}
+	return nil
+}
+
+// Merge returns p, with each non-zero parameter of override taking precedence.
+func (p Params) Merge(override Params) Params {
+	if override.HelloInterval != 0 {
+		p.HelloInterval = override.HelloInterval
+	}
+	if override.AllowedHelloLoss != 0 {
+		p.AllowedHelloLoss = override.AllowedHelloLoss
+	}
+	if override.ActiveRouteTimeout != 0 {
+		p.ActiveRouteTimeout = override.ActiveRouteTimeout
+	}
+	if override.NetTraversalTime != 0 {
+		p.NetTraversalTime = override.NetTraversalTime
+	}
+	if override.RREQRetries != 0 {
+		p.RREQRetries = override.RREQRetries
+	}
+	if override.NetDiameter != 0 {
+		p.NetDiameter = override.NetDiameter
+	}
+	return p
+}
+
+// deletePeriod is how long an invalid route is kept, so that its sequence number is remembered.
+func (p Params) deletePeriod() int {
+	if hello := p.AllowedHelloLoss * p.HelloInterval; hello > p.ActiveRouteTimeout {
+		return hello
+	}
+	return p.ActiveRouteTimeout
+}
+
+// pathDiscoveryTime is how long a RREQ is remembered, so that duplicates are ignored.
+func (p Params) pathDiscoveryTime() int {
+	return 2 * p.NetTraversalTime
+}
+
+type routeEntry struct {
+	dst     message.NodeID
+	nextHop message.NodeID
+
+	// hops is the number of hops needed to reach the destination.
+	hops int
+
+	// seq is the destination's sequence number, which is only known if validSeq is set.
+	seq      int
+	validSeq bool
+
+	valid bool
+
+	// lifetime is the tick a valid route becomes invalid at, or the tick an invalid route is deleted at.
+	lifetime int
+
+	// precursors are the neighbors which forward data along this route.
+	precursors map[message.NodeID]bool
+}
+
+// rreqKey identifies a RREQ.
+type rreqKey struct {
+	originator message.NodeID
+	id         int
+}
+
+// discovery is a route discovery in progress.
+type discovery struct {
+	// attempts is the number of RREQs sent so far.
+	attempts int
+
+	// deadline is the tick the latest RREQ is considered unanswered at.
+	deadline int
+}
+
+// Node represents a network node in the ad-hoc network. Node implements controller.Router.
+type Node struct {
+	id message.NodeID
+
+	params Params
+
+	// outbox holds the envelopes sent by the Node since it was last driven by the controller.
+	outbox []message.Envelope
+
+	// seq is the Node's own sequence number.
+	seq int
+
+	// rreqID is the ID of the latest RREQ originated by the Node.
+	rreqID int
+
+	// routes maps destinations to routing entries, both valid and invalid.
+	routes map[message.NodeID]*routeEntry
+
+	// seen holds the RREQs received recently, along with the tick they are forgotten at.
+	seen map[rreqKey]int
+
+	// buffered holds data waiting for a route, by destination.
+	buffered map[message.NodeID][]*message.DataMessage
+
+	// discoveries holds the route discoveries in progress, by destination.
+	discoveries map[message.NodeID]*discovery
+
+	// lastHeard holds the tick each neighbor was last heard from.
+	lastHeard map[message.NodeID]int
+
+	// currentTick is the number of ticks since the node came online.
+	currentTick int
+}
+
+// ID is the Node's identifier.
+func (n *Node) ID() message.NodeID {
+	return n.id
+}
+
+// Receive handles a message received by the Node. Messages other than RREQ, RREP, RERR and DATA are ignored.
+func (n *Node) Receive(env message.Envelope) []message.Envelope {
+	n.lastHeard[env.From] = n.currentTick
+	switch msg := env.Message.(type) {
+	case *message.RREQMessage:
+		n.handleRREQ(env.From, msg)
+	case *message.RREPMessage:
+		if env.To == message.Broadcast {
+			n.handleHello(env.From, msg)
+		} else {
+			n.handleRREP(env.From, msg)
+		}
+	case *message.RERRMessage:
+		n.handleRERR(env.From, msg)
+	case *message.DataMessage:
+		n.handleData(env.From, msg)
+	default:
+		log.Printf("node %d: ignoring message of unknown type: %s", n.id, env.Message.Type())
+	}
+	return n.flush()
+}
+
+// Send buffers data for dst, which is sent once a route to dst has been discovered.
+func (n *Node) Send(dst message.NodeID, data string) {
+	msg := &message.DataMessage{
+		Source:      n.id,
+		Destination: dst,
+		Data:        data,
+	}
+	n.buffered[dst] = append(n.buffered[dst], msg)
+}
+
+// Tick expires routes, detects broken links, sends buffered data or discovers routes for it, and sends a HELLO if the
+// Node is part of an active route.
+func (n *Node) Tick() []message.Envelope {
+	n.expireRoutes()
+	n.detectLinkBreaks()
+	for k, until := range n.seen {
+		if until <= n.currentTick {
+			delete(n.seen, k)
+		}
+	}
+
+	for _, dst := range sortedKeys(n.buffered) {
+		if r, ok := n.validRoute(dst); ok {
+			for _, msg := range n.buffered[dst] {
+				n.forward(r, msg)
+			}
+			delete(n.buffered, dst)
+			delete(n.discoveries, dst)
+			continue
+		}
+		n.discover(dst)
+	}
+
+	if n.currentTick%n.params.HelloInterval == 0 && n.active() {
+		n.send(message.Broadcast, &message.RREPMessage{
+			NextHop:             message.Broadcast,
+			FromNeighbor:        n.id,
+			Originator:          n.id,
+			Destination:         n.id,
+			DestinationSequence: n.seq,
+			Lifetime:            n.params.AllowedHelloLoss * n.params.HelloInterval,
+		})
+	}
+
+	n.currentTick++
+	return n.flush()
+}
+
+// Routes returns the Node's valid routes, sorted by destination.
+func (n *Node) Routes() []controller.Route {
+	routes := make([]controller.Route, 0, len(n.routes))
+	for _, r := range n.routes {
+		if r.valid {
+			routes = append(routes, controller.Route{Destination: r.dst, NextHop: r.nextHop, Distance: r.hops})
+		}
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].Destination < routes[j].Destination
+	})
+	return routes
+}
+
+// Inspect returns a snapshot of the Node's neighbors and valid routes. Neighbors are those heard from within
+// AllowedHelloLoss HELLO intervals. Node implements controller.Inspector.
+// It must not be called while the Node is being driven by the controller.
+func (n *Node) Inspect() controller.NodeState {
+	s := controller.NodeState{
+		ID:        n.id,
+		Tick:      n.currentTick,
+		Neighbors: make([]message.NodeID, 0, len(n.lastHeard)),
+		Routes:    n.Routes(),
+	}
+	timeout := n.params.AllowedHelloLoss * n.params.HelloInterval
+	for _, id := range sortedKeys(n.lastHeard) {
+		if n.currentTick-n.lastHeard[id] <= timeout {
+			s.Neighbors = append(s.Neighbors, id)
+		}
+	}
+	return s
+}
+
+// Sequences returns the Node's own sequence number, and the ID of its latest RREQ. Node implements
+// controller.Persister.
+func (n *Node) Sequences() map[string]int {
+	return map[string]int{"seq": n.seq, "rreq": n.rreqID}
+}
+
+// RestoreSequences continues from the sequence numbers of a previous Node.
+func (n *Node) RestoreSequences(seqs map[string]int) {
+	n.seq, n.rreqID = seqs["seq"], seqs["rreq"]
+}
+
+// send queues msg to be sent to a neighbor, or to every neighbor if to is message.Broadcast.
+func (n *Node) send(to message.NodeID, msg message.Message) {
+	n.outbox = append(n.outbox, message.Envelope{From: n.id, To: to, Message: msg})
+}
+
+// flush returns, and clears, the envelopes sent by the Node.
+func (n *Node) flush() []message.Envelope {
+	out := n.outbox
+	n.outbox = nil
+	return out
+}
+
+// validRoute returns the valid route to dst, if there is one.
+func (n *Node) validRoute(dst message.NodeID) (*routeEntry, bool) {
+	r, in := n.routes[dst]
+	if !in || !r.valid {
+		return nil, false
+	}
+	return r, true
+}
+
+// extend ensures r stays valid until at least the given tick.
+func extend(r *routeEntry, until int) {
+	if r.lifetime < until {
+		r.lifetime = until
+	}
+}
+
+// updateRoute creates or updates the route to dst if the new information is fresher, or equally fresh but shorter,
+// than the existing route. The route to dst is returned whether it was updated or not, or nil if dst is the Node.
+func (n *Node) updateRoute(dst, nextHop message.NodeID, hops, seq, lifetime int) *routeEntry {
+	if dst == n.id {
+		return nil
+	}
+	r, in := n.routes[dst]
+	if !in {
+		r = &routeEntry{dst: dst, precursors: make(map[message.NodeID]bool)}
+		n.routes[dst] = r
+	}
+	if in && r.validSeq && (seq < r.seq || (seq == r.seq && r.valid && hops >= r.hops)) {
+		return r
+	}
+	r.nextHop = nextHop
+	r.hops = hops
+	r.seq = seq
+	r.validSeq = true
+	r.valid = true
+	r.lifetime = lifetime
+	return r
+}
+
+// updateNeighbor creates or refreshes the route to a neighbor a message was received from, without a sequence
+// number.
+func (n *Node) updateNeighbor(neighbor message.NodeID) {
+	if neighbor == n.id {
+		return
+	}
+	until := n.currentTick + n.params.ActiveRouteTimeout
+	r, in := n.routes[neighbor]
+	if !in {
+		n.routes[neighbor] = &routeEntry{
+			dst:        neighbor,
+			nextHop:    neighbor,
+			hops:       1,
+			valid:      true,
+			lifetime:   until,
+			precursors: make(map[message.NodeID]bool),
+		}
+		return
+	}
+	if !r.valid || r.hops > 1 {
+		r.nextHop = neighbor
+		r.hops = 1
+		r.valid = true
+		r.lifetime = until
+		return
+	}
+	extend(r, until)
+}
+
+// invalidate marks r as invalid, recording the destination's latest sequence number.
+func (n *Node) invalidate(r *routeEntry, seq int) {
+	r.valid = false
+	r.seq = seq
+	r.lifetime = n.currentTick + n.params.deletePeriod()
+}
+
+// active determines whether the Node is part of an active route, and so should send HELLOs.
+func (n *Node) active() bool {
+	for _, r := range n.routes {
+		if r.valid && (r.hops > 1 || len(r.precursors) > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// forward sends msg, as this Node, along r, refreshing the routes it uses.
+func (n *Node) forward(r *routeEntry, msg *message.DataMessage) {
+	until := n.currentTick + n.params.ActiveRouteTimeout
+	extend(r, until)
+	if next, ok := n.validRoute(r.nextHop); ok {
+		extend(next, until)
+	}
+	if src, ok := n.validRoute(msg.Source); ok {
+		extend(src, until)
+	}
+
+	fwd := *msg
+	fwd.FromNeighbor = n.id
+	fwd.NextHop = r.nextHop
+	n.send(r.nextHop, &fwd)
+}
+
+// discover starts, or retries, a route discovery for dst. Once every retry has gone unanswered, the data buffered
+// for dst is dropped.
+func (n *Node) discover(dst message.NodeID) {
+	d, in := n.discoveries[dst]
+	if in && n.currentTick < d.deadline {
+		return
+	}
+	if !in {
+		d = &discovery{}
+		n.discoveries[dst] = d
+	}
+	if d.attempts > n.params.RREQRetries {
+		log.Printf("node %d: no route to %d: dropping %d messages", n.id, dst, len(n.buffered[dst]))
+		delete(n.buffered, dst)
+		delete(n.discoveries, dst)
+		return
+	}
+	d.deadline = n.currentTick + n.params.NetTraversalTime<<d.attempts
+	d.attempts++
+
+	n.seq++
+	n.rreqID++
+	n.seen[rreqKey{originator: n.id, id: n.rreqID}] = n.currentTick + n.params.pathDiscoveryTime()
+	rreq := &message.RREQMessage{
+		FromNeighbor:       n.id,
+		ID:                 n.rreqID,
+		Originator:         n.id,
+		OriginatorSequence: n.seq,
+		Destination:        dst,
+		UnknownSequence:    true,
+		TTL:                n.params.NetDiameter,
+	}
+	if r, in := n.routes[dst]; in && r.validSeq {
+		rreq.DestinationSequence = r.seq
+		rreq.UnknownSequence = false
+	}
+	n.send(message.Broadcast, rreq)
+}
+
+// handleRREQ records the reverse route to the originator of a RREQ, and then either replies, if this Node is the
+// destination or knows a fresh enough route to it, or rebroadcasts the request.
+func (n *Node) handleRREQ(from message.NodeID, msg *message.RREQMessage) {
+	n.updateNeighbor(from)
+	key := rreqKey{originator: msg.Originator, id: msg.ID}
+	if _, seen := n.seen[key]; seen || msg.Originator == n.id {
+		return
+	}
+	n.seen[key] = n.currentTick + n.params.pathDiscoveryTime()
+
+	reverse := n.updateRoute(msg.Originator, from, msg.HopCount+1, msg.OriginatorSequence, n.currentTick+n.params.ActiveRouteTimeout)
+	extend(reverse, n.currentTick+n.params.ActiveRouteTimeout)
+
+	if msg.Destination == n.id {
+		if !msg.UnknownSequence && msg.DestinationSequence > n.seq {
+			n.seq = msg.DestinationSequence
+		}
+		n.send(from, &message.RREPMessage{
+			NextHop:             from,
+			FromNeighbor:        n.id,
+			Originator:          msg.Originator,
+			Destination:         n.id,
+			DestinationSequence: n.seq,
+			Lifetime:            2 * n.params.ActiveRouteTimeout,
+		})
+		return
+	}
+
+	if r, ok := n.validRoute(msg.Destination); ok && r.validSeq && (msg.UnknownSequence || r.seq >= msg.DestinationSequence) {
+		r.precursors[from] = true
+		if next, ok := n.validRoute(r.nextHop); ok {
+			next.precursors[from] = true
+		}
+		reverse.precursors[r.nextHop] = true
+		n.send(from, &message.RREPMessage{
+			NextHop:             from,
+			FromNeighbor:        n.id,
+			Originator:          msg.Originator,
+			Destination:         msg.Destination,
+			DestinationSequence: r.seq,
+			HopCount:            r.hops,
+			Lifetime:            r.lifetime - n.currentTick,
+		})
+		return
+	}
+
+	if msg.TTL <= 1 {
+		return
+	}
+	fwd := *msg
+	fwd.FromNeighbor = n.id
+	fwd.HopCount++
+	fwd.TTL--
+	if r, in := n.routes[msg.Destination]; in && r.validSeq && (fwd.UnknownSequence || r.seq > fwd.DestinationSequence) {
+		fwd.DestinationSequence = r.seq
+		fwd.UnknownSequence = false
+	}
+	n.send(message.Broadcast, &fwd)
+}
+
+// handleRREP records the forward route to the destination of a RREP, and forwards the reply towards its originator.
+func (n *Node) handleRREP(from message.NodeID, msg *message.RREPMessage) {
+	n.updateNeighbor(from)
+	hops := msg.HopCount + 1
+	r := n.updateRoute(msg.Destination, from, hops, msg.DestinationSequence, n.currentTick+msg.Lifetime)
+	if msg.Originator == n.id || r == nil {
+		return
+	}
+
+	reverse, ok := n.validRoute(msg.Originator)
+	if !ok {
+		return
+	}
+	r.precursors[reverse.nextHop] = true
+	if next, ok := n.validRoute(from); ok {
+		next.precursors[reverse.nextHop] = true
+	}
+	extend(reverse, n.currentTick+n.params.ActiveRouteTimeout)
+
+	fwd := *msg
+	fwd.NextHop = reverse.nextHop
+	fwd.FromNeighbor = n.id
+	fwd.HopCount = hops
+	n.send(reverse.nextHop, &fwd)
+}
+
+// handleHello refreshes the route to the neighbor which sent a HELLO.
+func (n *Node) handleHello(from message.NodeID, msg *message.RREPMessage) {
+	n.updateNeighbor(from)
+	if r := n.updateRoute(from, from, 1, msg.DestinationSequence, n.currentTick+msg.Lifetime); r != nil {
+		extend(r, n.currentTick+msg.Lifetime)
+	}
+}
+
+// handleRERR invalidates the routes which the sender of a RERR used to provide, propagating the error to the
+// precursors of those routes.
+func (n *Node) handleRERR(from message.NodeID, msg *message.RERRMessage) {
+	var lost []message.Unreachable
+	for _, u := range msg.Unreachable {
+		r, ok := n.validRoute(u.Destination)
+		if !ok || r.nextHop != from {
+			continue
+		}
+		n.invalidate(r, u.Sequence)
+		if len(r.precursors) > 0 {
+			lost = append(lost, message.Unreachable{Destination: r.dst, Sequence: r.seq})
+		}
+	}
+	n.sendRERR(lost)
+}
+
+// handleData forwards a message.DataMessage towards its destination, unless this Node is the destination. A RERR is
+// sent if there is no route to the destination.
+func (n *Node) handleData(from message.NodeID, msg *message.DataMessage) {
+	n.updateNeighbor(from)
+	if msg.Destination == n.id {
+		return
+	}
+	r, ok := n.validRoute(msg.Destination)
+	if !ok {
+		u := message.Unreachable{Destination: msg.Destination}
+		if r, in := n.routes[msg.Destination]; in {
+			u.Sequence = r.seq
+		}
+		n.sendRERR([]message.Unreachable{u})
+		return
+	}
+	n.forward(r, msg)
+}
+
+// sendRERR broadcasts a RERR for the unreachable destinations, if there are any.
+func (n *Node) sendRERR(unreachable []message.Unreachable) {
+	if len(unreachable) == 0 {
+		return
+	}
+	n.send(message.Broadcast, &message.RERRMessage{
+		NextHop:      message.Broadcast,
+		FromNeighbor: n.id,
+		Unreachable:  unreachable,
+	})
+}
+
+// expireRoutes invalidates valid routes which have not been used for their lifetime, and deletes invalid routes which
+// have been kept for long enough.
+func (n *Node) expireRoutes() {
+	for _, dst := range sortedKeys(n.routes) {
+		r := n.routes[dst]
+		if r.lifetime > n.currentTick {
+			continue
+		}
+		if r.valid {
+			n.invalidate(r, r.seq)
+		} else {
+			delete(n.routes, dst)
+		}
+	}
+}
+
+// detectLinkBreaks considers the link to a neighbor broken when the neighbor is the next hop of a route which is part
+// of an active route, but has not been heard from for AllowedHelloLoss HELLO intervals. Every route via the neighbor
+// is invalidated, and a RERR is sent for those with precursors.
+func (n *Node) detectLinkBreaks() {
+	timeout := n.params.AllowedHelloLoss * n.params.HelloInterval
+	broken := make(map[message.NodeID]bool)
+	for _, r := range n.routes {
+		if r.valid && (r.hops > 1 || len(r.precursors) > 0) && n.currentTick-n.lastHeard[r.nextHop] > timeout {
+			broken[r.nextHop] = true
+		}
+	}
+	if len(broken) == 0 {
+		return
+	}
+
+	var lost []message.Unreachable
+	for _, dst := range sortedKeys(n.routes) {
+		r := n.routes[dst]
+		if !r.valid || !broken[r.nextHop] {
+			continue
+		}
+		seq := r.seq
+		if r.validSeq {
+			seq++
+		}
+		n.invalidate(r, seq)
+		if len(r.precursors) > 0 {
+			lost = append(lost, message.Unreachable{Destination: r.dst, Sequence: r.seq})
+		}
+	}
+	n.sendRERR(lost)
+}
+
+// sortedKeys returns the keys of m in increasing order.
+func sortedKeys[V any](m map[message.NodeID]V) []message.NodeID {
+	keys := make([]message.NodeID, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// New creates a network Node.
+func New(id message.NodeID, params Params) *Node {
+	n := Node{}
+	n.id = id
+	n.params = params
+	n.routes = make(map[message.NodeID]*routeEntry)
+	n.seen = make(map[rreqKey]int)
+	n.buffered = make(map[message.NodeID][]*message.DataMessage)
+	n.discoveries = make(map[message.NodeID]*discovery)
+	n.lastHeard = make(map[message.NodeID]int)
+	return &n
+}
+
+// Protocol returns a controller.Factory creating a Node, using params, for each node. An error is returned if params
+// are invalid.
+func Protocol(params Params) controller.Factory {
+	return func(config controller.NodeConfig) (controller.Router, error) {
+		if err := params.Validate(); err != nil {
+			return nil, err
+		}
+		return New(config.ID, params), nil
+	}
+}
@@ -0,0 +1,163 @@
+package aodv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/internal/routertest"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/topology"
+)
+
+// newLine creates a network of nodes 0 to size-1, each linked to the next.
+func newLine(size int, params Params) *routertest.Network[*Node] {
+	return routertest.NewLine(size, func(id message.NodeID) *Node { return New(id, params) })
+}
+
+func TestNode_discovery(t *testing.T) {
+	nw := newLine(4, DefaultParams())
+	nw.Nodes[0].Send(3, "hi")
+	nw.Run(10)
+
+	if want := []string{"hi"}; !reflect.DeepEqual(nw.Delivered, want) {
+		t.Errorf("delivered %v, want %v", nw.Delivered, want)
+	}
+	wantRoutes := map[message.NodeID]controller.Route{
+		0: {Destination: 3, NextHop: 1, Distance: 3},
+		1: {Destination: 3, NextHop: 2, Distance: 2},
+		2: {Destination: 3, NextHop: 3, Distance: 1},
+	}
+	for id, want := range wantRoutes {
+		r, ok := nw.Nodes[id].validRoute(3)
+		if !ok {
+			t.Errorf("node %d has no route to 3", id)
+			continue
+		}
+		if got := (controller.Route{Destination: r.dst, NextHop: r.nextHop, Distance: r.hops}); got != want {
+			t.Errorf("node %d route = %v, want %v", id, got, want)
+		}
+	}
+	if r, ok := nw.Nodes[3].validRoute(0); !ok || r.nextHop != 2 || r.hops != 3 {
+		t.Errorf("node 3 has no reverse route to 0 via 2")
+	}
+}
+
+func TestNode_linkBreak(t *testing.T) {
+	nw := newLine(4, DefaultParams())
+	nw.Nodes[0].Send(3, "hi")
+	nw.Run(10)
+	if _, ok := nw.Nodes[0].validRoute(3); !ok {
+		t.Fatal("node 0 has no route to 3")
+	}
+
+	nw.Link(2, 3, false)
+	p := DefaultParams()
+	nw.Run(p.AllowedHelloLoss*p.HelloInterval + p.HelloInterval + 4)
+
+	if _, ok := nw.Nodes[2].validRoute(3); ok {
+		t.Errorf("node 2 still has a route to 3 after the link broke")
+	}
+	if _, ok := nw.Nodes[0].validRoute(3); ok {
+		t.Errorf("node 0 still has a route to 3 after receiving a RERR")
+	}
+	if r := nw.Nodes[0].routes[3]; r == nil || r.seq < 1 {
+		t.Errorf("node 0 did not record the incremented sequence number of 3: %v", r)
+	}
+}
+
+func TestNode_unreachable(t *testing.T) {
+	p := DefaultParams()
+	nw := newLine(2, p)
+	nw.Link(0, 1, false)
+	nw.Nodes[0].Send(1, "hi")
+
+	// Every attempt waits twice as long as the last.
+	wait := 0
+	for i := 0; i <= p.RREQRetries; i++ {
+		wait += p.NetTraversalTime << i
+	}
+	nw.Run(wait + 2)
+
+	if len(nw.Nodes[0].buffered) != 0 {
+		t.Errorf("node 0 still buffers data after every route discovery failed")
+	}
+	if got := nw.Nodes[0].rreqID; got != p.RREQRetries+1 {
+		t.Errorf("node 0 sent %d RREQs, want %d", got, p.RREQRetries+1)
+	}
+}
+
+func TestNode_Receive_duplicateRREQ(t *testing.T) {
+	n := New(1, DefaultParams())
+	rreq := &message.RREQMessage{FromNeighbor: 0, ID: 1, Originator: 0, OriginatorSequence: 1, Destination: 5, UnknownSequence: true, TTL: 35}
+	if got := n.Receive(message.Envelope{From: 0, To: message.Broadcast, Message: rreq}); len(got) != 1 {
+		t.Fatalf("Receive() forwarded %d envelopes, want 1", len(got))
+	}
+	if got := n.Receive(message.Envelope{From: 2, To: message.Broadcast, Message: rreq}); len(got) != 0 {
+		t.Errorf("Receive() forwarded a duplicate RREQ: %v", got)
+	}
+}
+
+func TestProtocol_metrics(t *testing.T) {
+	nwt, err := topology.Read(strings.NewReader("0 UP 0 <-> 1\n0 UP 1 <-> 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := controller.New(nwt, 0, Protocol(DefaultParams()))
+	configs := []controller.NodeConfig{
+		{ID: 0, Message: controller.NodeMessage{Message: "hi", Delay: 5, Destination: 2}},
+		{ID: 1, Message: controller.NodeMessage{Sent: true}},
+		{ID: 2, Message: controller.NodeMessage{Sent: true}},
+	}
+	if err := c.Initialize(configs); err != nil {
+		t.Fatal(err)
+	}
+	c.Start(40)
+
+	// The reply from 2 is forwarded by 1, and the HELLOs of the nodes on the route are counted on their own.
+	m := c.Metrics()
+	wantSent := map[string]int{"RREQ": 2, "RREP": 2, "HELLO": 18, "DATA": 2}
+	if !reflect.DeepEqual(m.Sent, wantSent) {
+		t.Errorf("Metrics().Sent = %v, want %v", m.Sent, wantSent)
+	}
+	if m.DataDelivered != 1 {
+		t.Errorf("Metrics().DataDelivered = %d, want 1", m.DataDelivered)
+	}
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr bool
+	}{
+		{
+			name:    "defaults",
+			params:  DefaultParams(),
+			wantErr: false,
+		},
+		{
+			name:    "negative hello interval",
+			params:  DefaultParams().Merge(Params{HelloInterval: -1}),
+			wantErr: true,
+		},
+		{
+			name:    "negative retries",
+			params:  DefaultParams().Merge(Params{RREQRetries: -1}),
+			wantErr: true,
+		},
+		{
+			name:    "zero net diameter",
+			params:  Params{HelloInterval: 5, AllowedHelloLoss: 2, ActiveRouteTimeout: 30, NetTraversalTime: 20},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
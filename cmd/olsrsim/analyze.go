@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kprusa/olsrsim"
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/trace"
+)
+
+// analyzeCommand implements the analyze subcommand, reporting the traffic of one or more traced runs side-by-side,
+// followed by the traffic of each node.
+func analyzeCommand(args []string) int {
+	fs := newFlagSet("analyze")
+	nodes := fs.Bool("nodes", true, "Report the traffic of each node of every run")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		return usageError("analyze", "at least one trace must be given")
+	}
+
+	traces := make([]*trace.Trace, 0, fs.NArg())
+	names := make([]string, 0, fs.NArg())
+	metrics := make([]controller.Metrics, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		t, err := readTrace(path)
+		if err != nil {
+			return fail("analyze", "%s", err)
+		}
+		traces = append(traces, t)
+		names = append(names, t.Protocol)
+		metrics = append(metrics, t.Metrics())
+	}
+	if err := olsrsim.WriteReport(os.Stdout, names, metrics); err != nil {
+		return fail("analyze", "unable to write report: %s", err)
+	}
+
+	if !*nodes {
+		return exitOK
+	}
+	for i, t := range traces {
+		fmt.Printf("\n%s (%s):\n", fs.Arg(i), t.Protocol)
+		if err := olsrsim.WriteNodeReport(os.Stdout, t.NodeStats()); err != nil {
+			return fail("analyze", "unable to write report: %s", err)
+		}
+	}
+	return exitOK
+}
+
+// readTrace reads the trace file at path.
+func readTrace(path string) (*trace.Trace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := trace.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
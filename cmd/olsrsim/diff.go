@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+
+	"github.com/kprusa/olsrsim"
+)
+
+// diffCommand implements the diff subcommand, reporting where the behavior of two traced runs diverged.
+func diffCommand(args []string) int {
+	fs := newFlagSet("diff")
+	window := fs.Int("window", 0, "Number of ticks nodes may differ for before they diverge, to tolerate jitter")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 2 {
+		return usageError("diff", "exactly two traces must be given")
+	}
+	if *window < 0 {
+		return usageError("diff", "invalid window: %d: must not be negative", *window)
+	}
+
+	a, err := readTrace(fs.Arg(0))
+	if err != nil {
+		return fail("diff", "%s", err)
+	}
+	b, err := readTrace(fs.Arg(1))
+	if err != nil {
+		return fail("diff", "%s", err)
+	}
+
+	// Nodes log the messages they ignore, which would be logged a second time.
+	log.SetOutput(io.Discard)
+	d, err := olsrsim.DiffTraces(a, b, *window)
+	if err != nil {
+		return fail("diff", "%s", err)
+	}
+	if err := olsrsim.WriteDiff(os.Stdout, fs.Arg(0), fs.Arg(1), d); err != nil {
+		return fail("diff", "unable to write report: %s", err)
+	}
+	return exitOK
+}
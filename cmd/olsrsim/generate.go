@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/kprusa/olsrsim"
+)
+
+// generateCommand implements the generate subcommand, writing a generated topology and node configuration.
+func generateCommand(args []string) int {
+	fs := newFlagSet("generate")
+	shape := fs.String("shape", "grid", "Topology shape: {grid | ring | line | star | geometric | erdos-renyi}")
+	nodes := fs.Int("n", 9, "Number of nodes. Ignored for grid topologies")
+	rows := fs.Int("rows", 3, "Number of grid rows")
+	cols := fs.Int("cols", 3, "Number of grid columns")
+	radius := fs.Float64("radius", 0.4, "Connection radius of geometric topologies, within a unit square")
+	prob := fs.Float64("p", 0.3, "Link probability of erdos-renyi topologies")
+	asym := fs.Float64("asym", 0, "Probability of a link only being available in one direction")
+	churn := fs.Float64("churn", 0, "Probability, per tick, of a link changing state")
+	duration := fs.Int("rt", 120, "Number of ticks over which link churn is scheduled")
+	delay := fs.Int("delay", 30, "Delay, in ticks, of each node's generated message")
+	seed := fs.Int64("seed", 1, "Random seed, making generation reproducible")
+	tf := fs.String("tf", "", "Topology output file path. Written to stdout if empty")
+	nf := fs.String("nf", "", "Node configuration output file path. Not written if empty")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError("generate", "unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	g, err := olsrsim.Generate(olsrsim.GeneratorConfig{
+		Shape:        olsrsim.Shape(*shape),
+		Nodes:        *nodes,
+		Rows:         *rows,
+		Cols:         *cols,
+		Radius:       *radius,
+		Probability:  *prob,
+		Asymmetry:    *asym,
+		ChurnRate:    *churn,
+		Duration:     *duration,
+		MessageDelay: *delay,
+		Seed:         *seed,
+	})
+	if err != nil {
+		return fail("generate", "%s", err)
+	}
+
+	if *tf == "" {
+		if err := g.WriteTopology(os.Stdout); err != nil {
+			return fail("generate", "unable to write topology: %s", err)
+		}
+	} else {
+		f, err := os.Create(*tf)
+		if err != nil {
+			return fail("generate", "unable to create topology file: %s", *tf)
+		}
+		if err := g.WriteTopology(f); err != nil {
+			_ = f.Close()
+			return fail("generate", "unable to write topology file: %s", err)
+		}
+		if err := f.Close(); err != nil {
+			return fail("generate", "could not close topology file: %s", err)
+		}
+	}
+
+	if *nf != "" {
+		f, err := os.Create(*nf)
+		if err != nil {
+			return fail("generate", "unable to create node configuration file: %s", *nf)
+		}
+		if err := g.WriteNodeConfiguration(f); err != nil {
+			_ = f.Close()
+			return fail("generate", "unable to write node configuration file: %s", err)
+		}
+		if err := f.Close(); err != nil {
+			return fail("generate", "could not close node configuration file: %s", err)
+		}
+	}
+	return exitOK
+}
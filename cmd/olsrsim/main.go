@@ -0,0 +1,148 @@
+// Command olsrsim runs, steps through, validates, generates and analyzes simulations of ad hoc routing protocols.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Exit codes shared by every subcommand, except validate, whose exit code reflects the problems it finds.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// command is a subcommand of olsrsim.
+type command struct {
+	name string
+
+	// args describes the positional arguments of the subcommand, and summary what it does.
+	args    string
+	summary string
+
+	// run runs the subcommand with its arguments, returning its exit code.
+	run func(args []string) int
+}
+
+// commands holds every subcommand, in the order they are listed in the usage.
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "run", summary: "Run a simulation, and report its traffic", run: runCommand},
+		{name: "sweep", summary: "Run a scenario over ranges of parameters, writing the metrics of each run as CSV", run: sweepCommand},
+		{name: "repl", summary: "Step through a simulation interactively, inspecting the tables of its nodes", run: replCommand},
+		{name: "validate", summary: "Report problems with topology and node configuration files", run: validateCommand},
+		{name: "generate", summary: "Generate topology and node configuration files", run: generateCommand},
+		{name: "analyze", args: "<trace>...", summary: "Report the traffic of traced runs side-by-side", run: analyzeCommand},
+		{name: "diff", args: "<trace> <trace>", summary: "Report where the behavior of two traced runs diverged", run: diffCommand},
+		{name: "view", args: "<trace>", summary: "Play a traced run back in a web browser", run: viewCommand},
+		{name: "replay", args: "<trace>", summary: "Reconstruct the tables of a traced run's nodes at a tick", run: replayCommand},
+		{name: "render", args: "<trace>", summary: "Render a traced run as a Graphviz DOT graph", run: renderCommand},
+	}
+}
+
+func main() {
+	os.Exit(dispatch(os.Args[1:]))
+}
+
+// dispatch runs the subcommand named by the first argument, returning its exit code. Arguments starting with a flag
+// run a simulation, as olsrsim did before it had subcommands.
+func dispatch(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return exitUsage
+	}
+	switch args[0] {
+	case "-h", "-help", "--help":
+		usage()
+		return exitOK
+	case "help":
+		if len(args) == 1 {
+			usage()
+			return exitOK
+		}
+		c, ok := lookup(args[1])
+		if !ok {
+			return usageError("help", "unknown command: %s", args[1])
+		}
+		return c.run([]string{"-h"})
+	}
+	if strings.HasPrefix(args[0], "-") {
+		return runCommand(args)
+	}
+
+	c, ok := lookup(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "olsrsim: unknown command: %s\n", args[0])
+		usage()
+		return exitUsage
+	}
+	return c.run(args[1:])
+}
+
+// lookup returns the subcommand named name.
+func lookup(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// usage writes the list of subcommands to stderr.
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: olsrsim <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Run 'olsrsim help <command>' for the flags of a command. Flags given without a command run a simulation.")
+}
+
+// newFlagSet creates the flag set of the named subcommand, whose usage lists its flags.
+func newFlagSet(name string) *flag.FlagSet {
+	c, _ := lookup(name)
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s\n\n%s.\n\nFlags:\n", strings.TrimSpace("olsrsim "+c.name+" [flags] "+c.args), c.summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the arguments of a subcommand. If parsing fails, or help was asked for, it returns false along with the
+// exit code the subcommand must return.
+func parse(fs *flag.FlagSet, args []string) (int, bool) {
+	err := fs.Parse(args)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK, false
+	case err != nil:
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// fail writes an error of the named subcommand to stderr, returning exitFailure.
+func fail(name string, format string, a ...any) int {
+	fmt.Fprintf(os.Stderr, "olsrsim %s: %s\n", name, fmt.Sprintf(format, a...))
+	return exitFailure
+}
+
+// usageError writes a usage error of the named subcommand to stderr, returning exitUsage.
+func usageError(name string, format string, a ...any) int {
+	fmt.Fprintf(os.Stderr, "olsrsim %s: %s\n", name, fmt.Sprintf(format, a...))
+	if c, ok := lookup(name); ok {
+		fmt.Fprintf(os.Stderr, "Run 'olsrsim help %s' for usage.\n", c.name)
+	}
+	return exitUsage
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+)
+
+// renderCommand implements the render subcommand, writing a traced run as a Graphviz DOT graph of the links messages
+// were delivered along.
+func renderCommand(args []string) int {
+	fs := newFlagSet("render")
+	from := fs.Int("from", 0, "First tick rendered")
+	to := fs.Int("to", -1, "Last tick rendered. Negative renders until the end of the trace")
+	out := fs.String("o", "", "Output file path. Written to stdout if empty")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return usageError("render", "exactly one trace must be given")
+	}
+
+	t, err := readTrace(fs.Arg(0))
+	if err != nil {
+		return fail("render", "%s", err)
+	}
+	if *out == "" {
+		if err := t.WriteDOT(os.Stdout, *from, *to); err != nil {
+			return fail("render", "unable to write graph: %s", err)
+		}
+		return exitOK
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return fail("render", "unable to create output file: %s", *out)
+	}
+	if err := t.WriteDOT(f, *from, *to); err != nil {
+		_ = f.Close()
+		return fail("render", "unable to write graph: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		return fail("render", "could not close output file: %s", err)
+	}
+	return exitOK
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kprusa/olsrsim"
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/repl"
+)
+
+// replCommand implements the repl subcommand, running a simulation a few ticks at a time, as commands read from stdin
+// ask, so that its nodes may be inspected in between.
+func replCommand(args []string) int {
+	fs := newFlagSet("repl")
+	sf := fs.String("s", "", "Scenario file path. Replaces -tf and -nf")
+	tf := fs.String("tf", "", "Topology file path (Required without -s)")
+	nf := fs.String("nf", "", "Node configuration file path (Required without -s)")
+	seed := fs.Int64("seed", 1, "Random seed, deciding which messages are lost on lossy links.")
+	protocol := fs.String("protocol", olsrsim.OLSR, "Routing protocol: {"+strings.Join(olsrsim.Protocols, " | ")+"}")
+	out := fs.String("out", "log", "Directory node logs are written within, in a subdirectory for the run and protocol")
+	runID := fs.String("run", "", "Name of the run's subdirectory of -out. Defaults to the time the run started")
+	noLog := fs.Bool("nolog", false, "Disable node log files")
+	verbose := fs.Bool("v", false, "Log every message sent and received")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError("repl", "unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *sf == "" && (*tf == "" || *nf == "") {
+		return usageError("repl", "-s, or both -tf and -nf, must be given")
+	}
+
+	s, err := loadScenario(*sf, *tf, *nf)
+	if err != nil {
+		return fail("repl", "%s", err)
+	}
+	// Flags given explicitly take precedence over the scenario.
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "seed":
+			s.Seed = seed
+		case "protocol":
+			s.Protocol = *protocol
+		}
+	})
+	if s.Protocol == "" {
+		s.Protocol = olsrsim.OLSR
+	}
+	if *runID == "" {
+		*runID = time.Now().Format("20060102-150405")
+	}
+	if !*noLog {
+		s.Logs = controller.DirLogs(filepath.Join(*out, *runID, s.Protocol))
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	c, err := s.Controller()
+	if err != nil {
+		return fail("repl", "invalid scenario: %s", err)
+	}
+	defer c.Close()
+	if err := repl.Run(os.Stdin, os.Stdout, c); err != nil {
+		return fail("repl", "%s", err)
+	}
+	return exitOK
+}
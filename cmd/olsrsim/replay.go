@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+
+	"github.com/kprusa/olsrsim"
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/repl"
+)
+
+// replayCommand implements the replay subcommand, reconstructing the tables of a traced run's nodes at a tick.
+func replayCommand(args []string) int {
+	fs := newFlagSet("replay")
+	tick := fs.Int("tick", -1, "Tick at the end of which the tables are reconstructed. Negative reconstructs them at the end of the trace")
+	node := fs.Int("node", -1, "Node whose tables are reconstructed. Negative reconstructs those of every node")
+	asJSON := fs.Bool("json", false, "Write the tables as JSON, one node per line")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return usageError("replay", "exactly one trace must be given")
+	}
+
+	t, err := readTrace(fs.Arg(0))
+	if err != nil {
+		return fail("replay", "%s", err)
+	}
+	if *tick < 0 && len(t.Events) > 0 {
+		*tick = t.Events[len(t.Events)-1].Tick
+	}
+
+	// Nodes log the messages they ignore, which would be logged a second time.
+	log.SetOutput(io.Discard)
+	states, err := olsrsim.Replay(t, *tick)
+	if err != nil {
+		return fail("replay", "%s", err)
+	}
+	if *node >= 0 {
+		var found []controller.NodeState
+		for _, s := range states {
+			if int(s.ID) == *node {
+				found = append(found, s)
+			}
+		}
+		if len(found) == 0 {
+			return fail("replay", "node %d is not in the trace", *node)
+		}
+		states = found
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for i, s := range states {
+		if *asJSON {
+			err = enc.Encode(s)
+		} else {
+			if i > 0 {
+				_, _ = os.Stdout.WriteString("\n")
+			}
+			err = repl.WriteState(os.Stdout, s)
+		}
+		if err != nil {
+			return fail("replay", "unable to write tables: %s", err)
+		}
+	}
+	return exitOK
+}
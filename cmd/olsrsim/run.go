@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kprusa/olsrsim"
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/invariant"
+	"github.com/kprusa/olsrsim/olsr"
+	"github.com/kprusa/olsrsim/server"
+	"github.com/kprusa/olsrsim/trace"
+	"github.com/kprusa/olsrsim/tui"
+)
+
+// runCommand implements the run subcommand, running a simulation once per protocol and reporting its traffic.
+func runCommand(args []string) int {
+	fs := newFlagSet("run")
+	sf := fs.String("s", "", "Scenario file path. Replaces -tf and -nf")
+	tf := fs.String("tf", "", "Topology file path (Required without -s)")
+	nf := fs.String("nf", "", "Node configuration file path (Required without -s)")
+	t := fs.Int("t", 1000, "Tick duration in milliseconds. Specifies how fast the simulation will Run. 0 runs as fast as possible")
+	d := fs.Int("rt", 120, "Number of ticks to Run the simulation for.")
+	seed := fs.Int64("seed", 1, "Random seed, deciding which messages are lost on lossy links.")
+	protocol := fs.String("protocol", olsrsim.OLSR, "Comma separated routing protocols to run, one after the other: {"+strings.Join(olsrsim.Protocols, " | ")+"}")
+	out := fs.String("out", "log", "Directory node logs are written within, in a subdirectory for each run and protocol")
+	runID := fs.String("run", "", "Name of the run's subdirectory of -out. Defaults to the time the run started")
+	noLog := fs.Bool("nolog", false, "Disable node log files")
+	tracePath := fs.String("trace", "", "Trace file path. Each protocol's path is suffixed with its name when several are run. Not written if empty")
+	dashboard := fs.Bool("tui", false, "Show a terminal dashboard of the run in place of the log of every message")
+	snapshot := fs.Int("snapshot", 0, "Interval, in ticks, between snapshots of every node's tables in the trace, for the view command. 0 takes none")
+	httpAddr := fs.String("http", "", "Loopback address, such as localhost:8080, to serve an HTTP API controlling and inspecting the run on. Not served if empty")
+	pause := fs.Bool("pause", false, "Start the run paused, until it is resumed through the HTTP API. Requires -http")
+	check := fs.Bool("check", false, "Check protocol invariants once each tick is over, reporting violations to stderr")
+	abort := fs.Bool("abort", false, "Stop a run at the end of the first tick an invariant does not hold. Requires -check")
+	defaults := olsr.DefaultParams()
+	hello := fs.Int("hello", defaults.HelloInterval, "HELLO interval in ticks.")
+	tc := fs.Int("tc", defaults.TCInterval, "TC interval in ticks.")
+	nhold := fs.Int("nhold", defaults.NeighborHoldTime, "Neighbor hold time in ticks.")
+	thold := fs.Int("thold", defaults.TopologyHoldTime, "Topology hold time in ticks.")
+	retry := fs.Int("retry", defaults.DataRetryInterval, "Interval, in ticks, between attempts to send a node's message when there is no route.")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError("run", "unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if *sf == "" && (*tf == "" || *nf == "") {
+		return usageError("run", "-s, or both -tf and -nf, must be given")
+	}
+	s, err := loadScenario(*sf, *tf, *nf)
+	if err != nil {
+		return fail("run", "%s", err)
+	}
+
+	// Flags given explicitly take precedence over the scenario.
+	protocols := []string{s.Protocol}
+	tickSet := false
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "t":
+			tickSet = true
+		case "rt":
+			s.Ticks = *d
+		case "seed":
+			s.Seed = seed
+		case "protocol":
+			protocols = strings.Split(*protocol, ",")
+		case "hello":
+			s.Params.HelloInterval = *hello
+		case "tc":
+			s.Params.TCInterval = *tc
+		case "nhold":
+			s.Params.NeighborHoldTime = *nhold
+		case "thold":
+			s.Params.TopologyHoldTime = *thold
+		case "retry":
+			s.Params.DataRetryInterval = *retry
+		}
+	})
+
+	if *snapshot < 0 {
+		return usageError("run", "-snapshot must not be negative")
+	}
+	if *httpAddr != "" {
+		if len(protocols) > 1 {
+			return usageError("run", "-http serves a single protocol's run")
+		}
+		if err := server.CheckLoopback(*httpAddr); err != nil {
+			return usageError("run", "invalid -http: %s", err)
+		}
+		if *dashboard {
+			return usageError("run", "-tui and -http cannot be used together")
+		}
+	} else if *pause {
+		return usageError("run", "-pause requires -http")
+	}
+	if *abort && !*check {
+		return usageError("run", "-abort requires -check")
+	}
+	if *dashboard {
+		log.SetOutput(io.Discard)
+	}
+
+	if *runID == "" {
+		*runID = time.Now().Format("20060102-150405")
+	}
+
+	metrics := make([]controller.Metrics, 0, len(protocols))
+	splits := make([][]olsrsim.SplitMetrics, 0, len(protocols))
+	violations := 0
+	for i, p := range protocols {
+		if p == "" {
+			p = olsrsim.OLSR
+			protocols[i] = p
+		}
+		s.Protocol = p
+		if !*noLog {
+			s.Logs = controller.DirLogs(filepath.Join(*out, *runID, p))
+		}
+		c, err := s.Controller()
+		if err != nil {
+			return fail("run", "invalid scenario: %s", err)
+		}
+		if tickSet {
+			c.SetTickDuration(time.Millisecond * time.Duration(*t))
+		}
+
+		var finish func() error
+		if *tracePath != "" {
+			path := *tracePath
+			if len(protocols) > 1 {
+				path = suffixed(path, p)
+			}
+			if finish, err = traceTo(path, c, s); err != nil {
+				return fail("run", "%s", err)
+			}
+			c.SetSnapshotInterval(*snapshot)
+		}
+		splitRecorder := olsrsim.NewSplitRecorder(c, s.Splits)
+		var checker *invariant.Checker
+		if *check {
+			// Violations would be drawn over by the dashboard, so they are reported once it is done.
+			var report func(v invariant.Violation)
+			if !*dashboard {
+				report = func(v invariant.Violation) { fmt.Fprintf(os.Stderr, "%s: %s\n", p, v) }
+			}
+			checker = invariant.New(c, report, *abort)
+		}
+		switch {
+		case *httpAddr != "":
+			if err := serve(*httpAddr, c, s.Duration(), *pause); err != nil {
+				return fail("run", "%s", err)
+			}
+		case *dashboard:
+			title := p
+			if s.Name != "" {
+				title = s.Name + " (" + p + ")"
+			}
+			if err := tui.New(os.Stdout, c, s.Duration(), tui.Options{Title: title}).Run(); err != nil {
+				return fail("run", "unable to draw dashboard: %s", err)
+			}
+		default:
+			c.Start(s.Duration())
+		}
+		if finish != nil {
+			if err := finish(); err != nil {
+				return fail("run", "%s", err)
+			}
+		}
+		if checker != nil {
+			if *dashboard {
+				for _, v := range checker.Violations() {
+					fmt.Fprintf(os.Stderr, "%s: %s\n", p, v)
+				}
+			}
+			violations += len(checker.Violations())
+			if c.Stopped() {
+				fmt.Fprintf(os.Stderr, "%s: run stopped at the end of tick %d\n", p, c.CurrentTick()-1)
+			}
+		}
+		metrics = append(metrics, c.Metrics())
+		splits = append(splits, splitRecorder.Metrics())
+	}
+	if err := olsrsim.WriteReport(os.Stdout, protocols, metrics); err != nil {
+		return fail("run", "unable to write report: %s", err)
+	}
+	if len(s.Splits) > 0 {
+		fmt.Println()
+		if err := olsrsim.WriteSplitReport(os.Stdout, protocols, splits); err != nil {
+			return fail("run", "unable to write report: %s", err)
+		}
+	}
+	if violations > 0 {
+		return fail("run", "%d invariant violations", violations)
+	}
+	return exitOK
+}
+
+// loadScenario loads the scenario file at sf or, if it is empty, creates a scenario from the topology file at tf and
+// the node configuration file at nf.
+func loadScenario(sf, tf, nf string) (*olsrsim.Scenario, error) {
+	if sf != "" {
+		s, err := olsrsim.LoadScenario(sf)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load scenario: %w", err)
+		}
+		return s, nil
+	}
+
+	f, err := os.Open(nf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open node configuration file: %s", nf)
+	}
+	configs, err := controller.ReadNodeConfiguration(f)
+	_ = f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("invalid node configuration file: %w", err)
+	}
+	return olsrsim.NewScenario(tf, configs)
+}
+
+// serve runs c for the given number of ticks, serving the HTTP API on addr until the run is over and every event
+// stream has ended.
+func serve(addr string, c *controller.Controller, ticks int, paused bool) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := server.New(c, ticks, paused)
+	hs := &http.Server{Handler: srv.Handler()}
+	go func() {
+		if err := hs.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("http: %s", err)
+		}
+	}()
+	log.Printf("serving the HTTP API on http://%s/api/", l.Addr())
+	srv.Run()
+
+	// Give event streams time to send the end of the run.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return hs.Shutdown(ctx)
+}
+
+// traceTo records the run of c, simulating s, to a trace file at path. The returned function completes the trace, and
+// must be called once the run is over.
+func traceTo(path string, c *controller.Controller, s *olsrsim.Scenario) (func() error, error) {
+	h, err := s.TraceHeader()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := trace.NewWriter(f, h)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	c.Observe(w)
+	return func() error {
+		if err := w.Flush(); err != nil {
+			_ = f.Close()
+			return err
+		}
+		return f.Close()
+	}, nil
+}
+
+// suffixed inserts suffix before the extension of path: run.trace becomes run.olsr.trace.
+func suffixed(path, suffix string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + suffix + ext
+}
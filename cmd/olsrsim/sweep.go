@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/kprusa/olsrsim"
+	"github.com/kprusa/olsrsim/controller"
+)
+
+// sweepCommand implements the sweep subcommand, running a scenario over every combination of parameter values in
+// parallel, and writing the metrics of each run as CSV.
+func sweepCommand(args []string) int {
+	fs := newFlagSet("sweep")
+	sf := fs.String("s", "", "Base scenario file path. Replaces -tf and -nf")
+	tf := fs.String("tf", "", "Base topology file path")
+	nf := fs.String("nf", "", "Base node configuration file path")
+	d := fs.Int("rt", 0, "Number of ticks to run each simulation for. Defaults to the scenario's")
+	protocols := fs.String("protocol", "", "Comma separated routing protocols: {"+strings.Join(olsrsim.Protocols, " | ")+"}")
+	hello := fs.String("hello", "", "HELLO intervals, in ticks, of OLSR, OLSRv2 and AODV nodes. A range such as 2..10, 2..10:2 or 2,5,10")
+	hold := fs.String("hold", "", "Neighbor hold times, in ticks, of OLSR and OLSRv2 nodes, and route hold times of DSDV nodes. A range")
+	nodes := fs.String("nodes", "", "Numbers of nodes of generated topologies, which replace the base topology. A range")
+	shape := fs.String("shape", string(olsrsim.Geometric), "Shape of generated topologies: {ring | line | star | geometric | erdos-renyi}")
+	radius := fs.Float64("radius", 0.4, "Connection radius of generated geometric topologies, within a unit square")
+	prob := fs.Float64("p", 0.3, "Link probability of generated erdos-renyi topologies")
+	delay := fs.Int("delay", 30, "Delay, in ticks, of each generated node's message")
+	loss := fs.String("loss", "", "Loss rates replacing that of every link. A range such as 0.1,0.2 or 0..0.5:0.1")
+	seeds := fs.String("seeds", "", "Random seeds. A range such as 1..20")
+	workers := fs.Int("j", 0, "Number of simulations run at once. Defaults to the number of CPUs")
+	out := fs.String("o", "", "CSV output file path. Written to stdout if empty")
+	verbose := fs.Bool("v", false, "Log every message sent and received")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError("sweep", "unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	s := &olsrsim.Sweep{Workers: *workers}
+	var err error
+	if *protocols != "" {
+		s.Protocols = strings.Split(*protocols, ",")
+	}
+	if s.HelloIntervals, err = parseRange(*hello, strconv.Atoi); err != nil {
+		return usageError("sweep", "invalid -hello: %s", err)
+	}
+	if s.HoldTimes, err = parseRange(*hold, strconv.Atoi); err != nil {
+		return usageError("sweep", "invalid -hold: %s", err)
+	}
+	if s.Nodes, err = parseRange(*nodes, strconv.Atoi); err != nil {
+		return usageError("sweep", "invalid -nodes: %s", err)
+	}
+	if s.Losses, err = parseRange(*loss, parseFloat); err != nil {
+		return usageError("sweep", "invalid -loss: %s", err)
+	}
+	if s.Seeds, err = parseRange(*seeds, parseInt64); err != nil {
+		return usageError("sweep", "invalid -seeds: %s", err)
+	}
+	if len(s.Nodes) > 0 {
+		s.Generator = &olsrsim.GeneratorConfig{
+			Shape:        olsrsim.Shape(*shape),
+			Radius:       *radius,
+			Probability:  *prob,
+			MessageDelay: *delay,
+			Seed:         1,
+		}
+	}
+
+	switch {
+	case *sf != "":
+		if s.Base, err = olsrsim.LoadScenario(*sf); err != nil {
+			return fail("sweep", "unable to load scenario: %s", err)
+		}
+	case *tf != "" && *nf != "":
+		f, err := os.Open(*nf)
+		if err != nil {
+			return fail("sweep", "unable to open node configuration file: %s", *nf)
+		}
+		configs, err := controller.ReadNodeConfiguration(f)
+		_ = f.Close()
+		if err != nil {
+			return fail("sweep", "invalid node configuration file: %s", err)
+		}
+		if s.Base, err = olsrsim.NewScenario(*tf, configs); err != nil {
+			return fail("sweep", "%s", err)
+		}
+	case *tf != "" || *nf != "":
+		return usageError("sweep", "both -tf and -nf must be given")
+	case len(s.Nodes) == 0:
+		return usageError("sweep", "-s, -tf and -nf, or -nodes must be given")
+	}
+	if s.Base != nil && *d != 0 {
+		s.Base.Ticks = *d
+	} else if s.Base == nil {
+		s.Base = &olsrsim.Scenario{Ticks: *d}
+	}
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+	results, err := s.Run()
+	if err != nil {
+		return fail("sweep", "%s", err)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return fail("sweep", "unable to create output file: %s", *out)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := olsrsim.WriteSweepCSV(w, results); err != nil {
+		return fail("sweep", "unable to write results: %s", err)
+	}
+	return exitOK
+}
+
+// parseRange parses a comma separated list of values and ranges, parsing each value with parse. A range is of the form
+// {FIRST}..{LAST}, or {FIRST}..{LAST}:{STEP}, and includes both ends. The step of a range of integers defaults to 1.
+// An empty list has no values.
+func parseRange[V int | int64 | float64](list string, parse func(string) (V, error)) ([]V, error) {
+	if list == "" {
+		return nil, nil
+	}
+	var values []V
+	for _, item := range strings.Split(list, ",") {
+		bounds, rawStep, stepped := strings.Cut(item, ":")
+		rawFirst, rawLast, isRange := strings.Cut(bounds, "..")
+		first, err := parse(rawFirst)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: '%s'", rawFirst)
+		}
+		if !isRange {
+			if stepped {
+				return nil, fmt.Errorf("step given without a range: '%s'", item)
+			}
+			values = append(values, first)
+			continue
+		}
+
+		last, err := parse(rawLast)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: '%s'", rawLast)
+		}
+		step := V(1)
+		if stepped {
+			if step, err = parse(rawStep); err != nil {
+				return nil, fmt.Errorf("invalid step: '%s'", rawStep)
+			}
+		} else if half := 0.5; V(half) != 0 {
+			return nil, fmt.Errorf("a range of fractions requires a step: '%s'", item)
+		}
+		if step <= 0 || last < first {
+			return nil, fmt.Errorf("range must increase by a positive step: '%s'", item)
+		}
+		// Count the values, rather than accumulate the step, so that fractional ranges include their last value.
+		n := int(float64(last-first)/float64(step)+1e-9) + 1
+		for i := 0; i < n; i++ {
+			values = append(values, first+V(i)*step)
+		}
+	}
+	return values, nil
+}
+
+func parseInt64(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
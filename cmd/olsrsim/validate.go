@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kprusa/olsrsim"
+)
+
+// validateCommand implements the validate subcommand, returning an exit code reflecting the most serious problem
+// found: 0 if there are none, 1 for warnings and 2 for errors. Files which cannot be read, and usage errors, are
+// reported as errors.
+func validateCommand(args []string) int {
+	fs := newFlagSet("validate")
+	tf := fs.String("tf", "", "Topology file path (Required)")
+	nf := fs.String("nf", "", "Node configuration file path")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError("validate", "unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *tf == "" {
+		return usageError("validate", "-tf must be given")
+	}
+
+	tfile, err := os.Open(*tf)
+	if err != nil {
+		fail("validate", "unable to open topology file: %s", *tf)
+		return int(olsrsim.Error)
+	}
+	defer tfile.Close()
+
+	var configs io.Reader
+	if *nf != "" {
+		f, err := os.Open(*nf)
+		if err != nil {
+			fail("validate", "unable to open node configuration file: %s", *nf)
+			return int(olsrsim.Error)
+		}
+		defer f.Close()
+		configs = f
+	}
+
+	r, err := olsrsim.Validate(*tf, tfile, *nf, configs)
+	if err != nil {
+		fail("validate", "unable to read input: %s", err)
+		return int(olsrsim.Error)
+	}
+	for _, p := range r.Problems {
+		fmt.Println(p)
+	}
+	return int(r.Severity())
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/kprusa/olsrsim/server"
+	"github.com/kprusa/olsrsim/viz"
+)
+
+// viewCommand implements the view subcommand, serving a web page playing a traced run back until interrupted.
+func viewCommand(args []string) int {
+	fs := newFlagSet("view")
+	addr := fs.String("addr", "localhost:8080", "Loopback address to serve the page on")
+	if code, ok := parse(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return usageError("view", "exactly one trace must be given")
+	}
+	if err := server.CheckLoopback(*addr); err != nil {
+		return usageError("view", "invalid -addr: %s", err)
+	}
+
+	t, err := readTrace(fs.Arg(0))
+	if err != nil {
+		return fail("view", "%s", err)
+	}
+	h, err := viz.Handler(t)
+	if err != nil {
+		return fail("view", "%s", err)
+	}
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fail("view", "%s", err)
+	}
+	log.Printf("playing %s back on http://%s/", fs.Arg(0), l.Addr())
+	if err := http.Serve(l, h); err != nil {
+		return fail("view", "%s", err)
+	}
+	return exitOK
+}
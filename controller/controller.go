@@ -0,0 +1,602 @@
+// Package controller simulates the wireless medium of an ad-hoc network, driving nodes and routing their messages
+// according to a topology.
+package controller
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/topology"
+)
+
+// Controller is aware of the entire network typology and acts as a wireless network.
+// Only used for the simulation (a real ad-hoc network would not have a centralized controller).
+//
+// The simulation runs in lockstep: every Router is ticked once per tick, and an envelope sent during a tick arrives
+// during the next one, plus the delay of the link it traverses.
+type Controller struct {
+	// topology represents the network topology for the given set of nodes.
+	topology *topology.Topology
+
+	// factory creates the Router of each node.
+	factory Factory
+
+	// routers holds the Router of every node in the network, in the order they were configured.
+	routers []Router
+
+	// order holds every configured node, in the order they were configured, whether or not it is in the network.
+	order []message.NodeID
+
+	// configs holds the configuration of each node, to create a new Router when it joins or restarts.
+	configs map[message.NodeID]NodeConfig
+
+	// down holds the nodes which are DOWN.
+	down map[message.NodeID]bool
+
+	// sequences holds the sequence numbers of each node which went DOWN, for it to restart with if asked to.
+	sequences map[message.NodeID]map[string]int
+
+	// joinErrors holds the error of each node whose Router could not be created when it was to join the network.
+	joinErrors map[message.NodeID]error
+
+	// messages holds the data each node sends, by node.
+	messages map[message.NodeID]*NodeMessage
+
+	// newLogs creates the logs of each node. Nodes are not logged if it is nil.
+	newLogs Logs
+
+	// logs holds the logs of each node.
+	logs map[message.NodeID]NodeLogs
+
+	// inFlight holds the envelopes in transit, by the tick they arrive at.
+	inFlight map[int][]delivery
+
+	// tick is the current tick of the simulation.
+	tick int
+
+	// tickDuration controls how quickly the simulation runs. Zero runs the simulation as fast as possible.
+	tickDuration time.Duration
+
+	// rng decides which messages are lost on lossy links.
+	rng *rand.Rand
+
+	// metrics records the traffic of the simulation.
+	metrics *metricsRecorder
+
+	// observers are notified of every event of the simulation.
+	observers []Observer
+
+	// snapshotInterval is the number of ticks between snapshots of the state of every node. No snapshots are taken
+	// if it is zero.
+	snapshotInterval int
+
+	// stopped is set once the simulation is stopped before its end.
+	stopped bool
+}
+
+// delivery is an envelope in transit to a node.
+type delivery struct {
+	to  message.NodeID
+	env message.Envelope
+}
+
+// Seed seeds the random source used to decide which messages are lost on lossy links.
+func (c *Controller) Seed(seed int64) {
+	c.rng = rand.New(rand.NewSource(seed))
+}
+
+// SetLogs sets how the logs of each node are created. Nodes are not logged if logs is nil, as is the default.
+// It must be called before Initialize.
+func (c *Controller) SetLogs(logs Logs) {
+	c.newLogs = logs
+}
+
+// Initialize creates a Router, using the Controller's Factory, for each of the supplied configurations which joins
+// the network at tick 0. The Routers of the other nodes are created once they join. An error is returned if any
+// Router cannot be created, if a node is to leave the network before it joins it, or if its message is due while it
+// is not in the network, when it could never be sent.
+func (c *Controller) Initialize(nodes []NodeConfig) error {
+	for _, config := range nodes {
+		if config.Join < 0 || config.Leave < 0 || (config.Leave > 0 && config.Leave <= config.Join) {
+			return fmt.Errorf("node %d: must leave after it joins: join %d, leave %d", config.ID, config.Join, config.Leave)
+		}
+		if !config.Message.Sent && !config.Present(config.Message.Delay) {
+			return fmt.Errorf("node %d: sends its message at tick %d, while it is not in the network: join %d, leave %d",
+				config.ID, config.Message.Delay, config.Join, config.Leave)
+		}
+		if config.Join == 0 {
+			r, err := c.factory(config)
+			if err != nil {
+				return fmt.Errorf("node %d: %w", config.ID, err)
+			}
+			c.routers = append(c.routers, r)
+		}
+		if c.newLogs != nil {
+			l, err := c.newLogs(config.ID)
+			if err != nil {
+				return fmt.Errorf("node %d: unable to create logs: %w", config.ID, err)
+			}
+			c.logs[config.ID] = l
+		}
+		c.order = append(c.order, config.ID)
+		c.configs[config.ID] = config
+		msg := config.Message
+		c.messages[config.ID] = &msg
+	}
+	return nil
+}
+
+// Routers returns the routers of the nodes in the network, in the order they were configured: nodes which have yet to
+// join it, or which left it, have none. The routers must not be inspected while the Controller is running.
+func (c *Controller) Routers() []Router {
+	return c.routers
+}
+
+// Start runs the simulation for the given number of ticks, or until it is stopped, then closes the logs of every node
+// which are io.Closers.
+func (c *Controller) Start(ticks int) {
+	var pace <-chan time.Time
+	if c.tickDuration > 0 {
+		ticker := time.NewTicker(c.tickDuration)
+		defer ticker.Stop()
+		pace = ticker.C
+	}
+
+	for i := 0; i < ticks && !c.stopped; i++ {
+		c.Step()
+		if pace != nil {
+			<-pace
+		}
+	}
+	c.Close()
+	log.Println("done.")
+}
+
+// Stop stops the simulation before its end: Start, and anything else running it, returns once the current tick is
+// over. It must be called from the goroutine running the simulation, such as by an Observer.
+func (c *Controller) Stop() {
+	c.stopped = true
+}
+
+// Stopped reports whether the simulation was stopped.
+func (c *Controller) Stopped() bool {
+	return c.stopped
+}
+
+// Close closes the logs of every node which are io.Closers. Nodes are no longer logged once it has been called.
+func (c *Controller) Close() {
+	for id, l := range c.logs {
+		if err := l.Close(); err != nil {
+			log.Printf("node %d: could not close logs: %s", id, err)
+		}
+	}
+	c.logs = make(map[message.NodeID]NodeLogs)
+}
+
+// CurrentTick is the tick the simulation runs next.
+func (c *Controller) CurrentTick() int {
+	return c.tick
+}
+
+// Send hands data to a node to send to dst, as if the node's configuration had asked it to at the current tick. An
+// error is returned if the node is not in the network or is DOWN. It must not be called while the Controller is
+// running.
+func (c *Controller) Send(src, dst message.NodeID, data string) error {
+	r, ok := c.router(src)
+	if !ok {
+		return c.absent(src)
+	}
+	if c.down[src] {
+		return fmt.Errorf("node %d is down", src)
+	}
+	c.emit(Event{Tick: c.tick, Kind: EventOriginate, Node: src, Originated: &Originated{Destination: dst, Data: data}})
+	r.Send(dst, data)
+	return nil
+}
+
+// SetLink changes the state of the link from one node to another from the current tick onward, until the next
+// transition the topology already schedules for it. It must not be called while the Controller is running.
+func (c *Controller) SetLink(from, to message.NodeID, up bool, attrs topology.LinkAttributes) {
+	status := topology.LinkStatus(topology.DOWN)
+	if up {
+		status = topology.UP
+	}
+	c.topology.Set(topology.LinkState{Time: c.tick, Status: status, From: from, To: to, Attrs: attrs})
+}
+
+// Inspect returns a snapshot of a node's tables. Nodes whose Router is not an Inspector only report their routes, and
+// nodes which are DOWN report none. An error is returned if the node is not in the network. It must not be called
+// while the Controller is running.
+func (c *Controller) Inspect(id message.NodeID) (NodeState, error) {
+	r, ok := c.router(id)
+	if !ok {
+		return NodeState{}, c.absent(id)
+	}
+	if c.down[id] {
+		return NodeState{ID: id, Down: true}, nil
+	}
+	if i, ok := r.(Inspector); ok {
+		return i.Inspect(), nil
+	}
+	return NodeState{ID: id, Tick: c.tick, Routes: r.Routes()}, nil
+}
+
+// router returns the Router of a node.
+func (c *Controller) router(id message.NodeID) (Router, bool) {
+	if i := c.index(id); i >= 0 {
+		return c.routers[i], true
+	}
+	return nil, false
+}
+
+// index returns the index of a node's Router within routers, or -1 if the node does not exist.
+func (c *Controller) index(id message.NodeID) int {
+	for i, r := range c.routers {
+		if r.ID() == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// absent describes why a node has no Router.
+func (c *Controller) absent(id message.NodeID) error {
+	config, ok := c.configs[id]
+	switch {
+	case !ok:
+		return fmt.Errorf("node %d does not exist", id)
+	case c.tick < config.Join:
+		return fmt.Errorf("node %d has not joined the network yet: it joins at tick %d", id, config.Join)
+	case c.joinErrors[id] != nil:
+		return fmt.Errorf("node %d could not join the network at tick %d: %w", id, config.Join, c.joinErrors[id])
+	default:
+		return fmt.Errorf("node %d left the network at tick %d", id, config.Leave)
+	}
+}
+
+// membership adds the nodes joining the network at the current tick, with a new Router each, and removes those
+// leaving it, emitting an EventJoin or EventLeave for each. Nodes of the initial network joined during Initialize.
+func (c *Controller) membership() {
+	joins := func(config NodeConfig) bool { return config.Join > 0 && config.Join == c.tick }
+	leaves := func(config NodeConfig) bool { return config.Leave > 0 && config.Leave == c.tick }
+	changed := false
+	for _, config := range c.configs {
+		changed = changed || joins(config) || leaves(config)
+	}
+	if !changed {
+		return
+	}
+
+	current := make(map[message.NodeID]Router, len(c.routers))
+	for _, r := range c.routers {
+		current[r.ID()] = r
+	}
+	routers := make([]Router, 0, len(c.order))
+	for _, id := range c.order {
+		config := c.configs[id]
+		r, in := current[id]
+		switch {
+		case in && leaves(config):
+			delete(c.down, id)
+			delete(c.sequences, id)
+			c.emit(Event{Tick: c.tick, Kind: EventLeave, Node: id})
+			continue
+		case !in && joins(config):
+			var err error
+			if r, err = c.factory(config); err != nil {
+				log.Printf("node %d: could not join: %s", id, err)
+				c.joinErrors[id] = err
+				continue
+			}
+			c.emit(Event{Tick: c.tick, Kind: EventJoin, Node: id})
+		case !in:
+			continue
+		}
+		routers = append(routers, r)
+	}
+	c.routers = routers
+}
+
+// setNode brings a node DOWN, or back UP with a new Router, emitting an EventNode if its state changes. A node
+// restarting with Persist continues from the sequence numbers it had when it went DOWN, if its Router is a Persister.
+// Events of nodes which are not in the network are ignored.
+func (c *Controller) setNode(e topology.NodeEvent) {
+	i := c.index(e.Node)
+	up := e.Status == topology.UP
+	if i < 0 || c.down[e.Node] != up {
+		return
+	}
+
+	if !up {
+		if p, ok := c.routers[i].(Persister); ok {
+			c.sequences[e.Node] = p.Sequences()
+		}
+		c.down[e.Node] = true
+		c.emit(Event{Tick: c.tick, Kind: EventNode, Node: e.Node, NodeChange: &NodeChange{}})
+		return
+	}
+
+	r, err := c.factory(c.configs[e.Node])
+	if err != nil {
+		log.Printf("node %d: could not restart: %s", e.Node, err)
+		return
+	}
+	if p, ok := r.(Persister); ok && e.Persist && c.sequences[e.Node] != nil {
+		p.RestoreSequences(c.sequences[e.Node])
+	}
+	c.routers[i] = r
+	delete(c.down, e.Node)
+	c.emit(Event{Tick: c.tick, Kind: EventNode, Node: e.Node, NodeChange: &NodeChange{Up: true, Persist: e.Persist}})
+}
+
+// Metrics returns the traffic of the simulation so far.
+// It must not be called while the Controller is running.
+func (c *Controller) Metrics() Metrics {
+	return c.metrics.snapshot()
+}
+
+// Observe adds an Observer to be notified of every event of the simulation. It must be called before Start.
+func (c *Controller) Observe(o Observer) {
+	c.observers = append(c.observers, o)
+}
+
+// SetTickDuration changes how quickly the simulation runs. Zero runs the simulation as fast as possible.
+// It must be called before Start.
+func (c *Controller) SetTickDuration(d time.Duration) {
+	c.tickDuration = d
+}
+
+// SetSnapshotInterval asks for the state of every node to be recorded, as an EventState, at the end of every given
+// number of ticks, starting with the first. Zero, the default, takes no snapshots. It must be called before Start.
+func (c *Controller) SetSnapshotInterval(ticks int) {
+	c.snapshotInterval = ticks
+}
+
+// TickDuration is how long each tick lasts when the simulation is started.
+func (c *Controller) TickDuration() time.Duration {
+	return c.tickDuration
+}
+
+// Step runs a single tick of the simulation.
+func (c *Controller) Step() {
+	c.emit(Event{Tick: c.tick, Kind: EventTick})
+	for _, ls := range c.topology.Changes(c.tick) {
+		lc := &LinkChange{From: ls.From, To: ls.To, Up: ls.Status == topology.UP}
+		if lc.Up {
+			lc.Loss, lc.Delay = ls.Attrs.Loss, ls.Attrs.Delay
+		}
+		c.emit(Event{Tick: c.tick, Kind: EventLink, Node: ls.From, Link: lc})
+	}
+	c.membership()
+	for _, e := range c.topology.NodeEvents(c.tick) {
+		c.setNode(e)
+	}
+	inbox := make(map[message.NodeID][]message.Envelope)
+	for _, d := range c.inFlight[c.tick] {
+		inbox[d.to] = append(inbox[d.to], d.env)
+	}
+	delete(c.inFlight, c.tick)
+
+	for _, r := range c.routers {
+		id := r.ID()
+		// Nodes which are DOWN neither receive nor send, and the data they were to send is never sent.
+		if c.down[id] {
+			continue
+		}
+		var sent []message.Envelope
+		for _, env := range inbox[id] {
+			c.received(id, env)
+			sent = append(sent, r.Receive(env)...)
+		}
+
+		if msg := c.messages[id]; !msg.Sent && msg.Delay == c.tick {
+			c.emit(Event{Tick: c.tick, Kind: EventOriginate, Node: id, Originated: &Originated{Destination: msg.Destination, Data: msg.Message}})
+			r.Send(msg.Destination, msg.Message)
+			msg.Sent = true
+		}
+		sent = append(sent, r.Tick()...)
+
+		for _, env := range sent {
+			env.From = id
+			c.send(env)
+		}
+	}
+	c.tick++
+
+	// Snapshots are taken once the tick is over, as nodes are inspected between ticks.
+	if t := c.tick - 1; c.snapshotInterval > 0 && t%c.snapshotInterval == 0 {
+		for _, r := range c.routers {
+			state, _ := c.Inspect(r.ID())
+			c.emit(Event{Tick: t, Kind: EventState, Node: r.ID(), State: &state})
+		}
+	}
+	for _, o := range c.observers {
+		if to, ok := o.(TickObserver); ok {
+			to.TickOver(c.tick - 1)
+		}
+	}
+}
+
+// emit records an event, and notifies every Observer of it.
+func (c *Controller) emit(e Event) {
+	c.metrics.Event(e)
+	for _, o := range c.observers {
+		o.Event(e)
+	}
+}
+
+// received logs an envelope delivered to a node.
+func (c *Controller) received(id message.NodeID, env message.Envelope) {
+	log.Printf("node %d: received:\t%s\n", id, env)
+	c.emit(Event{Tick: c.tick, Kind: EventReceive, Node: id, Envelope: &env})
+	l := c.logs[id]
+	if l.Input != nil {
+		if _, err := fmt.Fprintln(l.Input, env); err != nil {
+			log.Printf("node %d: could not write input log: %s", id, err)
+		}
+	}
+	if dm, ok := env.Message.(*message.DataMessage); ok && dm.Destination == id && l.Received != nil {
+		if _, err := fmt.Fprintln(l.Received, dm.Data); err != nil {
+			log.Printf("node %d: could not write received log: %s", id, err)
+		}
+	}
+}
+
+// send logs an envelope sent by a node, and transmits it to every node it is addressed to which has a link with the
+// sender that is UP.
+func (c *Controller) send(env message.Envelope) {
+	log.Printf("node %d: Sent:\t%s", env.From, env)
+	c.emit(Event{Tick: c.tick, Kind: EventSend, Node: env.From, Envelope: &env})
+	if out := c.logs[env.From].Output; out != nil {
+		if _, err := fmt.Fprintln(out, env); err != nil {
+			log.Printf("node %d: could not write output log: %s", env.From, err)
+		}
+	}
+
+	if env.To != message.Broadcast {
+		c.transmit(env.To, env)
+		return
+	}
+	for _, r := range c.routers {
+		if r.ID() != env.From {
+			c.transmit(r.ID(), env)
+		}
+	}
+}
+
+// transmit sends env to a node along the link from the sender, if the link is UP.
+// The link's attributes determine whether the envelope is lost, and how long it takes to arrive.
+func (c *Controller) transmit(to message.NodeID, env message.Envelope) {
+	attrs, up := c.topology.Link(topology.QueryMsg{FromNode: env.From, ToNode: to, AtTime: c.tick})
+	if !up {
+		return
+	}
+	if attrs.Loss > 0 && c.rng.Float64() < attrs.Loss {
+		c.emit(Event{Tick: c.tick, Kind: EventLost, Node: to, Envelope: &env})
+		return
+	}
+	at := c.tick + 1 + attrs.Delay
+	c.inFlight[at] = append(c.inFlight[at], delivery{to: to, env: env})
+}
+
+// New creates a Controller based on the supplied network topology, whose nodes are created by factory.
+func New(topology *topology.Topology, tickDuration time.Duration, factory Factory) *Controller {
+	c := &Controller{}
+	c.topology = topology
+	c.factory = factory
+	c.messages = make(map[message.NodeID]*NodeMessage)
+	c.configs = make(map[message.NodeID]NodeConfig)
+	c.down = make(map[message.NodeID]bool)
+	c.sequences = make(map[message.NodeID]map[string]int)
+	c.joinErrors = make(map[message.NodeID]error)
+	c.logs = make(map[message.NodeID]NodeLogs)
+	c.inFlight = make(map[int][]delivery)
+	c.tickDuration = tickDuration
+	c.rng = rand.New(rand.NewSource(1))
+	c.metrics = newMetricsRecorder()
+	return c
+}
+
+// NodeMessage is data sent by a node after the specified Delay.
+type NodeMessage struct {
+	Message     string
+	Delay       int
+	Destination message.NodeID
+
+	// Sent is set once the data has been handed to the node's Router.
+	Sent bool
+}
+
+// NodeConfig is used for the creation of nodes by a Controller.
+type NodeConfig struct {
+	ID      message.NodeID
+	Message NodeMessage
+
+	// Join is the tick the node joins the network at, with empty tables. Nodes join at tick 0 by default.
+	Join int
+
+	// Leave, if positive, is the tick the node leaves the network at, never to come back. It must be after Join.
+	Leave int
+}
+
+// Present reports whether the node is in the network at tick, having joined it and not yet left it.
+func (config NodeConfig) Present(tick int) bool {
+	return tick >= config.Join && (config.Leave <= 0 || tick < config.Leave)
+}
+
+// nodeConfigRe matches a single node configuration line.
+var nodeConfigRe = regexp.MustCompile(`^(?P<Source>\d+) (?P<Destination>\d+) (?P<Message>".*?") (?P<Delay>\d+)(?: join=(?P<Join>\d+))?(?: leave=(?P<Leave>\d+))?$`)
+
+// ReadNodeConfiguration parses newline separated node configurations from an io.ReadCloser.
+// Configurations should be in the form: {Source} {Destination} "{Message}" {Delay} [join={Tick}] [leave={Tick}]
+func ReadNodeConfiguration(in io.Reader) ([]NodeConfig, error) {
+	configs := make([]NodeConfig, 0)
+	s := bufio.NewScanner(in)
+	num := 0
+	for s.Scan() {
+		num++
+		c, err := ParseNodeConfig(s.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", num, err)
+		}
+		configs = append(configs, *c)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// ParseNodeConfig parses a single node configuration line.
+func ParseNodeConfig(line string) (*NodeConfig, error) {
+	matches := nodeConfigRe.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, fmt.Errorf("invalid node config: must be of the form: '{SRC} {DST} \"{MSG}\" {DELAY} [join={TICK}] [leave={TICK}]': %s", line)
+	}
+
+	id, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid node config: ID is not an int: %s", line)
+	}
+	dst, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid node config: Destination is not an int: %s", line)
+	}
+	delay, err := strconv.Atoi(matches[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid node config: Delay is not an int: %s", line)
+	}
+	var join, leave int
+	if matches[5] != "" {
+		if join, err = strconv.Atoi(matches[5]); err != nil {
+			return nil, fmt.Errorf("invalid node config: join is not an int: %s", line)
+		}
+	}
+	if matches[6] != "" {
+		if leave, err = strconv.Atoi(matches[6]); err != nil {
+			return nil, fmt.Errorf("invalid node config: leave is not an int: %s", line)
+		}
+		if leave <= join {
+			return nil, fmt.Errorf("invalid node config: must leave after it joins: %s", line)
+		}
+	}
+
+	return &NodeConfig{
+		ID: message.NodeID(id),
+		Message: NodeMessage{
+			Message:     matches[3][1 : len(matches[3])-1],
+			Delay:       delay,
+			Destination: message.NodeID(dst),
+			Sent:        false,
+		},
+		Join:  join,
+		Leave: leave,
+	}, nil
+}
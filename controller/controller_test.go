@@ -0,0 +1,770 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/topology"
+)
+
+func TestReadNodeConfiguration(t *testing.T) {
+	type args struct {
+		in io.ReadCloser
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []NodeConfig
+		wantErr bool
+	}{
+		{
+			name: "working",
+			args: args{in: io.NopCloser(strings.NewReader("0 2 \"(0 -> 2)\" 30\n"))},
+			want: []NodeConfig{
+				{
+					ID: 0,
+					Message: NodeMessage{
+						Message:     "(0 -> 2)",
+						Delay:       30,
+						Destination: 2,
+						Sent:        false,
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "last line without newline",
+			args: args{in: io.NopCloser(strings.NewReader("0 2 \"(0 -> 2)\" 30\n1 4 \"(1 -> 4)\" 140"))},
+			want: []NodeConfig{
+				{
+					ID: 0,
+					Message: NodeMessage{
+						Message:     "(0 -> 2)",
+						Delay:       30,
+						Destination: 2,
+						Sent:        false,
+					},
+				},
+				{
+					ID: 1,
+					Message: NodeMessage{
+						Message:     "(1 -> 4)",
+						Delay:       140,
+						Destination: 4,
+						Sent:        false,
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "join and leave",
+			args: args{in: io.NopCloser(strings.NewReader("0 2 \"(0 -> 2)\" 30 join=10\n1 4 \"(1 -> 4)\" 140 join=40 leave=90\n2 0 \"(2 -> 0)\" 5 leave=60\n"))},
+			want: []NodeConfig{
+				{ID: 0, Message: NodeMessage{Message: "(0 -> 2)", Delay: 30, Destination: 2}, Join: 10},
+				{ID: 1, Message: NodeMessage{Message: "(1 -> 4)", Delay: 140, Destination: 4}, Join: 40, Leave: 90},
+				{ID: 2, Message: NodeMessage{Message: "(2 -> 0)", Delay: 5, Destination: 0}, Leave: 60},
+			},
+		},
+		{
+			name:    "leave before join",
+			args:    args{in: io.NopCloser(strings.NewReader("0 2 \"(0 -> 2)\" 30 join=10 leave=10\n"))},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "leave then join",
+			args:    args{in: io.NopCloser(strings.NewReader("0 2 \"(0 -> 2)\" 30 leave=20 join=10\n"))},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "invalid line",
+			args:    args{in: io.NopCloser(strings.NewReader("0 2 \"(0 -> 2)\" 30\n0 2 (0 -> 2) 30\n"))},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadNodeConfiguration(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadNodeConfiguration() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadNodeConfiguration() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// FuzzReadNodeConfiguration checks node configurations are read back as they are written. Inputs of more than 8 lines,
+// or 512 bytes, are skipped: they drive no new code, and minimizing them stalls the fuzzer.
+func FuzzReadNodeConfiguration(f *testing.F) {
+	for _, seed := range []string{"0 2 \"(0 -> 2)\" 30\n1 4 \"(1 -> 4)\" 140 join=40 leave=90", "0 2 (0 -> 2) 30\n", "0 2 \"a\" \"b\" 3", "", "\n"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		if len(in) > 512 || strings.Count(in, "\n") > 8 {
+			return
+		}
+		configs, err := ReadNodeConfiguration(strings.NewReader(in))
+		if err != nil {
+			return
+		}
+		// Configurations are written back in the form they are read in, as generate writes them.
+		var b strings.Builder
+		for _, c := range configs {
+			fmt.Fprintf(&b, "%d %d \"%s\" %d", c.ID, c.Message.Destination, c.Message.Message, c.Message.Delay)
+			if c.Join > 0 {
+				fmt.Fprintf(&b, " join=%d", c.Join)
+			}
+			if c.Leave > 0 {
+				fmt.Fprintf(&b, " leave=%d", c.Leave)
+			}
+			b.WriteString("\n")
+		}
+		again, err := ReadNodeConfiguration(strings.NewReader(b.String()))
+		if err != nil {
+			t.Fatalf("ReadNodeConfiguration(%q) error = %v, reading back %q", b.String(), err, in)
+		}
+		if !reflect.DeepEqual(again, configs) {
+			t.Fatalf("ReadNodeConfiguration(%q) got = %v, want %v", b.String(), again, configs)
+		}
+	})
+}
+
+// testMessage is a message only understood by testRouter.
+type testMessage string
+
+func (m testMessage) Type() string {
+	return "TEST"
+}
+
+func (m testMessage) String() string {
+	return string(m)
+}
+
+// testRouter sends a scripted envelope at given ticks, and records everything it is given.
+type testRouter struct {
+	id       message.NodeID
+	tick     int
+	script   map[int]message.Envelope
+	received []received
+	data     []string
+}
+
+// received is an envelope received by a testRouter, along with the tick it was received at.
+type received struct {
+	tick int
+	env  message.Envelope
+}
+
+func (r *testRouter) ID() message.NodeID {
+	return r.id
+}
+
+func (r *testRouter) Receive(env message.Envelope) []message.Envelope {
+	r.received = append(r.received, received{tick: r.tick, env: env})
+	return nil
+}
+
+func (r *testRouter) Send(_ message.NodeID, data string) {
+	r.data = append(r.data, data)
+}
+
+func (r *testRouter) Tick() []message.Envelope {
+	defer func() { r.tick++ }()
+	if env, in := r.script[r.tick]; in {
+		return []message.Envelope{env}
+	}
+	return nil
+}
+
+func (r *testRouter) Routes() []Route {
+	return nil
+}
+
+func TestController_Start(t *testing.T) {
+	hello := message.Envelope{To: message.Broadcast, Message: testMessage("hello")}
+	tests := []struct {
+		name   string
+		links  []string
+		script map[int]message.Envelope
+		want1  []received
+		want2  []received
+	}{
+		{
+			name:   "broadcast arrives next tick",
+			links:  []string{"0 UP 0 <-> 1", "0 UP 0 2"},
+			script: map[int]message.Envelope{1: hello},
+			want1:  []received{{tick: 2, env: message.Envelope{From: 0, To: message.Broadcast, Message: testMessage("hello")}}},
+			want2:  []received{{tick: 2, env: message.Envelope{From: 0, To: message.Broadcast, Message: testMessage("hello")}}},
+		},
+		{
+			name:   "unicast only reaches destination",
+			links:  []string{"0 UP 0 1", "0 UP 0 2"},
+			script: map[int]message.Envelope{0: {To: 2, Message: testMessage("hi")}},
+			want2:  []received{{tick: 1, env: message.Envelope{From: 0, To: 2, Message: testMessage("hi")}}},
+		},
+		{
+			name:   "link down",
+			links:  []string{"0-2 UP 0 1"},
+			script: map[int]message.Envelope{2: hello},
+		},
+		{
+			name:   "delayed link",
+			links:  []string{"0 UP 0 1 delay=3"},
+			script: map[int]message.Envelope{0: hello},
+			want1:  []received{{tick: 4, env: message.Envelope{From: 0, To: message.Broadcast, Message: testMessage("hello")}}},
+		},
+		{
+			name:   "lossy link",
+			links:  []string{"0 UP 0 1 loss=1"},
+			script: map[int]message.Envelope{0: hello},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nwt, err := topology.Read(strings.NewReader(strings.Join(tt.links, "\n")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			routers := map[message.NodeID]*testRouter{
+				0: {id: 0, script: tt.script},
+				1: {id: 1},
+				2: {id: 2},
+			}
+			c := New(nwt, 0, func(config NodeConfig) (Router, error) {
+				return routers[config.ID], nil
+			})
+			err = c.Initialize([]NodeConfig{
+				{ID: 0, Message: NodeMessage{Message: "data", Delay: 3, Destination: 2}},
+				{ID: 1, Message: NodeMessage{Sent: true}},
+				{ID: 2, Message: NodeMessage{Sent: true}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Start(6)
+
+			if !reflect.DeepEqual(routers[1].received, tt.want1) {
+				t.Errorf("node 1 received %v, want %v", routers[1].received, tt.want1)
+			}
+			if !reflect.DeepEqual(routers[2].received, tt.want2) {
+				t.Errorf("node 2 received %v, want %v", routers[2].received, tt.want2)
+			}
+			if want := []string{"data"}; !reflect.DeepEqual(routers[0].data, want) {
+				t.Errorf("node 0 was given data %v, want %v", routers[0].data, want)
+			}
+		})
+	}
+}
+
+func TestController_Initialize(t *testing.T) {
+	c := New(topology.New(nil), 0, func(config NodeConfig) (Router, error) {
+		return nil, errors.New("invalid")
+	})
+	if err := c.Initialize([]NodeConfig{{ID: 0}}); err == nil {
+		t.Errorf("Initialize() error = nil, want error")
+	}
+	if err := c.Initialize([]NodeConfig{{ID: 1, Join: 20, Leave: 10}}); err == nil {
+		t.Errorf("Initialize() error = nil for a node leaving before it joins, want error")
+	}
+
+	// Nodes joining later are not created by Initialize, so that only their messages are checked.
+	tests := []struct {
+		name    string
+		config  NodeConfig
+		wantErr bool
+	}{
+		{name: "message due at join", config: NodeConfig{ID: 2, Message: NodeMessage{Delay: 20}, Join: 20, Leave: 30}},
+		{name: "no message", config: NodeConfig{ID: 3, Message: NodeMessage{Sent: true}, Join: 20}},
+		{name: "message due before join", config: NodeConfig{ID: 4, Message: NodeMessage{Delay: 10}, Join: 20}, wantErr: true},
+		{name: "message due at leave", config: NodeConfig{ID: 5, Message: NodeMessage{Delay: 30}, Join: 20, Leave: 30}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.Initialize([]NodeConfig{tt.config}); (err != nil) != tt.wantErr {
+				t.Errorf("Initialize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestController_Metrics(t *testing.T) {
+	nwt, err := topology.Read(strings.NewReader("0 UP 0 <-> 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := &message.DataMessage{Source: 0, Destination: 1, NextHop: 1, FromNeighbor: 0, Data: "data"}
+	routers := map[message.NodeID]*testRouter{
+		0: {id: 0, script: map[int]message.Envelope{
+			0: {To: message.Broadcast, Message: testMessage("hello")},
+			3: {To: 1, Message: data},
+			4: {To: 1, Message: data},
+		}},
+		1: {id: 1},
+	}
+	c := New(nwt, 0, func(config NodeConfig) (Router, error) {
+		return routers[config.ID], nil
+	})
+	err = c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Message: "data", Delay: 2, Destination: 1}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := &eventLog{}
+	c.Observe(events)
+	c.Start(6)
+
+	want := Metrics{
+		Ticks:          6,
+		Sent:           map[string]int{"TEST": 1, "DATA": 2},
+		Received:       map[string]int{"TEST": 1, "DATA": 2},
+		DataOriginated: 1,
+		DataDelivered:  1,
+		Latency:        2,
+	}
+	got := c.Metrics()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Metrics() got = %v, want %v", got, want)
+	}
+	if got.ControlSent() != 1 || got.TotalSent() != 3 || got.DeliveryRatio() != 1 || got.MeanLatency() != 2 {
+		t.Errorf("Metrics() got control sent %d, total sent %d, delivery ratio %v, mean latency %v", got.ControlSent(), got.TotalSent(), got.DeliveryRatio(), got.MeanLatency())
+	}
+	if got := ComputeMetrics(events.events); !reflect.DeepEqual(got, want) {
+		t.Errorf("ComputeMetrics() got = %v, want %v", got, want)
+	}
+}
+
+// eventLog is an Observer recording every event.
+type eventLog struct {
+	events []Event
+}
+
+func (l *eventLog) Event(e Event) {
+	l.events = append(l.events, e)
+}
+
+func TestController_Observe(t *testing.T) {
+	nwt, err := topology.Read(strings.NewReader("0 UP 0 1\n0 UP 0 2 loss=1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hello := message.Envelope{From: 0, To: message.Broadcast, Message: testMessage("hello")}
+	routers := map[message.NodeID]*testRouter{
+		0: {id: 0, script: map[int]message.Envelope{0: hello}},
+		1: {id: 1},
+		2: {id: 2},
+	}
+	c := New(nwt, 0, func(config NodeConfig) (Router, error) {
+		return routers[config.ID], nil
+	})
+	err = c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Message: "data", Delay: 1, Destination: 1}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+		{ID: 2, Message: NodeMessage{Sent: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := &eventLog{}
+	c.Observe(events)
+	c.SetSnapshotInterval(2)
+	c.Start(2)
+
+	want := []Event{
+		{Tick: 0, Kind: EventTick},
+		{Tick: 0, Kind: EventLink, Node: 0, Link: &LinkChange{From: 0, To: 1, Up: true}},
+		{Tick: 0, Kind: EventLink, Node: 0, Link: &LinkChange{From: 0, To: 2, Up: true, Loss: 1}},
+		{Tick: 0, Kind: EventSend, Node: 0, Envelope: &hello},
+		{Tick: 0, Kind: EventLost, Node: 2, Envelope: &hello},
+		{Tick: 0, Kind: EventState, Node: 0, State: &NodeState{ID: 0, Tick: 1}},
+		{Tick: 0, Kind: EventState, Node: 1, State: &NodeState{ID: 1, Tick: 1}},
+		{Tick: 0, Kind: EventState, Node: 2, State: &NodeState{ID: 2, Tick: 1}},
+		{Tick: 1, Kind: EventTick},
+		{Tick: 1, Kind: EventOriginate, Node: 0, Originated: &Originated{Destination: 1, Data: "data"}},
+		{Tick: 1, Kind: EventReceive, Node: 1, Envelope: &hello},
+	}
+	if !reflect.DeepEqual(events.events, want) {
+		t.Errorf("Observe() got events %v, want %v", events.events, want)
+	}
+}
+
+// closeBuffer is a bytes.Buffer recording whether it was closed.
+type closeBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestController_SetLogs(t *testing.T) {
+	nwt, err := topology.Read(strings.NewReader("0 UP 0 <-> 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := &message.DataMessage{Source: 0, Destination: 1, NextHop: 1, FromNeighbor: 0, Data: "data"}
+	routers := map[message.NodeID]*testRouter{
+		0: {id: 0, script: map[int]message.Envelope{1: {To: 1, Message: data}}},
+		1: {id: 1},
+	}
+	c := New(nwt, 0, func(config NodeConfig) (Router, error) {
+		return routers[config.ID], nil
+	})
+	logs := make(map[message.NodeID][]*closeBuffer)
+	c.SetLogs(func(id message.NodeID) (NodeLogs, error) {
+		l := []*closeBuffer{{}, {}, {}}
+		logs[id] = l
+		return NodeLogs{Output: l[0], Input: l[1], Received: l[2]}, nil
+	})
+	err = c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Sent: true}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Start(3)
+
+	env := message.Envelope{From: 0, To: 1, Message: data}.String() + "\n"
+	want := map[message.NodeID][]string{
+		0: {env, "", ""},
+		1: {"", env, "data\n"},
+	}
+	for id, l := range logs {
+		for i, b := range l {
+			if got := b.String(); got != want[id][i] {
+				t.Errorf("node %d log %d = %q, want %q", id, i, got, want[id][i])
+			}
+			if !b.closed {
+				t.Errorf("node %d log %d was not closed", id, i)
+			}
+		}
+	}
+}
+
+func TestDirLogs(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run", "olsr")
+	l, err := DirLogs(dir)(3)
+	if err != nil {
+		t.Fatalf("DirLogs() error = %v", err)
+	}
+	if _, err := fmt.Fprintln(l.Received, "data"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	for _, name := range []string{"3_in.txt", "3_out.txt", "3_received.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("DirLogs() did not create %s: %v", name, err)
+		}
+	}
+	if got, err := os.ReadFile(filepath.Join(dir, "3_received.txt")); err != nil || string(got) != "data\n" {
+		t.Errorf("received log = %q, %v, want %q", got, err, "data\n")
+	}
+}
+
+func TestController_Step(t *testing.T) {
+	nwt, err := topology.Read(strings.NewReader("0 UP 0 <-> 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hello := message.Envelope{To: message.Broadcast, Message: testMessage("hello")}
+	routers := map[message.NodeID]*testRouter{
+		0: {id: 0, script: map[int]message.Envelope{0: hello, 2: hello}},
+		1: {id: 1},
+	}
+	c := New(nwt, 0, func(config NodeConfig) (Router, error) {
+		return routers[config.ID], nil
+	})
+	err = c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Sent: true}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Step()
+	c.Step()
+	// The link goes down before the second hello is sent.
+	c.SetLink(0, 1, false, topology.LinkAttributes{})
+	if err := c.Send(0, 1, "data"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	c.Step()
+	c.Step()
+
+	if got := c.CurrentTick(); got != 4 {
+		t.Errorf("CurrentTick() = %d, want 4", got)
+	}
+	if got := len(routers[1].received); got != 1 {
+		t.Errorf("node 1 received %d envelopes, want 1", got)
+	}
+	if want := []string{"data"}; !reflect.DeepEqual(routers[0].data, want) {
+		t.Errorf("node 0 was given data %v, want %v", routers[0].data, want)
+	}
+	if got := c.Metrics().DataOriginated; got != 1 {
+		t.Errorf("Metrics().DataOriginated = %d, want 1", got)
+	}
+	if err := c.Send(5, 1, "data"); err == nil {
+		t.Errorf("Send() error = nil for an unknown node, want error")
+	}
+
+	got, err := c.Inspect(1)
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	if want := (NodeState{ID: 1, Tick: 4}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Inspect() = %+v, want %+v", got, want)
+	}
+	if _, err := c.Inspect(5); err == nil {
+		t.Errorf("Inspect() error = nil for an unknown node, want error")
+	}
+}
+
+// seqRouter is a testRouter whose sequence number increases every tick.
+type seqRouter struct {
+	testRouter
+	seq int
+}
+
+func (r *seqRouter) Tick() []message.Envelope {
+	r.seq++
+	return r.testRouter.Tick()
+}
+
+func (r *seqRouter) Sequences() map[string]int {
+	return map[string]int{"seq": r.seq}
+}
+
+func (r *seqRouter) RestoreSequences(seqs map[string]int) {
+	r.seq = seqs["seq"]
+}
+
+func TestController_nodeEvents(t *testing.T) {
+	hello := message.Envelope{To: message.Broadcast, Message: testMessage("hello")}
+	tests := []struct {
+		name    string
+		persist string
+		wantSeq int
+	}{
+		{name: "restart", wantSeq: 2},
+		{name: "restart with persisted sequences", persist: " PERSIST", wantSeq: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nwt, err := topology.Read(strings.NewReader("0 UP 0 <-> 1\n2-4 NODE DOWN 1" + tt.persist + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			created := make(map[message.NodeID][]*seqRouter)
+			c := New(nwt, 0, func(config NodeConfig) (Router, error) {
+				r := &seqRouter{testRouter: testRouter{id: config.ID}}
+				if config.ID == 0 {
+					r.script = map[int]message.Envelope{0: hello, 1: hello, 2: hello, 3: hello, 4: hello}
+				}
+				created[config.ID] = append(created[config.ID], r)
+				return r, nil
+			})
+			err = c.Initialize([]NodeConfig{
+				{ID: 0, Message: NodeMessage{Sent: true}},
+				{ID: 1, Message: NodeMessage{Message: "data", Delay: 3, Destination: 0}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			events := &eventLog{}
+			c.Observe(events)
+
+			for i := 0; i < 3; i++ {
+				c.Step()
+			}
+			if got, err := c.Inspect(1); err != nil || !reflect.DeepEqual(got, NodeState{ID: 1, Down: true}) {
+				t.Errorf("Inspect() = %+v, %v while the node is down, want it down", got, err)
+			}
+			if err := c.Send(1, 0, "data"); err == nil {
+				t.Errorf("Send() error = nil while the node is down, want error")
+			}
+			for i := 0; i < 3; i++ {
+				c.Step()
+			}
+
+			routers := created[1]
+			if len(routers) != 2 {
+				t.Fatalf("node 1 was created %d times, want 2", len(routers))
+			}
+			// The hello sent at tick 1 arrives once the node is down, and the data it was to send while down is not
+			// sent.
+			if got := len(routers[0].received); got != 1 {
+				t.Errorf("node 1 received %d envelopes before going down, want 1", got)
+			}
+			if got := len(routers[1].received); got != 2 {
+				t.Errorf("node 1 received %d envelopes after restarting, want 2", got)
+			}
+			if len(routers[0].data) != 0 || len(routers[1].data) != 0 {
+				t.Errorf("node 1 was given data while down")
+			}
+			if got := routers[1].seq; got != tt.wantSeq {
+				t.Errorf("node 1 sequence = %d after restarting, want %d", got, tt.wantSeq)
+			}
+
+			var got []Event
+			for _, e := range events.events {
+				if e.Kind == EventNode {
+					got = append(got, e)
+				}
+			}
+			want := []Event{
+				{Tick: 2, Kind: EventNode, Node: 1, NodeChange: &NodeChange{}},
+				{Tick: 4, Kind: EventNode, Node: 1, NodeChange: &NodeChange{Up: true, Persist: tt.persist != ""}},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("node events = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestController_membership(t *testing.T) {
+	hello := message.Envelope{To: message.Broadcast, Message: testMessage("hello")}
+	nwt, err := topology.Read(strings.NewReader("0 UP 0 <-> 1\n0 UP 1 <-> 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := make(map[message.NodeID]*testRouter)
+	c := New(nwt, 0, func(config NodeConfig) (Router, error) {
+		r := &testRouter{id: config.ID}
+		if config.ID == 1 {
+			r.script = map[int]message.Envelope{0: hello, 1: hello, 2: hello}
+		}
+		created[config.ID] = r
+		return r, nil
+	})
+	err = c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Sent: true}, Join: 2},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+		{ID: 2, Message: NodeMessage{Sent: true}, Leave: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := &eventLog{}
+	c.Observe(events)
+
+	ids := func() []message.NodeID {
+		var ids []message.NodeID
+		for _, r := range c.Routers() {
+			ids = append(ids, r.ID())
+		}
+		return ids
+	}
+	if got, want := ids(), []message.NodeID{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Routers() = %v before node 0 joined, want %v", got, want)
+	}
+	if _, err := c.Inspect(0); err == nil {
+		t.Errorf("Inspect() error = nil before node 0 joined, want error")
+	}
+	if _, in := created[0]; in {
+		t.Errorf("node 0 was created before it joined")
+	}
+
+	for i := 0; i < 3; i++ {
+		c.Step()
+	}
+	// Node 0 joined at tick 2, in its configured place.
+	if got, want := ids(), []message.NodeID{0, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Routers() = %v after node 0 joined, want %v", got, want)
+	}
+	c.Step()
+	if got, want := ids(), []message.NodeID{0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Routers() = %v after node 2 left, want %v", got, want)
+	}
+	if err := c.Send(2, 1, "data"); err == nil {
+		t.Errorf("Send() error = nil after node 2 left, want error")
+	}
+	c.Step()
+
+	// Node 0 only hears the hello sent once it joined, and node 2 runs until it leaves.
+	if got := len(created[0].received); got != 1 {
+		t.Errorf("node 0 received %d envelopes, want 1", got)
+	}
+	if got := created[0].tick; got != 3 {
+		t.Errorf("node 0 ran %d ticks, want 3", got)
+	}
+	if got := created[2].tick; got != 3 {
+		t.Errorf("node 2 ran %d ticks, want 3", got)
+	}
+
+	var got []Event
+	for _, e := range events.events {
+		if e.Kind == EventJoin || e.Kind == EventLeave {
+			got = append(got, e)
+		}
+	}
+	want := []Event{{Tick: 2, Kind: EventJoin, Node: 0}, {Tick: 3, Kind: EventLeave, Node: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("membership events = %+v, want %+v", got, want)
+	}
+}
+
+func TestController_absent(t *testing.T) {
+	nwt, err := topology.Read(strings.NewReader("0 UP 0 <-> 1\n0 UP 1 <-> 2\n0 UP 1 <-> 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(nwt, 0, func(config NodeConfig) (Router, error) {
+		if config.ID == 3 {
+			return nil, errors.New("invalid params")
+		}
+		return &testRouter{id: config.ID}, nil
+	})
+	err = c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Sent: true}, Join: 5},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+		{ID: 2, Message: NodeMessage{Sent: true}, Leave: 2},
+		{ID: 3, Message: NodeMessage{Sent: true}, Join: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		c.Step()
+	}
+
+	tests := []struct {
+		id   message.NodeID
+		want string
+	}{
+		{id: 0, want: "node 0 has not joined the network yet: it joins at tick 5"},
+		{id: 2, want: "node 2 left the network at tick 2"},
+		{id: 3, want: "node 3 could not join the network at tick 1: invalid params"},
+		{id: 9, want: "node 9 does not exist"},
+	}
+	for _, tt := range tests {
+		if _, err := c.Inspect(tt.id); err == nil || err.Error() != tt.want {
+			t.Errorf("Inspect(%d) error = %v, want %s", tt.id, err, tt.want)
+		}
+		if err := c.Send(tt.id, 1, "data"); err == nil || err.Error() != tt.want {
+			t.Errorf("Send(%d) error = %v, want %s", tt.id, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"github.com/kprusa/olsrsim/message"
+)
+
+// EventKind is the kind of an Event.
+type EventKind string
+
+// Kinds of Event recorded by the Controller.
+const (
+	// EventTick starts each tick, before any envelope is delivered.
+	EventTick EventKind = "tick"
+
+	// EventOriginate records data being handed to a node to send.
+	EventOriginate EventKind = "originate"
+
+	// EventSend records a node sending an envelope. A broadcast is a single event.
+	EventSend EventKind = "send"
+
+	// EventReceive records an envelope being delivered to a node.
+	EventReceive EventKind = "receive"
+
+	// EventLost records an envelope being lost on a lossy link to a node.
+	EventLost EventKind = "lost"
+
+	// EventLink records a link changing state, at the start of the tick it takes effect, after EventTick. The links
+	// of the initial topology change state at tick 0.
+	EventLink EventKind = "link"
+
+	// EventJoin records a node joining the network with empty tables, at the start of the tick it joins at, after
+	// every EventLink of the tick. The nodes of the initial network join it at tick 0 without an event.
+	EventJoin EventKind = "join"
+
+	// EventLeave records a node leaving the network for good, at the start of the tick it leaves at, after every
+	// EventLink of the tick.
+	EventLeave EventKind = "leave"
+
+	// EventNode records a node going DOWN, as if it crashed, or coming back UP with empty tables, as if it restarted,
+	// at the start of the tick it takes effect, after every EventJoin and EventLeave of the tick.
+	EventNode EventKind = "node"
+
+	// EventState records a snapshot of a node's tables, at the end of a tick. Snapshots are only taken if the
+	// Controller is asked to.
+	EventState EventKind = "state"
+)
+
+// Event is something which happened during a simulation. Together, the events of a run are a trace of it, from which
+// its Metrics may be recomputed.
+type Event struct {
+	Tick int       `json:"tick"`
+	Kind EventKind `json:"kind"`
+
+	// Node is the node the event happened at: the sender of a sent envelope, the receiver of a received or lost one,
+	// the source of originated data, the source of a link, the node joining, leaving, going DOWN or UP, and the node
+	// whose state was recorded. It is unset for EventTick.
+	Node message.NodeID `json:"node"`
+
+	// Envelope is the envelope sent, received or lost.
+	Envelope *message.Envelope `json:"envelope,omitempty"`
+
+	// Originated is the data handed to the node, for EventOriginate.
+	Originated *Originated `json:"originated,omitempty"`
+
+	// Link is the new state of the link, for EventLink.
+	Link *LinkChange `json:"link,omitempty"`
+
+	// NodeChange is the new state of the node, for EventNode.
+	NodeChange *NodeChange `json:"nodeChange,omitempty"`
+
+	// State is the snapshot of the node, for EventState.
+	State *NodeState `json:"state,omitempty"`
+}
+
+// Originated is data handed to a node to send.
+type Originated struct {
+	Destination message.NodeID `json:"destination"`
+	Data        string         `json:"data"`
+}
+
+// LinkChange is the new state of a link.
+type LinkChange struct {
+	From message.NodeID `json:"from"`
+	To   message.NodeID `json:"to"`
+	Up   bool           `json:"up"`
+
+	// Loss and Delay are the attributes of a link which is UP.
+	Loss  float64 `json:"loss,omitempty"`
+	Delay int     `json:"delay,omitempty"`
+}
+
+// NodeChange is the new state of a node.
+type NodeChange struct {
+	Up bool `json:"up"`
+
+	// Persist is set for a node restarting with the sequence numbers it had when it went DOWN.
+	Persist bool `json:"persist,omitempty"`
+}
+
+// Observer is notified of every Event of a simulation, in the order they happen.
+type Observer interface {
+	Event(e Event)
+}
+
+// TickObserver is implemented by Observers which are also notified once each tick is over, when nodes may be
+// inspected.
+type TickObserver interface {
+	Observer
+	TickOver(tick int)
+}
+
+// ComputeMetrics summarizes the traffic of a simulation from its events. The result is the same as the Metrics of the
+// Controller which recorded them.
+func ComputeMetrics(events []Event) Metrics {
+	m := newMetricsRecorder()
+	for _, e := range events {
+		m.Event(e)
+	}
+	return m.snapshot()
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kprusa/olsrsim/message"
+)
+
+// NodeLogs are where the traffic of a node is logged, one line per message. A nil writer discards what would be
+// written to it.
+type NodeLogs struct {
+	// Output is where every envelope the node sends is written.
+	Output io.Writer
+
+	// Input is where every envelope delivered to the node is written.
+	Input io.Writer
+
+	// Received is where the data delivered to the node, as its destination, is written.
+	Received io.Writer
+}
+
+// Close closes every log which is an io.Closer, returning the first error.
+func (l NodeLogs) Close() error {
+	var firstErr error
+	for _, w := range []io.Writer{l.Input, l.Output, l.Received} {
+		if c, ok := w.(io.Closer); ok {
+			if err := c.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Logs creates the NodeLogs of a node.
+type Logs func(id message.NodeID) (NodeLogs, error)
+
+// DirLogs returns Logs creating three files for each node within dir, which is created if it does not exist:
+// {ID}_in.txt, {ID}_out.txt and {ID}_received.txt.
+func DirLogs(dir string) Logs {
+	return func(id message.NodeID) (NodeLogs, error) {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return NodeLogs{}, err
+		}
+		var l NodeLogs
+		for _, f := range []struct {
+			w    *io.Writer
+			name string
+		}{
+			{w: &l.Input, name: "in"},
+			{w: &l.Output, name: "out"},
+			{w: &l.Received, name: "received"},
+		} {
+			file, err := os.Create(filepath.Join(dir, fmt.Sprintf("%d_%s.txt", id, f.name)))
+			if err != nil {
+				_ = l.Close()
+				return NodeLogs{}, err
+			}
+			*f.w = file
+		}
+		return l, nil
+	}
+}
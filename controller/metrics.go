@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"github.com/kprusa/olsrsim/message"
+)
+
+// Metrics summarize the traffic of a simulation, so that protocols may be compared on the same scenario.
+type Metrics struct {
+	// Ticks is the number of ticks simulated.
+	Ticks int `json:"ticks"`
+
+	// Sent counts the envelopes sent by nodes, by message type. A broadcast counts once, however many nodes
+	// receive it.
+	Sent map[string]int `json:"sent"`
+
+	// Received counts the envelopes delivered to nodes, by message type.
+	Received map[string]int `json:"received"`
+
+	// DataOriginated counts the data handed to nodes to send.
+	DataOriginated int `json:"dataOriginated"`
+
+	// DataDelivered counts the data which reached its destination. Data delivered more than once counts once.
+	DataDelivered int `json:"dataDelivered"`
+
+	// Latency is the total number of ticks between data being handed to a node and reaching its destination, over
+	// all delivered data.
+	Latency int `json:"latency"`
+}
+
+// ControlSent counts the envelopes sent by nodes which do not carry data.
+func (m Metrics) ControlSent() int {
+	total := 0
+	for t, n := range m.Sent {
+		if t != "DATA" {
+			total += n
+		}
+	}
+	return total
+}
+
+// TotalSent counts every envelope sent by nodes, whether it carries data or not. Protocols which flood data have
+// no control overhead, but send far more data.
+func (m Metrics) TotalSent() int {
+	total := 0
+	for _, n := range m.Sent {
+		total += n
+	}
+	return total
+}
+
+// DeliveryRatio is the fraction of originated data which reached its destination, or 0 if no data was originated.
+func (m Metrics) DeliveryRatio() float64 {
+	if m.DataOriginated == 0 {
+		return 0
+	}
+	return float64(m.DataDelivered) / float64(m.DataOriginated)
+}
+
+// MeanLatency is the mean number of ticks taken to deliver data, or 0 if no data was delivered.
+func (m Metrics) MeanLatency() float64 {
+	if m.DataDelivered == 0 {
+		return 0
+	}
+	return float64(m.Latency) / float64(m.DataDelivered)
+}
+
+// dataKey identifies data handed to a node to send.
+type dataKey struct {
+	source      message.NodeID
+	destination message.NodeID
+	data        string
+}
+
+// metricsRecorder records Metrics from the events of a simulation.
+type metricsRecorder struct {
+	Metrics
+
+	// originated holds the tick each piece of data was handed to its source at.
+	originated map[dataKey]int
+
+	// delivered holds the data which has reached its destination.
+	delivered map[dataKey]bool
+}
+
+func newMetricsRecorder() *metricsRecorder {
+	return &metricsRecorder{
+		Metrics: Metrics{
+			Sent:     make(map[string]int),
+			Received: make(map[string]int),
+		},
+		originated: make(map[dataKey]int),
+		delivered:  make(map[dataKey]bool),
+	}
+}
+
+// Event records the traffic of e.
+func (m *metricsRecorder) Event(e Event) {
+	switch e.Kind {
+	case EventTick:
+		m.Ticks = e.Tick + 1
+	case EventOriginate:
+		m.originate(e.Tick, e.Node, *e.Originated)
+	case EventSend:
+		m.Sent[e.Envelope.Message.Type()]++
+	case EventReceive:
+		m.receive(e.Tick, e.Node, *e.Envelope)
+	}
+}
+
+// originate records data being handed to a node at tick.
+func (m *metricsRecorder) originate(tick int, source message.NodeID, o Originated) {
+	k := dataKey{source: source, destination: o.Destination, data: o.Data}
+	if _, in := m.originated[k]; in {
+		return
+	}
+	m.originated[k] = tick
+	m.DataOriginated++
+}
+
+// receive records an envelope being delivered to a node at tick.
+func (m *metricsRecorder) receive(tick int, to message.NodeID, env message.Envelope) {
+	m.Received[env.Message.Type()]++
+
+	dm, ok := env.Message.(*message.DataMessage)
+	if !ok || dm.Destination != to {
+		return
+	}
+	k := dataKey{source: dm.Source, destination: dm.Destination, data: dm.Data}
+	start, in := m.originated[k]
+	if !in || m.delivered[k] {
+		return
+	}
+	m.delivered[k] = true
+	m.DataDelivered++
+	m.Latency += tick - start
+}
+
+// snapshot returns a copy of the recorded Metrics.
+func (m *metricsRecorder) snapshot() Metrics {
+	s := m.Metrics
+	s.Sent = make(map[string]int, len(m.Sent))
+	for t, n := range m.Sent {
+		s.Sent[t] = n
+	}
+	s.Received = make(map[string]int, len(m.Received))
+	for t, n := range m.Received {
+		s.Received[t] = n
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"github.com/kprusa/olsrsim/message"
+)
+
+// Router is a node running a routing protocol. The Controller drives every Router one tick at a time: it delivers the
+// envelopes that arrived during the tick, hands over any data the node starts sending, and then ticks the Router.
+// Envelopes returned by a Router are transmitted by the Controller according to the topology.
+//
+// A Router is only ever called by one goroutine at a time, so it needs no synchronization of its own.
+type Router interface {
+	// ID is the node's address.
+	ID() message.NodeID
+
+	// Receive handles an envelope delivered to the node, returning the envelopes the node sends in response.
+	// Envelopes carrying messages the protocol does not understand must be ignored.
+	Receive(env message.Envelope) []message.Envelope
+
+	// Send asks the node to deliver data to the destination. The protocol decides when, and whether, the data is sent.
+	Send(dst message.NodeID, data string)
+
+	// Tick advances the node's clock by one tick, returning the envelopes the node sends during the tick.
+	Tick() []message.Envelope
+
+	// Routes returns the node's current routes, sorted by destination.
+	Routes() []Route
+}
+
+// Persister is implemented by Routers which can keep the sequence numbers they originate messages with across a
+// restart, so that their neighbors do not mistake new messages for old ones.
+type Persister interface {
+	// Sequences returns the sequence numbers the Router originates messages with, by name.
+	Sequences() map[string]int
+
+	// RestoreSequences continues from sequence numbers returned by the Sequences of a previous Router of the node.
+	RestoreSequences(seqs map[string]int)
+}
+
+// Route is a route from a node to a destination.
+type Route struct {
+	// Destination is the node the route leads to.
+	Destination message.NodeID `json:"destination"`
+
+	// NextHop is the neighbor messages for the destination are sent to.
+	NextHop message.NodeID `json:"nextHop"`
+
+	// Distance is the number of hops to the destination.
+	Distance int `json:"distance"`
+}
+
+// Factory creates the Router of a node.
+type Factory func(config NodeConfig) (Router, error)
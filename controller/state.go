@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"github.com/kprusa/olsrsim/message"
+)
+
+// NodeState is a snapshot of what a node knows of the network, in a form common to every protocol, so that nodes can
+// be inspected while a simulation is paused. Protocols fill in the tables they keep. All slices are sorted by ID.
+type NodeState struct {
+	ID message.NodeID `json:"id"`
+
+	// Tick is the number of ticks the node has been running for, since it last restarted.
+	Tick int `json:"tick"`
+
+	// Down is set for a node which went DOWN, and whose tables are therefore empty.
+	Down bool `json:"down,omitempty"`
+
+	// Neighbors are the node's symmetric one-hop neighbors.
+	Neighbors []message.NodeID `json:"neighbors"`
+
+	TwoHopNeighbors []TwoHopNeighbor `json:"twoHopNeighbors,omitempty"`
+
+	// MPRs are the neighbors the node selected as multipoint relays, to flood its broadcasts.
+	MPRs []message.NodeID `json:"mprs,omitempty"`
+
+	// MPRSelectors are the neighbors which selected the node as a multipoint relay.
+	MPRSelectors []message.NodeID `json:"mprSelectors,omitempty"`
+
+	// Topology holds the links the node learnt of from other nodes' advertisements.
+	Topology []TopologyLink `json:"topology,omitempty"`
+
+	Routes []Route `json:"routes"`
+}
+
+// TwoHopNeighbor is a node reachable through a one-hop neighbor.
+type TwoHopNeighbor struct {
+	ID  message.NodeID `json:"id"`
+	Via message.NodeID `json:"via"`
+}
+
+// TopologyLink is a link advertised by From, to To.
+type TopologyLink struct {
+	From message.NodeID `json:"from"`
+	To   message.NodeID `json:"to"`
+}
+
+// Inspector is implemented by Routers whose tables can be inspected. Inspect must not be called while the Router is
+// being driven by the Controller.
+type Inspector interface {
+	Inspect() NodeState
+}
@@ -0,0 +1,359 @@
+package olsrsim
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/trace"
+)
+
+// TraceDiff compares two traced runs tick by tick, to find where their behavior diverged.
+type TraceDiff struct {
+	// Ticks is the number of ticks compared, those both traces cover.
+	Ticks int
+
+	// Nodes compares each node taking part in both runs, sorted by ID.
+	Nodes []NodeDiff
+
+	// A and B summarize the traffic of each run.
+	A, B controller.Metrics
+}
+
+// NodeDiff holds where the behavior of a node first diverged between two runs. Each divergence is nil if the node
+// behaved the same in both throughout.
+type NodeDiff struct {
+	ID message.NodeID
+
+	// MPRs is the first divergence of the MPRs the node selected.
+	MPRs *Divergence
+
+	// Routes is the first divergence of the node's routing table.
+	Routes *Divergence
+
+	// Messages is the first divergence of the number of messages of each type the node had sent.
+	Messages *Divergence
+}
+
+// First returns the earliest divergence of the node, or nil if it behaved the same in both runs.
+func (d NodeDiff) First() *Divergence {
+	var first *Divergence
+	for _, div := range []*Divergence{d.MPRs, d.Routes, d.Messages} {
+		if div != nil && (first == nil || div.Tick < first.Tick) {
+			first = div
+		}
+	}
+	return first
+}
+
+// Divergence is the first tick at the end of which a node differed between two runs.
+type Divergence struct {
+	Tick int
+
+	// What names what differed: mprs, routes or messages.
+	What string
+
+	// A and B describe what differed, as it was in each run at the tick.
+	A, B string
+}
+
+// DiffTraces compares two traced runs tick by tick, reporting the first divergence of each node. The state of the
+// nodes is reconstructed at the end of each tick, as Replay does.
+//
+// A node only diverges once it differs for longer than window ticks: its state in one run at a tick must match its
+// state in the other at some tick within window of it, and the number of messages of each type it sent likewise. A
+// window of 0 compares runs which are meant to be identical, while a larger window tolerates runs whose nodes act a
+// few ticks apart, such as runs with jitter.
+func DiffTraces(a, b *trace.Trace, window int) (*TraceDiff, error) {
+	if window < 0 {
+		return nil, fmt.Errorf("diff: invalid window: %d: must not be negative", window)
+	}
+	ticks := lastTick(a) + 1
+	if last := lastTick(b) + 1; last < ticks {
+		ticks = last
+	}
+	ha, err := record(a, ticks)
+	if err != nil {
+		return nil, fmt.Errorf("diff: first trace: %w", err)
+	}
+	hb, err := record(b, ticks)
+	if err != nil {
+		return nil, fmt.Errorf("diff: second trace: %w", err)
+	}
+
+	d := &TraceDiff{Ticks: ticks, A: a.Metrics(), B: b.Metrics()}
+	for _, id := range sortedIDs(ha.states) {
+		if _, ok := hb.states[id]; !ok {
+			continue
+		}
+		d.Nodes = append(d.Nodes, diffNode(id, ha, hb, ticks, window))
+	}
+	return d, nil
+}
+
+// history holds what every node of a run was at the end of each tick.
+type history struct {
+	states map[message.NodeID][]controller.NodeState
+
+	// sent counts the messages each node had sent, by type.
+	sent map[message.NodeID][]map[string]int
+}
+
+// record replays the first ticks of a trace, recording the history of its nodes.
+func record(t *trace.Trace, ticks int) (*history, error) {
+	r, err := newReplayer(t)
+	if err != nil {
+		return nil, err
+	}
+	h := &history{
+		states: make(map[message.NodeID][]controller.NodeState, len(t.Nodes)),
+		sent:   make(map[message.NodeID][]map[string]int, len(t.Nodes)),
+	}
+	sent := make(map[message.NodeID]map[string]int, len(t.Nodes))
+	for _, id := range t.Nodes {
+		sent[id] = make(map[string]int)
+	}
+	next := 0
+	for tick := 0; tick < ticks; tick++ {
+		for ; next < len(t.Events) && t.Events[next].Tick <= tick; next++ {
+			e := t.Events[next]
+			if counts, ok := sent[e.Node]; ok && e.Kind == controller.EventSend {
+				counts[e.Envelope.Message.Type()]++
+			}
+		}
+
+		if err := r.advance(tick); err != nil {
+			return nil, err
+		}
+		// Nodes which are not in the network have empty tables.
+		states := make(map[message.NodeID]controller.NodeState, len(t.Nodes))
+		for _, s := range r.states() {
+			states[s.ID] = s
+		}
+		for _, id := range t.Nodes {
+			s, ok := states[id]
+			if !ok {
+				s = controller.NodeState{ID: id}
+			}
+			h.states[id] = append(h.states[id], s)
+			counts := make(map[string]int, len(sent[id]))
+			for kind, n := range sent[id] {
+				counts[kind] = n
+			}
+			h.sent[id] = append(h.sent[id], counts)
+		}
+	}
+	return h, nil
+}
+
+// diffNode compares a node over the history of two runs.
+func diffNode(id message.NodeID, ha, hb *history, ticks, window int) NodeDiff {
+	sa, sb := ha.states[id], hb.states[id]
+	d := NodeDiff{ID: id}
+
+	if tick := firstDivergence(ticks, window, func(i, j int) bool {
+		return equal(sa[i].MPRs, sb[j].MPRs)
+	}); tick >= 0 {
+		d.MPRs = &Divergence{Tick: tick, What: "mprs", A: describeIDs(sa[tick].MPRs), B: describeIDs(sb[tick].MPRs)}
+	}
+
+	if tick := firstDivergence(ticks, window, func(i, j int) bool {
+		return equal(sa[i].Routes, sb[j].Routes)
+	}); tick >= 0 {
+		a, b := describeRoutes(sa[tick].Routes, sb[tick].Routes)
+		d.Routes = &Divergence{Tick: tick, What: "routes", A: a, B: b}
+	}
+
+	// Each type of message is compared on its own, so that the window tolerates types being sent apart.
+	ca, cb := ha.sent[id], hb.sent[id]
+	types := make(map[string]bool)
+	for _, counts := range [][]map[string]int{ca, cb} {
+		if len(counts) > 0 {
+			for kind := range counts[len(counts)-1] {
+				types[kind] = true
+			}
+		}
+	}
+	first := -1
+	for kind := range types {
+		kind := kind
+		tick := firstDivergence(ticks, window, func(i, j int) bool {
+			return ca[i][kind] == cb[j][kind]
+		})
+		if tick >= 0 && (first < 0 || tick < first) {
+			first = tick
+		}
+	}
+	if first >= 0 {
+		a, b := describeCounts(ca[first], cb[first])
+		d.Messages = &Divergence{Tick: first, What: "messages", A: a, B: b}
+	}
+	return d
+}
+
+// firstDivergence returns the first of ticks ticks at which two runs differ for longer than window ticks, or -1 if
+// they never do. match reports whether the first run at tick i matches the second at tick j. Runs differ at a tick if
+// either matches the other at no tick within window of it.
+func firstDivergence(ticks, window int, match func(i, j int) bool) int {
+	for tick := 0; tick < ticks; tick++ {
+		lo, hi := tick-window, tick+window
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > ticks-1 {
+			hi = ticks - 1
+		}
+		matchedA, matchedB := false, false
+		for other := lo; other <= hi && !(matchedA && matchedB); other++ {
+			matchedA = matchedA || match(tick, other)
+			matchedB = matchedB || match(other, tick)
+		}
+		if !matchedA || !matchedB {
+			return tick
+		}
+	}
+	return -1
+}
+
+// equal reports whether two slices hold the same elements in the same order, whether they are nil or empty.
+func equal[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// describeIDs lists nodes on a single line.
+func describeIDs(ids []message.NodeID) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.Itoa(int(id))
+	}
+	return strings.Join(strs, " ")
+}
+
+// describeRoutes describes the routes which differ between two routing tables, to each destination either has a route
+// to.
+func describeRoutes(a, b []controller.Route) (string, string) {
+	byDest := func(routes []controller.Route) map[message.NodeID]controller.Route {
+		m := make(map[message.NodeID]controller.Route, len(routes))
+		for _, r := range routes {
+			m[r.Destination] = r
+		}
+		return m
+	}
+	ra, rb := byDest(a), byDest(b)
+	dests := make(map[message.NodeID]bool)
+	for _, r := range append(append([]controller.Route(nil), a...), b...) {
+		dests[r.Destination] = true
+	}
+
+	var da, db []string
+	for _, dest := range sortedIDs(dests) {
+		routeA, inA := ra[dest]
+		routeB, inB := rb[dest]
+		if inA == inB && routeA == routeB {
+			continue
+		}
+		da = append(da, describeRoute(dest, routeA, inA))
+		db = append(db, describeRoute(dest, routeB, inB))
+	}
+	return strings.Join(da, ", "), strings.Join(db, ", ")
+}
+
+// describeRoute describes the route to a destination, if there is one.
+func describeRoute(dest message.NodeID, r controller.Route, ok bool) string {
+	if !ok {
+		return fmt.Sprintf("%d unreachable", dest)
+	}
+	return fmt.Sprintf("%d via %d in %d hops", dest, r.NextHop, r.Distance)
+}
+
+// describeCounts describes the counts of messages which differ between two runs, by type.
+func describeCounts(a, b map[string]int) (string, string) {
+	types := make([]string, 0, len(a)+len(b))
+	for _, counts := range []map[string]int{a, b} {
+		for kind := range counts {
+			if a[kind] != b[kind] && !contains(types, kind) {
+				types = append(types, kind)
+			}
+		}
+	}
+	sort.Strings(types)
+
+	var da, db []string
+	for _, kind := range types {
+		da = append(da, fmt.Sprintf("%d %s", a[kind], kind))
+		db = append(db, fmt.Sprintf("%d %s", b[kind], kind))
+	}
+	return strings.Join(da, ", "), strings.Join(db, ", ")
+}
+
+// contains reports whether s holds v.
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteDiff writes a comparison of two traced runs, named a and b: the tick at which each node first diverged in each
+// way, a description of each node's first divergence, and the metrics of both runs along with their difference.
+func WriteDiff(w io.Writer, a, b string, d *TraceDiff) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "compared %d ticks\n\n", d.Ticks)
+	fmt.Fprintln(tw, "node\tmprs\troutes\tmessages")
+	tick := func(div *Divergence) string {
+		if div == nil {
+			return "-"
+		}
+		return strconv.Itoa(div.Tick)
+	}
+	for _, n := range d.Nodes {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", n.ID, tick(n.MPRs), tick(n.Routes), tick(n.Messages))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, n := range d.Nodes {
+		div := n.First()
+		if div == nil {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "\nnode %d first diverged at tick %d, in %s:\n  %s: %s\n  %s: %s\n", n.ID, div.Tick, div.What, a, div.A, b, div.B); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+	metrics := []controller.Metrics{d.A, d.B}
+	fmt.Fprintf(tw, "\t%s\t%s\tdelta\n", a, b)
+	for _, r := range metricRows(metrics) {
+		va, vb := r.value(d.A), r.value(d.B)
+		delta := fmt.Sprintf(strings.Replace(r.format, "%", "%+", 1), vb-va)
+		if strings.Trim(delta, "+-0.%") == "" {
+			// Deltas rounding to zero are written as +0, whatever their sign.
+			delta = fmt.Sprintf(strings.Replace(r.format, "%", "%+", 1), 0.0)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.label, fmt.Sprintf(r.format, va), fmt.Sprintf(r.format, vb), delta)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,126 @@
+package olsrsim
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_firstDivergence(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   []int
+		window int
+		want   int
+	}{
+		{name: "identical", a: []int{0, 1, 1, 2}, b: []int{0, 1, 1, 2}, want: -1},
+		{name: "different", a: []int{0, 1, 1, 2}, b: []int{0, 1, 2, 2}, want: 2},
+		{name: "shifted without window", a: []int{0, 1, 1, 2, 2}, b: []int{0, 0, 1, 1, 2}, want: 1},
+		{name: "shifted within window", a: []int{0, 1, 1, 2, 2}, b: []int{0, 0, 1, 1, 2}, window: 1, want: -1},
+		{name: "shifted beyond window", a: []int{0, 1, 1, 1, 2, 2}, b: []int{0, 0, 0, 1, 1, 2}, window: 1, want: 1},
+		{name: "diverged for good", a: []int{0, 1, 2, 3, 4, 5}, b: []int{0, 1, 2, 2, 2, 2}, window: 2, want: 3},
+		{name: "matched one way only", a: []int{0, 0, 0}, b: []int{0, 1, 0}, window: 1, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := firstDivergence(len(tt.a), tt.window, func(i, j int) bool { return tt.a[i] == tt.b[j] })
+			if got != tt.want {
+				t.Errorf("firstDivergence() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffTraces(t *testing.T) {
+	scenario := func(lines ...string) *Scenario {
+		return &Scenario{
+			Ticks:         70,
+			TopologyLines: append([]string{"0 UP 0 <-> 1", "0 UP 1 <-> 2", "0 UP 2 <-> 3", "0 UP 3 <-> 4", "0 UP 1 <-> 3"}, lines...),
+			Nodes:         []ScenarioNode{{ID: 0}, {ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}},
+			Traffic:       []Traffic{{Source: 0, Destination: 4, Message: "hello", Delay: 25}},
+		}
+	}
+	a := recordTrace(t, scenario())
+
+	d, err := DiffTraces(a, recordTrace(t, scenario()), 0)
+	if err != nil {
+		t.Fatalf("DiffTraces() error = %v", err)
+	}
+	if d.Ticks != 70 || len(d.Nodes) != 5 {
+		t.Fatalf("DiffTraces() compared %d ticks of %d nodes, want 70 ticks of 5 nodes", d.Ticks, len(d.Nodes))
+	}
+	for _, n := range d.Nodes {
+		if div := n.First(); div != nil {
+			t.Errorf("DiffTraces() node %d diverged from an identical run: %+v", n.ID, *div)
+		}
+	}
+
+	// Node 3 no longer reaches node 1 directly from tick 30, so that node 2 is the only way between them.
+	d, err = DiffTraces(a, recordTrace(t, scenario("30 DOWN 1 <-> 3")), 0)
+	if err != nil {
+		t.Fatalf("DiffTraces() error = %v", err)
+	}
+	for _, n := range d.Nodes {
+		if div := n.First(); div != nil && div.Tick < 30 {
+			t.Errorf("DiffTraces() node %d diverged at tick %d, before the runs did", n.ID, div.Tick)
+		}
+	}
+	// OLSR keeps the link until its neighbor's hold time runs out.
+	want := Divergence{Tick: 41, What: "routes", A: "0 via 1 in 2 hops, 1 via 1 in 1 hops", B: "0 via 2 in 3 hops, 1 via 2 in 2 hops"}
+	if got := d.Nodes[3].Routes; got == nil || *got != want {
+		t.Errorf("DiffTraces() node 3 routes diverged at %+v, want %+v", got, want)
+	}
+	if d.A.DataOriginated != 2 || d.B.TotalSent() == d.A.TotalSent() {
+		t.Errorf("DiffTraces() metrics = %+v and %+v, want those of each run", d.A, d.B)
+	}
+
+	if _, err := DiffTraces(a, a, -1); err == nil {
+		t.Errorf("DiffTraces() error = nil for a negative window, want error")
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	d := &TraceDiff{
+		Ticks: 10,
+		Nodes: []NodeDiff{
+			{ID: 0},
+			{
+				ID:       1,
+				MPRs:     &Divergence{Tick: 6, What: "mprs", A: "2", B: "none"},
+				Messages: &Divergence{Tick: 4, What: "messages", A: "2 HELLO", B: "3 HELLO"},
+			},
+		},
+	}
+	d.A.Ticks, d.B.Ticks = 10, 10
+	d.A.Sent = map[string]int{"HELLO": 4}
+	d.B.Sent = map[string]int{"HELLO": 6}
+	d.A.DataOriginated, d.B.DataOriginated = 3, 3
+	d.A.DataDelivered, d.B.DataDelivered = 3, 3
+	d.A.Latency, d.B.Latency = 5, 5
+	want := `compared 10 ticks
+
+node  mprs  routes  messages
+0     -     -       -
+1     6     -       4
+
+node 1 first diverged at tick 4, in messages:
+  a: 2 HELLO
+  b: 3 HELLO
+
+                 a       b       delta
+ticks            10      10      +0
+HELLO sent       4       6       +2
+control sent     4       6       +2
+total sent       4       6       +2
+data originated  3       3       +0
+data delivered   3       3       +0
+delivery ratio   100.0%  100.0%  +0.0%
+mean latency     1.7     1.7     +0.0
+`
+	var b bytes.Buffer
+	if err := WriteDiff(&b, "a", "b", d); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("WriteDiff() got =\n%s\nwant =\n%s", got, want)
+	}
+}
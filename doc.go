@@ -0,0 +1,44 @@
+// Package olsrsim simulates a simplified OLSR (RFC 3626) ad hoc network.
+//
+// A controller drives every node one tick at a time, and routes messages between nodes according to a topology
+// describing which links are UP at each tick. The sub-packages expose each part of the simulation:
+//
+//   - message defines the messages exchanged by nodes.
+//   - topology parses and queries network topologies.
+//   - olsr implements the OLSR node.
+//   - olsrv2 implements the OLSRv2 (RFC 7181) node, discovering its neighborhood with NHDP (RFC 6130).
+//   - rfc5444 encodes and decodes the RFC 5444 packets exchanged by OLSRv2 nodes.
+//   - aodv implements the AODV (RFC 3561) node, a reactive baseline to compare OLSR against.
+//   - dsdv and flooding implement DSDV and blind flooding nodes, baselines to compare MPR flooding against.
+//   - controller drives nodes, through the Router interface, and routes their messages.
+//   - repl steps through a simulation interactively, inspecting the tables of its nodes.
+//   - tui runs a simulation behind a terminal dashboard.
+//   - server serves an HTTP API controlling and inspecting a running simulation.
+//   - trace records the events of a run, so that it may be analyzed and rendered after the fact.
+//   - viz serves a web page playing a traced run back.
+//   - invariant checks protocol invariants while a simulation runs.
+//
+// This package ties them together: a Scenario describes a whole run, and may be loaded from a JSON file or built
+// programmatically. WriteReport compares the metrics of runs of different protocols on the same scenario.
+// Replay reconstructs the state of the nodes of a traced run at any tick, and DiffTraces finds where two traced runs
+// diverged. A SplitRecorder measures how routes react to a scenario's network splitting into partitions and merging
+// back.
+//
+//	s := &olsrsim.Scenario{
+//		TickMillis:    10,
+//		Ticks:         60,
+//		TopologyLines: []string{"0 UP 0 <-> 1", "0 UP 1 <-> 2"},
+//		Nodes:         []olsrsim.ScenarioNode{{ID: 0}, {ID: 1}, {ID: 2}},
+//		Traffic:       []olsrsim.Traffic{{Source: 0, Destination: 2, Message: "hello", Delay: 20}},
+//	}
+//	result, err := s.Run()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	for _, state := range result.States {
+//		fmt.Println(state.ID, state.Routes)
+//	}
+//
+// Validate reports problems with topology and node configuration files, and Generate creates topologies of
+// parameterized shapes.
+package olsrsim
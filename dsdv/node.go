@@ -0,0 +1,340 @@
+// Package dsdv implements a simplified DSDV (destination-sequenced distance-vector) node, a baseline to compare
+// link-state routing against.
+//
+// Nodes periodically broadcast their whole routing table, along with a new even sequence number for themselves, and
+// broadcast the routes which changed as soon as they change. Routes with a newer sequence number, or the same sequence
+// number and a shorter distance, replace older ones. A node which stops hearing from a neighbor advertises every route
+// through it as unreachable, with the next odd sequence number.
+package dsdv
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+// routeEntry is a route to a destination. Unreachable destinations keep their entry, with an infinite metric, so
+// that their sequence number is remembered.
+type routeEntry struct {
+	nextHop message.NodeID
+	metric  int
+	seq     int
+
+	// changed is set until the route has been advertised since it last changed.
+	changed bool
+}
+
+func (r *routeEntry) reachable() bool {
+	return r.metric < message.InfiniteMetric
+}
+
+// pendingData is data waiting for a route to its destination.
+type pendingData struct {
+	msg *message.DataMessage
+
+	// at is the tick the data is next attempted to be sent at.
+	at int
+}
+
+// Params are the DSDV protocol parameters of a Node, all in ticks.
+type Params struct {
+	// UpdateInterval is how often a Node broadcasts its whole routing table.
+	UpdateInterval int `json:"updateInterval,omitempty"`
+
+	// HoldTime is how long a Node keeps routes through a neighbor it has not heard from.
+	HoldTime int `json:"holdTime,omitempty"`
+
+	// DataRetryInterval is how long a Node waits to retry sending data when there is no route.
+	DataRetryInterval int `json:"dataRetryInterval,omitempty"`
+}
+
+// DefaultParams returns the parameters used by a Node unless they are overridden.
+func DefaultParams() Params {
+	return Params{
+		UpdateInterval:    10,
+		HoldTime:          30,
+		DataRetryInterval: 30,
+	}
+}
+
+// Validate checks the parameters are usable, and that routes are held for longer than they are refreshed.
+func (p Params) Validate() error {
+	switch {
+	case p.UpdateInterval <= 0:
+		return fmt.Errorf("invalid params: update interval must be positive: %d", p.UpdateInterval)
+	case p.DataRetryInterval <= 0:
+		return fmt.Errorf("invalid params: data retry interval must be positive: %d", p.DataRetryInterval)
+	case p.HoldTime <= p.UpdateInterval:
+		return fmt.Errorf("invalid params: hold time (%d) must be greater than the update interval (%d)", p.HoldTime, p.UpdateInterval)
+	}
+	return nil
+}
+
+// Merge returns p, with each non-zero parameter of override taking precedence.
+func (p Params) Merge(override Params) Params {
+	if override.UpdateInterval != 0 {
+		p.UpdateInterval = override.UpdateInterval
+	}
+	if override.HoldTime != 0 {
+		p.HoldTime = override.HoldTime
+	}
+	if override.DataRetryInterval != 0 {
+		p.DataRetryInterval = override.DataRetryInterval
+	}
+	return p
+}
+
+// Node represents a network node running DSDV. Node implements controller.Router.
+type Node struct {
+	id     message.NodeID
+	params Params
+
+	// outbox holds the envelopes sent by the Node since it was last driven by the controller.
+	outbox []message.Envelope
+
+	// pending holds the data waiting for a route to its destination.
+	pending []pendingData
+
+	// seq is the Node's own sequence number, which is always even.
+	seq int
+
+	routes map[message.NodeID]*routeEntry
+
+	// lastHeard holds the tick each neighbor was last heard from.
+	lastHeard map[message.NodeID]int
+
+	// currentTick is the number of ticks since the node came online.
+	currentTick int
+}
+
+// ID is the Node's identifier.
+func (n *Node) ID() message.NodeID {
+	return n.id
+}
+
+// Receive handles a message received by the Node. Messages other than UPDATE and DATA are ignored.
+func (n *Node) Receive(env message.Envelope) []message.Envelope {
+	switch msg := env.Message.(type) {
+	case *message.UpdateMessage:
+		n.handleUpdate(msg)
+	case *message.DataMessage:
+		n.handleData(msg)
+	default:
+		log.Printf("node %d: ignoring message of unknown type: %s", n.id, env.Message.Type())
+	}
+	return n.flush()
+}
+
+// Send queues data to be sent to dst once there is a route to it.
+func (n *Node) Send(dst message.NodeID, data string) {
+	msg := &message.DataMessage{
+		Source:      n.id,
+		Destination: dst,
+		Data:        data,
+	}
+	n.pending = append(n.pending, pendingData{msg: msg, at: n.currentTick})
+}
+
+// Tick breaks the routes through neighbors no longer heard from, broadcasts a full or incremental update, sends any
+// pending data, and advances the Node's clock.
+func (n *Node) Tick() []message.Envelope {
+	for neighbor, heard := range n.lastHeard {
+		if heard+n.params.HoldTime <= n.currentTick {
+			delete(n.lastHeard, neighbor)
+			n.breakRoutes(neighbor)
+		}
+	}
+
+	if n.currentTick%n.params.UpdateInterval == 0 {
+		n.seq += 2
+		n.sendUpdate(true)
+	} else {
+		n.sendUpdate(false)
+	}
+
+	// Attempt to send pending data, retrying later if there is no route.
+	remaining := n.pending[:0]
+	for _, p := range n.pending {
+		if p.at == n.currentTick {
+			if n.sendData(p.msg) {
+				continue
+			}
+			p.at += n.params.DataRetryInterval
+		}
+		remaining = append(remaining, p)
+	}
+	n.pending = remaining
+
+	n.currentTick++
+	return n.flush()
+}
+
+// Routes returns the Node's reachable destinations, sorted by destination.
+// It must not be called while the Node is being driven by the controller.
+func (n *Node) Routes() []controller.Route {
+	routes := make([]controller.Route, 0, len(n.routes))
+	for _, dst := range sortedKeys(n.routes) {
+		r := n.routes[dst]
+		if r.reachable() {
+			routes = append(routes, controller.Route{Destination: dst, NextHop: r.nextHop, Distance: r.metric})
+		}
+	}
+	return routes
+}
+
+// Inspect returns a snapshot of the Node's neighbors and reachable destinations. Neighbors are those heard from within
+// HoldTime. Node implements controller.Inspector.
+// It must not be called while the Node is being driven by the controller.
+func (n *Node) Inspect() controller.NodeState {
+	return controller.NodeState{
+		ID:        n.id,
+		Tick:      n.currentTick,
+		Neighbors: sortedKeys(n.lastHeard),
+		Routes:    n.Routes(),
+	}
+}
+
+// Sequences returns the Node's own sequence number. Node implements controller.Persister.
+func (n *Node) Sequences() map[string]int {
+	return map[string]int{"seq": n.seq}
+}
+
+// RestoreSequences continues from the sequence number of a previous Node.
+func (n *Node) RestoreSequences(seqs map[string]int) {
+	n.seq = seqs["seq"]
+}
+
+// send queues msg to be sent to a neighbor, or to every neighbor if to is message.Broadcast.
+func (n *Node) send(to message.NodeID, msg message.Message) {
+	n.outbox = append(n.outbox, message.Envelope{From: n.id, To: to, Message: msg})
+}
+
+// flush returns, and clears, the envelopes sent by the Node.
+func (n *Node) flush() []message.Envelope {
+	out := n.outbox
+	n.outbox = nil
+	return out
+}
+
+// sendUpdate broadcasts every route if full is set, or otherwise the routes which changed since they were last
+// advertised, if any.
+func (n *Node) sendUpdate(full bool) {
+	update := &message.UpdateMessage{FromNeighbor: n.id, Full: full}
+	if full {
+		update.Routes = append(update.Routes, message.Advertisement{Destination: n.id, Sequence: n.seq})
+	}
+	for _, dst := range sortedKeys(n.routes) {
+		r := n.routes[dst]
+		if full || r.changed {
+			update.Routes = append(update.Routes, message.Advertisement{Destination: dst, Metric: r.metric, Sequence: r.seq})
+		}
+		r.changed = false
+	}
+	if len(update.Routes) > 0 {
+		n.send(message.Broadcast, update)
+	}
+}
+
+// handleUpdate updates the routes through the neighbor which sent msg.
+func (n *Node) handleUpdate(msg *message.UpdateMessage) {
+	n.lastHeard[msg.FromNeighbor] = n.currentTick
+	for _, adv := range msg.Routes {
+		if adv.Destination == n.id {
+			continue
+		}
+		metric := adv.Metric + 1
+		if metric > message.InfiniteMetric {
+			metric = message.InfiniteMetric
+		}
+
+		r, in := n.routes[adv.Destination]
+		switch {
+		case !in:
+			if metric >= message.InfiniteMetric {
+				continue
+			}
+		case adv.Sequence > r.seq:
+		case adv.Sequence == r.seq && (metric < r.metric || (r.nextHop == msg.FromNeighbor && metric != r.metric)):
+		default:
+			continue
+		}
+
+		changed := !in || r.nextHop != msg.FromNeighbor || r.metric != metric
+		n.routes[adv.Destination] = &routeEntry{
+			nextHop: msg.FromNeighbor,
+			metric:  metric,
+			seq:     adv.Sequence,
+			changed: changed || (in && r.changed),
+		}
+	}
+}
+
+// breakRoutes makes every route through neighbor unreachable.
+func (n *Node) breakRoutes(neighbor message.NodeID) {
+	for _, r := range n.routes {
+		if r.nextHop == neighbor && r.reachable() {
+			r.metric = message.InfiniteMetric
+			r.seq++
+			r.changed = true
+		}
+	}
+}
+
+// sendData sends msg, as this Node, if there is a route to the destination.
+// msg must not be shared, as its hop fields are updated.
+func (n *Node) sendData(msg *message.DataMessage) bool {
+	r, in := n.routes[msg.Destination]
+	if !in || !r.reachable() {
+		return false
+	}
+	msg.FromNeighbor = n.id
+	msg.NextHop = r.nextHop
+	n.send(r.nextHop, msg)
+	return true
+}
+
+// handleData forwards a message.DataMessage towards its destination, unless this Node is the destination.
+func (n *Node) handleData(msg *message.DataMessage) {
+	if msg.Destination == n.id {
+		return
+	}
+	// The received message is shared with the controller, so forward a copy.
+	fwd := *msg
+	n.sendData(&fwd)
+}
+
+// sortedKeys returns the keys of m in increasing order.
+func sortedKeys[V any](m map[message.NodeID]V) []message.NodeID {
+	keys := make([]message.NodeID, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// New creates a network Node.
+func New(id message.NodeID, params Params) *Node {
+	return &Node{
+		id:        id,
+		params:    params,
+		routes:    make(map[message.NodeID]*routeEntry),
+		lastHeard: make(map[message.NodeID]int),
+	}
+}
+
+// Protocol returns a controller.Factory creating a Node for each node, using params. An error is returned if params
+// are invalid.
+func Protocol(params Params) controller.Factory {
+	return func(config controller.NodeConfig) (controller.Router, error) {
+		if err := params.Validate(); err != nil {
+			return nil, err
+		}
+		return New(config.ID, params), nil
+	}
+}
@@ -0,0 +1,120 @@
+package dsdv
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/internal/routertest"
+	"github.com/kprusa/olsrsim/message"
+)
+
+// newLine creates a network of nodes 0 to size-1, each linked to the next.
+func newLine(size int, params Params) *routertest.Network[*Node] {
+	return routertest.NewLine(size, func(id message.NodeID) *Node { return New(id, params) })
+}
+
+func TestNode_routes(t *testing.T) {
+	nw := newLine(4, DefaultParams())
+	nw.Nodes[0].Send(3, "hi")
+	nw.Run(40)
+	if got := nw.Nodes[1].Inspect().Neighbors; !reflect.DeepEqual(got, []message.NodeID{0, 2}) {
+		t.Errorf("Inspect().Neighbors = %v, want %v", got, []message.NodeID{0, 2})
+	}
+
+	want := []controller.Route{
+		{Destination: 1, NextHop: 1, Distance: 1},
+		{Destination: 2, NextHop: 1, Distance: 2},
+		{Destination: 3, NextHop: 1, Distance: 3},
+	}
+	if got := nw.Nodes[0].Routes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Routes() = %v, want %v", got, want)
+	}
+	if want := []string{"hi"}; !reflect.DeepEqual(nw.Delivered, want) {
+		t.Errorf("delivered %v, want %v", nw.Delivered, want)
+	}
+}
+
+func TestNode_linkBreak(t *testing.T) {
+	p := DefaultParams()
+	nw := newLine(4, p)
+	nw.Run(40)
+	if r := nw.Nodes[0].routes[3]; r == nil || !r.reachable() {
+		t.Fatal("node 0 has no route to 3")
+	}
+	seq := nw.Nodes[0].routes[3].seq
+
+	nw.Link(2, 3, false)
+	nw.Run(p.HoldTime + 5)
+
+	for _, id := range []int{0, 1, 2} {
+		r := nw.Nodes[id].routes[3]
+		if r.reachable() {
+			t.Errorf("node %d still has a route to 3 after the link broke", id)
+		}
+		if r.seq%2 != 1 || r.seq <= seq {
+			t.Errorf("node %d has sequence number %d for 3, want an odd number greater than %d", id, r.seq, seq)
+		}
+	}
+
+	// Once the link is restored, 3's next even sequence number replaces the broken routes.
+	nw.Link(2, 3, true)
+	nw.Run(2 * p.UpdateInterval)
+	if r := nw.Nodes[0].routes[3]; !r.reachable() || r.metric != 3 {
+		t.Errorf("node 0 route to 3 = %+v, want 3 hops", r)
+	}
+}
+
+func TestNode_Receive_staleUpdate(t *testing.T) {
+	n := New(0, DefaultParams())
+	n.Receive(message.Envelope{From: 1, To: message.Broadcast, Message: &message.UpdateMessage{
+		FromNeighbor: 1,
+		Routes:       []message.Advertisement{{Destination: 5, Metric: 2, Sequence: 4}},
+	}})
+	n.Receive(message.Envelope{From: 2, To: message.Broadcast, Message: &message.UpdateMessage{
+		FromNeighbor: 2,
+		Routes:       []message.Advertisement{{Destination: 5, Metric: 1, Sequence: 2}},
+	}})
+	if r := n.routes[5]; r.nextHop != 1 || r.metric != 3 || r.seq != 4 {
+		t.Errorf("route to 5 = %+v, want the newer route via 1", r)
+	}
+
+	n.Receive(message.Envelope{From: 2, To: message.Broadcast, Message: &message.UpdateMessage{
+		FromNeighbor: 2,
+		Routes:       []message.Advertisement{{Destination: 5, Metric: 0, Sequence: 4}},
+	}})
+	if r := n.routes[5]; r.nextHop != 2 || r.metric != 1 {
+		t.Errorf("route to 5 = %+v, want the shorter route via 2", r)
+	}
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr bool
+	}{
+		{
+			name:    "defaults",
+			params:  DefaultParams(),
+			wantErr: false,
+		},
+		{
+			name:    "negative update interval",
+			params:  DefaultParams().Merge(Params{UpdateInterval: -1}),
+			wantErr: true,
+		},
+		{
+			name:    "hold time shorter than interval",
+			params:  DefaultParams().Merge(Params{HoldTime: 5}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
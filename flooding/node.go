@@ -0,0 +1,107 @@
+// Package flooding implements a blind flooding node, a baseline to compare MPR flooding against.
+//
+// Nodes keep no routes. Data is broadcast by its source, and every other node rebroadcasts it once, unless it is the
+// destination. A node recognizes data it has already handled by its source, destination and contents.
+package flooding
+
+import (
+	"log"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+// dataKey identifies data flooded through the network.
+type dataKey struct {
+	source      message.NodeID
+	destination message.NodeID
+	data        string
+}
+
+// Node represents a network node flooding data. Node implements controller.Router.
+type Node struct {
+	id message.NodeID
+
+	// outbox holds the envelopes sent by the Node since it was last driven by the controller.
+	outbox []message.Envelope
+
+	// seen holds the data the Node has already sent, forwarded or received as its destination.
+	seen map[dataKey]bool
+}
+
+// ID is the Node's identifier.
+func (n *Node) ID() message.NodeID {
+	return n.id
+}
+
+// Receive rebroadcasts data the first time it is received, unless the Node is its destination. Messages other than
+// DATA are ignored.
+func (n *Node) Receive(env message.Envelope) []message.Envelope {
+	msg, ok := env.Message.(*message.DataMessage)
+	if !ok {
+		log.Printf("node %d: ignoring message of unknown type: %s", n.id, env.Message.Type())
+		return nil
+	}
+
+	k := dataKey{source: msg.Source, destination: msg.Destination, data: msg.Data}
+	if n.seen[k] {
+		return nil
+	}
+	n.seen[k] = true
+	if msg.Destination == n.id {
+		return nil
+	}
+
+	// The received message is shared with the controller, so forward a copy.
+	fwd := *msg
+	n.broadcast(&fwd)
+	return n.flush()
+}
+
+// Send broadcasts data to every neighbor.
+func (n *Node) Send(dst message.NodeID, data string) {
+	msg := &message.DataMessage{
+		Source:      n.id,
+		Destination: dst,
+		Data:        data,
+	}
+	n.seen[dataKey{source: n.id, destination: dst, data: data}] = true
+	n.broadcast(msg)
+}
+
+// Tick returns the data sent since the Node was last driven. Nodes have no periodic messages.
+func (n *Node) Tick() []message.Envelope {
+	return n.flush()
+}
+
+// Routes is always empty, as data is flooded rather than routed.
+func (n *Node) Routes() []controller.Route {
+	return nil
+}
+
+// broadcast sends msg, as this Node, to every neighbor.
+// msg must not be shared, as its hop fields are updated.
+func (n *Node) broadcast(msg *message.DataMessage) {
+	msg.FromNeighbor = n.id
+	msg.NextHop = message.Broadcast
+	n.outbox = append(n.outbox, message.Envelope{From: n.id, To: message.Broadcast, Message: msg})
+}
+
+// flush returns, and clears, the envelopes sent by the Node.
+func (n *Node) flush() []message.Envelope {
+	out := n.outbox
+	n.outbox = nil
+	return out
+}
+
+// New creates a network Node.
+func New(id message.NodeID) *Node {
+	return &Node{id: id, seen: make(map[dataKey]bool)}
+}
+
+// Protocol returns a controller.Factory creating a Node for each node.
+func Protocol() controller.Factory {
+	return func(config controller.NodeConfig) (controller.Router, error) {
+		return New(config.ID), nil
+	}
+}
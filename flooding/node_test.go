@@ -0,0 +1,73 @@
+package flooding
+
+import (
+	"testing"
+
+	"github.com/kprusa/olsrsim/message"
+)
+
+func TestNode_Receive(t *testing.T) {
+	data := &message.DataMessage{Source: 0, Destination: 5, NextHop: message.Broadcast, FromNeighbor: 0, Data: "hi"}
+	tests := []struct {
+		name string
+		id   message.NodeID
+		envs []message.Envelope
+		want int
+	}{
+		{
+			name: "rebroadcast",
+			id:   1,
+			envs: []message.Envelope{{From: 0, To: message.Broadcast, Message: data}},
+			want: 1,
+		},
+		{
+			name: "duplicate",
+			id:   1,
+			envs: []message.Envelope{
+				{From: 0, To: message.Broadcast, Message: data},
+				{From: 2, To: message.Broadcast, Message: data},
+			},
+			want: 1,
+		},
+		{
+			name: "destination",
+			id:   5,
+			envs: []message.Envelope{{From: 0, To: message.Broadcast, Message: data}},
+			want: 0,
+		},
+		{
+			name: "source",
+			id:   0,
+			envs: []message.Envelope{{From: 1, To: message.Broadcast, Message: data}},
+			want: 0,
+		},
+		{
+			name: "unknown message",
+			id:   1,
+			envs: []message.Envelope{{From: 0, To: message.Broadcast, Message: &message.TCMessage{}}},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := New(tt.id)
+			if tt.id == data.Source {
+				n.Send(data.Destination, data.Data)
+				n.Tick()
+			}
+			var sent []message.Envelope
+			for _, env := range tt.envs {
+				sent = append(sent, n.Receive(env)...)
+			}
+			if len(sent) != tt.want {
+				t.Fatalf("Receive() sent %d envelopes, want %d: %v", len(sent), tt.want, sent)
+			}
+			for _, env := range sent {
+				fwd := env.Message.(*message.DataMessage)
+				if env.To != message.Broadcast || fwd.FromNeighbor != tt.id || fwd == data {
+					t.Errorf("Receive() sent %v, want a copy broadcast by %d", env, tt.id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package olsrsim
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/topology"
+)
+
+// Shape identifies a family of generated network topologies.
+type Shape string
+
+const (
+	// Grid places nodes on a Rows x Cols lattice, linking each node to its horizontal and vertical neighbors.
+	Grid Shape = "grid"
+
+	// Ring links each node to its predecessor and successor, wrapping around.
+	Ring Shape = "ring"
+
+	// Line links each node to its predecessor and successor, without wrapping around.
+	Line Shape = "line"
+
+	// Star links node 0 to every other node.
+	Star Shape = "star"
+
+	// Geometric places nodes uniformly at random in a unit square and links nodes within Radius of each other.
+	Geometric Shape = "geometric"
+
+	// ErdosRenyi links each pair of nodes independently with Probability.
+	ErdosRenyi Shape = "erdos-renyi"
+)
+
+// GeneratorConfig parameterizes the generation of a topology and its matching node configurations.
+type GeneratorConfig struct {
+	// Shape is the family of topology to generate.
+	Shape Shape
+
+	// Nodes is the number of nodes. Ignored for Grid, which uses Rows * Cols.
+	Nodes int
+
+	// Rows and Cols are the dimensions of a Grid.
+	Rows int
+	Cols int
+
+	// Radius is the connection radius of a Geometric topology, within a unit square.
+	Radius float64
+
+	// Probability is the chance of any pair of nodes being linked in an ErdosRenyi topology.
+	Probability float64
+
+	// Asymmetry is the chance of a link only being available in one (randomly chosen) direction.
+	Asymmetry float64
+
+	// ChurnRate is the chance, per tick, of a link changing state between UP and DOWN.
+	ChurnRate float64
+
+	// Duration is the number of ticks over which link churn is scheduled.
+	Duration int
+
+	// MessageDelay is the delay, in ticks, of each node's generated message.
+	MessageDelay int
+
+	// Seed seeds the random source, making generation reproducible.
+	Seed int64
+}
+
+// GeneratedScenario is the result of generating a topology.
+type GeneratedScenario struct {
+	// States are the link transitions, sorted by increasing time.
+	States []topology.LinkState
+
+	// Configs holds a configuration for each generated node.
+	Configs []controller.NodeConfig
+}
+
+// edge is an undirected pair of nodes, where a < b.
+type edge struct {
+	a, b message.NodeID
+}
+
+// Generate creates a topology and node configurations based on the supplied configuration.
+func Generate(cfg GeneratorConfig) (*GeneratedScenario, error) {
+	if cfg.Asymmetry < 0 || cfg.Asymmetry > 1 {
+		return nil, errors.New("generate: asymmetry must be within [0, 1]")
+	}
+	if cfg.ChurnRate < 0 || cfg.ChurnRate > 1 {
+		return nil, errors.New("generate: churn rate must be within [0, 1]")
+	}
+	rng := rand.New(rand.NewSource(cfg.Seed))
+
+	nodes := cfg.Nodes
+	var edges []edge
+	switch cfg.Shape {
+	case Grid:
+		if cfg.Rows < 1 || cfg.Cols < 1 {
+			return nil, errors.New("generate: grid requires at least one row and column")
+		}
+		nodes = cfg.Rows * cfg.Cols
+		for r := 0; r < cfg.Rows; r++ {
+			for c := 0; c < cfg.Cols; c++ {
+				id := message.NodeID(r*cfg.Cols + c)
+				if c+1 < cfg.Cols {
+					edges = append(edges, edge{a: id, b: id + 1})
+				}
+				if r+1 < cfg.Rows {
+					edges = append(edges, edge{a: id, b: id + message.NodeID(cfg.Cols)})
+				}
+			}
+		}
+	case Ring, Line:
+		if nodes < 2 {
+			return nil, fmt.Errorf("generate: %s requires at least 2 nodes", cfg.Shape)
+		}
+		for i := 0; i+1 < nodes; i++ {
+			edges = append(edges, edge{a: message.NodeID(i), b: message.NodeID(i + 1)})
+		}
+		if cfg.Shape == Ring && nodes > 2 {
+			edges = append(edges, edge{a: 0, b: message.NodeID(nodes - 1)})
+		}
+	case Star:
+		if nodes < 2 {
+			return nil, errors.New("generate: star requires at least 2 nodes")
+		}
+		for i := 1; i < nodes; i++ {
+			edges = append(edges, edge{a: 0, b: message.NodeID(i)})
+		}
+	case Geometric:
+		if nodes < 1 || cfg.Radius <= 0 {
+			return nil, errors.New("generate: geometric requires at least 1 node and a positive radius")
+		}
+		xs := make([]float64, nodes)
+		ys := make([]float64, nodes)
+		for i := range xs {
+			xs[i] = rng.Float64()
+			ys[i] = rng.Float64()
+		}
+		for i := 0; i < nodes; i++ {
+			for j := i + 1; j < nodes; j++ {
+				if math.Hypot(xs[i]-xs[j], ys[i]-ys[j]) <= cfg.Radius {
+					edges = append(edges, edge{a: message.NodeID(i), b: message.NodeID(j)})
+				}
+			}
+		}
+	case ErdosRenyi:
+		if nodes < 1 || cfg.Probability < 0 || cfg.Probability > 1 {
+			return nil, errors.New("generate: erdos-renyi requires at least 1 node and a probability within [0, 1]")
+		}
+		for i := 0; i < nodes; i++ {
+			for j := i + 1; j < nodes; j++ {
+				if rng.Float64() < cfg.Probability {
+					edges = append(edges, edge{a: message.NodeID(i), b: message.NodeID(j)})
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("generate: unknown shape: '%s'", cfg.Shape)
+	}
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].a != edges[j].a {
+			return edges[i].a < edges[j].a
+		}
+		return edges[i].b < edges[j].b
+	})
+
+	// Decide which directions of each edge are available.
+	type directions struct {
+		forward, backward bool
+	}
+	dirs := make([]directions, len(edges))
+	for i := range edges {
+		dirs[i] = directions{forward: true, backward: true}
+		if rng.Float64() < cfg.Asymmetry {
+			if rng.Intn(2) == 0 {
+				dirs[i].forward = false
+			} else {
+				dirs[i].backward = false
+			}
+		}
+	}
+
+	g := &GeneratedScenario{}
+	transition := func(time int, status topology.LinkStatus, e edge, d directions) {
+		if d.forward {
+			g.States = append(g.States, topology.LinkState{Time: time, Status: status, From: e.a, To: e.b})
+		}
+		if d.backward {
+			g.States = append(g.States, topology.LinkState{Time: time, Status: status, From: e.b, To: e.a})
+		}
+	}
+
+	// All links start UP, then churn over the duration of the scenario.
+	up := make([]bool, len(edges))
+	for i, e := range edges {
+		up[i] = true
+		transition(0, topology.UP, e, dirs[i])
+	}
+	for t := 1; t < cfg.Duration; t++ {
+		for i, e := range edges {
+			if rng.Float64() >= cfg.ChurnRate {
+				continue
+			}
+			up[i] = !up[i]
+			if up[i] {
+				transition(t, topology.UP, e, dirs[i])
+			} else {
+				transition(t, topology.DOWN, e, dirs[i])
+			}
+		}
+	}
+
+	// Each node sends a single message to a randomly chosen peer.
+	for i := 0; i < nodes; i++ {
+		if nodes < 2 {
+			break
+		}
+		dst := rng.Intn(nodes - 1)
+		if dst >= i {
+			dst++
+		}
+		g.Configs = append(g.Configs, controller.NodeConfig{
+			ID: message.NodeID(i),
+			Message: controller.NodeMessage{
+				Message:     fmt.Sprintf("(%d -> %d)", i, dst),
+				Delay:       cfg.MessageDelay,
+				Destination: message.NodeID(dst),
+			},
+		})
+	}
+
+	return g, nil
+}
+
+// Scenario creates a Scenario embedding the generated topology and node configurations.
+func (g *GeneratedScenario) Scenario() *Scenario {
+	s := &Scenario{TopologyLines: make([]string, 0, len(g.States))}
+	for _, ls := range g.States {
+		s.TopologyLines = append(s.TopologyLines, ls.String())
+	}
+	s.configure(g.Configs)
+	return s
+}
+
+// WriteTopology writes the generated link transitions in the topology file format.
+func (g *GeneratedScenario) WriteTopology(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	for _, s := range g.States {
+		if _, err := fmt.Fprintln(w, s.String()); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// WriteNodeConfiguration writes the generated node configurations in the node configuration file format.
+func (g *GeneratedScenario) WriteNodeConfiguration(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	for _, c := range g.Configs {
+		_, err := fmt.Fprintf(w, "%d %d \"%s\" %d\n", c.ID, c.Message.Destination, c.Message.Message, c.Message.Delay)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
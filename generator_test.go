@@ -0,0 +1,167 @@
+package olsrsim
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/topology"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       GeneratorConfig
+		wantLinks int
+		wantNodes int
+		wantErr   bool
+	}{
+		{
+			name:      "grid",
+			cfg:       GeneratorConfig{Shape: Grid, Rows: 2, Cols: 3},
+			wantLinks: 14,
+			wantNodes: 6,
+		},
+		{
+			name:      "ring",
+			cfg:       GeneratorConfig{Shape: Ring, Nodes: 4},
+			wantLinks: 8,
+			wantNodes: 4,
+		},
+		{
+			name:      "line",
+			cfg:       GeneratorConfig{Shape: Line, Nodes: 4},
+			wantLinks: 6,
+			wantNodes: 4,
+		},
+		{
+			name:      "star",
+			cfg:       GeneratorConfig{Shape: Star, Nodes: 5},
+			wantLinks: 8,
+			wantNodes: 5,
+		},
+		{
+			name:      "fully connected geometric",
+			cfg:       GeneratorConfig{Shape: Geometric, Nodes: 4, Radius: 2},
+			wantLinks: 12,
+			wantNodes: 4,
+		},
+		{
+			name:      "empty erdos-renyi",
+			cfg:       GeneratorConfig{Shape: ErdosRenyi, Nodes: 4, Probability: 0},
+			wantLinks: 0,
+			wantNodes: 4,
+		},
+		{
+			name:      "fully asymmetric",
+			cfg:       GeneratorConfig{Shape: Line, Nodes: 4, Asymmetry: 1},
+			wantLinks: 3,
+			wantNodes: 4,
+		},
+		{
+			name:    "unknown shape",
+			cfg:     GeneratorConfig{Shape: "blob", Nodes: 4},
+			wantErr: true,
+		},
+		{
+			name:    "ring too small",
+			cfg:     GeneratorConfig{Shape: Ring, Nodes: 1},
+			wantErr: true,
+		},
+		{
+			name:    "negative asymmetry",
+			cfg:     GeneratorConfig{Shape: Line, Nodes: 4, Asymmetry: -0.1},
+			wantErr: true,
+		},
+		{
+			name:    "asymmetry above 1",
+			cfg:     GeneratorConfig{Shape: Line, Nodes: 4, Asymmetry: 1.5},
+			wantErr: true,
+		},
+		{
+			name:    "negative churn rate",
+			cfg:     GeneratorConfig{Shape: Line, Nodes: 4, ChurnRate: -1, Duration: 10},
+			wantErr: true,
+		},
+		{
+			name:    "churn rate above 1",
+			cfg:     GeneratorConfig{Shape: Line, Nodes: 4, ChurnRate: 2, Duration: 10},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Generate(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Generate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got.States) != tt.wantLinks {
+				t.Errorf("Generate() got %d link states, want %d", len(got.States), tt.wantLinks)
+			}
+			if len(got.Configs) != tt.wantNodes {
+				t.Errorf("Generate() got %d node configs, want %d", len(got.Configs), tt.wantNodes)
+			}
+			for _, c := range got.Configs {
+				if c.Message.Destination == c.ID {
+					t.Errorf("Generate() node %d sends to itself", c.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerate_reproducible(t *testing.T) {
+	cfg := GeneratorConfig{Shape: ErdosRenyi, Nodes: 12, Probability: 0.4, ChurnRate: 0.05, Duration: 60, Seed: 7}
+	a, err := Generate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Generate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("Generate() is not reproducible for the same seed")
+	}
+}
+
+func TestGeneratedScenario_roundTrip(t *testing.T) {
+	g, err := Generate(GeneratorConfig{Shape: Grid, Rows: 4, Cols: 4, ChurnRate: 0.1, Duration: 50, MessageDelay: 30, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var states bytes.Buffer
+	if err := g.WriteTopology(&states); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := topology.Read(strings.NewReader(states.String())); err != nil {
+		t.Errorf("generated topology does not parse: %s", err)
+	}
+
+	var configs bytes.Buffer
+	if err := g.WriteNodeConfiguration(&configs); err != nil {
+		t.Fatal(err)
+	}
+	got, err := controller.ReadNodeConfiguration(strings.NewReader(configs.String()))
+	if err != nil {
+		t.Fatalf("generated node configuration does not parse: %s", err)
+	}
+	if !reflect.DeepEqual(got, g.Configs) {
+		t.Errorf("ReadNodeConfiguration() got = %v, want %v", got, g.Configs)
+	}
+
+	s := g.Scenario()
+	if _, err := s.Topology(); err != nil {
+		t.Errorf("Scenario() topology does not parse: %s", err)
+	}
+	if got := s.NodeConfigs(); !reflect.DeepEqual(got, g.Configs) {
+		t.Errorf("Scenario() NodeConfigs() got = %v, want %v", got, g.Configs)
+	}
+}
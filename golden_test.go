@@ -0,0 +1,98 @@
+package olsrsim
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim/trace"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files of TestGolden with the current output")
+
+// TestGolden runs every scenario of testdata to completion, and compares the trace of the run and the final routing
+// tables of its nodes against the golden files of testdata/golden, to catch any change in behavior. Run with -update
+// to rewrite the golden files once a change is intended.
+func TestGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no scenarios in testdata")
+	}
+	for _, path := range paths {
+		path := path
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			s, err := LoadScenario(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := s.Controller()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetTickDuration(0)
+			h, err := s.TraceHeader()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tb bytes.Buffer
+			w, err := trace.NewWriter(&tb, h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Observe(w)
+			c.Start(s.Duration())
+			if err := w.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			var rb bytes.Buffer
+			for _, r := range c.Routers() {
+				fmt.Fprintf(&rb, "node %d:\n", r.ID())
+				for _, route := range r.Routes() {
+					fmt.Fprintf(&rb, "  %s\n", describeRoute(route.Destination, route, true))
+				}
+			}
+
+			golden(t, filepath.Join("testdata", "golden", name+".trace"), tb.Bytes())
+			golden(t, filepath.Join("testdata", "golden", name+".routes"), rb.Bytes())
+		})
+	}
+}
+
+// golden compares got against the golden file at path, or rewrites it with got if -update is given. Mismatches are
+// reported by their first differing line, as golden files are too long to be reported whole.
+func golden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s: %v: run go test -run TestGolden -update to create it", path, err)
+	}
+	if bytes.Equal(got, want) {
+		return
+	}
+	gotLines, wantLines := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) && i < len(wantLines); i++ {
+		if gotLines[i] != wantLines[i] {
+			t.Fatalf("%s: line %d differs:\ngot  %s\nwant %s\nrun go test -run TestGolden -update if the change is intended", path, i+1, gotLines[i], wantLines[i])
+		}
+	}
+	t.Fatalf("%s: got %d lines, want %d: run go test -run TestGolden -update if the change is intended", path, len(gotLines), len(wantLines))
+}
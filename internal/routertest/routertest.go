@@ -0,0 +1,111 @@
+// Package routertest drives routers in lockstep, as the controller does, so that the tests of a protocol can inspect
+// its nodes between ticks without a topology.
+package routertest
+
+import (
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+// Network is a set of nodes linked by symmetric links which may be cut. Lossy links drop every other envelope sent
+// over them, in each direction.
+type Network[N controller.Router] struct {
+	// Nodes are ticked in order.
+	Nodes []N
+
+	links map[[2]message.NodeID]bool
+	lossy map[[2]message.NodeID]bool
+
+	// sent counts the envelopes sent over each lossy link, in each direction.
+	sent map[[2]message.NodeID]int
+
+	// inFlight holds the envelopes to deliver during the next tick, along with their recipient.
+	inFlight []delivery
+
+	// Delivered holds the data which reached its destination.
+	Delivered []string
+}
+
+type delivery struct {
+	to  message.NodeID
+	env message.Envelope
+}
+
+// New creates a Network of nodes 0 to size-1, made by newNode, without any links.
+func New[N controller.Router](size int, newNode func(id message.NodeID) N) *Network[N] {
+	nw := &Network[N]{
+		links: make(map[[2]message.NodeID]bool),
+		lossy: make(map[[2]message.NodeID]bool),
+		sent:  make(map[[2]message.NodeID]int),
+	}
+	for i := 0; i < size; i++ {
+		nw.Nodes = append(nw.Nodes, newNode(message.NodeID(i)))
+	}
+	return nw
+}
+
+// NewLine creates a Network of nodes 0 to size-1, made by newNode, each linked to the next.
+func NewLine[N controller.Router](size int, newNode func(id message.NodeID) N) *Network[N] {
+	nw := New(size, newNode)
+	for i := 1; i < size; i++ {
+		nw.Link(message.NodeID(i-1), message.NodeID(i), true)
+	}
+	return nw
+}
+
+// Link brings the link between a and b up or down, in both directions.
+func (nw *Network[N]) Link(a, b message.NodeID, up bool) {
+	for _, l := range [][2]message.NodeID{{a, b}, {b, a}} {
+		nw.links[l] = up
+		nw.lossy[l] = false
+	}
+}
+
+// LinkLossy brings the link between a and b up, as a lossy link.
+func (nw *Network[N]) LinkLossy(a, b message.NodeID) {
+	for _, l := range [][2]message.NodeID{{a, b}, {b, a}} {
+		nw.links[l] = true
+		nw.lossy[l] = true
+	}
+}
+
+// Tick delivers the envelopes sent during the previous tick, then ticks every node, in order.
+func (nw *Network[N]) Tick() {
+	inbox := nw.inFlight
+	nw.inFlight = nil
+	var sent []message.Envelope
+	for _, n := range nw.Nodes {
+		for _, d := range inbox {
+			if d.to != n.ID() {
+				continue
+			}
+			if dm, ok := d.env.Message.(*message.DataMessage); ok && dm.Destination == n.ID() {
+				nw.Delivered = append(nw.Delivered, dm.Data)
+			}
+			sent = append(sent, n.Receive(d.env)...)
+		}
+		sent = append(sent, n.Tick()...)
+	}
+	for _, env := range sent {
+		for _, n := range nw.Nodes {
+			l := [2]message.NodeID{env.From, n.ID()}
+			if n.ID() == env.From || (env.To != message.Broadcast && env.To != n.ID()) || !nw.links[l] {
+				continue
+			}
+			if nw.lossy[l] {
+				nw.sent[l]++
+				if nw.sent[l]%2 == 0 {
+					continue
+				}
+			}
+			nw.inFlight = append(nw.inFlight, delivery{to: n.ID(), env: env})
+		}
+	}
+}
+
+// Run runs the given number of ticks.
+func (nw *Network[N]) Run(ticks int) {
+	for i := 0; i < ticks; i++ {
+		nw.Tick()
+	}
+}
@@ -0,0 +1,302 @@
+// Package invariant checks invariants of the routing protocols while a simulation runs, once each tick is over:
+//
+//   - ms-set: every MPR selector of a node listed the node as an MPR in the last HELLO the node received from it.
+//   - mpr-coverage: the MPRs of a node cover every strict two-hop neighbor, reachable only through a symmetric
+//     neighbor.
+//   - routing-loop: following routes to a destination from node to node never visits a node twice.
+//   - next-hop: the next hop of every route is a symmetric one-hop neighbor.
+//   - tc-sequence: the TC messages each node originates have increasing sequence numbers.
+//
+// Invariants are checked against the tables of every node, as controller.Inspector reports them, and the messages
+// they exchange. Invariants over tables or messages a protocol does not have hold trivially: ms-set and tc-sequence
+// only apply to the HELLO and TC messages of OLSR, and next-hop to nodes of link-state protocols, which implement
+// LinkState.
+package invariant
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+// Names of the invariants.
+const (
+	MSSet       = "ms-set"
+	MPRCoverage = "mpr-coverage"
+	RoutingLoop = "routing-loop"
+	NextHop     = "next-hop"
+	TCSequence  = "tc-sequence"
+)
+
+// LinkState is implemented by Routers of link-state protocols, such as OLSR and OLSRv2, which compute their routes
+// from the symmetric neighbors they report. The next-hop invariant only holds for them: distance-vector protocols,
+// such as AODV, keep routes to neighbors they no longer hear from until the routes time out.
+type LinkState interface {
+	controller.Inspector
+
+	// LinkState does nothing, and only marks the Router as being of a link-state protocol.
+	LinkState()
+}
+
+// Violation is an invariant which did not hold.
+type Violation struct {
+	// Tick is the tick at the end of which the invariant did not hold, or during which the message breaking it was
+	// sent.
+	Tick int `json:"tick"`
+
+	// Node is the node the invariant did not hold for.
+	Node message.NodeID `json:"node"`
+
+	// Invariant names the invariant.
+	Invariant string `json:"invariant"`
+
+	// Detail describes how the invariant was broken.
+	Detail string `json:"detail"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("tick %d: node %d: %s: %s", v.Tick, v.Node, v.Invariant, v.Detail)
+}
+
+// Checker checks the invariants of a running simulation. Checker implements controller.TickObserver.
+type Checker struct {
+	c *controller.Controller
+
+	// report is passed each violation as it is found, if it is not nil.
+	report func(v Violation)
+
+	// abort stops the simulation at the end of the first tick with a violation.
+	abort bool
+
+	// hellos holds the last HELLO each node received from each of its neighbors, by receiver then sender.
+	hellos map[message.NodeID]map[message.NodeID]*message.HelloMessage
+
+	// tcSequences holds the sequence number of the last TC each node originated.
+	tcSequences map[message.NodeID]int
+
+	violations []Violation
+
+	// violated is set once a violation was found during the current tick.
+	violated bool
+}
+
+// New creates a Checker of c's simulation, registering it as an Observer of c. Violations are passed to report as
+// they are found, if it is not nil. If abort is set, c is stopped at the end of the first tick with a violation.
+func New(c *controller.Controller, report func(v Violation), abort bool) *Checker {
+	k := &Checker{
+		c:           c,
+		report:      report,
+		abort:       abort,
+		hellos:      make(map[message.NodeID]map[message.NodeID]*message.HelloMessage),
+		tcSequences: make(map[message.NodeID]int),
+	}
+	c.Observe(k)
+	return k
+}
+
+// Violations returns every violation found so far, in the order they were found.
+func (k *Checker) Violations() []Violation {
+	return k.violations
+}
+
+// violation records a violation.
+func (k *Checker) violation(tick int, node message.NodeID, invariant string, format string, a ...any) {
+	v := Violation{Tick: tick, Node: node, Invariant: invariant, Detail: fmt.Sprintf(format, a...)}
+	k.violations = append(k.violations, v)
+	k.violated = true
+	if k.report != nil {
+		k.report(v)
+	}
+}
+
+// Event records the messages invariants are checked against. It is called by the Controller.
+func (k *Checker) Event(e controller.Event) {
+	switch e.Kind {
+	case controller.EventReceive:
+		if hello, ok := e.Envelope.Message.(*message.HelloMessage); ok {
+			if k.hellos[e.Node] == nil {
+				k.hellos[e.Node] = make(map[message.NodeID]*message.HelloMessage)
+			}
+			k.hellos[e.Node][hello.Source] = hello
+		}
+	case controller.EventNode:
+		// A restarted node knows nothing of what it received, and starts its sequence numbers over unless they persist.
+		delete(k.hellos, e.Node)
+		if e.NodeChange.Up && !e.NodeChange.Persist {
+			delete(k.tcSequences, e.Node)
+		}
+	case controller.EventJoin, controller.EventLeave:
+		delete(k.hellos, e.Node)
+		delete(k.tcSequences, e.Node)
+	case controller.EventSend:
+		// Forwarded TCs keep the sequence number they were originated with.
+		tc, ok := e.Envelope.Message.(*message.TCMessage)
+		if !ok || tc.Source != e.Node || tc.FromNeighbor != e.Node {
+			return
+		}
+		if last, in := k.tcSequences[e.Node]; in && tc.Sequence <= last {
+			k.violation(e.Tick, e.Node, TCSequence, "originated TC with sequence %d after sequence %d", tc.Sequence, last)
+		}
+		k.tcSequences[e.Node] = tc.Sequence
+	}
+}
+
+// TickOver checks the tables of every node, stopping the simulation if asked to once an invariant did not hold. It
+// is called by the Controller.
+func (k *Checker) TickOver(tick int) {
+	routers := k.c.Routers()
+	states := make(map[message.NodeID]controller.NodeState, len(routers))
+	for _, r := range routers {
+		state, err := k.c.Inspect(r.ID())
+		if err != nil {
+			continue
+		}
+		states[r.ID()] = state
+	}
+
+	for _, r := range routers {
+		state := states[r.ID()]
+		k.checkMSSet(tick, state)
+		k.checkMPRCoverage(tick, state)
+		if _, ok := r.(LinkState); ok {
+			k.checkNextHops(tick, state)
+		}
+		k.checkLoops(tick, state, states)
+	}
+
+	if k.abort && k.violated {
+		k.c.Stop()
+	}
+	k.violated = false
+}
+
+// checkMSSet checks every MPR selector of a node selected it in the last HELLO the node received from it. Nodes
+// which received no HELLO do not exchange them, and are not checked.
+func (k *Checker) checkMSSet(tick int, state controller.NodeState) {
+	hellos, ok := k.hellos[state.ID]
+	if !ok {
+		return
+	}
+	for _, selector := range state.MPRSelectors {
+		hello, ok := hellos[selector]
+		if !ok {
+			k.violation(tick, state.ID, MSSet, "MPR selector %d never sent it a HELLO", selector)
+			continue
+		}
+		if !contains(hello.MultipointRelay, state.ID) {
+			k.violation(tick, state.ID, MSSet, "MPR selector %d did not select it in its last HELLO (sequence %d), which selected %s",
+				selector, hello.Sequence, describe(hello.MultipointRelay))
+		}
+	}
+}
+
+// checkMPRCoverage checks every strict two-hop neighbor of a node is reachable through one of its MPRs.
+func (k *Checker) checkMPRCoverage(tick int, state controller.NodeState) {
+	covered := make(map[message.NodeID]bool)
+	var strict []message.NodeID
+	for _, th := range state.TwoHopNeighbors {
+		if th.ID == state.ID || contains(state.Neighbors, th.ID) || !contains(state.Neighbors, th.Via) {
+			continue
+		}
+		if contains(state.MPRs, th.Via) {
+			covered[th.ID] = true
+		}
+		if !contains(strict, th.ID) {
+			strict = append(strict, th.ID)
+		}
+	}
+	for _, id := range strict {
+		if !covered[id] {
+			k.violation(tick, state.ID, MPRCoverage, "two-hop neighbor %d is not reachable through its MPRs %s", id, describe(state.MPRs))
+		}
+	}
+}
+
+// checkNextHops checks every route of a node goes through a symmetric neighbor.
+func (k *Checker) checkNextHops(tick int, state controller.NodeState) {
+	for _, r := range state.Routes {
+		if !contains(state.Neighbors, r.NextHop) {
+			k.violation(tick, state.ID, NextHop, "route to %d goes through %d, which is not a symmetric neighbor", r.Destination, r.NextHop)
+		}
+	}
+}
+
+// checkLoops follows the routes of a node from node to node, checking none comes back to it. Each loop is reported
+// once, by the node of the loop with the lowest ID.
+func (k *Checker) checkLoops(tick int, state controller.NodeState, states map[message.NodeID]controller.NodeState) {
+	for _, r := range state.Routes {
+		path := []message.NodeID{state.ID}
+		for hop := r.NextHop; hop != r.Destination; {
+			if i := index(path, hop); i >= 0 {
+				loop := append(path[i:], hop)
+				if i == 0 && lowest(loop) == state.ID {
+					k.violation(tick, state.ID, RoutingLoop, "route to %d loops: %s", r.Destination, describePath(loop))
+				}
+				break
+			}
+			path = append(path, hop)
+			next, ok := route(states[hop], r.Destination)
+			if !ok {
+				break
+			}
+			hop = next.NextHop
+		}
+	}
+}
+
+// route returns the route of a node to a destination, if it has one.
+func route(state controller.NodeState, dst message.NodeID) (controller.Route, bool) {
+	for _, r := range state.Routes {
+		if r.Destination == dst {
+			return r, true
+		}
+	}
+	return controller.Route{}, false
+}
+
+// index returns the index of id in ids, or -1 if it is not in it.
+func index(ids []message.NodeID, id message.NodeID) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// contains reports whether ids holds id.
+func contains(ids []message.NodeID, id message.NodeID) bool {
+	return index(ids, id) >= 0
+}
+
+// lowest returns the lowest of ids, which must not be empty.
+func lowest(ids []message.NodeID) message.NodeID {
+	min := ids[0]
+	for _, id := range ids[1:] {
+		if id < min {
+			min = id
+		}
+	}
+	return min
+}
+
+// describe lists nodes as a set.
+func describe(ids []message.NodeID) string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.Itoa(int(id))
+	}
+	return "{" + strings.Join(strs, " ") + "}"
+}
+
+// describePath lists the nodes of a path, in order.
+func describePath(ids []message.NodeID) string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.Itoa(int(id))
+	}
+	return strings.Join(strs, " -> ")
+}
@@ -0,0 +1,280 @@
+package invariant
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim"
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/olsr"
+	"github.com/kprusa/olsrsim/topology"
+)
+
+// fakeRouter has fixed tables, and sends scripted messages at given ticks.
+type fakeRouter struct {
+	state  controller.NodeState
+	script map[int][]message.Message
+	tick   int
+}
+
+func (r *fakeRouter) ID() message.NodeID {
+	return r.state.ID
+}
+
+func (r *fakeRouter) Receive(message.Envelope) []message.Envelope {
+	return nil
+}
+
+func (r *fakeRouter) Send(message.NodeID, string) {}
+
+func (r *fakeRouter) Tick() []message.Envelope {
+	defer func() { r.tick++ }()
+	var out []message.Envelope
+	for _, msg := range r.script[r.tick] {
+		out = append(out, message.Envelope{To: message.Broadcast, Message: msg})
+	}
+	return out
+}
+
+func (r *fakeRouter) Routes() []controller.Route {
+	return r.state.Routes
+}
+
+func (r *fakeRouter) Inspect() controller.NodeState {
+	return r.state
+}
+
+func (r *fakeRouter) LinkState() {}
+
+// run runs routers over links for ticks, checking invariants, and returns the violations found and whether the run
+// was stopped.
+func run(t *testing.T, links string, routers []*fakeRouter, ticks int, abort bool) ([]Violation, bool) {
+	t.Helper()
+	nwt, err := topology.Read(strings.NewReader(links))
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[message.NodeID]*fakeRouter)
+	configs := make([]controller.NodeConfig, 0, len(routers))
+	for _, r := range routers {
+		byID[r.state.ID] = r
+		configs = append(configs, controller.NodeConfig{ID: r.state.ID, Message: controller.NodeMessage{Sent: true}})
+	}
+	c := controller.New(nwt, 0, func(config controller.NodeConfig) (controller.Router, error) {
+		return byID[config.ID], nil
+	})
+	if err := c.Initialize(configs); err != nil {
+		t.Fatal(err)
+	}
+	var reported []Violation
+	k := New(c, func(v Violation) { reported = append(reported, v) }, abort)
+	c.Start(ticks)
+	if !reflect.DeepEqual(reported, k.Violations()) {
+		t.Errorf("reported %v, want %v", reported, k.Violations())
+	}
+	return k.Violations(), c.Stopped()
+}
+
+func TestChecker(t *testing.T) {
+	line := "0 UP 0 <-> 1\n0 UP 1 <-> 2\n"
+	tests := []struct {
+		name    string
+		links   string
+		routers []*fakeRouter
+		want    []Violation
+	}{
+		{
+			name:  "consistent",
+			links: line,
+			routers: []*fakeRouter{
+				{
+					state: controller.NodeState{ID: 0, Neighbors: []message.NodeID{1}, MPRs: []message.NodeID{1},
+						TwoHopNeighbors: []controller.TwoHopNeighbor{{ID: 2, Via: 1}},
+						Routes:          []controller.Route{{Destination: 1, NextHop: 1, Distance: 1}, {Destination: 2, NextHop: 1, Distance: 2}}},
+					script: map[int][]message.Message{
+						0: {&message.HelloMessage{Source: 0, MultipointRelay: []message.NodeID{1}, Sequence: 0}},
+						1: {&message.TCMessage{Source: 0, FromNeighbor: 0, Sequence: 0}},
+						2: {&message.TCMessage{Source: 0, FromNeighbor: 0, Sequence: 1}},
+					},
+				},
+				{
+					state: controller.NodeState{ID: 1, Neighbors: []message.NodeID{0, 2}, MPRSelectors: []message.NodeID{0},
+						Routes: []controller.Route{{Destination: 0, NextHop: 0, Distance: 1}, {Destination: 2, NextHop: 2, Distance: 1}}},
+					// Forwarded TCs keep the sequence number of their originator.
+					script: map[int][]message.Message{
+						2: {&message.TCMessage{Source: 0, FromNeighbor: 1, Sequence: 0}},
+					},
+				},
+				{state: controller.NodeState{ID: 2, Neighbors: []message.NodeID{1}}},
+			},
+		},
+		{
+			name:  "ms-set",
+			links: line,
+			routers: []*fakeRouter{
+				{
+					state: controller.NodeState{ID: 0, Neighbors: []message.NodeID{1}},
+					script: map[int][]message.Message{
+						0: {&message.HelloMessage{Source: 0, MultipointRelay: []message.NodeID{2}, Sequence: 4}},
+					},
+				},
+				{state: controller.NodeState{ID: 1, Neighbors: []message.NodeID{0, 2}, MPRSelectors: []message.NodeID{0, 2}}},
+				{state: controller.NodeState{ID: 2, Neighbors: []message.NodeID{1}}},
+			},
+			// Node 1 is not checked until it receives node 0's HELLO, at tick 1.
+			want: []Violation{
+				{Tick: 1, Node: 1, Invariant: MSSet, Detail: "MPR selector 0 did not select it in its last HELLO (sequence 4), which selected {2}"},
+				{Tick: 1, Node: 1, Invariant: MSSet, Detail: "MPR selector 2 never sent it a HELLO"},
+				{Tick: 2, Node: 1, Invariant: MSSet, Detail: "MPR selector 0 did not select it in its last HELLO (sequence 4), which selected {2}"},
+				{Tick: 2, Node: 1, Invariant: MSSet, Detail: "MPR selector 2 never sent it a HELLO"},
+			},
+		},
+		{
+			name:  "mpr coverage",
+			links: line,
+			routers: []*fakeRouter{
+				{state: controller.NodeState{ID: 0, Neighbors: []message.NodeID{1, 3}, MPRs: []message.NodeID{1},
+					TwoHopNeighbors: []controller.TwoHopNeighbor{
+						{ID: 2, Via: 1},
+						// 3 is a neighbor, and 5 is only reachable through 6, which is not a symmetric neighbor.
+						{ID: 3, Via: 1},
+						{ID: 4, Via: 1},
+						{ID: 4, Via: 3},
+						{ID: 5, Via: 6},
+						{ID: 6, Via: 3},
+					}}},
+			},
+			want: []Violation{
+				{Tick: 0, Node: 0, Invariant: MPRCoverage, Detail: "two-hop neighbor 6 is not reachable through its MPRs {1}"},
+				{Tick: 1, Node: 0, Invariant: MPRCoverage, Detail: "two-hop neighbor 6 is not reachable through its MPRs {1}"},
+				{Tick: 2, Node: 0, Invariant: MPRCoverage, Detail: "two-hop neighbor 6 is not reachable through its MPRs {1}"},
+			},
+		},
+		{
+			name:  "next hop",
+			links: line,
+			routers: []*fakeRouter{
+				{state: controller.NodeState{ID: 0, Neighbors: []message.NodeID{1},
+					Routes: []controller.Route{{Destination: 1, NextHop: 1, Distance: 1}, {Destination: 3, NextHop: 3, Distance: 1}}}},
+			},
+			want: []Violation{
+				{Tick: 0, Node: 0, Invariant: NextHop, Detail: "route to 3 goes through 3, which is not a symmetric neighbor"},
+				{Tick: 1, Node: 0, Invariant: NextHop, Detail: "route to 3 goes through 3, which is not a symmetric neighbor"},
+				{Tick: 2, Node: 0, Invariant: NextHop, Detail: "route to 3 goes through 3, which is not a symmetric neighbor"},
+			},
+		},
+		{
+			name:  "routing loop",
+			links: line,
+			routers: []*fakeRouter{
+				// Node 0 leads into the loop, which is reported once, by node 1.
+				{state: controller.NodeState{ID: 0, Neighbors: []message.NodeID{1}, Routes: []controller.Route{{Destination: 3, NextHop: 1, Distance: 3}}}},
+				{state: controller.NodeState{ID: 1, Neighbors: []message.NodeID{0, 2}, Routes: []controller.Route{{Destination: 3, NextHop: 2, Distance: 2}}}},
+				{state: controller.NodeState{ID: 2, Neighbors: []message.NodeID{1}, Routes: []controller.Route{{Destination: 3, NextHop: 1, Distance: 2}}}},
+			},
+			want: []Violation{
+				{Tick: 0, Node: 1, Invariant: RoutingLoop, Detail: "route to 3 loops: 1 -> 2 -> 1"},
+				{Tick: 1, Node: 1, Invariant: RoutingLoop, Detail: "route to 3 loops: 1 -> 2 -> 1"},
+				{Tick: 2, Node: 1, Invariant: RoutingLoop, Detail: "route to 3 loops: 1 -> 2 -> 1"},
+			},
+		},
+		{
+			name:  "tc sequence",
+			links: line,
+			routers: []*fakeRouter{
+				{
+					state: controller.NodeState{ID: 0},
+					script: map[int][]message.Message{
+						0: {&message.TCMessage{Source: 0, FromNeighbor: 0, Sequence: 3}},
+						2: {&message.TCMessage{Source: 0, FromNeighbor: 0, Sequence: 3}},
+					},
+				},
+			},
+			want: []Violation{
+				{Tick: 2, Node: 0, Invariant: TCSequence, Detail: "originated TC with sequence 3 after sequence 3"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stopped := run(t, tt.links, tt.routers, 3, false)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Violations() = %v, want %v", got, tt.want)
+			}
+			if stopped {
+				t.Errorf("Stopped() = true without abort, want false")
+			}
+		})
+	}
+}
+
+func TestChecker_abort(t *testing.T) {
+	routers := []*fakeRouter{
+		{state: controller.NodeState{ID: 0, Routes: []controller.Route{{Destination: 1, NextHop: 1, Distance: 1}}}},
+		{state: controller.NodeState{ID: 1}},
+	}
+	got, stopped := run(t, "0 UP 0 <-> 1\n", routers, 10, true)
+	want := []Violation{{Tick: 0, Node: 0, Invariant: NextHop, Detail: "route to 1 goes through 1, which is not a symmetric neighbor"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Violations() = %v, want %v", got, want)
+	}
+	if !stopped {
+		t.Errorf("Stopped() = false, want true")
+	}
+}
+
+// TestChecker_olsr checks OLSR nodes uphold every invariant as links go down and come back up.
+func TestChecker_olsr(t *testing.T) {
+	links := "0 UP 0 <-> 1\n0 UP 1 <-> 2\n0 UP 2 <-> 3\n0 UP 3 <-> 4\n0 UP 1 <-> 3\n30 DOWN 1 <-> 3\n60 DOWN 3 <-> 4\n80 UP 1 <-> 3\n"
+	nwt, err := topology.Read(strings.NewReader(links))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := controller.New(nwt, 0, olsr.Protocol(olsr.DefaultParams(), nil))
+	var configs []controller.NodeConfig
+	for id := message.NodeID(0); id < 5; id++ {
+		configs = append(configs, controller.NodeConfig{ID: id, Message: controller.NodeMessage{Sent: true}})
+	}
+	if err := c.Initialize(configs); err != nil {
+		t.Fatal(err)
+	}
+	k := New(c, nil, false)
+	c.Start(120)
+	if got := k.Violations(); len(got) > 0 {
+		t.Errorf("Violations() = %v, want none", got)
+	}
+}
+
+// TestChecker_protocols checks nodes of every protocol uphold the invariants which apply to them over the demonstration
+// scenario. AODV nodes keep routes to neighbors they stopped hearing HELLOs from, which next-hop must not report.
+func TestChecker_protocols(t *testing.T) {
+	for _, protocol := range []string{"olsr", "olsrv2", "aodv", "dsdv", "flooding"} {
+		t.Run(protocol, func(t *testing.T) {
+			s, err := olsrsim.LoadScenario("../testdata/test_scenario.json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Protocol = protocol
+			c, err := s.Controller()
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.SetTickDuration(0)
+			k := New(c, nil, false)
+			c.Start(s.Duration())
+			if got := k.Violations(); len(got) > 0 {
+				t.Errorf("Violations() = %v, want none", got)
+			}
+		})
+	}
+}
+
+func TestViolation_String(t *testing.T) {
+	v := Violation{Tick: 12, Node: 3, Invariant: NextHop, Detail: "route to 5 goes through 4, which is not a symmetric neighbor"}
+	want := "tick 12: node 3: next-hop: route to 5 goes through 4, which is not a symmetric neighbor"
+	if got := v.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
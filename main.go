@@ -1,54 +0,0 @@
-package main
-
-import (
-	"flag"
-	"fmt"
-	"os"
-	"time"
-)
-
-func main() {
-	tf := flag.String("tf", "", "Topology file path (Required)")
-	nf := flag.String("nf", "", "Node configuration file path (Required)")
-	t := flag.Int("t", 1000, "Tick duration in milliseconds. Specifies how fast the simulation will Run")
-	d := flag.Int("rt", 120, "Number of ticks to Run the simulation for.")
-	flag.Parse()
-
-	if *tf == "" || *nf == "" {
-		flag.PrintDefaults()
-		os.Exit(1)
-	}
-
-	f, err := os.Open(*tf)
-	if err != nil {
-		fmt.Printf("unable to open topology file: %s", *tf)
-		os.Exit(1)
-	}
-	nwt, err := NewNetworkTypology(f)
-	if err != nil {
-		fmt.Printf("invalid network topology file: %s", err)
-		os.Exit(1)
-	}
-	if err := f.Close(); err != nil {
-		fmt.Printf("could not close network topology file: %s", err)
-	}
-
-	f, err = os.Open(*nf)
-	if err != nil {
-		fmt.Printf("unable to open topology file: %s", *tf)
-		os.Exit(1)
-	}
-	configs, err := ReadNodeConfiguration(f)
-	if err != nil {
-		fmt.Printf("invalid node configuration file: %s", err)
-		os.Exit(1)
-	}
-	if err := f.Close(); err != nil {
-		fmt.Printf("could not close node configuration file: %s", err)
-	}
-
-	td := time.Millisecond * time.Duration(*t)
-	c := NewController(*nwt, td)
-	c.Initialize(configs)
-	c.Start(*d)
-}
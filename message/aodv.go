@@ -0,0 +1,114 @@
+package message
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RREQMessage represents an AODV route request (RREQ) message.
+type RREQMessage struct {
+	// FromNeighbor is the node which last broadcast the request.
+	FromNeighbor NodeID `json:"fromNeighbor"`
+
+	// ID identifies the request, together with Originator.
+	ID int `json:"id"`
+
+	Originator         NodeID `json:"originator"`
+	OriginatorSequence int    `json:"originatorSequence"`
+
+	Destination         NodeID `json:"destination"`
+	DestinationSequence int    `json:"destinationSequence"`
+
+	// UnknownSequence is set when the originator knows no sequence number for Destination.
+	UnknownSequence bool `json:"unknownSequence"`
+
+	// HopCount is the number of hops from Originator to the node handling the request.
+	HopCount int `json:"hopCount"`
+
+	// TTL is the number of further hops the request may be rebroadcast.
+	TTL int `json:"ttl"`
+}
+
+// Type is RREQ.
+func (m RREQMessage) Type() string {
+	return "RREQ"
+}
+
+func (m RREQMessage) String() string {
+	dstSeq := strconv.Itoa(m.DestinationSequence)
+	if m.UnknownSequence {
+		dstSeq = "?"
+	}
+	f := "* %d RREQ ID %d ORIG %d %d DST %d %s HOPS %d TTL %d"
+	return fmt.Sprintf(f, m.FromNeighbor, m.ID, m.Originator, m.OriginatorSequence, m.Destination, dstSeq, m.HopCount, m.TTL)
+}
+
+// RREPMessage represents an AODV route reply (RREP) message.
+// A RREP broadcast by a node for itself, with a HopCount of 0, is a HELLO.
+type RREPMessage struct {
+	// NextHop is the neighbor the reply is sent to, or Broadcast for a HELLO.
+	NextHop NodeID `json:"nextHop"`
+
+	// FromNeighbor is the node which last sent the reply.
+	FromNeighbor NodeID `json:"fromNeighbor"`
+
+	Originator NodeID `json:"originator"`
+
+	Destination         NodeID `json:"destination"`
+	DestinationSequence int    `json:"destinationSequence"`
+
+	// HopCount is the number of hops from the node handling the reply to Destination.
+	HopCount int `json:"hopCount"`
+
+	// Lifetime is the number of ticks the route to Destination is valid for.
+	Lifetime int `json:"lifetime"`
+}
+
+// Hello determines whether the reply is a HELLO, broadcast by a node for itself.
+func (m RREPMessage) Hello() bool {
+	return m.NextHop == Broadcast
+}
+
+// Type is HELLO for a HELLO, and RREP otherwise.
+func (m RREPMessage) Type() string {
+	if m.Hello() {
+		return "HELLO"
+	}
+	return "RREP"
+}
+
+func (m RREPMessage) String() string {
+	f := "%s %d RREP ORIG %d DST %d %d HOPS %d LIFETIME %d"
+	return fmt.Sprintf(f, m.NextHop, m.FromNeighbor, m.Originator, m.Destination, m.DestinationSequence, m.HopCount, m.Lifetime)
+}
+
+// Unreachable is a destination which has become unreachable, along with its latest sequence number.
+type Unreachable struct {
+	Destination NodeID `json:"destination"`
+	Sequence    int    `json:"sequence"`
+}
+
+// RERRMessage represents an AODV route error (RERR) message.
+type RERRMessage struct {
+	// NextHop is the neighbor the error is sent to, or Broadcast.
+	NextHop NodeID `json:"nextHop"`
+
+	// FromNeighbor is the node which sent the error.
+	FromNeighbor NodeID `json:"fromNeighbor"`
+
+	Unreachable []Unreachable `json:"unreachable"`
+}
+
+// Type is RERR.
+func (m RERRMessage) Type() string {
+	return "RERR"
+}
+
+func (m RERRMessage) String() string {
+	dsts := make([]string, 0, len(m.Unreachable))
+	for _, u := range m.Unreachable {
+		dsts = append(dsts, fmt.Sprintf("%d:%d", u.Destination, u.Sequence))
+	}
+	return fmt.Sprintf("%s %d RERR %s", m.NextHop, m.FromNeighbor, strings.Join(dsts, " "))
+}
@@ -0,0 +1,88 @@
+package message
+
+import "testing"
+
+func TestRREQMessage_String(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  RREQMessage
+		want string
+	}{
+		{
+			name: "known sequence",
+			msg:  RREQMessage{FromNeighbor: 1, ID: 2, Originator: 0, OriginatorSequence: 3, Destination: 4, DestinationSequence: 5, HopCount: 1, TTL: 34},
+			want: "* 1 RREQ ID 2 ORIG 0 3 DST 4 5 HOPS 1 TTL 34",
+		},
+		{
+			name: "unknown sequence",
+			msg:  RREQMessage{FromNeighbor: 0, ID: 1, Originator: 0, OriginatorSequence: 1, Destination: 4, UnknownSequence: true, TTL: 35},
+			want: "* 0 RREQ ID 1 ORIG 0 1 DST 4 ? HOPS 0 TTL 35",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.msg.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRREPMessage_String(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  RREPMessage
+		want string
+	}{
+		{
+			name: "reply",
+			msg:  RREPMessage{NextHop: 2, FromNeighbor: 3, Originator: 0, Destination: 4, DestinationSequence: 5, HopCount: 1, Lifetime: 60},
+			want: "2 3 RREP ORIG 0 DST 4 5 HOPS 1 LIFETIME 60",
+		},
+		{
+			name: "hello",
+			msg:  RREPMessage{NextHop: Broadcast, FromNeighbor: 3, Originator: 3, Destination: 3, DestinationSequence: 1, Lifetime: 10},
+			want: "* 3 RREP ORIG 3 DST 3 1 HOPS 0 LIFETIME 10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.msg.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRREPMessage_Type(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  RREPMessage
+		want string
+	}{
+		{
+			name: "reply",
+			msg:  RREPMessage{NextHop: 2, FromNeighbor: 3, Originator: 0, Destination: 4, DestinationSequence: 5, HopCount: 1, Lifetime: 60},
+			want: "RREP",
+		},
+		{
+			name: "hello",
+			msg:  RREPMessage{NextHop: Broadcast, FromNeighbor: 3, Originator: 3, Destination: 3, DestinationSequence: 1, Lifetime: 10},
+			want: "HELLO",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.msg.Type(); got != tt.want {
+				t.Errorf("Type() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRERRMessage_String(t *testing.T) {
+	m := RERRMessage{NextHop: Broadcast, FromNeighbor: 2, Unreachable: []Unreachable{{Destination: 3, Sequence: 1}, {Destination: 5, Sequence: 4}}}
+	if got, want := m.String(), "* 2 RERR 3:1 5:4"; got != want {
+		t.Errorf("String() = %v, want %v", got, want)
+	}
+}
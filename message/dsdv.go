@@ -0,0 +1,55 @@
+package message
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// InfiniteMetric is the metric advertised by DSDV for an unreachable destination.
+const InfiniteMetric = 255
+
+// Advertisement is a route advertised in an UpdateMessage.
+type Advertisement struct {
+	Destination NodeID `json:"destination"`
+
+	// Metric is the number of hops from the advertising node to Destination, or InfiniteMetric.
+	Metric int `json:"metric"`
+
+	// Sequence is the sequence number of Destination. Even sequence numbers are issued by Destination, and odd ones
+	// by a node which lost its route to Destination.
+	Sequence int `json:"sequence"`
+}
+
+// UpdateMessage represents a DSDV routing table update, broadcast periodically with every route, or when routes
+// change with only the changed routes.
+type UpdateMessage struct {
+	// FromNeighbor is the node which sent the update.
+	FromNeighbor NodeID `json:"fromNeighbor"`
+
+	// Full is set for a periodic update advertising every route.
+	Full bool `json:"full"`
+
+	Routes []Advertisement `json:"routes"`
+}
+
+// Type is UPDATE.
+func (m UpdateMessage) Type() string {
+	return "UPDATE"
+}
+
+func (m UpdateMessage) String() string {
+	kind := "INCR"
+	if m.Full {
+		kind = "FULL"
+	}
+	routes := make([]string, 0, len(m.Routes))
+	for _, r := range m.Routes {
+		metric := strconv.Itoa(r.Metric)
+		if r.Metric >= InfiniteMetric {
+			metric = "inf"
+		}
+		routes = append(routes, fmt.Sprintf("%d:%s:%d", r.Destination, metric, r.Sequence))
+	}
+	return fmt.Sprintf("* %d UPDATE %s %s", m.FromNeighbor, kind, strings.Join(routes, " "))
+}
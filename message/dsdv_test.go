@@ -0,0 +1,29 @@
+package message
+
+import "testing"
+
+func TestUpdateMessage_String(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  UpdateMessage
+		want string
+	}{
+		{
+			name: "full",
+			msg:  UpdateMessage{FromNeighbor: 2, Full: true, Routes: []Advertisement{{Destination: 2, Sequence: 4}, {Destination: 3, Metric: 1, Sequence: 6}}},
+			want: "* 2 UPDATE FULL 2:0:4 3:1:6",
+		},
+		{
+			name: "unreachable",
+			msg:  UpdateMessage{FromNeighbor: 2, Routes: []Advertisement{{Destination: 3, Metric: InfiniteMetric, Sequence: 7}}},
+			want: "* 2 UPDATE INCR 3:inf:7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.msg.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
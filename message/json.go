@@ -0,0 +1,126 @@
+package message
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// MarshalJSON encodes the NodeID as a number, or as "*" for Broadcast.
+func (n NodeID) MarshalJSON() ([]byte, error) {
+	if n == Broadcast {
+		return []byte(`"*"`), nil
+	}
+	return strconv.AppendUint(nil, uint64(n), 10), nil
+}
+
+// UnmarshalJSON decodes a NodeID encoded by MarshalJSON.
+func (n *NodeID) UnmarshalJSON(data []byte) error {
+	if string(data) == `"*"` {
+		*n = Broadcast
+		return nil
+	}
+	id, err := strconv.ParseUint(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid node ID: %s", data)
+	}
+	*n = NodeID(id)
+	return nil
+}
+
+// registry maps the kinds of registered messages to their types, and back.
+var registry = struct {
+	sync.RWMutex
+	types map[string]reflect.Type
+	kinds map[reflect.Type]string
+}{
+	types: make(map[string]reflect.Type),
+	kinds: make(map[reflect.Type]string),
+}
+
+// Register makes messages of the same type as m encodable as JSON, as part of an Envelope, under the name kind.
+// Protocols register the messages they define, so that recorded envelopes can be decoded. Register panics if kind, or
+// the type of m, is already registered.
+func Register(kind string, m Message) {
+	registry.Lock()
+	defer registry.Unlock()
+	t := reflect.TypeOf(m)
+	if _, in := registry.types[kind]; in {
+		panic(fmt.Sprintf("message: kind %s registered twice", kind))
+	}
+	if _, in := registry.kinds[t]; in {
+		panic(fmt.Sprintf("message: type %s registered twice", t))
+	}
+	registry.types[kind] = t
+	registry.kinds[t] = kind
+}
+
+func init() {
+	Register("HELLO", &HelloMessage{})
+	Register("DATA", &DataMessage{})
+	Register("TC", &TCMessage{})
+	Register("RREQ", &RREQMessage{})
+	Register("RREP", &RREPMessage{})
+	Register("RERR", &RERRMessage{})
+	Register("UPDATE", &UpdateMessage{})
+}
+
+// envelopeJSON is the JSON encoding of an Envelope.
+type envelopeJSON struct {
+	From    NodeID          `json:"from"`
+	To      NodeID          `json:"to"`
+	Kind    string          `json:"kind"`
+	Message json.RawMessage `json:"message"`
+}
+
+// MarshalJSON encodes the Envelope, along with the kind its message was registered under. An error is returned if
+// the message's type is not registered.
+func (e Envelope) MarshalJSON() ([]byte, error) {
+	registry.RLock()
+	kind, in := registry.kinds[reflect.TypeOf(e.Message)]
+	registry.RUnlock()
+	if !in {
+		return nil, fmt.Errorf("message: unregistered message type %T", e.Message)
+	}
+	msg, err := json.Marshal(e.Message)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelopeJSON{From: e.From, To: e.To, Kind: kind, Message: msg})
+}
+
+// UnmarshalJSON decodes an Envelope encoded by MarshalJSON. An error is returned if the kind of its message is not
+// registered.
+func (e *Envelope) UnmarshalJSON(data []byte) error {
+	var ej envelopeJSON
+	if err := json.Unmarshal(data, &ej); err != nil {
+		return err
+	}
+	registry.RLock()
+	t, in := registry.types[ej.Kind]
+	registry.RUnlock()
+	if !in {
+		return fmt.Errorf("message: unregistered message kind: %s", ej.Kind)
+	}
+
+	// Messages are registered either as pointers to structs, or as plain values.
+	var ptr reflect.Value
+	if t.Kind() == reflect.Pointer {
+		ptr = reflect.New(t.Elem())
+	} else {
+		ptr = reflect.New(t)
+	}
+	if err := json.Unmarshal(ej.Message, ptr.Interface()); err != nil {
+		return fmt.Errorf("message: invalid %s message: %w", ej.Kind, err)
+	}
+	msg := ptr
+	if t.Kind() != reflect.Pointer {
+		msg = ptr.Elem()
+	}
+
+	e.From, e.To = ej.From, ej.To
+	e.Message = msg.Interface().(Message)
+	return nil
+}
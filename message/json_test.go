@@ -0,0 +1,99 @@
+package message
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEnvelope_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		env  Envelope
+	}{
+		{
+			name: "hello",
+			env:  Envelope{From: 1, To: Broadcast, Message: &HelloMessage{Source: 1, Bidirectional: []NodeID{2, 3}, Sequence: 4}},
+		},
+		{
+			name: "data",
+			env:  Envelope{From: 1, To: 2, Message: &DataMessage{Source: 0, Destination: 5, NextHop: 2, FromNeighbor: 1, Data: "hi"}},
+		},
+		{
+			name: "rerr",
+			env:  Envelope{From: 3, To: Broadcast, Message: &RERRMessage{NextHop: Broadcast, FromNeighbor: 3, Unreachable: []Unreachable{{Destination: 4, Sequence: 2}}}},
+		},
+		{
+			name: "update",
+			env:  Envelope{From: 3, To: Broadcast, Message: &UpdateMessage{FromNeighbor: 3, Full: true, Routes: []Advertisement{{Destination: 3, Sequence: 2}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.env)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var got Envelope
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.env) {
+				t.Errorf("Unmarshal() = %#v, want %#v", got, tt.env)
+			}
+		})
+	}
+}
+
+func TestEnvelope_MarshalJSON_format(t *testing.T) {
+	env := Envelope{From: 1, To: Broadcast, Message: &TCMessage{Source: 0, FromNeighbor: 1, Sequence: 2, MultipointRelaySet: []NodeID{3}}}
+	data, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"from":1,"to":"*","kind":"TC","message":{"source":0,"fromNeighbor":1,"sequence":2,"mprSet":[3]}}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+}
+
+type unregistered string
+
+func (m unregistered) Type() string   { return "UNREGISTERED" }
+func (m unregistered) String() string { return string(m) }
+
+func TestEnvelope_MarshalJSON_unregistered(t *testing.T) {
+	if _, err := json.Marshal(Envelope{Message: unregistered("x")}); err == nil {
+		t.Errorf("Marshal() succeeded for an unregistered message type")
+	}
+	var env Envelope
+	if err := json.Unmarshal([]byte(`{"from":1,"to":2,"kind":"BABEL","message":{}}`), &env); err == nil {
+		t.Errorf("Unmarshal() succeeded for an unregistered message kind")
+	}
+}
+
+func TestNodeID_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    NodeID
+		wantErr bool
+	}{
+		{name: "number", in: `7`, want: 7},
+		{name: "broadcast", in: `"*"`, want: Broadcast},
+		{name: "negative", in: `-1`, wantErr: true},
+		{name: "string", in: `"7"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got NodeID
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Unmarshal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
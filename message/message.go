@@ -0,0 +1,122 @@
+// Package message defines the messages exchanged by nodes of the simulated ad-hoc network.
+package message
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NodeID is a unique identifier used to differentiate nodes.
+type NodeID uint
+
+func (n NodeID) String() string {
+	if n == Broadcast {
+		return "*"
+	}
+	return strconv.Itoa(int(n))
+}
+
+// Broadcast is the destination of an Envelope sent to every node within range.
+const Broadcast = ^NodeID(0)
+
+// Message is a message exchanged by nodes.
+// Messages are shared by every node that receives them, so nodes must not modify messages they receive.
+type Message interface {
+	fmt.Stringer
+
+	// Type names the kind of message, such as HELLO.
+	Type() string
+}
+
+// Envelope carries a Message over a single hop.
+type Envelope struct {
+	// From is the node transmitting the envelope.
+	From NodeID
+
+	// To is the node the envelope is addressed to, or Broadcast.
+	To NodeID
+
+	Message Message
+}
+
+func (e Envelope) String() string {
+	return e.Message.String()
+}
+
+// separatedString creates a string from the items separated by the separator.
+func separatedString[T fmt.Stringer](items []T, separator string) string {
+	var strs []string
+	for _, item := range items {
+		strs = append(strs, item.String())
+	}
+	return strings.Join(strs, separator)
+}
+
+// HelloMessage represents a HELLO OLSR message.
+type HelloMessage struct {
+	Source          NodeID   `json:"source"`
+	Unidirectional  []NodeID `json:"unidirectional"`
+	Bidirectional   []NodeID `json:"bidirectional"`
+	MultipointRelay []NodeID `json:"mpr"`
+
+	// Sequence numbers are added to ensure hello messages are delivered in order.
+	// The sequence number is needed for the simulation, as hello messages may be delivered out-of-order due to
+	// scheduling of goroutines.
+	// In a real life scenario, a hello message transmitted by a node could never arrive at a neighbor before a
+	// previously transmitted hello message.
+	Sequence int `json:"sequence"`
+}
+
+// Type is HELLO.
+func (m HelloMessage) Type() string {
+	return "HELLO"
+}
+
+func (m HelloMessage) String() string {
+	f := "* %d HELLO UNIDIR %s BIDIR %s MPR %s"
+	return fmt.Sprintf(
+		f,
+		m.Source,
+		separatedString(m.Unidirectional, " "),
+		separatedString(m.Bidirectional, " "),
+		separatedString(m.MultipointRelay, " "),
+	)
+}
+
+// DataMessage represents a DATA message. Every protocol carries data in a DataMessage.
+type DataMessage struct {
+	Source       NodeID `json:"source"`
+	Destination  NodeID `json:"destination"`
+	NextHop      NodeID `json:"nextHop"`
+	FromNeighbor NodeID `json:"fromNeighbor"`
+	Data         string `json:"data"`
+}
+
+// Type is DATA.
+func (m DataMessage) Type() string {
+	return "DATA"
+}
+
+func (m DataMessage) String() string {
+	f := "%s %d DATA %d %d %s"
+	return fmt.Sprintf(f, m.NextHop, m.FromNeighbor, m.Source, m.Destination, m.Data)
+}
+
+// TCMessage represents a topology control (TC) OLSR message.
+type TCMessage struct {
+	Source             NodeID   `json:"source"`
+	FromNeighbor       NodeID   `json:"fromNeighbor"`
+	Sequence           int      `json:"sequence"`
+	MultipointRelaySet []NodeID `json:"mprSet"`
+}
+
+// Type is TC.
+func (m TCMessage) Type() string {
+	return "TC"
+}
+
+func (m TCMessage) String() string {
+	f := "* %d TC %d %d MS %s"
+	return fmt.Sprintf(f, m.FromNeighbor, m.Source, m.Sequence, separatedString(m.MultipointRelaySet, " "))
+}
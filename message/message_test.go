@@ -1,4 +1,4 @@
-package main
+package message
 
 import "testing"
 
@@ -64,6 +64,17 @@ func TestDataMessage_String(t *testing.T) {
 			},
 			want: "3 9 DATA 1 4 hello there",
 		},
+		{
+			name: "broadcast",
+			fields: fields{
+				src:     1,
+				dst:     4,
+				nxtHop:  Broadcast,
+				fromnbr: 1,
+				data:    "hello there",
+			},
+			want: "* 1 DATA 1 4 hello there",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -118,3 +129,29 @@ func TestHelloMessage_String(t *testing.T) {
 		})
 	}
 }
+
+func TestNodeID_String(t *testing.T) {
+	tests := []struct {
+		name string
+		id   NodeID
+		want string
+	}{
+		{
+			name: "node",
+			id:   12,
+			want: "12",
+		},
+		{
+			name: "broadcast",
+			id:   Broadcast,
+			want: "*",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.id.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,733 @@
+// Package olsr implements a simplified OLSR (RFC 3626) node.
+package olsr
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+type topologyEntry struct {
+	// dst is the mpr selector in the received TCMessage.
+	dst message.NodeID
+
+	// originator is the originator of the TCMessage (last-hop node to the destination).
+	originator message.NodeID
+
+	// holdUntil determines how long an entry will be held for before being expelled.
+	holdUntil int
+
+	// seq
+	seq int
+}
+
+type routingEntry struct {
+	// dst is the destination node address (NodeID in this case).
+	dst message.NodeID
+
+	// nextHop is where to send a message to in order to reach the destination.
+	nextHop message.NodeID
+
+	// distance is the number of hops needed to reach the destination.
+	distance int
+}
+
+// NeighborState represents a Node's perception of the state of a link with a neighbor, based on HelloMessage(s).
+type NeighborState int
+
+const (
+	// bidirectional is a link which the Node has received a HelloMessage via, where the HelloMessage includes
+	// the receiving Node's ID in the unidirectional list.
+	bidirectional NeighborState = iota
+
+	// unidirectional is a link which a Node has received a HelloMessage via.
+	unidirectional
+
+	// mpr is a link which a Node has selected as a multipoint relay.
+	mpr
+)
+
+// oneHopNeighborEntry are neighbors that can be reached along a direct link.
+type oneHopNeighborEntry struct {
+	neighborID message.NodeID
+	state      NeighborState
+	holdUntil  int
+}
+
+// pendingData is data waiting for a route to its destination.
+type pendingData struct {
+	msg *message.DataMessage
+
+	// at is the tick the data is next attempted to be sent at.
+	at int
+}
+
+// Node represents a network node in the ad-hoc network. Node implements controller.Router.
+type Node struct {
+	id message.NodeID
+
+	// outbox holds the envelopes sent by the Node since it was last driven by the controller.
+	outbox []message.Envelope
+
+	// pending holds the data waiting for a route to its destination.
+	pending []pendingData
+
+	// routingTable maps destinations to routing entries.
+	routingTable map[message.NodeID]routingEntry
+
+	// routesChanged determines if the routingTable needs to be recalculated.
+	routesChanged bool
+
+	// topologyTable represents the Node's current perception of the network topology.
+	// The first NodeID is the destination's mpr, while the second NodeID is the destination.
+	topologyTable map[message.NodeID]map[message.NodeID]topologyEntry
+
+	// topologyHoldTime is how long, in ticks, topology table entries will be held until they are expelled.
+	topologyHoldTime int
+
+	// tcSequenceNum is the current TCMessage sequence number.
+	tcSequenceNum int
+
+	// tcSequences ensures the node handles, and forwards, each TCMessage at most once by caching the most
+	// recent sequence number received from each originator.
+	tcSequences map[message.NodeID]int
+
+	// oneHopNeighbors is the set of 1-hop neighbors discovered by this node.
+	oneHopNeighbors map[message.NodeID]oneHopNeighborEntry
+
+	// twoHopNeighbors represents the 2-hop neighbors that can be reached via a 1-hop neighbor.
+	// The second map is used for uniqueness and merely maps NodeID(s) to themselves.
+	twoHopNeighbors map[message.NodeID]map[message.NodeID]message.NodeID
+
+	// msSet is the set of nodes that have selected this Node as an mpr.
+	msSet map[message.NodeID]message.NodeID
+
+	// currentTick is the number of ticks since the node came online.
+	currentTick int
+
+	// neighborHoldTime is how long, in ticks, neighbor table entries will be held until they are expelled.
+	neighborHoldTime int
+
+	// helloSequences ensures the node ignores hello messages sent out-of-order by caching the most recent HelloMessage
+	// sequence number received from a Node.
+	helloSequences map[message.NodeID]int
+
+	// helloSequenceNum is the Node's HelloMessage sequence number.
+	helloSequenceNum int
+
+	// helloInterval is how often, in ticks, the Node sends a HelloMessage.
+	helloInterval int
+
+	// tcInterval is how often, in ticks, the Node sends a TCMessage.
+	tcInterval int
+
+	// dataRetryInterval is how long, in ticks, the Node waits to retry sending pending data when there is no route.
+	dataRetryInterval int
+}
+
+// Params are the OLSR protocol parameters of a Node, all in ticks.
+type Params struct {
+	// HelloInterval is how often a Node sends a HelloMessage.
+	HelloInterval int `json:"helloInterval,omitempty"`
+
+	// TCInterval is how often a Node sends a TCMessage.
+	TCInterval int `json:"tcInterval,omitempty"`
+
+	// NeighborHoldTime is how long neighbor table entries are held until they are expelled.
+	NeighborHoldTime int `json:"neighborHoldTime,omitempty"`
+
+	// TopologyHoldTime is how long topology table entries are held until they are expelled.
+	TopologyHoldTime int `json:"topologyHoldTime,omitempty"`
+
+	// DataRetryInterval is how long a Node waits to retry sending data when there is no route.
+	DataRetryInterval int `json:"dataRetryInterval,omitempty"`
+}
+
+// DefaultParams returns the parameters used by a Node unless they are overridden.
+func DefaultParams() Params {
+	return Params{
+		HelloInterval:     5,
+		TCInterval:        10,
+		NeighborHoldTime:  15,
+		TopologyHoldTime:  30,
+		DataRetryInterval: 30,
+	}
+}
+
+// Validate checks the parameters are usable, and that entries are held for longer than they are refreshed.
+func (p Params) Validate() error {
+	switch {
+	case p.HelloInterval <= 0:
+		return fmt.Errorf("invalid params: hello interval must be positive: %d", p.HelloInterval)
+	case p.TCInterval <= 0:
+		return fmt.Errorf("invalid params: tc interval must be positive: %d", p.TCInterval)
+	case p.DataRetryInterval <= 0:
+		return fmt.Errorf("invalid params: data retry interval must be positive: %d", p.DataRetryInterval)
+	case p.NeighborHoldTime <= p.HelloInterval:
+		return fmt.Errorf("invalid params: neighbor hold time (%d) must be greater than the hello interval (%d)", p.NeighborHoldTime, p.HelloInterval)
+	case p.TopologyHoldTime <= p.TCInterval:
+		return fmt.Errorf("invalid params: topology hold time (%d) must be greater than the tc interval (%d)", p.TopologyHoldTime, p.TCInterval)
+	}
+	return nil
+}
+
+// Merge returns p, with each non-zero parameter of override taking precedence.
+func (p Params) Merge(override Params) Params {
+	if override.HelloInterval != 0 {
+		p.HelloInterval = override.HelloInterval
+	}
+	if override.TCInterval != 0 {
+		p.TCInterval = override.TCInterval
+	}
+	if override.NeighborHoldTime != 0 {
+		p.NeighborHoldTime = override.NeighborHoldTime
+	}
+	if override.TopologyHoldTime != 0 {
+		p.TopologyHoldTime = override.TopologyHoldTime
+	}
+	if override.DataRetryInterval != 0 {
+		p.DataRetryInterval = override.DataRetryInterval
+	}
+	return p
+}
+
+// orDefault returns p, with each parameter which is not positive replaced by its default.
+func (p Params) orDefault() Params {
+	d := DefaultParams()
+	if p.HelloInterval <= 0 {
+		p.HelloInterval = d.HelloInterval
+	}
+	if p.TCInterval <= 0 {
+		p.TCInterval = d.TCInterval
+	}
+	if p.NeighborHoldTime <= 0 {
+		p.NeighborHoldTime = d.NeighborHoldTime
+	}
+	if p.TopologyHoldTime <= 0 {
+		p.TopologyHoldTime = d.TopologyHoldTime
+	}
+	if p.DataRetryInterval <= 0 {
+		p.DataRetryInterval = d.DataRetryInterval
+	}
+	return p
+}
+
+// Receive handles a message received by the Node. Messages other than HELLO, TC and DATA are ignored.
+func (n *Node) Receive(env message.Envelope) []message.Envelope {
+	switch msg := env.Message.(type) {
+	case *message.HelloMessage:
+		n.handleHello(msg)
+	case *message.DataMessage:
+		n.handleData(msg)
+	case *message.TCMessage:
+		n.handleTC(msg)
+	default:
+		log.Printf("node %d: ignoring message of unknown type: %s", n.id, env.Message.Type())
+	}
+	return n.flush()
+}
+
+// Send queues data to be sent to dst once there is a route to it.
+func (n *Node) Send(dst message.NodeID, data string) {
+	msg := &message.DataMessage{
+		Source:      n.id,
+		Destination: dst,
+		Data:        data,
+	}
+	n.pending = append(n.pending, pendingData{msg: msg, at: n.currentTick})
+}
+
+// Tick sends the Node's periodic messages and any pending data, expires old table entries, and advances the Node's
+// clock.
+func (n *Node) Tick() []message.Envelope {
+	if n.currentTick%n.helloInterval == 0 {
+		n.sendHello()
+	}
+	if n.currentTick%n.tcInterval == 0 && len(n.msSet) > 0 {
+		n.sendTC()
+	}
+
+	// Attempt to send pending data, retrying later if there is no route.
+	remaining := n.pending[:0]
+	for _, p := range n.pending {
+		if p.at == n.currentTick {
+			if n.sendData(p.msg) {
+				continue
+			}
+			p.at += n.dataRetryInterval
+		}
+		remaining = append(remaining, p)
+	}
+	n.pending = remaining
+
+	// Remove old entries from the neighbor tables. MPRs and routes are recalculated without them. A neighbor is also
+	// no longer an MPR selector, and its HELLO sequence numbers are forgotten, so that it is heard again if it
+	// restarts.
+	expired := false
+	for k, entry := range n.oneHopNeighbors {
+		if entry.holdUntil <= n.currentTick {
+			delete(n.oneHopNeighbors, k)
+			delete(n.twoHopNeighbors, k)
+			delete(n.msSet, k)
+			delete(n.helloSequences, k)
+			expired = true
+		}
+	}
+	if expired {
+		n.oneHopNeighbors = calculateMPRs(n.oneHopNeighbors, n.twoHopNeighbors)
+		n.routesChanged = true
+	}
+	// Remove old entries from the TC tables. The TC sequence numbers of an originator are forgotten along with its last
+	// entry, as with its HELLOs.
+	for orig, dst := range n.topologyTable {
+		for k, entry := range dst {
+			if entry.holdUntil <= n.currentTick {
+				delete(dst, k)
+				n.routesChanged = true
+			}
+		}
+		if len(dst) == 0 {
+			delete(n.topologyTable, orig)
+			delete(n.tcSequences, orig)
+		}
+	}
+
+	if n.routesChanged {
+		n.calculateRoutingTable()
+		n.routesChanged = false
+	}
+
+	n.currentTick++
+	return n.flush()
+}
+
+// send queues msg to be sent to a neighbor, or to every neighbor if to is message.Broadcast.
+func (n *Node) send(to message.NodeID, msg message.Message) {
+	n.outbox = append(n.outbox, message.Envelope{From: n.id, To: to, Message: msg})
+}
+
+// flush returns, and clears, the envelopes sent by the Node.
+func (n *Node) flush() []message.Envelope {
+	out := n.outbox
+	n.outbox = nil
+	return out
+}
+
+// sendData sends msg, as this Node, if there is a route to the destination.
+// msg must not be shared, as its hop fields are updated.
+func (n *Node) sendData(msg *message.DataMessage) bool {
+	route, in := n.routingTable[msg.Destination]
+	if in {
+		msg.FromNeighbor = n.id
+		msg.NextHop = route.nextHop
+		n.send(route.nextHop, msg)
+		return true
+	}
+	return false
+}
+
+// sendHello sends a HelloMessage for this node.
+func (n *Node) sendHello() {
+	// Gather one-hop neighbor entries.
+	biNeighbors := make([]message.NodeID, 0)
+	uniNeighbors := make([]message.NodeID, 0)
+	mprNeighbors := make([]message.NodeID, 0)
+	for _, o := range n.oneHopNeighbors {
+		switch o.state {
+		case unidirectional:
+			uniNeighbors = append(uniNeighbors, o.neighborID)
+		case bidirectional:
+			biNeighbors = append(biNeighbors, o.neighborID)
+		case mpr:
+			mprNeighbors = append(mprNeighbors, o.neighborID)
+		default:
+			log.Panicf("node %d: invalid one-hop neighbor type: %d", n.id, o.state)
+		}
+	}
+
+	sortIDs(uniNeighbors)
+	sortIDs(biNeighbors)
+	sortIDs(mprNeighbors)
+
+	hello := &message.HelloMessage{
+		Source:          n.id,
+		Unidirectional:  uniNeighbors,
+		Bidirectional:   biNeighbors,
+		MultipointRelay: mprNeighbors,
+		Sequence:        n.helloSequenceNum,
+	}
+	n.helloSequenceNum++
+	n.send(message.Broadcast, hello)
+}
+
+// sendTC sends a TCMessage including the most recent MultipointRelaySet set for this node.
+func (n *Node) sendTC() {
+	// Get the MS set node IDs to include in the TC message.
+	msSet := make([]message.NodeID, 0)
+	for _, id := range n.msSet {
+		msSet = append(msSet, id)
+	}
+	sort.SliceStable(msSet, func(i, j int) bool {
+		return msSet[i] < msSet[j]
+	})
+
+	tc := &message.TCMessage{
+		Source:             n.id,
+		FromNeighbor:       n.id,
+		Sequence:           n.tcSequenceNum,
+		MultipointRelaySet: msSet,
+	}
+	n.send(message.Broadcast, tc)
+
+	n.tcSequenceNum++
+}
+
+// calculateRoutingTable calculates all reachable destinations based on the topologyTable.
+func (n *Node) calculateRoutingTable() {
+	// Wipe the table clean, ensuring no stale routes.
+	n.routingTable = make(map[message.NodeID]routingEntry)
+
+	// Add all symmetric one-hop neighbors.
+	for _, neighbor := range n.oneHopNeighbors {
+		if neighbor.state == bidirectional || neighbor.state == mpr {
+			n.routingTable[neighbor.neighborID] = routingEntry{
+				dst:      neighbor.neighborID,
+				nextHop:  neighbor.neighborID,
+				distance: 1,
+			}
+		}
+	}
+
+	// Add all two-hop neighbors, through symmetric neighbors only. Neighbors are visited in order of ID, so that routes
+	// do not depend on map iteration order.
+	for _, neighbor := range sortedKeys(n.twoHopNeighbors) {
+		if r, in := n.routingTable[neighbor]; !in || r.distance != 1 {
+			continue
+		}
+		for _, dst := range sortedKeys(n.twoHopNeighbors[neighbor]) {
+			_, in := n.routingTable[dst]
+			if !in {
+				n.routingTable[dst] = routingEntry{
+					dst:      dst,
+					nextHop:  neighbor,
+					distance: 2,
+				}
+			}
+		}
+	}
+
+	// Add all remaining routes from topology table.
+	for h := 2; h < 256; h++ {
+		newEntry := false
+		for _, originator := range sortedKeys(n.topologyTable) {
+			neighborDsts := n.topologyTable[originator]
+			for _, dst := range sortedKeys(neighborDsts) {
+				entry := neighborDsts[dst]
+				// Check if there already exists a routing entry for the destination.
+				_, in := n.routingTable[entry.dst]
+				if !in {
+					// No destination. Check if there's a routing entry that can reach the MultipointRelay of the destination.
+					rEntry, in := n.routingTable[entry.originator]
+					if in && rEntry.distance == h {
+						newEntry = true
+						n.routingTable[entry.dst] = routingEntry{
+							dst:      entry.dst,
+							nextHop:  rEntry.nextHop,
+							distance: h + 1,
+						}
+					}
+				}
+			}
+		}
+		if !newEntry {
+			break
+		}
+	}
+}
+
+// sortedKeys returns the keys of m in increasing order.
+func sortedKeys[V any](m map[message.NodeID]V) []message.NodeID {
+	keys := make([]message.NodeID, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sortIDs(keys)
+	return keys
+}
+
+// updateOneHopNeighbors adds all new one-hop neighbors that can be reached.
+func updateOneHopNeighbors(msg *message.HelloMessage, oneHopNeighbors map[message.NodeID]oneHopNeighborEntry, holdUntil int, id message.NodeID) map[message.NodeID]oneHopNeighborEntry {
+	entry, in := oneHopNeighbors[msg.Source]
+	if !in {
+		// First time neighbor
+		oneHopNeighbors[msg.Source] = oneHopNeighborEntry{
+			neighborID: msg.Source,
+			state:      unidirectional,
+			holdUntil:  holdUntil,
+		}
+	} else {
+		// Already unidirectional neighbor
+		entry.holdUntil = holdUntil
+
+		// Check if the link state should be updated.
+		included := false
+		for _, nodeID := range append(msg.Unidirectional, append(msg.Bidirectional, msg.MultipointRelay...)...) {
+			if nodeID == id {
+				included = true
+				break
+			}
+		}
+
+		if included {
+			entry.state = bidirectional
+		} else {
+			entry.state = unidirectional
+		}
+
+		oneHopNeighbors[msg.Source] = entry
+	}
+	return oneHopNeighbors
+}
+
+// updateTwoHopNeighbors adds all new two-hop neighbors that can be reached.
+func updateTwoHopNeighbors(msg *message.HelloMessage, twoHopNeighbors map[message.NodeID]map[message.NodeID]message.NodeID, id message.NodeID) map[message.NodeID]map[message.NodeID]message.NodeID {
+	// Delete all previous entries for the source by creating a new map.
+	twoHops := make(map[message.NodeID]message.NodeID)
+	for _, nodeID := range append(msg.Bidirectional, msg.MultipointRelay...) {
+		// Check for own ID.
+		if nodeID == id {
+			continue
+		}
+		twoHops[nodeID] = nodeID
+	}
+	twoHopNeighbors[msg.Source] = twoHops
+	return twoHopNeighbors
+}
+
+// calculateMPRs creates a new mpr set based on the current neighbor tables.
+func calculateMPRs(oneHopNeighbors map[message.NodeID]oneHopNeighborEntry, twoHopNeighbors map[message.NodeID]map[message.NodeID]message.NodeID) map[message.NodeID]oneHopNeighborEntry {
+	// Copy one hop neighbors
+	remainingTwoHops := make(map[message.NodeID]message.NodeID)
+	nodes := make([]struct {
+		id      message.NodeID
+		reaches int
+	}, 0)
+	for neighbor, twoHops := range twoHopNeighbors {
+		// Only consider nodes as MPRs if they are bidirectional neighbors.
+		ohn, in := oneHopNeighbors[neighbor]
+		if !in || ohn.state == unidirectional {
+			continue
+		}
+		nodes = append(nodes, struct {
+			id      message.NodeID
+			reaches int
+		}{id: neighbor, reaches: len(twoHops)})
+
+		for k := range twoHops {
+			remainingTwoHops[k] = k
+		}
+	}
+
+	// Sort neighbors based on the number of two-hop neighbors they reach, breaking ties by ID so that the selection
+	// does not depend on map iteration order.
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].reaches != nodes[j].reaches {
+			return nodes[i].reaches > nodes[j].reaches
+		}
+		return nodes[i].id < nodes[j].id
+	})
+
+	// Set of MPRs
+	mprs := make(map[message.NodeID]message.NodeID)
+
+	// Every remaining two-hop neighbor is reachable through a remaining node, but stop once none remain regardless.
+	for len(remainingTwoHops) > 0 && len(nodes) > 0 {
+		maxTwoHops := nodes[0]
+		nodes = nodes[1:]
+
+		mprs[maxTwoHops.id] = maxTwoHops.id
+
+		for k := range twoHopNeighbors[maxTwoHops.id] {
+			delete(remainingTwoHops, k)
+		}
+	}
+
+	// Update states of one-hop neighbors based on newly selected MPRs.
+	for id, neigh := range oneHopNeighbors {
+		_, in := mprs[id]
+		if in {
+			neigh.state = mpr
+			oneHopNeighbors[id] = neigh
+		} else {
+			if neigh.state == mpr {
+				neigh.state = bidirectional
+				oneHopNeighbors[id] = neigh
+			}
+		}
+	}
+	return oneHopNeighbors
+}
+
+// handleHello handles the processing of a HelloMessage.
+func (n *Node) handleHello(msg *message.HelloMessage) {
+	// Ignore HELLO messages Sent by this node, which would make it a neighbor of itself.
+	if msg.Source == n.id {
+		return
+	}
+
+	// Ignore hello messages Sent out-of-order
+	seq, in := n.helloSequences[msg.Source]
+	if !in {
+		n.helloSequences[msg.Source] = msg.Sequence
+	} else {
+		if msg.Sequence <= seq {
+			return
+		} else {
+			n.helloSequences[msg.Source] = msg.Sequence
+		}
+	}
+
+	// Update one-hop neighbors.
+	n.oneHopNeighbors = updateOneHopNeighbors(msg, n.oneHopNeighbors, n.currentTick+n.neighborHoldTime, n.id)
+
+	// Update two-hop neighbors
+	n.twoHopNeighbors = updateTwoHopNeighbors(msg, n.twoHopNeighbors, n.id)
+
+	n.oneHopNeighbors = calculateMPRs(n.oneHopNeighbors, n.twoHopNeighbors)
+
+	// Update the msSet
+	_, in = n.msSet[msg.Source]
+	isMS := false
+	// Check if this node is in the MultipointRelay set from the HELLO message.
+	for _, nodeID := range msg.MultipointRelay {
+		if nodeID == n.id {
+			isMS = true
+			break
+		}
+	}
+	// Previously an MS, but no longer are.
+	if in && !isMS {
+		delete(n.msSet, msg.Source)
+	}
+	// New MS.
+	if !in && isMS {
+		n.msSet[msg.Source] = msg.Source
+	}
+
+	n.routesChanged = true
+}
+
+// handleData forwards a DataMessage towards its destination, unless this Node is the destination.
+func (n *Node) handleData(msg *message.DataMessage) {
+	if msg.Destination == n.id {
+		return
+	}
+	// The received message is shared with the controller, so forward a copy.
+	fwd := *msg
+	n.sendData(&fwd)
+}
+
+func updateTopologyTable(msg *message.TCMessage, topologyTable map[message.NodeID]map[message.NodeID]topologyEntry, holdUntil int, id message.NodeID) map[message.NodeID]map[message.NodeID]topologyEntry {
+	entries, in := topologyTable[msg.Source]
+	if in {
+		// Check if sequence number is new.
+		for _, dst := range msg.MultipointRelaySet {
+			entry, in := entries[dst]
+			if in && entry.seq > msg.Sequence {
+				return topologyTable
+			}
+		}
+	}
+	// New sequence TC message. Clear all old entries and add new entries.
+	topologyTable[msg.Source] = make(map[message.NodeID]topologyEntry)
+
+	for _, dst := range msg.MultipointRelaySet {
+		if dst == id {
+			continue
+		}
+		entries, _ := topologyTable[msg.Source]
+		entries[dst] = topologyEntry{
+			dst:        dst,
+			originator: msg.Source,
+			holdUntil:  holdUntil,
+			seq:        msg.Sequence,
+		}
+		topologyTable[msg.Source] = entries
+	}
+
+	return topologyTable
+}
+
+func (n *Node) handleTC(msg *message.TCMessage) {
+	// Ignore TC messages Sent by this node.
+	if msg.Source == n.id {
+		return
+	}
+
+	// Ignore TC messages already handled, which would otherwise be forwarded back and forth between MPRs forever.
+	if seq, in := n.tcSequences[msg.Source]; in && msg.Sequence <= seq {
+		return
+	}
+	n.tcSequences[msg.Source] = msg.Sequence
+
+	n.topologyTable = updateTopologyTable(msg, n.topologyTable, n.currentTick+n.topologyHoldTime, n.id)
+	n.routesChanged = true
+
+	// Only forward TC message if this node is an MultipointRelay of the neighbor which Sent the TC message.
+	doFwd := false
+	for _, id := range n.msSet {
+		if id == msg.FromNeighbor {
+			doFwd = true
+		}
+	}
+	if !doFwd {
+		return
+	}
+
+	// Update the from-neighbor field of a copy, as the received message is shared with every other receiver.
+	fwd := *msg
+	fwd.FromNeighbor = n.id
+
+	// Send the updated Message.
+	n.send(message.Broadcast, &fwd)
+}
+
+// New creates a network Node. Parameters which are not positive are replaced by their defaults.
+func New(id message.NodeID, params Params) *Node {
+	params = params.orDefault()
+	n := Node{}
+	n.id = id
+
+	n.helloSequences = make(map[message.NodeID]int)
+	n.tcSequences = make(map[message.NodeID]int)
+
+	n.routingTable = make(map[message.NodeID]routingEntry)
+	n.routesChanged = true
+
+	n.topologyTable = make(map[message.NodeID]map[message.NodeID]topologyEntry)
+	n.topologyHoldTime = params.TopologyHoldTime
+
+	n.oneHopNeighbors = make(map[message.NodeID]oneHopNeighborEntry)
+	n.twoHopNeighbors = make(map[message.NodeID]map[message.NodeID]message.NodeID)
+	n.msSet = make(map[message.NodeID]message.NodeID)
+	n.neighborHoldTime = params.NeighborHoldTime
+	n.helloInterval = params.HelloInterval
+	n.tcInterval = params.TCInterval
+	n.dataRetryInterval = params.DataRetryInterval
+	return &n
+}
+
+// Protocol returns a controller.Factory creating a Node for each node. Nodes use params, merged with their entry in
+// overrides, if any. An error is returned for nodes whose merged parameters are invalid.
+func Protocol(params Params, overrides map[message.NodeID]Params) controller.Factory {
+	return func(config controller.NodeConfig) (controller.Router, error) {
+		p := params.Merge(overrides[config.ID])
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		return New(config.ID, p), nil
+	}
+}
@@ -0,0 +1,887 @@
+package olsr
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/topology"
+)
+
+func Test_updateOneHopNeighbors(t *testing.T) {
+	type args struct {
+		msg             *message.HelloMessage
+		oneHopNeighbors map[message.NodeID]oneHopNeighborEntry
+		time            int
+		holdTime        int
+		id              message.NodeID
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[message.NodeID]oneHopNeighborEntry
+	}{
+		{
+			name: "new unidirectional neighbor",
+			args: args{
+				msg: &message.HelloMessage{
+					Source:          1,
+					Unidirectional:  nil,
+					Bidirectional:   []message.NodeID{2, 3},
+					MultipointRelay: nil,
+				},
+				oneHopNeighbors: map[message.NodeID]oneHopNeighborEntry{
+					message.NodeID(2): {
+						neighborID: 1,
+						state:      unidirectional,
+						holdUntil:  15,
+					},
+				},
+				time:     10,
+				holdTime: 10,
+				id:       0,
+			},
+			want: map[message.NodeID]oneHopNeighborEntry{
+				message.NodeID(2): {
+					neighborID: 1,
+					state:      unidirectional,
+					holdUntil:  15,
+				},
+				message.NodeID(1): {
+					neighborID: 1,
+					state:      unidirectional,
+					holdUntil:  20,
+				},
+			},
+		},
+		{
+			name: "new bidirectional neighbor",
+			args: args{
+				msg: &message.HelloMessage{
+					Source:          1,
+					Unidirectional:  nil,
+					Bidirectional:   []message.NodeID{0, 2, 3},
+					MultipointRelay: nil,
+				},
+				oneHopNeighbors: map[message.NodeID]oneHopNeighborEntry{
+					message.NodeID(1): {
+						neighborID: 1,
+						state:      unidirectional,
+						holdUntil:  15,
+					},
+					message.NodeID(2): {
+						neighborID: 1,
+						state:      unidirectional,
+						holdUntil:  15,
+					},
+				},
+				time:     10,
+				holdTime: 10,
+				id:       0,
+			},
+			want: map[message.NodeID]oneHopNeighborEntry{
+				message.NodeID(1): {
+					neighborID: 1,
+					state:      bidirectional,
+					holdUntil:  20,
+				},
+				message.NodeID(2): {
+					neighborID: 1,
+					state:      unidirectional,
+					holdUntil:  15,
+				},
+			},
+		},
+		{
+			name: "new bidirectional neighbor from MultipointRelay",
+			args: args{
+				msg: &message.HelloMessage{
+					Source:          1,
+					Unidirectional:  nil,
+					Bidirectional:   nil,
+					MultipointRelay: []message.NodeID{0},
+				},
+				oneHopNeighbors: map[message.NodeID]oneHopNeighborEntry{
+					message.NodeID(1): {
+						neighborID: 1,
+						state:      unidirectional,
+						holdUntil:  15,
+					},
+				},
+				time:     10,
+				holdTime: 10,
+				id:       0,
+			},
+			want: map[message.NodeID]oneHopNeighborEntry{
+				message.NodeID(1): {
+					neighborID: 1,
+					state:      bidirectional,
+					holdUntil:  20,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := updateOneHopNeighbors(tt.args.msg, tt.args.oneHopNeighbors, tt.args.time+tt.args.holdTime, tt.args.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("updateOneHopNeighbors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_updateTwoHopNeighbors(t *testing.T) {
+	type args struct {
+		msg             *message.HelloMessage
+		twoHopNeighbors map[message.NodeID]map[message.NodeID]message.NodeID
+		id              message.NodeID
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[message.NodeID]map[message.NodeID]message.NodeID
+	}{
+		{
+			name: "new two hop",
+			args: args{
+				msg: &message.HelloMessage{
+					Source:          1,
+					Unidirectional:  nil,
+					Bidirectional:   []message.NodeID{2},
+					MultipointRelay: nil,
+				},
+				twoHopNeighbors: map[message.NodeID]map[message.NodeID]message.NodeID{},
+				id:              0,
+			},
+			want: map[message.NodeID]map[message.NodeID]message.NodeID{
+				message.NodeID(1): {
+					message.NodeID(2): message.NodeID(2),
+				},
+			},
+		},
+		{
+			name: "include mprs",
+			args: args{
+				msg: &message.HelloMessage{
+					Source:          1,
+					Unidirectional:  nil,
+					Bidirectional:   []message.NodeID{2},
+					MultipointRelay: []message.NodeID{3},
+				},
+				twoHopNeighbors: map[message.NodeID]map[message.NodeID]message.NodeID{},
+				id:              0,
+			},
+			want: map[message.NodeID]map[message.NodeID]message.NodeID{
+				message.NodeID(1): {
+					message.NodeID(2): message.NodeID(2),
+					message.NodeID(3): message.NodeID(3),
+				},
+			},
+		},
+
+		{
+			name: "delete previous entries",
+			args: args{
+				msg: &message.HelloMessage{
+					Source:          1,
+					Unidirectional:  nil,
+					Bidirectional:   []message.NodeID{3},
+					MultipointRelay: nil,
+				},
+				twoHopNeighbors: map[message.NodeID]map[message.NodeID]message.NodeID{
+					message.NodeID(1): {
+						message.NodeID(2): message.NodeID(2),
+					},
+				},
+				id: 0,
+			},
+			want: map[message.NodeID]map[message.NodeID]message.NodeID{
+				message.NodeID(1): {
+					message.NodeID(3): message.NodeID(3),
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := updateTwoHopNeighbors(tt.args.msg, tt.args.twoHopNeighbors, tt.args.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("updateTwoHopNeighbors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_calculateMPRs(t *testing.T) {
+	type args struct {
+		oneHopNeighbors map[message.NodeID]oneHopNeighborEntry
+		twoHopNeighbors map[message.NodeID]map[message.NodeID]message.NodeID
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[message.NodeID]oneHopNeighborEntry
+	}{
+		{
+			name: "ensure greedy",
+			args: struct {
+				oneHopNeighbors map[message.NodeID]oneHopNeighborEntry
+				twoHopNeighbors map[message.NodeID]map[message.NodeID]message.NodeID
+			}{
+				oneHopNeighbors: map[message.NodeID]oneHopNeighborEntry{
+					message.NodeID(1): {
+						neighborID: 1,
+						state:      bidirectional,
+						holdUntil:  20,
+					},
+					message.NodeID(2): {
+						neighborID: 1,
+						state:      bidirectional,
+						holdUntil:  20,
+					},
+				},
+				twoHopNeighbors: map[message.NodeID]map[message.NodeID]message.NodeID{
+					message.NodeID(1): {
+						message.NodeID(3): message.NodeID(3),
+						message.NodeID(4): message.NodeID(4),
+					},
+					message.NodeID(2): {
+						message.NodeID(3): message.NodeID(3),
+					},
+				},
+			},
+			want: map[message.NodeID]oneHopNeighborEntry{
+				message.NodeID(1): {
+					neighborID: 1,
+					state:      mpr,
+					holdUntil:  20,
+				},
+				message.NodeID(2): {
+					neighborID: 1,
+					state:      bidirectional,
+					holdUntil:  20,
+				},
+			},
+		},
+		{
+			name: "ensure coverage",
+			args: struct {
+				oneHopNeighbors map[message.NodeID]oneHopNeighborEntry
+				twoHopNeighbors map[message.NodeID]map[message.NodeID]message.NodeID
+			}{
+				oneHopNeighbors: map[message.NodeID]oneHopNeighborEntry{
+					message.NodeID(1): {
+						neighborID: 1,
+						state:      bidirectional,
+						holdUntil:  20,
+					},
+					message.NodeID(2): {
+						neighborID: 1,
+						state:      bidirectional,
+						holdUntil:  20,
+					},
+				},
+				twoHopNeighbors: map[message.NodeID]map[message.NodeID]message.NodeID{
+					message.NodeID(1): {
+						message.NodeID(3): message.NodeID(3),
+					},
+					message.NodeID(2): {
+						message.NodeID(4): message.NodeID(4),
+					},
+				},
+			},
+			want: map[message.NodeID]oneHopNeighborEntry{
+				message.NodeID(1): {
+					neighborID: 1,
+					state:      mpr,
+					holdUntil:  20,
+				},
+				message.NodeID(2): {
+					neighborID: 1,
+					state:      mpr,
+					holdUntil:  20,
+				},
+			},
+		},
+		{
+			name: "two-hop neighbors only through unknown or unidirectional neighbors",
+			args: struct {
+				oneHopNeighbors map[message.NodeID]oneHopNeighborEntry
+				twoHopNeighbors map[message.NodeID]map[message.NodeID]message.NodeID
+			}{
+				oneHopNeighbors: map[message.NodeID]oneHopNeighborEntry{
+					message.NodeID(1): {
+						neighborID: 1,
+						state:      unidirectional,
+						holdUntil:  20,
+					},
+				},
+				twoHopNeighbors: map[message.NodeID]map[message.NodeID]message.NodeID{
+					message.NodeID(1): {
+						message.NodeID(3): message.NodeID(3),
+					},
+					message.NodeID(2): {
+						message.NodeID(4): message.NodeID(4),
+					},
+				},
+			},
+			want: map[message.NodeID]oneHopNeighborEntry{
+				message.NodeID(1): {
+					neighborID: 1,
+					state:      unidirectional,
+					holdUntil:  20,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateMPRs(tt.args.oneHopNeighbors, tt.args.twoHopNeighbors); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calculateMPRs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_updateTopologyTable1(t *testing.T) {
+	type args struct {
+		msg           *message.TCMessage
+		topologyTable map[message.NodeID]map[message.NodeID]topologyEntry
+		holdTime      int
+		id            message.NodeID
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[message.NodeID]map[message.NodeID]topologyEntry
+	}{
+		{
+			name: "new nodes",
+			args: args{
+				msg: &message.TCMessage{
+					Source:       2,
+					FromNeighbor: 1,
+					Sequence:     0,
+					MultipointRelaySet: []message.NodeID{
+						message.NodeID(1),
+						message.NodeID(3),
+					},
+				},
+				topologyTable: map[message.NodeID]map[message.NodeID]topologyEntry{},
+				holdTime:      30,
+			},
+			want: map[message.NodeID]map[message.NodeID]topologyEntry{
+				message.NodeID(2): {
+					message.NodeID(1): topologyEntry{
+						dst:        1,
+						originator: 2,
+						holdUntil:  30,
+						seq:        0,
+					},
+					message.NodeID(3): topologyEntry{
+						dst:        3,
+						originator: 2,
+						holdUntil:  30,
+						seq:        0,
+					},
+				},
+			},
+		},
+		{
+			name: "multiple mprs",
+			args: args{
+				msg: &message.TCMessage{
+					Source:       1,
+					FromNeighbor: 1,
+					Sequence:     0,
+					MultipointRelaySet: []message.NodeID{
+						message.NodeID(2),
+					},
+				},
+				topologyTable: map[message.NodeID]map[message.NodeID]topologyEntry{
+					message.NodeID(3): {
+						message.NodeID(2): topologyEntry{
+							dst:        2,
+							originator: 3,
+							holdUntil:  30,
+							seq:        0,
+						},
+					},
+				},
+				holdTime: 30,
+			},
+			want: map[message.NodeID]map[message.NodeID]topologyEntry{
+				message.NodeID(3): {
+					message.NodeID(2): topologyEntry{
+						dst:        2,
+						originator: 3,
+						holdUntil:  30,
+						seq:        0,
+					},
+				},
+				message.NodeID(1): {
+					message.NodeID(2): topologyEntry{
+						dst:        2,
+						originator: 1,
+						holdUntil:  30,
+						seq:        0,
+					},
+				},
+			},
+		},
+		{
+			name: "ignore Destination if same as ID",
+			args: args{
+				msg: &message.TCMessage{
+					Source:       1,
+					FromNeighbor: 1,
+					Sequence:     0,
+					MultipointRelaySet: []message.NodeID{
+						message.NodeID(2),
+						message.NodeID(0),
+					},
+				},
+				topologyTable: map[message.NodeID]map[message.NodeID]topologyEntry{},
+				holdTime:      30,
+				id:            message.NodeID(0),
+			},
+			want: map[message.NodeID]map[message.NodeID]topologyEntry{
+				message.NodeID(1): {
+					message.NodeID(2): topologyEntry{
+						dst:        2,
+						originator: 1,
+						holdUntil:  30,
+						seq:        0,
+					},
+				},
+			},
+		},
+		{
+			name: "update if larger sequence",
+			args: args{
+				msg: &message.TCMessage{
+					Source:       1,
+					FromNeighbor: 1,
+					Sequence:     1,
+					MultipointRelaySet: []message.NodeID{
+						message.NodeID(2),
+						message.NodeID(3),
+					},
+				},
+				topologyTable: map[message.NodeID]map[message.NodeID]topologyEntry{
+					message.NodeID(1): {
+						message.NodeID(2): topologyEntry{
+							dst:        2,
+							originator: 1,
+							holdUntil:  23,
+							seq:        0,
+						},
+					},
+				},
+				holdTime: 30,
+				id:       message.NodeID(0),
+			},
+			want: map[message.NodeID]map[message.NodeID]topologyEntry{
+				message.NodeID(1): {
+					message.NodeID(2): topologyEntry{
+						dst:        2,
+						originator: 1,
+						holdUntil:  30,
+						seq:        1,
+					},
+					message.NodeID(3): topologyEntry{
+						dst:        3,
+						originator: 1,
+						holdUntil:  30,
+						seq:        1,
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := updateTopologyTable(tt.args.msg, tt.args.topologyTable, tt.args.holdTime, tt.args.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("updateTopologyTable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParams_Merge(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   Params
+		override Params
+		want     Params
+	}{
+		{
+			name:     "no override",
+			params:   DefaultParams(),
+			override: Params{},
+			want:     DefaultParams(),
+		},
+		{
+			name:     "partial override",
+			params:   DefaultParams(),
+			override: Params{HelloInterval: 2, TopologyHoldTime: 60},
+			want:     Params{HelloInterval: 2, TCInterval: 10, NeighborHoldTime: 15, TopologyHoldTime: 60, DataRetryInterval: 30},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.params.Merge(tt.override); got != tt.want {
+				t.Errorf("Merge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr bool
+	}{
+		{
+			name:    "defaults",
+			params:  DefaultParams(),
+			wantErr: false,
+		},
+		{
+			name:    "zero hello interval",
+			params:  DefaultParams().Merge(Params{HelloInterval: -1}),
+			wantErr: true,
+		},
+		{
+			name:    "zero tc interval",
+			params:  Params{HelloInterval: 5, NeighborHoldTime: 15, TopologyHoldTime: 30, DataRetryInterval: 30},
+			wantErr: true,
+		},
+		{
+			name:    "zero data retry interval",
+			params:  Params{HelloInterval: 5, TCInterval: 10, NeighborHoldTime: 15, TopologyHoldTime: 30},
+			wantErr: true,
+		},
+		{
+			name:    "neighbor hold time not greater than hello interval",
+			params:  DefaultParams().Merge(Params{HelloInterval: 15}),
+			wantErr: true,
+		},
+		{
+			name:    "topology hold time not greater than tc interval",
+			params:  DefaultParams().Merge(Params{TCInterval: 40}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProtocol(t *testing.T) {
+	factory := Protocol(DefaultParams(), map[message.NodeID]Params{1: {HelloInterval: 20}})
+	if _, err := factory(controller.NodeConfig{ID: 0}); err != nil {
+		t.Errorf("Protocol() node 0 error = %v, want nil", err)
+	}
+	if _, err := factory(controller.NodeConfig{ID: 1}); err == nil {
+		t.Errorf("Protocol() node 1 error = nil, want error")
+	}
+}
+
+func TestNew_defaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		params Params
+		want   Params
+	}{
+		{
+			name:   "zero",
+			params: Params{},
+			want:   DefaultParams(),
+		},
+		{
+			name:   "negative",
+			params: Params{HelloInterval: -1, TCInterval: -10, NeighborHoldTime: -15, TopologyHoldTime: 0, DataRetryInterval: -30},
+			want:   DefaultParams(),
+		},
+		{
+			name:   "partly set",
+			params: Params{HelloInterval: 2, NeighborHoldTime: 6},
+			want:   Params{HelloInterval: 2, TCInterval: 10, NeighborHoldTime: 6, TopologyHoldTime: 30, DataRetryInterval: 30},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := New(0, tt.params)
+			got := Params{
+				HelloInterval:     n.helloInterval,
+				TCInterval:        n.tcInterval,
+				NeighborHoldTime:  n.neighborHoldTime,
+				TopologyHoldTime:  n.topologyHoldTime,
+				DataRetryInterval: n.dataRetryInterval,
+			}
+			if got != tt.want {
+				t.Errorf("New() params = %+v, want %+v", got, tt.want)
+			}
+			// Ticking must not divide by a zero interval.
+			for i := 0; i < 20; i++ {
+				n.Tick()
+			}
+		})
+	}
+}
+
+func TestNode_Receive_unknownMessage(t *testing.T) {
+	n := New(0, DefaultParams())
+	if got := n.Receive(message.Envelope{From: 1, To: message.Broadcast, Message: unknownMessage{}}); got != nil {
+		t.Errorf("Receive() = %v, want nil", got)
+	}
+}
+
+// unknownMessage is a message no Node understands.
+type unknownMessage struct{}
+
+func (unknownMessage) Type() string {
+	return "UNKNOWN"
+}
+
+func (unknownMessage) String() string {
+	return "UNKNOWN"
+}
+
+func TestNode_routes(t *testing.T) {
+	nwt, err := topology.Read(strings.NewReader("0 UP 0 <-> 1\n0 UP 1 <-> 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := controller.New(nwt, 0, Protocol(DefaultParams(), nil))
+	var received bytes.Buffer
+	c.SetLogs(func(id message.NodeID) (controller.NodeLogs, error) {
+		if id == 2 {
+			return controller.NodeLogs{Received: &received}, nil
+		}
+		return controller.NodeLogs{}, nil
+	})
+	configs := []controller.NodeConfig{
+		{ID: 0, Message: controller.NodeMessage{Message: "hi", Delay: 20, Destination: 2}},
+		{ID: 1, Message: controller.NodeMessage{Sent: true}},
+		{ID: 2, Message: controller.NodeMessage{Sent: true}},
+	}
+	if err := c.Initialize(configs); err != nil {
+		t.Fatal(err)
+	}
+	c.Start(40)
+
+	n := c.Routers()[0].(*Node)
+	wantRoutes := []controller.Route{
+		{Destination: 1, NextHop: 1, Distance: 1},
+		{Destination: 2, NextHop: 1, Distance: 2},
+	}
+	if got := n.Routes(); !reflect.DeepEqual(got, wantRoutes) {
+		t.Errorf("Routes() got = %v, want %v", got, wantRoutes)
+	}
+	if got := n.State().MPRs; !reflect.DeepEqual(got, []message.NodeID{1}) {
+		t.Errorf("State().MPRs got = %v, want %v", got, []message.NodeID{1})
+	}
+	got := n.Inspect()
+	if !reflect.DeepEqual(got.Neighbors, []message.NodeID{1}) || !reflect.DeepEqual(got.MPRs, []message.NodeID{1}) ||
+		!reflect.DeepEqual(got.TwoHopNeighbors, []controller.TwoHopNeighbor{{ID: 2, Via: 1}}) {
+		t.Errorf("Inspect() got = %+v, want neighbor and MPR 1, with 2-hop neighbor 2", got)
+	}
+
+	if received.String() != "hi\n" {
+		t.Errorf("node 2 received %q, want %q", received, "hi\n")
+	}
+}
+
+func TestNode_Receive_duplicateTC(t *testing.T) {
+	n := New(0, DefaultParams())
+	n.msSet[1] = 1
+	tc := &message.TCMessage{Source: 2, FromNeighbor: 1, Sequence: 3, MultipointRelaySet: []message.NodeID{1}}
+	if got := n.Receive(message.Envelope{From: 1, To: message.Broadcast, Message: tc}); len(got) != 1 {
+		t.Fatalf("Receive() forwarded %d envelopes, want 1", len(got))
+	}
+	if got := n.Receive(message.Envelope{From: 1, To: message.Broadcast, Message: tc}); len(got) != 0 {
+		t.Errorf("Receive() forwarded a duplicate TC: %v", got)
+	}
+	if tc.FromNeighbor != 1 {
+		t.Errorf("Receive() modified the received TC")
+	}
+}
+
+func TestNode_Receive_ownHello(t *testing.T) {
+	n := New(0, DefaultParams())
+	// A HELLO of node 0 heard back, such as through a looping link, must not make it a neighbor of itself.
+	for seq := 0; seq < 2; seq++ {
+		hello := &message.HelloMessage{Source: 0, Bidirectional: []message.NodeID{0}, Sequence: seq}
+		n.Receive(message.Envelope{From: 0, To: message.Broadcast, Message: hello})
+	}
+	n.Tick()
+	if got := n.Inspect().Neighbors; len(got) != 0 {
+		t.Errorf("Inspect().Neighbors got = %v after hearing its own HELLO, want none", got)
+	}
+	if got := n.Routes(); len(got) != 0 {
+		t.Errorf("Routes() got = %v after hearing its own HELLO, want none", got)
+	}
+}
+
+func TestNode_Tick_expiry(t *testing.T) {
+	p := DefaultParams()
+	n := New(0, p)
+	// The second HELLO makes node 1 a symmetric neighbor, through which node 2 is reachable.
+	for seq := 0; seq < 2; seq++ {
+		hello := &message.HelloMessage{Source: 1, Bidirectional: []message.NodeID{0, 2}, Sequence: seq}
+		n.Receive(message.Envelope{From: 1, To: message.Broadcast, Message: hello})
+	}
+	n.Tick()
+	wantRoutes := []controller.Route{
+		{Destination: 1, NextHop: 1, Distance: 1},
+		{Destination: 2, NextHop: 1, Distance: 2},
+	}
+	if got := n.Routes(); !reflect.DeepEqual(got, wantRoutes) {
+		t.Fatalf("Routes() got = %v, want %v", got, wantRoutes)
+	}
+
+	// Once node 1 is no longer heard from, neither MPRs nor routes go through it.
+	for i := 0; i < p.NeighborHoldTime; i++ {
+		n.Tick()
+	}
+	if got := n.Routes(); len(got) != 0 {
+		t.Errorf("Routes() got = %v after node 1 expired, want none", got)
+	}
+	if got := n.State().MPRs; len(got) != 0 {
+		t.Errorf("State().MPRs got = %v after node 1 expired, want none", got)
+	}
+
+	// Node 1 restarted, and its sequence numbers with it, which the node no longer remembers.
+	for seq := 0; seq < 2; seq++ {
+		hello := &message.HelloMessage{Source: 1, Bidirectional: []message.NodeID{0}, Sequence: seq}
+		n.Receive(message.Envelope{From: 1, To: message.Broadcast, Message: hello})
+	}
+	n.Tick()
+	if got, want := n.Routes(), wantRoutes[:1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Routes() got = %v after node 1 restarted, want %v", got, want)
+	}
+}
+
+func TestNode_Tick_unidirectionalNeighbor(t *testing.T) {
+	n := New(0, DefaultParams())
+	// Node 1 does not hear node 0, so node 0 must not route through it.
+	for seq := 0; seq < 2; seq++ {
+		hello := &message.HelloMessage{Source: 1, Bidirectional: []message.NodeID{2}, Sequence: seq}
+		n.Receive(message.Envelope{From: 1, To: message.Broadcast, Message: hello})
+	}
+	n.Tick()
+	if got := n.Routes(); len(got) != 0 {
+		t.Errorf("Routes() got = %v through a unidirectional neighbor, want none", got)
+	}
+}
+
+// FuzzNode feeds a node sequences of HELLO and TC messages from up to 8 nodes, including itself, ticking it in between,
+// and checks its tables stay consistent. Each step is 5 bytes: an operation followed by its arguments. Inputs of more
+// than maxFuzzSteps steps are skipped: each of their executions is slower, and minimizing them stalls the fuzzer.
+func FuzzNode(f *testing.F) {
+	const maxFuzzSteps = 32
+	f.Add([]byte{
+		0, 1, 0, 0b0011, 0b0001, // HELLO from 1, hearing 0 and 1, and selecting 0 as an MPR.
+		0, 1, 1, 0b0101, 0b0001, // HELLO from 1, with 0 and 2 as symmetric neighbors.
+		1, 3, 1, 0, 0b0100, // TC from 3, forwarded by 1, advertising 2.
+		2, 0, 0, 0, 0, // Tick.
+		3, 3, 0, 0, 0, // Data for 3.
+	})
+	f.Add([]byte{0, 2, 5, 0b1111, 0b1111, 0, 2, 4, 0b0001, 0, 2, 0, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > 5*maxFuzzSteps {
+			return
+		}
+		ids := func(mask byte) []message.NodeID {
+			var ids []message.NodeID
+			for i := 0; i < 8; i++ {
+				if mask&(1<<i) != 0 {
+					ids = append(ids, message.NodeID(i))
+				}
+			}
+			return ids
+		}
+		p := DefaultParams()
+		p.NeighborHoldTime, p.TopologyHoldTime = 3, 6
+		n := New(0, p)
+		for ; len(data) >= 5; data = data[5:] {
+			op, a, b, c, d := data[0]%4, data[1]%8, data[2], data[3], data[4]
+			switch op {
+			case 0:
+				hello := &message.HelloMessage{
+					Source:          message.NodeID(a),
+					Unidirectional:  ids(d &^ c),
+					Bidirectional:   ids(c),
+					MultipointRelay: ids(c & d),
+					Sequence:        int(b),
+				}
+				n.Receive(message.Envelope{From: hello.Source, To: message.Broadcast, Message: hello})
+			case 1:
+				tc := &message.TCMessage{
+					Source:             message.NodeID(a),
+					FromNeighbor:       message.NodeID(b % 8),
+					Sequence:           int(c),
+					MultipointRelaySet: ids(d),
+				}
+				n.Receive(message.Envelope{From: tc.FromNeighbor, To: message.Broadcast, Message: tc})
+			case 2:
+				n.Tick()
+				checkNode(t, n)
+			case 3:
+				n.Send(message.NodeID(a), "data")
+			}
+		}
+		n.Tick()
+		checkNode(t, n)
+	})
+}
+
+// checkNode checks the tables of a node are consistent with each other.
+func checkNode(t *testing.T, n *Node) {
+	t.Helper()
+	s := n.Inspect()
+	neighbors := make(map[message.NodeID]bool)
+	for _, id := range s.Neighbors {
+		neighbors[id] = true
+	}
+	for _, id := range s.MPRs {
+		if !neighbors[id] {
+			t.Fatalf("tick %d: MPR %d is not a symmetric neighbor: %+v", s.Tick, id, s)
+		}
+	}
+	mprs := make(map[message.NodeID]bool)
+	for _, id := range s.MPRs {
+		mprs[id] = true
+	}
+	covered := make(map[message.NodeID]bool)
+	for _, th := range s.TwoHopNeighbors {
+		if neighbors[th.Via] && mprs[th.Via] {
+			covered[th.ID] = true
+		}
+	}
+	for _, th := range s.TwoHopNeighbors {
+		if th.ID != s.ID && !neighbors[th.ID] && neighbors[th.Via] && !covered[th.ID] {
+			t.Fatalf("tick %d: two-hop neighbor %d is not covered by MPRs %v: %+v", s.Tick, th.ID, s.MPRs, s)
+		}
+	}
+	for _, r := range s.Routes {
+		if r.Destination == s.ID {
+			t.Fatalf("tick %d: route to itself: %+v", s.Tick, s)
+		}
+		if !neighbors[r.NextHop] {
+			t.Fatalf("tick %d: route to %d goes through %d, which is not a symmetric neighbor: %+v", s.Tick, r.Destination, r.NextHop, s)
+		}
+		if (r.Distance == 1) != (r.Destination == r.NextHop) || r.Distance < 1 {
+			t.Fatalf("tick %d: route to %d through %d is %d hops long: %+v", s.Tick, r.Destination, r.NextHop, r.Distance, s)
+		}
+	}
+}
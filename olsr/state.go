@@ -0,0 +1,206 @@
+package olsr
+
+import (
+	"sort"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+func (s NeighborState) String() string {
+	switch s {
+	case bidirectional:
+		return "BIDIR"
+	case unidirectional:
+		return "UNIDIR"
+	case mpr:
+		return "MPR"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// MarshalText encodes the NeighborState as its name.
+func (s NeighborState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Neighbor is a 1-hop neighbor of a Node.
+type Neighbor struct {
+	ID        message.NodeID `json:"id"`
+	State     NeighborState  `json:"state"`
+	HoldUntil int            `json:"holdUntil"`
+}
+
+// TwoHopNeighbor is a 2-hop neighbor of a Node, reachable via a 1-hop neighbor.
+type TwoHopNeighbor struct {
+	ID  message.NodeID `json:"id"`
+	Via message.NodeID `json:"via"`
+}
+
+// TopologyEntry is an entry of a Node's topology table, learnt from a TCMessage.
+type TopologyEntry struct {
+	// Destination is the MPR selector advertised by Originator.
+	Destination message.NodeID `json:"destination"`
+
+	// Originator is the last-hop node to Destination.
+	Originator message.NodeID `json:"originator"`
+
+	Sequence  int `json:"sequence"`
+	HoldUntil int `json:"holdUntil"`
+}
+
+// State is a snapshot of a Node's tables. All slices are sorted by ID.
+type State struct {
+	ID   message.NodeID `json:"id"`
+	Tick int            `json:"tick"`
+
+	Neighbors       []Neighbor       `json:"neighbors"`
+	TwoHopNeighbors []TwoHopNeighbor `json:"twoHopNeighbors"`
+
+	// MPRs are the neighbors this Node has selected as multipoint relays.
+	MPRs []message.NodeID `json:"mprs"`
+
+	// MPRSelectors are the neighbors that have selected this Node as a multipoint relay.
+	MPRSelectors []message.NodeID `json:"mprSelectors"`
+
+	Topology []TopologyEntry    `json:"topology"`
+	Routes   []controller.Route `json:"routes"`
+}
+
+// ID is the Node's identifier.
+func (n *Node) ID() message.NodeID {
+	return n.id
+}
+
+// State creates a snapshot of the Node's tables.
+// It must not be called while the Node is being driven by the controller.
+func (n *Node) State() State {
+	s := State{
+		ID:              n.id,
+		Tick:            n.currentTick,
+		Neighbors:       make([]Neighbor, 0, len(n.oneHopNeighbors)),
+		TwoHopNeighbors: make([]TwoHopNeighbor, 0),
+		MPRs:            make([]message.NodeID, 0),
+		MPRSelectors:    make([]message.NodeID, 0, len(n.msSet)),
+		Topology:        make([]TopologyEntry, 0),
+		Routes:          n.Routes(),
+	}
+
+	for _, e := range n.oneHopNeighbors {
+		s.Neighbors = append(s.Neighbors, Neighbor{ID: e.neighborID, State: e.state, HoldUntil: e.holdUntil})
+		if e.state == mpr {
+			s.MPRs = append(s.MPRs, e.neighborID)
+		}
+	}
+	sort.SliceStable(s.Neighbors, func(i, j int) bool {
+		return s.Neighbors[i].ID < s.Neighbors[j].ID
+	})
+	sortIDs(s.MPRs)
+
+	for via, twoHops := range n.twoHopNeighbors {
+		for id := range twoHops {
+			s.TwoHopNeighbors = append(s.TwoHopNeighbors, TwoHopNeighbor{ID: id, Via: via})
+		}
+	}
+	sort.SliceStable(s.TwoHopNeighbors, func(i, j int) bool {
+		a, b := s.TwoHopNeighbors[i], s.TwoHopNeighbors[j]
+		if a.ID != b.ID {
+			return a.ID < b.ID
+		}
+		return a.Via < b.Via
+	})
+
+	for id := range n.msSet {
+		s.MPRSelectors = append(s.MPRSelectors, id)
+	}
+	sortIDs(s.MPRSelectors)
+
+	for _, dsts := range n.topologyTable {
+		for _, e := range dsts {
+			s.Topology = append(s.Topology, TopologyEntry{
+				Destination: e.dst,
+				Originator:  e.originator,
+				Sequence:    e.seq,
+				HoldUntil:   e.holdUntil,
+			})
+		}
+	}
+	sort.SliceStable(s.Topology, func(i, j int) bool {
+		a, b := s.Topology[i], s.Topology[j]
+		if a.Destination != b.Destination {
+			return a.Destination < b.Destination
+		}
+		return a.Originator < b.Originator
+	})
+
+	return s
+}
+
+// Routes returns the Node's routing table, sorted by destination.
+func (n *Node) Routes() []controller.Route {
+	routes := make([]controller.Route, 0, len(n.routingTable))
+	for _, e := range n.routingTable {
+		routes = append(routes, controller.Route{Destination: e.dst, NextHop: e.nextHop, Distance: e.distance})
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].Destination < routes[j].Destination
+	})
+	return routes
+}
+
+// sortIDs sorts ids in increasing order.
+func sortIDs(ids []message.NodeID) {
+	sort.SliceStable(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+}
+
+// LinkState marks the Node as routing only through the symmetric neighbors it reports. Node implements
+// invariant.LinkState.
+func (n *Node) LinkState() {}
+
+// Inspect returns a snapshot of the Node's tables, in the form common to every protocol. Node implements
+// controller.Inspector.
+// It must not be called while the Node is being driven by the controller.
+func (n *Node) Inspect() controller.NodeState {
+	s := n.State()
+	ns := controller.NodeState{
+		ID:           s.ID,
+		Tick:         s.Tick,
+		Neighbors:    make([]message.NodeID, 0, len(s.Neighbors)),
+		MPRs:         s.MPRs,
+		MPRSelectors: s.MPRSelectors,
+		Routes:       s.Routes,
+	}
+	for _, nb := range s.Neighbors {
+		if nb.State != unidirectional {
+			ns.Neighbors = append(ns.Neighbors, nb.ID)
+		}
+	}
+	for _, th := range s.TwoHopNeighbors {
+		ns.TwoHopNeighbors = append(ns.TwoHopNeighbors, controller.TwoHopNeighbor{ID: th.ID, Via: th.Via})
+	}
+	for _, e := range s.Topology {
+		ns.Topology = append(ns.Topology, controller.TopologyLink{From: e.Originator, To: e.Destination})
+	}
+	sort.SliceStable(ns.Topology, func(i, j int) bool {
+		a, b := ns.Topology[i], ns.Topology[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return ns
+}
+
+// Sequences returns the sequence numbers of the HELLO and TC messages the Node originates next. Node implements
+// controller.Persister.
+func (n *Node) Sequences() map[string]int {
+	return map[string]int{"hello": n.helloSequenceNum, "tc": n.tcSequenceNum}
+}
+
+// RestoreSequences continues from the sequence numbers of a previous Node.
+func (n *Node) RestoreSequences(seqs map[string]int) {
+	n.helloSequenceNum, n.tcSequenceNum = seqs["hello"], seqs["tc"]
+}
@@ -0,0 +1,80 @@
+package olsr
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+func TestNode_State(t *testing.T) {
+	n := &Node{
+		id:          0,
+		currentTick: 12,
+		oneHopNeighbors: map[message.NodeID]oneHopNeighborEntry{
+			2: {neighborID: 2, state: bidirectional, holdUntil: 20},
+			1: {neighborID: 1, state: mpr, holdUntil: 18},
+			3: {neighborID: 3, state: unidirectional, holdUntil: 16},
+		},
+		twoHopNeighbors: map[message.NodeID]map[message.NodeID]message.NodeID{
+			1: {4: 4, 5: 5},
+			2: {4: 4},
+		},
+		msSet: map[message.NodeID]message.NodeID{2: 2, 1: 1},
+		topologyTable: map[message.NodeID]map[message.NodeID]topologyEntry{
+			4: {6: {dst: 6, originator: 4, holdUntil: 40, seq: 3}},
+		},
+		routingTable: map[message.NodeID]routingEntry{
+			6: {dst: 6, nextHop: 1, distance: 3},
+			1: {dst: 1, nextHop: 1, distance: 1},
+		},
+	}
+
+	want := State{
+		ID:   0,
+		Tick: 12,
+		Neighbors: []Neighbor{
+			{ID: 1, State: mpr, HoldUntil: 18},
+			{ID: 2, State: bidirectional, HoldUntil: 20},
+			{ID: 3, State: unidirectional, HoldUntil: 16},
+		},
+		TwoHopNeighbors: []TwoHopNeighbor{
+			{ID: 4, Via: 1},
+			{ID: 4, Via: 2},
+			{ID: 5, Via: 1},
+		},
+		MPRs:         []message.NodeID{1},
+		MPRSelectors: []message.NodeID{1, 2},
+		Topology: []TopologyEntry{
+			{Destination: 6, Originator: 4, Sequence: 3, HoldUntil: 40},
+		},
+		Routes: []controller.Route{
+			{Destination: 1, NextHop: 1, Distance: 1},
+			{Destination: 6, NextHop: 1, Distance: 3},
+		},
+	}
+	if got := n.State(); !reflect.DeepEqual(got, want) {
+		t.Errorf("State() got = %v, want %v", got, want)
+	}
+}
+
+func TestNode_RestoreSequences(t *testing.T) {
+	n := New(0, DefaultParams())
+	for i := 0; i < 7; i++ {
+		n.Tick()
+	}
+	seqs := n.Sequences()
+
+	restarted := New(0, DefaultParams())
+	restarted.RestoreSequences(seqs)
+	if got := restarted.Sequences(); !reflect.DeepEqual(got, seqs) {
+		t.Errorf("Sequences() = %v after RestoreSequences(), want %v", got, seqs)
+	}
+	// The restarted node carries on from where the previous one stopped.
+	for _, env := range restarted.Tick() {
+		if hello, ok := env.Message.(*message.HelloMessage); ok && hello.Sequence != seqs["hello"] {
+			t.Errorf("Tick() sent a HELLO with sequence %d, want %d", hello.Sequence, seqs["hello"])
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package olsrv2
+
+import (
+	"sort"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+// calculateMPRs selects the flooding and routing MPRs of the Node among its symmetric neighbors.
+//
+// Flooding MPRs cover every strict 2-hop neighbor, so that a TC flooded through them reaches every node. Routing MPRs
+// cover every 2-hop neighbor whose shortest path to the Node has two hops, so that advertising routing MPR selectors
+// in TCs is enough for every node to calculate shortest paths to the Node.
+//
+// As routing MPRs advertise the links to their selectors, they are selected by the metrics of incoming links.
+func (n *Node) calculateMPRs() {
+	floodingWill := make(map[message.NodeID]int)
+	routingWill := make(map[message.NodeID]int)
+	for id, l := range n.links {
+		if n.symmetric(id) {
+			floodingWill[id] = l.floodingWillingness
+			routingWill[id] = l.routingWillingness
+		}
+	}
+
+	// The metric of the shortest path of two hops from each 2-hop neighbor.
+	best := make(map[message.NodeID]int)
+	for x, twoHops := range n.twoHops {
+		for y, th := range twoHops {
+			if y == n.id {
+				continue
+			}
+			d := th.in + n.links[x].inMetric()
+			if b, in := best[y]; !in || d < b {
+				best[y] = d
+			}
+		}
+	}
+
+	flooding := make(map[message.NodeID]map[message.NodeID]bool)
+	routing := make(map[message.NodeID]map[message.NodeID]bool)
+	for x, twoHops := range n.twoHops {
+		flooding[x] = make(map[message.NodeID]bool)
+		routing[x] = make(map[message.NodeID]bool)
+		for y, th := range twoHops {
+			if y == n.id {
+				continue
+			}
+			if !n.symmetric(y) {
+				flooding[x][y] = true
+			}
+
+			// 2-hop neighbors with a direct link at least as short as any path through a neighbor need no routing MPR.
+			if n.symmetric(y) && n.links[y].inMetric() <= best[y] {
+				continue
+			}
+			if th.in+n.links[x].inMetric() == best[y] {
+				routing[x][y] = true
+			}
+		}
+	}
+
+	n.floodingMPRs = selectMPRs(floodingWill, flooding)
+	n.routingMPRs = selectMPRs(routingWill, routing)
+}
+
+// selectMPRs greedily selects MPRs among neighbors, given their willingness, so that every node covered by a neighbor
+// is covered by a selected MPR. Neighbors which are always willing are selected, as well as the neighbors covering the
+// most uncovered nodes, breaking ties by willingness and then ID.
+func selectMPRs(willingness map[message.NodeID]int, covers map[message.NodeID]map[message.NodeID]bool) map[message.NodeID]bool {
+	mprs := make(map[message.NodeID]bool)
+	uncovered := make(map[message.NodeID]bool)
+	for x, ys := range covers {
+		if willingness[x] == willNever {
+			continue
+		}
+		for y := range ys {
+			uncovered[y] = true
+		}
+	}
+
+	selectMPR := func(x message.NodeID) {
+		mprs[x] = true
+		for y := range covers[x] {
+			delete(uncovered, y)
+		}
+	}
+	for _, x := range sortedKeys(willingness) {
+		if willingness[x] == willAlways {
+			selectMPR(x)
+		}
+	}
+
+	candidates := sortedKeys(willingness)
+	for len(uncovered) > 0 {
+		var best message.NodeID
+		bestCount := 0
+		for _, x := range candidates {
+			if mprs[x] || willingness[x] == willNever {
+				continue
+			}
+			count := 0
+			for y := range covers[x] {
+				if uncovered[y] {
+					count++
+				}
+			}
+			if count > bestCount || (count == bestCount && count > 0 && willingness[x] > willingness[best]) {
+				best, bestCount = x, count
+			}
+		}
+		if bestCount == 0 {
+			break
+		}
+		selectMPR(best)
+	}
+	return mprs
+}
+
+// calculateRoutes finds the shortest path to every reachable node, over the links to symmetric neighbors, the links
+// advertised by them in HELLOs and the links advertised by TCs. Paths of equal metric are broken by hop count, and
+// then by the ID of the next hop.
+func (n *Node) calculateRoutes() {
+	edges := make(map[message.NodeID]map[message.NodeID]int)
+	addEdge := func(from, to message.NodeID, metric int) {
+		if edges[from] == nil {
+			edges[from] = make(map[message.NodeID]int)
+		}
+		if m, in := edges[from][to]; !in || metric < m {
+			edges[from][to] = metric
+		}
+	}
+	for id, l := range n.links {
+		if n.symmetric(id) {
+			addEdge(n.id, id, n.outMetric(l))
+		}
+	}
+	for x, twoHops := range n.twoHops {
+		for y, th := range twoHops {
+			addEdge(x, y, th.out)
+		}
+	}
+	for orig, t := range n.topology {
+		for dst, m := range t.neighbors {
+			addEdge(orig, dst, m)
+		}
+	}
+
+	// Dijkstra's algorithm, visiting nodes in order of metric, hops and ID.
+	routes := make(map[message.NodeID]routingEntry)
+	visited := map[message.NodeID]bool{n.id: true}
+	frontier := make(map[message.NodeID]routingEntry)
+	relax := func(from message.NodeID, via routingEntry) {
+		for _, to := range sortedKeys(edges[from]) {
+			if visited[to] {
+				continue
+			}
+			r := routingEntry{nextHop: via.nextHop, metric: via.metric + edges[from][to], hops: via.hops + 1}
+			if from == n.id {
+				r.nextHop = to
+			}
+			if cur, in := frontier[to]; !in || shorter(r, cur) {
+				frontier[to] = r
+			}
+		}
+	}
+	relax(n.id, routingEntry{})
+	for len(frontier) > 0 {
+		ids := sortedKeys(frontier)
+		next := ids[0]
+		for _, id := range ids[1:] {
+			if shorter(frontier[id], frontier[next]) {
+				next = id
+			}
+		}
+		r := frontier[next]
+		delete(frontier, next)
+		visited[next] = true
+		routes[next] = r
+		relax(next, r)
+	}
+	n.routes = routes
+}
+
+// shorter reports whether route a is preferred to route b.
+func shorter(a, b routingEntry) bool {
+	if a.metric != b.metric {
+		return a.metric < b.metric
+	}
+	if a.hops != b.hops {
+		return a.hops < b.hops
+	}
+	return a.nextHop < b.nextHop
+}
+
+// Routes returns the Node's routing table, sorted by destination.
+// It must not be called while the Node is being driven by the controller.
+func (n *Node) Routes() []controller.Route {
+	routes := make([]controller.Route, 0, len(n.routes))
+	for _, dst := range sortedKeys(n.routes) {
+		r := n.routes[dst]
+		routes = append(routes, controller.Route{Destination: dst, NextHop: r.nextHop, Distance: r.hops})
+	}
+	return routes
+}
+
+// LinkState marks the Node as routing only through the symmetric neighbors it reports. Node implements
+// invariant.LinkState.
+func (n *Node) LinkState() {}
+
+// Inspect returns a snapshot of the Node's tables, in the form common to every protocol. MPRs are the flooding MPRs,
+// and MPR selectors the neighbors which selected the Node as a flooding MPR. Node implements controller.Inspector.
+// It must not be called while the Node is being driven by the controller.
+func (n *Node) Inspect() controller.NodeState {
+	s := controller.NodeState{
+		ID:        n.id,
+		Tick:      n.currentTick,
+		Neighbors: make([]message.NodeID, 0, len(n.links)),
+		Routes:    n.Routes(),
+	}
+	for _, id := range sortedKeys(n.links) {
+		if !n.symmetric(id) {
+			continue
+		}
+		s.Neighbors = append(s.Neighbors, id)
+		if n.links[id].floodingSelector {
+			s.MPRSelectors = append(s.MPRSelectors, id)
+		}
+	}
+	s.MPRs = sortedKeys(n.floodingMPRs)
+
+	for _, x := range sortedKeys(n.twoHops) {
+		for _, y := range sortedKeys(n.twoHops[x]) {
+			if y != n.id {
+				s.TwoHopNeighbors = append(s.TwoHopNeighbors, controller.TwoHopNeighbor{ID: y, Via: x})
+			}
+		}
+	}
+	sort.SliceStable(s.TwoHopNeighbors, func(i, j int) bool {
+		return s.TwoHopNeighbors[i].ID < s.TwoHopNeighbors[j].ID
+	})
+
+	for _, orig := range sortedKeys(n.topology) {
+		for _, dst := range sortedKeys(n.topology[orig].neighbors) {
+			s.Topology = append(s.Topology, controller.TopologyLink{From: orig, To: dst})
+		}
+	}
+	return s
+}
+
+// Sequences returns the sequence numbers of the HELLO and TC messages the Node originates next, and its ANSN. Node
+// implements controller.Persister.
+func (n *Node) Sequences() map[string]int {
+	return map[string]int{"hello": int(n.helloSeq), "tc": int(n.tcSeq), "ansn": int(n.ansn)}
+}
+
+// RestoreSequences continues from the sequence numbers of a previous Node.
+func (n *Node) RestoreSequences(seqs map[string]int) {
+	n.helloSeq, n.tcSeq, n.ansn = uint16(seqs["hello"]), uint16(seqs["tc"]), uint16(seqs["ansn"])
+}
+
+// sortedKeys returns the keys of m in increasing order.
+func sortedKeys[V any](m map[message.NodeID]V) []message.NodeID {
+	keys := make([]message.NodeID, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,592 @@
+// Package olsrv2 implements a simplified OLSRv2 (RFC 7181) node, discovering its neighborhood with NHDP (RFC 6130)
+// and exchanging RFC 5444 packets.
+//
+// Each node has a single interface, whose address is its ID. Nodes select separate flooding MPRs, which forward TC
+// messages, and routing MPRs, which are advertised in TC messages so that shortest paths are preserved. Routes
+// minimize the sum of link metrics, which nodes measure from the fraction of HELLOs received from each neighbor.
+package olsrv2
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/rfc5444"
+)
+
+// Willingness of a Node to act as an MPR.
+const (
+	willNever   = 0
+	willDefault = 3
+	willAlways  = 7
+)
+
+// hopMetric is the metric of a link over which no HELLOs are lost.
+const hopMetric = 256
+
+// link is a Node's perception of the link with a neighbor, from the HELLOs received from it.
+type link struct {
+	neighbor message.NodeID
+
+	// heardUntil is the tick until which the neighbor is heard, and symUntil the tick until which the link is
+	// symmetric.
+	heardUntil int
+	symUntil   int
+
+	// lastSeq is the sequence number of the last HELLO received from the neighbor.
+	lastSeq uint16
+
+	// received and expected count the HELLOs received from the neighbor, and those it sent, over recent HELLOs.
+	received int
+	expected int
+
+	// outMetric is the metric of the link to the neighbor, as measured by the neighbor.
+	outMetric int
+
+	floodingWillingness int
+	routingWillingness  int
+
+	// floodingSelector and routingSelector are set if the neighbor selected the Node as a flooding or routing MPR.
+	floodingSelector bool
+	routingSelector  bool
+}
+
+// inMetric is the metric of the link from the neighbor, which increases as the fraction of HELLOs received falls.
+func (l *link) inMetric() int {
+	if l.received == 0 {
+		return maxMetric
+	}
+	m := (hopMetric*l.expected + l.received - 1) / l.received
+	if m > maxMetric {
+		return maxMetric
+	}
+	return m
+}
+
+// twoHopLink holds the metrics of the link between a symmetric neighbor and one of its own symmetric neighbors.
+type twoHopLink struct {
+	// in is the metric of the link to the neighbor, and out the metric of the link from it.
+	in  int
+	out int
+}
+
+// topologyEntry holds the neighbors advertised in the last TC received from an originator.
+type topologyEntry struct {
+	ansn      uint16
+	holdUntil int
+
+	// neighbors maps the advertised neighbors to the metric of the link from the originator to them.
+	neighbors map[message.NodeID]int
+}
+
+// routingEntry is the shortest path to a destination.
+type routingEntry struct {
+	nextHop message.NodeID
+	metric  int
+	hops    int
+}
+
+// pendingData is data waiting for a route to its destination.
+type pendingData struct {
+	msg *message.DataMessage
+
+	// at is the tick the data is next attempted to be sent at.
+	at int
+}
+
+// Node represents a network node running OLSRv2. Node implements controller.Router.
+type Node struct {
+	id     message.NodeID
+	params Params
+
+	// outbox holds the envelopes sent by the Node since it was last driven by the controller.
+	outbox []message.Envelope
+
+	// pending holds the data waiting for a route to its destination.
+	pending []pendingData
+
+	// links maps neighbors to the state of the link with them.
+	links map[message.NodeID]*link
+
+	// twoHops maps symmetric neighbors to their own symmetric neighbors, along with the metrics of the links with them.
+	twoHops map[message.NodeID]map[message.NodeID]twoHopLink
+
+	// floodingMPRs and routingMPRs are the neighbors selected as flooding and routing MPRs.
+	floodingMPRs map[message.NodeID]bool
+	routingMPRs  map[message.NodeID]bool
+
+	// topology maps TC originators to the neighbors they advertise.
+	topology map[message.NodeID]*topologyEntry
+
+	// processed holds the sequence number of the last TC handled from each originator, so that each TC is handled,
+	// and forwarded, at most once.
+	processed map[message.NodeID]uint16
+
+	routes map[message.NodeID]routingEntry
+
+	// changed is set when the neighborhood or topology changes, and MPRs and routes must be recalculated.
+	changed bool
+
+	helloSeq uint16
+	tcSeq    uint16
+
+	// ansn is the advertised neighbor sequence number, and advertised the neighbors last advertised along with it.
+	ansn       uint16
+	advertised map[message.NodeID]int
+
+	// currentTick is the number of ticks since the node came online.
+	currentTick int
+}
+
+// Params are the OLSRv2 protocol parameters of a Node. Times are in ticks.
+type Params struct {
+	// HelloInterval is how often a Node sends a HELLO.
+	HelloInterval int `json:"helloInterval,omitempty"`
+
+	// TCInterval is how often a Node with routing MPR selectors sends a TC.
+	TCInterval int `json:"tcInterval,omitempty"`
+
+	// NeighborHoldTime is how long the information in a HELLO is held.
+	NeighborHoldTime int `json:"neighborHoldTime,omitempty"`
+
+	// TopologyHoldTime is how long the information in a TC is held.
+	TopologyHoldTime int `json:"topologyHoldTime,omitempty"`
+
+	// DataRetryInterval is how long a Node waits to retry sending data when there is no route.
+	DataRetryInterval int `json:"dataRetryInterval,omitempty"`
+
+	// FloodingWillingness and RoutingWillingness are how willing a Node is to be selected as a flooding or routing
+	// MPR, from 1 to 7. Nodes with a willingness of 7 are always selected.
+	FloodingWillingness int `json:"floodingWillingness,omitempty"`
+	RoutingWillingness  int `json:"routingWillingness,omitempty"`
+
+	// MetricWindow is the number of HELLOs over which the fraction of HELLOs received from a neighbor is measured.
+	MetricWindow int `json:"metricWindow,omitempty"`
+}
+
+// DefaultParams returns the parameters used by a Node unless they are overridden.
+func DefaultParams() Params {
+	return Params{
+		HelloInterval:       5,
+		TCInterval:          10,
+		NeighborHoldTime:    15,
+		TopologyHoldTime:    30,
+		DataRetryInterval:   30,
+		FloodingWillingness: willDefault,
+		RoutingWillingness:  willDefault,
+		MetricWindow:        8,
+	}
+}
+
+// Validate checks the parameters are usable, and that information is held for longer than it is refreshed.
+func (p Params) Validate() error {
+	switch {
+	case p.HelloInterval <= 0:
+		return fmt.Errorf("invalid params: hello interval must be positive: %d", p.HelloInterval)
+	case p.TCInterval <= 0:
+		return fmt.Errorf("invalid params: tc interval must be positive: %d", p.TCInterval)
+	case p.DataRetryInterval <= 0:
+		return fmt.Errorf("invalid params: data retry interval must be positive: %d", p.DataRetryInterval)
+	case p.NeighborHoldTime <= p.HelloInterval:
+		return fmt.Errorf("invalid params: neighbor hold time (%d) must be greater than the hello interval (%d)", p.NeighborHoldTime, p.HelloInterval)
+	case p.TopologyHoldTime <= p.TCInterval:
+		return fmt.Errorf("invalid params: topology hold time (%d) must be greater than the tc interval (%d)", p.TopologyHoldTime, p.TCInterval)
+	case p.FloodingWillingness <= willNever || p.FloodingWillingness > willAlways:
+		return fmt.Errorf("invalid params: flooding willingness must be between 1 and 7: %d", p.FloodingWillingness)
+	case p.RoutingWillingness <= willNever || p.RoutingWillingness > willAlways:
+		return fmt.Errorf("invalid params: routing willingness must be between 1 and 7: %d", p.RoutingWillingness)
+	case p.MetricWindow <= 0:
+		return fmt.Errorf("invalid params: metric window must be positive: %d", p.MetricWindow)
+	}
+	return nil
+}
+
+// Merge returns p, with each non-zero parameter of override taking precedence.
+func (p Params) Merge(override Params) Params {
+	if override.HelloInterval != 0 {
+		p.HelloInterval = override.HelloInterval
+	}
+	if override.TCInterval != 0 {
+		p.TCInterval = override.TCInterval
+	}
+	if override.NeighborHoldTime != 0 {
+		p.NeighborHoldTime = override.NeighborHoldTime
+	}
+	if override.TopologyHoldTime != 0 {
+		p.TopologyHoldTime = override.TopologyHoldTime
+	}
+	if override.DataRetryInterval != 0 {
+		p.DataRetryInterval = override.DataRetryInterval
+	}
+	if override.FloodingWillingness != 0 {
+		p.FloodingWillingness = override.FloodingWillingness
+	}
+	if override.RoutingWillingness != 0 {
+		p.RoutingWillingness = override.RoutingWillingness
+	}
+	if override.MetricWindow != 0 {
+		p.MetricWindow = override.MetricWindow
+	}
+	return p
+}
+
+// ID is the Node's identifier.
+func (n *Node) ID() message.NodeID {
+	return n.id
+}
+
+// Receive handles a message received by the Node. Messages other than RFC 5444 datagrams and DATA are ignored, as are
+// malformed datagrams.
+func (n *Node) Receive(env message.Envelope) []message.Envelope {
+	switch msg := env.Message.(type) {
+	case Datagram:
+		n.handleDatagram(env.From, msg)
+	case *message.DataMessage:
+		n.handleData(msg)
+	default:
+		log.Printf("node %d: ignoring message of unknown type: %s", n.id, env.Message.Type())
+	}
+	return n.flush()
+}
+
+// Send queues data to be sent to dst once there is a route to it.
+func (n *Node) Send(dst message.NodeID, data string) {
+	msg := &message.DataMessage{
+		Source:      n.id,
+		Destination: dst,
+		Data:        data,
+	}
+	n.pending = append(n.pending, pendingData{msg: msg, at: n.currentTick})
+}
+
+// Tick expires old information, recalculates MPRs and routes if needed, sends the Node's periodic messages and any
+// pending data, and advances the Node's clock.
+func (n *Node) Tick() []message.Envelope {
+	n.expire()
+	if n.changed {
+		n.calculateMPRs()
+		n.calculateRoutes()
+		n.changed = false
+	}
+
+	if n.currentTick%n.params.HelloInterval == 0 {
+		n.sendHello()
+	}
+	if n.currentTick%n.params.TCInterval == 0 && n.hasRoutingSelectors() {
+		n.sendTC()
+	}
+
+	// Attempt to send pending data, retrying later if there is no route.
+	remaining := n.pending[:0]
+	for _, p := range n.pending {
+		if p.at == n.currentTick {
+			if n.sendData(p.msg) {
+				continue
+			}
+			p.at += n.params.DataRetryInterval
+		}
+		remaining = append(remaining, p)
+	}
+	n.pending = remaining
+
+	n.currentTick++
+	return n.flush()
+}
+
+// send queues msg to be sent to a neighbor, or to every neighbor if to is message.Broadcast.
+func (n *Node) send(to message.NodeID, msg message.Message) {
+	n.outbox = append(n.outbox, message.Envelope{From: n.id, To: to, Message: msg})
+}
+
+// flush returns, and clears, the envelopes sent by the Node.
+func (n *Node) flush() []message.Envelope {
+	out := n.outbox
+	n.outbox = nil
+	return out
+}
+
+// sendData sends msg, as this Node, if there is a route to the destination.
+// msg must not be shared, as its hop fields are updated.
+func (n *Node) sendData(msg *message.DataMessage) bool {
+	route, in := n.routes[msg.Destination]
+	if !in {
+		return false
+	}
+	msg.FromNeighbor = n.id
+	msg.NextHop = route.nextHop
+	n.send(route.nextHop, msg)
+	return true
+}
+
+// handleData forwards a message.DataMessage towards its destination, unless this Node is the destination.
+func (n *Node) handleData(msg *message.DataMessage) {
+	if msg.Destination == n.id {
+		return
+	}
+	// The received message is shared with the controller, so forward a copy.
+	fwd := *msg
+	n.sendData(&fwd)
+}
+
+// symmetric reports whether the link with neighbor is symmetric.
+func (n *Node) symmetric(neighbor message.NodeID) bool {
+	l, in := n.links[neighbor]
+	return in && l.symUntil > n.currentTick
+}
+
+// expire removes links, 2-hop neighbors and topology whose information is no longer held.
+func (n *Node) expire() {
+	for id, l := range n.links {
+		if l.heardUntil <= n.currentTick {
+			delete(n.links, id)
+			n.changed = true
+		}
+		if !n.symmetric(id) {
+			if _, in := n.twoHops[id]; in {
+				delete(n.twoHops, id)
+				n.changed = true
+			}
+		}
+	}
+	for id, t := range n.topology {
+		if t.holdUntil <= n.currentTick {
+			delete(n.topology, id)
+			n.changed = true
+		}
+	}
+}
+
+func (n *Node) hasRoutingSelectors() bool {
+	for id, l := range n.links {
+		if l.routingSelector && n.symmetric(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendHello sends a HELLO advertising every heard neighbor, along with the metrics of the links to them and the MPRs
+// selected among them.
+func (n *Node) sendHello() {
+	h := hello{
+		originator:          n.id,
+		seq:                 n.helloSeq,
+		validity:            n.params.NeighborHoldTime,
+		interval:            n.params.HelloInterval,
+		floodingWillingness: n.params.FloodingWillingness,
+		routingWillingness:  n.params.RoutingWillingness,
+	}
+	n.helloSeq++
+
+	for _, id := range sortedKeys(n.links) {
+		l := n.links[id]
+		hl := helloLink{neighbor: id, status: linkHeard, inMetric: l.inMetric()}
+		if n.symmetric(id) {
+			hl.status = linkSymmetric
+			hl.outMetric = n.outMetric(l)
+			if n.floodingMPRs[id] {
+				hl.mpr |= mprFlooding
+			}
+			if n.routingMPRs[id] {
+				hl.mpr |= mprRouting
+			}
+		}
+		h.links = append(h.links, hl)
+	}
+	n.send(message.Broadcast, newDatagram(h.message()))
+}
+
+// outMetric is the metric of the link to a neighbor. Until the neighbor has reported it, it is taken to be lossless.
+func (n *Node) outMetric(l *link) int {
+	return orHopMetric(l.outMetric)
+}
+
+// orHopMetric returns metric, or hopMetric if the metric is unknown.
+func orHopMetric(metric int) int {
+	if metric == 0 {
+		return hopMetric
+	}
+	return metric
+}
+
+// sendTC sends a TC advertising the routing MPR selectors of the Node, incrementing the ANSN if they have changed.
+func (n *Node) sendTC() {
+	advertised := make(map[message.NodeID]int)
+	for id, l := range n.links {
+		if l.routingSelector && n.symmetric(id) {
+			advertised[id] = n.outMetric(l)
+		}
+	}
+	if !equalMetrics(advertised, n.advertised) {
+		n.ansn++
+		n.advertised = advertised
+	}
+
+	t := tc{
+		originator: n.id,
+		seq:        n.tcSeq,
+		hopLimit:   255,
+		ansn:       n.ansn,
+		validity:   n.params.TopologyHoldTime,
+		interval:   n.params.TCInterval,
+	}
+	n.tcSeq++
+	for _, id := range sortedKeys(advertised) {
+		t.neighbors = append(t.neighbors, tcNeighbor{id: id, metric: advertised[id]})
+	}
+	n.send(message.Broadcast, newDatagram(t.message()))
+}
+
+func equalMetrics(a, b map[message.NodeID]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id, m := range a {
+		if bm, in := b[id]; !in || bm != m {
+			return false
+		}
+	}
+	return true
+}
+
+// handleDatagram handles every message of an RFC 5444 packet received from a neighbor.
+func (n *Node) handleDatagram(from message.NodeID, d Datagram) {
+	var p rfc5444.Packet
+	if err := p.UnmarshalBinary(d); err != nil {
+		log.Printf("node %d: ignoring datagram from %d: %v", n.id, from, err)
+		return
+	}
+	for _, m := range p.Messages {
+		switch m.Type {
+		case msgHello:
+			h, err := parseHello(m)
+			if err != nil {
+				log.Printf("node %d: ignoring HELLO from %d: %v", n.id, from, err)
+				continue
+			}
+			n.handleHello(h)
+		case msgTC:
+			t, err := parseTC(m)
+			if err != nil {
+				log.Printf("node %d: ignoring TC from %d: %v", n.id, from, err)
+				continue
+			}
+			n.handleTC(from, t, m)
+		default:
+			log.Printf("node %d: ignoring message of unknown type %d from %d", n.id, m.Type, from)
+		}
+	}
+}
+
+// handleHello updates the link with the originator of h, and the 2-hop neighbors reached through it.
+func (n *Node) handleHello(h hello) {
+	if h.originator == n.id {
+		return
+	}
+	l, in := n.links[h.originator]
+	if !in {
+		l = &link{neighbor: h.originator, lastSeq: h.seq - 1}
+		n.links[h.originator] = l
+	}
+
+	// Count the HELLOs lost since the last one received, ignoring those received out of order.
+	gap := int(h.seq - l.lastSeq)
+	if gap == 0 || gap > 1<<15 {
+		return
+	}
+	l.lastSeq = h.seq
+	l.received++
+	l.expected += gap
+	if l.expected > n.params.MetricWindow {
+		l.received = (l.received*n.params.MetricWindow + l.expected - 1) / l.expected
+		l.expected = n.params.MetricWindow
+	}
+
+	l.heardUntil = n.currentTick + h.validity
+	l.floodingWillingness = h.floodingWillingness
+	l.routingWillingness = h.routingWillingness
+	l.floodingSelector, l.routingSelector = false, false
+
+	twoHops := make(map[message.NodeID]twoHopLink)
+	for _, hl := range h.links {
+		if hl.neighbor == n.id {
+			switch hl.status {
+			case linkSymmetric, linkHeard:
+				l.symUntil = n.currentTick + h.validity
+			default:
+				l.symUntil = n.currentTick
+			}
+			l.outMetric = hl.inMetric
+			l.floodingSelector = hl.mpr&mprFlooding != 0
+			l.routingSelector = hl.mpr&mprRouting != 0
+			continue
+		}
+		if hl.status == linkSymmetric {
+			twoHops[hl.neighbor] = twoHopLink{in: orHopMetric(hl.inMetric), out: orHopMetric(hl.outMetric)}
+		}
+	}
+
+	if n.symmetric(h.originator) {
+		n.twoHops[h.originator] = twoHops
+	} else {
+		delete(n.twoHops, h.originator)
+	}
+	n.changed = true
+}
+
+// handleTC records the neighbors advertised by t, and forwards it if the neighbor it was received from selected this
+// Node as a flooding MPR. m is the message t was decoded from.
+func (n *Node) handleTC(from message.NodeID, t tc, m rfc5444.Message) {
+	// TCs are only accepted from symmetric neighbors, and TCs sent by this node are ignored.
+	if t.originator == n.id || !n.symmetric(from) {
+		return
+	}
+	if seq, in := n.processed[t.originator]; in && int16(t.seq-seq) <= 0 {
+		return
+	}
+	n.processed[t.originator] = t.seq
+
+	// TCs advertising an older ANSN than already held are outdated, but are still forwarded.
+	if entry, in := n.topology[t.originator]; !in || int16(t.ansn-entry.ansn) >= 0 {
+		entry = &topologyEntry{ansn: t.ansn, holdUntil: n.currentTick + t.validity, neighbors: make(map[message.NodeID]int)}
+		for _, nbr := range t.neighbors {
+			entry.neighbors[nbr.id] = nbr.metric
+		}
+		n.topology[t.originator] = entry
+		n.changed = true
+	}
+
+	if n.links[from].floodingSelector && m.HopLimit > 1 {
+		m.HopLimit--
+		m.HopCount++
+		n.send(message.Broadcast, newDatagram(m))
+	}
+}
+
+// New creates a network Node.
+func New(id message.NodeID, params Params) *Node {
+	return &Node{
+		id:           id,
+		params:       params,
+		links:        make(map[message.NodeID]*link),
+		twoHops:      make(map[message.NodeID]map[message.NodeID]twoHopLink),
+		floodingMPRs: make(map[message.NodeID]bool),
+		routingMPRs:  make(map[message.NodeID]bool),
+		topology:     make(map[message.NodeID]*topologyEntry),
+		processed:    make(map[message.NodeID]uint16),
+		routes:       make(map[message.NodeID]routingEntry),
+		advertised:   make(map[message.NodeID]int),
+	}
+}
+
+// Protocol returns a controller.Factory creating a Node for each node, using params. An error is returned if params
+// are invalid.
+func Protocol(params Params) controller.Factory {
+	return func(config controller.NodeConfig) (controller.Router, error) {
+		if err := params.Validate(); err != nil {
+			return nil, err
+		}
+		return New(config.ID, params), nil
+	}
+}
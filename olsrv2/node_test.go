@@ -0,0 +1,212 @@
+package olsrv2
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/internal/routertest"
+	"github.com/kprusa/olsrsim/message"
+)
+
+// newNetwork creates a network of nodes 0 to size-1, without any links.
+func newNetwork(size int, params Params) *routertest.Network[*Node] {
+	return routertest.New(size, func(id message.NodeID) *Node { return New(id, params) })
+}
+
+func TestNode_routes(t *testing.T) {
+	nw := newNetwork(4, DefaultParams())
+	for i := 1; i < 4; i++ {
+		nw.Link(message.NodeID(i-1), message.NodeID(i), true)
+	}
+	nw.Nodes[0].Send(3, "hi")
+	nw.Run(60)
+
+	want := []controller.Route{
+		{Destination: 1, NextHop: 1, Distance: 1},
+		{Destination: 2, NextHop: 1, Distance: 2},
+		{Destination: 3, NextHop: 1, Distance: 3},
+	}
+	if got := nw.Nodes[0].Routes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Routes() = %v, want %v", got, want)
+	}
+	want = []controller.Route{
+		{Destination: 0, NextHop: 2, Distance: 3},
+		{Destination: 1, NextHop: 2, Distance: 2},
+		{Destination: 2, NextHop: 2, Distance: 1},
+	}
+	if got := nw.Nodes[3].Routes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Routes() = %v, want %v", got, want)
+	}
+	if want := []string{"hi"}; !reflect.DeepEqual(nw.Delivered, want) {
+		t.Errorf("delivered %v, want %v", nw.Delivered, want)
+	}
+}
+
+func TestNode_metrics(t *testing.T) {
+	// 0 reaches 3 through either 1 or 2, but the link between 1 and 3 loses half of its messages.
+	nw := newNetwork(4, DefaultParams())
+	nw.Link(0, 1, true)
+	nw.Link(0, 2, true)
+	nw.LinkLossy(1, 3)
+	nw.Link(2, 3, true)
+	nw.Run(60)
+
+	n := nw.Nodes[0]
+	if got := n.routes[3]; got.nextHop != 2 || got.hops != 2 || got.metric != 2*hopMetric {
+		t.Errorf("route to 3 = %+v, want via 2 with metric %d", got, 2*hopMetric)
+	}
+
+	// Any neighbor covers 3 for flooding, but only 2 is on the shortest path from 3.
+	if want := map[message.NodeID]bool{1: true}; !reflect.DeepEqual(n.floodingMPRs, want) {
+		t.Errorf("flooding MPRs = %v, want %v", n.floodingMPRs, want)
+	}
+	if want := map[message.NodeID]bool{2: true}; !reflect.DeepEqual(n.routingMPRs, want) {
+		t.Errorf("routing MPRs = %v, want %v", n.routingMPRs, want)
+	}
+	if l := nw.Nodes[3].links[1]; l.inMetric() <= hopMetric {
+		t.Errorf("metric of the lossy link = %d, want more than %d", l.inMetric(), hopMetric)
+	}
+}
+
+func TestNode_Receive_duplicateTC(t *testing.T) {
+	n := New(1, DefaultParams())
+	n.links[0] = &link{neighbor: 0, heardUntil: 100, symUntil: 100, floodingSelector: true}
+
+	tcMsg := tc{originator: 5, seq: 3, hopLimit: 255, ansn: 1, validity: 30, neighbors: []tcNeighbor{{id: 6, metric: hopMetric}}}
+	env := message.Envelope{From: 0, To: message.Broadcast, Message: newDatagram(tcMsg.message())}
+	if got := n.Receive(env); len(got) != 1 {
+		t.Fatalf("Receive() forwarded %d envelopes, want 1", len(got))
+	}
+	if got := n.Receive(env); len(got) != 0 {
+		t.Errorf("Receive() forwarded a duplicate TC: %v", got)
+	}
+	if got := n.topology[5]; got == nil || got.neighbors[6] != hopMetric {
+		t.Errorf("topology from 5 = %+v, want 6 advertised", got)
+	}
+}
+
+func TestNode_Receive_asymmetricTC(t *testing.T) {
+	n := New(1, DefaultParams())
+	n.links[0] = &link{neighbor: 0, heardUntil: 100, floodingSelector: true}
+
+	tcMsg := tc{originator: 5, seq: 3, hopLimit: 255, ansn: 1, validity: 30}
+	env := message.Envelope{From: 0, To: message.Broadcast, Message: newDatagram(tcMsg.message())}
+	if got := n.Receive(env); len(got) != 0 {
+		t.Errorf("Receive() forwarded a TC from an asymmetric neighbor: %v", got)
+	}
+	if _, in := n.topology[5]; in {
+		t.Errorf("Receive() accepted a TC from an asymmetric neighbor")
+	}
+}
+
+func TestSelectMPRs(t *testing.T) {
+	tests := []struct {
+		name        string
+		willingness map[message.NodeID]int
+		covers      map[message.NodeID]map[message.NodeID]bool
+		want        map[message.NodeID]bool
+	}{
+		{
+			name:        "nothing to cover",
+			willingness: map[message.NodeID]int{1: willDefault},
+			covers:      map[message.NodeID]map[message.NodeID]bool{1: {}},
+			want:        map[message.NodeID]bool{},
+		},
+		{
+			name:        "most covered",
+			willingness: map[message.NodeID]int{1: willDefault, 2: willDefault},
+			covers:      map[message.NodeID]map[message.NodeID]bool{1: {5: true}, 2: {5: true, 6: true}},
+			want:        map[message.NodeID]bool{2: true},
+		},
+		{
+			name:        "ties broken by willingness",
+			willingness: map[message.NodeID]int{1: willDefault, 2: 5},
+			covers:      map[message.NodeID]map[message.NodeID]bool{1: {5: true}, 2: {5: true}},
+			want:        map[message.NodeID]bool{2: true},
+		},
+		{
+			name:        "ties broken by ID",
+			willingness: map[message.NodeID]int{1: willDefault, 2: willDefault},
+			covers:      map[message.NodeID]map[message.NodeID]bool{1: {5: true}, 2: {5: true}},
+			want:        map[message.NodeID]bool{1: true},
+		},
+		{
+			name:        "always willing",
+			willingness: map[message.NodeID]int{1: willDefault, 2: willAlways},
+			covers:      map[message.NodeID]map[message.NodeID]bool{1: {5: true, 6: true}, 2: {}},
+			want:        map[message.NodeID]bool{1: true, 2: true},
+		},
+		{
+			name:        "never willing",
+			willingness: map[message.NodeID]int{1: willNever},
+			covers:      map[message.NodeID]map[message.NodeID]bool{1: {5: true}},
+			want:        map[message.NodeID]bool{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectMPRs(tt.willingness, tt.covers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectMPRs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr bool
+	}{
+		{
+			name:    "defaults",
+			params:  DefaultParams(),
+			wantErr: false,
+		},
+		{
+			name:    "hold time shorter than interval",
+			params:  DefaultParams().Merge(Params{NeighborHoldTime: 2}),
+			wantErr: true,
+		},
+		{
+			name:    "willingness out of range",
+			params:  DefaultParams().Merge(Params{RoutingWillingness: 8}),
+			wantErr: true,
+		},
+		{
+			name:    "zero metric window",
+			params:  Params{HelloInterval: 5, TCInterval: 10, NeighborHoldTime: 15, TopologyHoldTime: 30, DataRetryInterval: 30, FloodingWillingness: 3, RoutingWillingness: 3},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNode_Inspect(t *testing.T) {
+	nw := newNetwork(4, DefaultParams())
+	for i := 1; i < 4; i++ {
+		nw.Link(message.NodeID(i-1), message.NodeID(i), true)
+	}
+	nw.Run(60)
+
+	want := controller.NodeState{
+		ID:              1,
+		Tick:            60,
+		Neighbors:       []message.NodeID{0, 2},
+		TwoHopNeighbors: []controller.TwoHopNeighbor{{ID: 3, Via: 2}},
+		MPRs:            []message.NodeID{2},
+		MPRSelectors:    []message.NodeID{0, 2},
+		Topology:        []controller.TopologyLink{{From: 2, To: 1}, {From: 2, To: 3}},
+		Routes:          nw.Nodes[1].Routes(),
+	}
+	if got := nw.Nodes[1].Inspect(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Inspect() = %+v, want %+v", got, want)
+	}
+}
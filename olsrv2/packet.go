@@ -0,0 +1,416 @@
+package olsrv2
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/rfc5444"
+)
+
+// Message types of RFC 6130 and RFC 7181.
+const (
+	msgHello = 0
+	msgTC    = 1
+)
+
+// Message TLV types.
+const (
+	tlvIntervalTime = 0
+	tlvValidityTime = 1
+	tlvMPRWilling   = 7
+	tlvContSeqNum   = 22
+)
+
+// Address block TLV types.
+const (
+	tlvLinkStatus  = 3
+	tlvOtherNeighb = 4
+	tlvLinkMetric  = 7
+	tlvMPR         = 8
+	tlvNbrAddrType = 9
+)
+
+// Values of the LINK_STATUS and OTHER_NEIGHB TLVs.
+const (
+	linkLost      = 0
+	linkSymmetric = 1
+	linkHeard     = 2
+)
+
+// Values of the MPR TLV, which are flags.
+const (
+	mprFlooding = 1
+	mprRouting  = 2
+)
+
+// Flags of the LINK_METRIC TLV value, saying which metrics it holds.
+const (
+	metricIncomingLink     = 0x8
+	metricOutgoingLink     = 0x4
+	metricIncomingNeighbor = 0x2
+	metricOutgoingNeighbor = 0x1
+)
+
+// nbrAddrRoutableOrig is the value of the NBR_ADDR_TYPE TLV for an address which is both an originator and routable.
+const nbrAddrRoutableOrig = 3
+
+// contSeqNumComplete is the type extension of a CONT_SEQ_NUM TLV in a TC advertising every neighbor.
+const contSeqNumComplete = 0
+
+// maxMetric is the largest link metric which can be represented.
+const maxMetric = (257+255)<<15 - 256
+
+// encodeMetric compresses a link metric to the 12 bits of RFC 7181, rounding up to the next representable value.
+func encodeMetric(metric int) uint16 {
+	if metric < 1 {
+		metric = 1
+	}
+	for b := 0; b < 16; b++ {
+		// The smallest mantissa with (257+a)*2^b - 256 >= metric.
+		a := (metric + 256 + (1<<b - 1)) >> b
+		a -= 257
+		if a < 0 {
+			a = 0
+		}
+		if a <= 255 {
+			return uint16(b<<8 | a)
+		}
+	}
+	return 0xfff
+}
+
+// decodeMetric decompresses the 12 bit link metric of RFC 7181.
+func decodeMetric(v uint16) int {
+	a, b := int(v&0xff), int(v>>8&0xf)
+	return (257+a)<<b - 256
+}
+
+// Datagram is an RFC 5444 packet, as transmitted by a Node. Datagram implements message.Message.
+type Datagram []byte
+
+func init() {
+	message.Register("RFC5444", Datagram(nil))
+}
+
+// newDatagram encodes msg as the only message of a packet.
+func newDatagram(msg rfc5444.Message) Datagram {
+	data, err := rfc5444.Packet{Messages: []rfc5444.Message{msg}}.MarshalBinary()
+	if err != nil {
+		panic(fmt.Sprintf("olsrv2: encoding message: %v", err))
+	}
+	return data
+}
+
+// Type is the type of the first message of the datagram: HELLO or TC.
+func (d Datagram) Type() string {
+	var p rfc5444.Packet
+	if err := p.UnmarshalBinary(d); err != nil || len(p.Messages) == 0 {
+		return "MALFORMED"
+	}
+	switch p.Messages[0].Type {
+	case msgHello:
+		return "HELLO"
+	case msgTC:
+		return "TC"
+	default:
+		return fmt.Sprintf("MSG%d", p.Messages[0].Type)
+	}
+}
+
+func (d Datagram) String() string {
+	var p rfc5444.Packet
+	if err := p.UnmarshalBinary(d); err != nil {
+		return fmt.Sprintf("* MALFORMED %x", []byte(d))
+	}
+	msgs := make([]string, 0, len(p.Messages))
+	for _, m := range p.Messages {
+		switch m.Type {
+		case msgHello:
+			h, err := parseHello(m)
+			if err != nil {
+				msgs = append(msgs, fmt.Sprintf("* %d HELLO MALFORMED", m.Originator))
+				continue
+			}
+			msgs = append(msgs, h.String())
+		case msgTC:
+			t, err := parseTC(m)
+			if err != nil {
+				msgs = append(msgs, fmt.Sprintf("* TC %d MALFORMED", m.Originator))
+				continue
+			}
+			msgs = append(msgs, t.String())
+		default:
+			msgs = append(msgs, fmt.Sprintf("* %d MSG%d", m.Originator, m.Type))
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// hello is an NHDP HELLO message.
+type hello struct {
+	originator message.NodeID
+	seq        uint16
+
+	// validity is how long the receiver may hold the information in the message, and interval how long until the
+	// next HELLO.
+	validity int
+	interval int
+
+	floodingWillingness int
+	routingWillingness  int
+
+	links []helloLink
+}
+
+// helloLink is a neighbor advertised in a hello, along with the state of the link to it.
+type helloLink struct {
+	neighbor message.NodeID
+	status   int
+
+	// mpr holds the mprFlooding and mprRouting flags of the neighbor.
+	mpr int
+
+	// inMetric is the metric of the link from the neighbor to the originator, and outMetric that of the link from the
+	// originator to the neighbor. Either is 0 if it is not advertised.
+	inMetric  int
+	outMetric int
+}
+
+func (h hello) String() string {
+	var sym, heard, lost, fmpr, rmpr []message.NodeID
+	for _, l := range h.links {
+		switch l.status {
+		case linkSymmetric:
+			sym = append(sym, l.neighbor)
+		case linkHeard:
+			heard = append(heard, l.neighbor)
+		default:
+			lost = append(lost, l.neighbor)
+		}
+		if l.mpr&mprFlooding != 0 {
+			fmpr = append(fmpr, l.neighbor)
+		}
+		if l.mpr&mprRouting != 0 {
+			rmpr = append(rmpr, l.neighbor)
+		}
+	}
+	f := "* %d HELLO %d WILL %d %d SYM %s HEARD %s LOST %s FMPR %s RMPR %s"
+	return fmt.Sprintf(f, h.originator, h.seq, h.floodingWillingness, h.routingWillingness, ids(sym), ids(heard), ids(lost), ids(fmpr), ids(rmpr))
+}
+
+func (h hello) message() rfc5444.Message {
+	m := rfc5444.Message{
+		Type:           msgHello,
+		Originator:     h.originator,
+		HopLimit:       1,
+		SequenceNumber: h.seq,
+		TLVs: []rfc5444.TLV{
+			{Type: tlvValidityTime, Value: []byte{rfc5444.EncodeTime(h.validity)}},
+			{Type: tlvIntervalTime, Value: []byte{rfc5444.EncodeTime(h.interval)}},
+			{Type: tlvMPRWilling, Value: []byte{byte(h.floodingWillingness<<4 | h.routingWillingness)}},
+		},
+	}
+
+	for start := 0; start < len(h.links); start += 255 {
+		links := h.links[start:]
+		if len(links) > 255 {
+			links = links[:255]
+		}
+		b := rfc5444.AddressBlock{}
+		for i, l := range links {
+			b.Addresses = append(b.Addresses, l.neighbor)
+			b.TLVs = append(b.TLVs, addressTLV(i, tlvLinkStatus, []byte{byte(l.status)}))
+			if l.mpr != 0 {
+				b.TLVs = append(b.TLVs, addressTLV(i, tlvMPR, []byte{byte(l.mpr)}))
+			}
+			if l.inMetric != 0 {
+				b.TLVs = append(b.TLVs, metricTLV(i, metricIncomingLink, l.inMetric))
+			}
+			if l.outMetric != 0 {
+				b.TLVs = append(b.TLVs, metricTLV(i, metricOutgoingNeighbor, l.outMetric))
+			}
+		}
+		m.AddressBlocks = append(m.AddressBlocks, b)
+	}
+	return m
+}
+
+// parseHello decodes a hello from m. Addresses with no LINK_STATUS TLV are ignored, unless they have an OTHER_NEIGHB
+// TLV, as they are neighbors of the originator on another interface.
+func parseHello(m rfc5444.Message) (hello, error) {
+	h := hello{originator: m.Originator, seq: m.SequenceNumber}
+	var err error
+	if h.validity, err = timeTLV(m, tlvValidityTime); err != nil {
+		return h, err
+	}
+	h.interval, _ = timeTLV(m, tlvIntervalTime)
+	if t, ok := m.TLV(tlvMPRWilling); ok && len(t.Value) == 1 {
+		h.floodingWillingness = int(t.Value[0] >> 4)
+		h.routingWillingness = int(t.Value[0] & 0xf)
+	}
+
+	for _, b := range m.AddressBlocks {
+		for i, addr := range b.Addresses {
+			l := helloLink{neighbor: addr, status: -1}
+			if v, ok := byteTLV(&b, i, tlvLinkStatus); ok {
+				l.status = int(v)
+			} else if v, ok := byteTLV(&b, i, tlvOtherNeighb); ok && v == linkSymmetric {
+				l.status = linkSymmetric
+			}
+			if l.status < 0 {
+				continue
+			}
+			if v, ok := byteTLV(&b, i, tlvMPR); ok {
+				l.mpr = int(v)
+			}
+			l.inMetric = linkMetric(&b, i, metricIncomingLink)
+			l.outMetric = linkMetric(&b, i, metricOutgoingNeighbor)
+			h.links = append(h.links, l)
+		}
+	}
+	return h, nil
+}
+
+// tc is an OLSRv2 TC message, advertising the originator's routing MPR selectors.
+type tc struct {
+	originator message.NodeID
+	seq        uint16
+	hopLimit   uint8
+	hopCount   uint8
+
+	// ansn is the advertised neighbor sequence number, which increases whenever the advertised neighbors change.
+	ansn uint16
+
+	validity int
+	interval int
+
+	neighbors []tcNeighbor
+}
+
+// tcNeighbor is a neighbor advertised in a tc, along with the metric of the link from the originator to it.
+type tcNeighbor struct {
+	id     message.NodeID
+	metric int
+}
+
+func (t tc) String() string {
+	nbrs := make([]string, 0, len(t.neighbors))
+	for _, n := range t.neighbors {
+		nbrs = append(nbrs, fmt.Sprintf("%d:%d", n.id, n.metric))
+	}
+	f := "* TC %d %d HOPS %d ANSN %d NBRS %s"
+	return fmt.Sprintf(f, t.originator, t.seq, t.hopCount, t.ansn, strings.Join(nbrs, " "))
+}
+
+func (t tc) message() rfc5444.Message {
+	ansn := make([]byte, 2)
+	binary.BigEndian.PutUint16(ansn, t.ansn)
+	m := rfc5444.Message{
+		Type:           msgTC,
+		Originator:     t.originator,
+		HopLimit:       t.hopLimit,
+		HopCount:       t.hopCount,
+		SequenceNumber: t.seq,
+		TLVs: []rfc5444.TLV{
+			{Type: tlvValidityTime, Value: []byte{rfc5444.EncodeTime(t.validity)}},
+			{Type: tlvIntervalTime, Value: []byte{rfc5444.EncodeTime(t.interval)}},
+			{Type: tlvContSeqNum, TypeExt: contSeqNumComplete, Value: ansn},
+		},
+	}
+
+	for start := 0; start < len(t.neighbors); start += 255 {
+		nbrs := t.neighbors[start:]
+		if len(nbrs) > 255 {
+			nbrs = nbrs[:255]
+		}
+		b := rfc5444.AddressBlock{}
+		for i, n := range nbrs {
+			b.Addresses = append(b.Addresses, n.id)
+			b.TLVs = append(b.TLVs,
+				addressTLV(i, tlvNbrAddrType, []byte{nbrAddrRoutableOrig}),
+				metricTLV(i, metricOutgoingNeighbor, n.metric),
+			)
+		}
+		m.AddressBlocks = append(m.AddressBlocks, b)
+	}
+	return m
+}
+
+// parseTC decodes a tc from m. Advertised addresses without a metric are ignored, as they cannot be routed through.
+func parseTC(m rfc5444.Message) (tc, error) {
+	t := tc{originator: m.Originator, seq: m.SequenceNumber, hopLimit: m.HopLimit, hopCount: m.HopCount}
+	var err error
+	if t.validity, err = timeTLV(m, tlvValidityTime); err != nil {
+		return t, err
+	}
+	t.interval, _ = timeTLV(m, tlvIntervalTime)
+	ansn, ok := m.TLV(tlvContSeqNum)
+	if !ok || len(ansn.Value) != 2 {
+		return t, fmt.Errorf("tc from %d has no ANSN", m.Originator)
+	}
+	t.ansn = binary.BigEndian.Uint16(ansn.Value)
+
+	for _, b := range m.AddressBlocks {
+		for i, addr := range b.Addresses {
+			if metric := linkMetric(&b, i, metricOutgoingNeighbor); metric != 0 {
+				t.neighbors = append(t.neighbors, tcNeighbor{id: addr, metric: metric})
+			}
+		}
+	}
+	return t, nil
+}
+
+func addressTLV(index int, typ uint8, value []byte) rfc5444.AddressTLV {
+	return rfc5444.AddressTLV{TLV: rfc5444.TLV{Type: typ, Value: value}, IndexStart: index, IndexStop: index}
+}
+
+// metricTLV creates a LINK_METRIC TLV holding metric, as the kinds of metric given by flags.
+func metricTLV(index int, flags uint16, metric int) rfc5444.AddressTLV {
+	v := make([]byte, 2)
+	binary.BigEndian.PutUint16(v, flags<<12|encodeMetric(metric))
+	return addressTLV(index, tlvLinkMetric, v)
+}
+
+// linkMetric returns the metric of the kind given by flag held by a LINK_METRIC TLV of the address at index, or 0.
+func linkMetric(b *rfc5444.AddressBlock, index int, flag uint16) int {
+	for _, t := range b.AddressTLVs(index, tlvLinkMetric) {
+		if len(t.Value) != 2 {
+			continue
+		}
+		v := binary.BigEndian.Uint16(t.Value)
+		if v>>12&flag != 0 {
+			return decodeMetric(v & 0xfff)
+		}
+	}
+	return 0
+}
+
+// byteTLV returns the value of a single octet TLV of the address at index.
+func byteTLV(b *rfc5444.AddressBlock, index int, typ uint8) (byte, bool) {
+	for _, t := range b.AddressTLVs(index, typ) {
+		if len(t.Value) == 1 {
+			return t.Value[0], true
+		}
+	}
+	return 0, false
+}
+
+// timeTLV decodes a message TLV holding a time.
+func timeTLV(m rfc5444.Message, typ uint8) (int, error) {
+	t, ok := m.TLV(typ)
+	if !ok || len(t.Value) != 1 {
+		return 0, fmt.Errorf("message from %d has no time TLV of type %d", m.Originator, typ)
+	}
+	return rfc5444.DecodeTime(t.Value[0]), nil
+}
+
+// ids formats node IDs as a space separated list.
+func ids(nodes []message.NodeID) string {
+	s := make([]string, len(nodes))
+	for i, n := range nodes {
+		s[i] = n.String()
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,135 @@
+package olsrv2
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/rfc5444"
+)
+
+func TestEncodeMetric(t *testing.T) {
+	tests := []struct {
+		name   string
+		metric int
+		want   int
+	}{
+		{name: "minimum", metric: 1, want: 1},
+		{name: "exact", metric: hopMetric, want: hopMetric},
+		{name: "rounded up", metric: 513, want: 514},
+		{name: "maximum", metric: maxMetric, want: maxMetric},
+		{name: "too large", metric: maxMetric + 1, want: maxMetric},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeMetric(encodeMetric(tt.metric)); got != tt.want {
+				t.Errorf("decodeMetric(encodeMetric(%d)) = %d, want %d", tt.metric, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHello_message(t *testing.T) {
+	h := hello{
+		originator:          4,
+		seq:                 9,
+		validity:            15,
+		interval:            5,
+		floodingWillingness: 3,
+		routingWillingness:  7,
+		links: []helloLink{
+			{neighbor: 1, status: linkSymmetric, mpr: mprFlooding | mprRouting, inMetric: 256, outMetric: 512},
+			{neighbor: 2, status: linkHeard, inMetric: 1024},
+		},
+	}
+	got, err := parseHello(h.message())
+	if err != nil {
+		t.Fatalf("parseHello() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, h) {
+		t.Errorf("parseHello() = %+v, want %+v", got, h)
+	}
+}
+
+func TestTC_message(t *testing.T) {
+	msg := tc{
+		originator: 4,
+		seq:        2,
+		hopLimit:   254,
+		hopCount:   1,
+		ansn:       0xffff,
+		validity:   30,
+		interval:   10,
+		neighbors:  []tcNeighbor{{id: 1, metric: 256}, {id: 3, metric: 768}},
+	}
+	got, err := parseTC(msg.message())
+	if err != nil {
+		t.Fatalf("parseTC() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, msg) {
+		t.Errorf("parseTC() = %+v, want %+v", got, msg)
+	}
+}
+
+func TestDatagram_String(t *testing.T) {
+	tests := []struct {
+		name     string
+		d        Datagram
+		wantType string
+		want     string
+	}{
+		{
+			name: "hello",
+			d: newDatagram(hello{originator: 4, seq: 9, validity: 15, interval: 5, floodingWillingness: 3, routingWillingness: 3, links: []helloLink{
+				{neighbor: 1, status: linkSymmetric, mpr: mprRouting, inMetric: 256, outMetric: 256},
+				{neighbor: 2, status: linkHeard, inMetric: 256},
+			}}.message()),
+			wantType: "HELLO",
+			want:     "* 4 HELLO 9 WILL 3 3 SYM 1 HEARD 2 LOST  FMPR  RMPR 1",
+		},
+		{
+			name:     "tc",
+			d:        newDatagram(tc{originator: 4, seq: 2, hopCount: 1, ansn: 5, validity: 30, neighbors: []tcNeighbor{{id: 1, metric: 256}}}.message()),
+			wantType: "TC",
+			want:     "* TC 4 2 HOPS 1 ANSN 5 NBRS 1:256",
+		},
+		{
+			name:     "unknown message",
+			d:        newDatagram(rfc5444.Message{Type: 9, Originator: 4}),
+			wantType: "MSG9",
+			want:     "* 4 MSG9",
+		},
+		{
+			name:     "malformed",
+			d:        Datagram{0x10},
+			wantType: "MALFORMED",
+			want:     "* MALFORMED 10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.d.Type(); got != tt.wantType {
+				t.Errorf("Type() = %v, want %v", got, tt.wantType)
+			}
+			if got := tt.d.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatagram_MarshalJSON(t *testing.T) {
+	env := message.Envelope{From: 4, To: message.Broadcast, Message: newDatagram(tc{originator: 4, ansn: 1, validity: 30}.message())}
+	data, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got message.Envelope
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, env) {
+		t.Errorf("Unmarshal() = %v, want %v", got, env)
+	}
+}
@@ -0,0 +1,255 @@
+package olsrsim
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/topology"
+)
+
+// SplitMetrics measures how the routes of the nodes reacted to the network splitting into partitions, and to the
+// partitions merging back. Only routes between nodes of different partitions are measured.
+type SplitMetrics struct {
+	// Tick is the tick the network split at.
+	Tick int `json:"tick"`
+
+	// Merge is the tick the partitions merged back at, or zero if they did not during the run.
+	Merge int `json:"merge,omitempty"`
+
+	// Stale counts the routes to nodes of another partition held at the end of the tick before the split.
+	Stale int `json:"stale"`
+
+	// Linger is the total number of ticks stale routes were still held at the end of, from the split on, and
+	// MaxLinger the most any stale route was. Routes never dropped count until the merge, or the end of the run.
+	Linger    int `json:"linger"`
+	MaxLinger int `json:"maxLinger"`
+
+	// Lingering counts the stale routes never dropped before the merge, or the end of the run.
+	Lingering int `json:"lingering"`
+
+	// Pairs counts the pairs of nodes of different partitions, both in the network and UP, at the end of the tick the
+	// partitions merged at, and Restored those a route between reappeared for before the next split, or the end of
+	// the run.
+	Pairs    int `json:"pairs"`
+	Restored int `json:"restored"`
+
+	// Recovery is the total number of ticks restored routes took to reappear, counting the tick of the merge, and
+	// MaxRecovery the most any took.
+	Recovery    int `json:"recovery"`
+	MaxRecovery int `json:"maxRecovery"`
+}
+
+// MeanLinger is the mean number of ticks stale routes were held for after the split, or zero if there were none.
+func (m SplitMetrics) MeanLinger() float64 {
+	if m.Stale == 0 {
+		return 0
+	}
+	return float64(m.Linger) / float64(m.Stale)
+}
+
+// MeanRecovery is the mean number of ticks restored routes took to reappear after the merge, or zero if none did.
+func (m SplitMetrics) MeanRecovery() float64 {
+	if m.Restored == 0 {
+		return 0
+	}
+	return float64(m.Recovery) / float64(m.Restored)
+}
+
+// route is a route from a node to a destination.
+type route struct {
+	from, to message.NodeID
+}
+
+// splitRecord is what a SplitRecorder knows of a single split.
+type splitRecord struct {
+	split   topology.Split
+	metrics SplitMetrics
+
+	// held holds the stale routes not dropped yet.
+	held map[route]bool
+
+	// missing holds the pairs of nodes of different partitions no route has reappeared between yet, once merged.
+	missing map[route]bool
+}
+
+// SplitRecorder measures the SplitMetrics of every split of a running simulation, by inspecting the routes of every
+// node once each tick is over. SplitRecorder implements controller.TickObserver.
+type SplitRecorder struct {
+	c       *controller.Controller
+	records []*splitRecord
+
+	// ticks is the number of ticks over.
+	ticks int
+}
+
+// NewSplitRecorder creates a SplitRecorder of the splits of c's simulation, registering it as an Observer of c.
+// Splits must not overlap, and be given in order.
+func NewSplitRecorder(c *controller.Controller, splits []ScenarioSplit) *SplitRecorder {
+	r := &SplitRecorder{c: c}
+	for _, s := range splits {
+		r.records = append(r.records, &splitRecord{split: s.split(), metrics: SplitMetrics{Tick: s.Tick}})
+	}
+	c.Observe(r)
+	return r
+}
+
+// Event does nothing: routes are inspected once each tick is over.
+func (r *SplitRecorder) Event(controller.Event) {}
+
+// TickOver inspects the routes of every node, measuring those between nodes of different partitions. It is called by
+// the Controller.
+func (r *SplitRecorder) TickOver(tick int) {
+	r.ticks = tick + 1
+	routes := r.routes()
+	for i, rec := range r.records {
+		s := rec.split
+		switch {
+		case tick == s.Time-1:
+			rec.held = make(map[route]bool)
+			for rt := range routes {
+				if s.Separates(rt.from, rt.to) {
+					rec.held[rt] = true
+				}
+			}
+			rec.metrics.Stale = len(rec.held)
+		case tick >= s.Time && (s.Merge <= 0 || tick < s.Merge):
+			for rt := range rec.held {
+				if !routes[rt] {
+					delete(rec.held, rt)
+					rec.metrics.Linger += tick - s.Time
+					if tick-s.Time > rec.metrics.MaxLinger {
+						rec.metrics.MaxLinger = tick - s.Time
+					}
+				}
+			}
+		case s.Merge > 0 && tick >= s.Merge && (i == len(r.records)-1 || tick < r.records[i+1].split.Time):
+			if tick == s.Merge {
+				rec.metrics.Merge = s.Merge
+				rec.missing = r.pairs(s)
+				rec.metrics.Pairs = len(rec.missing)
+			}
+			for rt := range rec.missing {
+				if routes[rt] {
+					delete(rec.missing, rt)
+					rec.metrics.Restored++
+					rec.metrics.Recovery += tick - s.Merge + 1
+					if tick-s.Merge+1 > rec.metrics.MaxRecovery {
+						rec.metrics.MaxRecovery = tick - s.Merge + 1
+					}
+				}
+			}
+		}
+	}
+}
+
+// routes returns the routes of every node in the network.
+func (r *SplitRecorder) routes() map[route]bool {
+	routes := make(map[route]bool)
+	for _, router := range r.c.Routers() {
+		state, err := r.c.Inspect(router.ID())
+		if err != nil {
+			continue
+		}
+		for _, rt := range state.Routes {
+			routes[route{from: state.ID, to: rt.Destination}] = true
+		}
+	}
+	return routes
+}
+
+// pairs returns every pair of nodes of different partitions of a split which are both in the network and UP.
+func (r *SplitRecorder) pairs(s topology.Split) map[route]bool {
+	var up []message.NodeID
+	for _, router := range r.c.Routers() {
+		if state, err := r.c.Inspect(router.ID()); err == nil && !state.Down {
+			up = append(up, state.ID)
+		}
+	}
+	pairs := make(map[route]bool)
+	for _, from := range up {
+		for _, to := range up {
+			if s.Separates(from, to) {
+				pairs[route{from: from, to: to}] = true
+			}
+		}
+	}
+	return pairs
+}
+
+// Metrics returns the SplitMetrics of every split, in order, as of the last tick over. Splits which have yet to
+// happen have none.
+func (r *SplitRecorder) Metrics() []SplitMetrics {
+	metrics := make([]SplitMetrics, 0, len(r.records))
+	for _, rec := range r.records {
+		if r.ticks <= rec.split.Time {
+			break
+		}
+		m := rec.metrics
+		if len(rec.held) > 0 {
+			end := r.ticks
+			if rec.split.Merge > 0 && rec.split.Merge < end {
+				end = rec.split.Merge
+			}
+			m.Lingering = len(rec.held)
+			m.Linger += len(rec.held) * (end - rec.split.Time)
+			if end-rec.split.Time > m.MaxLinger {
+				m.MaxLinger = end - rec.split.Time
+			}
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+// WriteSplitReport writes a table of the SplitMetrics of one or more runs side-by-side, so that protocols and
+// parameters can be compared on how they react to the network splitting and merging. Each run is a column, headed by
+// its name. Runs which stopped before a split, or a merge, have no metrics for it.
+func WriteSplitReport(w io.Writer, names []string, metrics [][]SplitMetrics) error {
+	if len(names) != len(metrics) {
+		return fmt.Errorf("report: %d names given for %d runs", len(names), len(metrics))
+	}
+	// splits holds each split, merged if any run merged it.
+	var splits []SplitMetrics
+	for _, run := range metrics {
+		for i, m := range run {
+			if i == len(splits) {
+				splits = append(splits, m)
+			} else if m.Merge > 0 {
+				splits[i].Merge = m.Merge
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\t"+strings.Join(names, "\t"))
+	row := func(label string, i int, merged bool, cell func(m SplitMetrics) string) {
+		cells := []string{label}
+		for _, run := range metrics {
+			if i >= len(run) || (merged && run[i].Merge == 0) {
+				cells = append(cells, "-")
+				continue
+			}
+			cells = append(cells, cell(run[i]))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	for i, s := range splits {
+		row(fmt.Sprintf("split at %d: stale routes", s.Tick), i, false, func(m SplitMetrics) string { return fmt.Sprint(m.Stale) })
+		row("  mean linger", i, false, func(m SplitMetrics) string { return fmt.Sprintf("%.1f", m.MeanLinger()) })
+		row("  max linger", i, false, func(m SplitMetrics) string { return fmt.Sprint(m.MaxLinger) })
+		row("  never dropped", i, false, func(m SplitMetrics) string { return fmt.Sprint(m.Lingering) })
+		if s.Merge == 0 {
+			continue
+		}
+		row(fmt.Sprintf("merge at %d: routes restored", s.Merge), i, true, func(m SplitMetrics) string {
+			return fmt.Sprintf("%d/%d", m.Restored, m.Pairs)
+		})
+		row("  mean recovery", i, true, func(m SplitMetrics) string { return fmt.Sprintf("%.1f", m.MeanRecovery()) })
+		row("  max recovery", i, true, func(m SplitMetrics) string { return fmt.Sprint(m.MaxRecovery) })
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,89 @@
+package olsrsim
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kprusa/olsrsim/message"
+)
+
+func TestSplitRecorder(t *testing.T) {
+	s := &Scenario{
+		Ticks:         160,
+		TickMillis:    1,
+		TopologyLines: []string{"0 UP 0 <-> 1", "0 UP 1 <-> 2", "0 UP 2 <-> 3", "0 UP 3 <-> 4", "0 UP 4 <-> 5", "0 UP 1 <-> 4"},
+		Nodes:         []ScenarioNode{{ID: 0}, {ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}},
+		Splits: []ScenarioSplit{
+			{Tick: 40, Merge: 80, Partitions: map[string][]message.NodeID{"west": {0, 1, 2}, "east": {3, 4, 5}}},
+			// Node 5 is cut off for good, which routes to it do not survive.
+			{Tick: 120, Partitions: map[string][]message.NodeID{"rest": {0, 1, 2, 3, 4}, "alone": {5}}},
+		},
+	}
+	r, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Splits) != 2 {
+		t.Fatalf("Run() measured %d splits, want 2", len(r.Splits))
+	}
+
+	// Every node of a partition routed to every node of the other before the split, and again after the merge.
+	halves := r.Splits[0]
+	if halves.Stale != 18 || halves.Lingering != 0 || halves.MaxLinger == 0 {
+		t.Errorf("split at 40 = %+v, want 18 stale routes, all dropped after lingering", halves)
+	}
+	if halves.Merge != 80 || halves.Pairs != 18 || halves.Restored != 18 || halves.MaxRecovery == 0 {
+		t.Errorf("merge at 80 = %+v, want every route of 18 restored", halves)
+	}
+	if got := halves.MeanLinger(); got <= 0 || got > float64(halves.MaxLinger) {
+		t.Errorf("MeanLinger() = %v, want within (0, %d]", got, halves.MaxLinger)
+	}
+
+	alone := r.Splits[1]
+	if alone.Stale != 10 || alone.Lingering != 0 || alone.Merge != 0 || alone.Pairs != 0 {
+		t.Errorf("split at 120 = %+v, want 10 stale routes, all dropped, and no merge", alone)
+	}
+
+	// A split the run does not reach has no metrics.
+	s.Ticks = 100
+	if r, err = s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Splits) != 1 {
+		t.Errorf("Run() measured %d splits of a run ending before the second, want 1", len(r.Splits))
+	}
+}
+
+func TestWriteSplitReport(t *testing.T) {
+	metrics := [][]SplitMetrics{
+		{
+			{Tick: 40, Merge: 80, Stale: 4, Linger: 30, MaxLinger: 12, Pairs: 4, Restored: 4, Recovery: 10, MaxRecovery: 4},
+			{Tick: 120, Stale: 2, Linger: 20, MaxLinger: 10, Lingering: 2},
+		},
+		// The second run stopped before the merge.
+		{{Tick: 40, Stale: 4, Linger: 8, MaxLinger: 2}},
+	}
+	want := `                              a     b
+split at 40: stale routes     4     4
+  mean linger                 7.5   2.0
+  max linger                  12    2
+  never dropped               0     0
+merge at 80: routes restored  4/4   -
+  mean recovery               2.5   -
+  max recovery                4     -
+split at 120: stale routes    2     -
+  mean linger                 10.0  -
+  max linger                  10    -
+  never dropped               2     -
+`
+	var b bytes.Buffer
+	if err := WriteSplitReport(&b, []string{"a", "b"}, metrics); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("WriteSplitReport() got =\n%s\nwant =\n%s", got, want)
+	}
+	if err := WriteSplitReport(&b, []string{"a"}, metrics); err == nil {
+		t.Errorf("WriteSplitReport() error = nil for too few names, want error")
+	}
+}
@@ -0,0 +1,366 @@
+// Package repl implements an interactive shell over a simulation, which runs it a few ticks at a time and inspects the
+// tables of its nodes in between.
+//
+// Each line read is a command:
+//
+//	step [N]                          run N ticks, 1 by default
+//	tick                              show the tick run next
+//	state ID                          show every table of a node
+//	neighbors ID                      show a node's symmetric neighbors
+//	twohop ID                         show a node's two-hop neighbors
+//	mprs ID                           show the MPRs a node selected
+//	selectors ID                      show the nodes which selected a node as an MPR
+//	routes ID                         show a node's routing table
+//	topology ID                       show the links a node learnt of from advertisements
+//	link A B {up | down} [loss=P] [delay=T]
+//	                                  change the link between two nodes, in both directions, or only from A with
+//	                                  A -> B
+//	send SRC DST "DATA"               have a node send data to another
+//	help                              list the commands
+//	quit                              stop the shell
+//
+// Tables a protocol does not keep are empty.
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/topology"
+)
+
+// Prompt is written before each command is read.
+const Prompt = "> "
+
+// errQuit is returned by the quit command to stop the shell.
+var errQuit = errors.New("quit")
+
+// shell holds the state of a running REPL.
+type shell struct {
+	c   *controller.Controller
+	out io.Writer
+}
+
+// command is a command of the shell.
+type command struct {
+	args    string
+	summary string
+	run     func(s *shell, args []string) error
+}
+
+// commands holds every command of the shell, by name. Its order of listing is given by order.
+var commands map[string]command
+
+var order = []string{"step", "tick", "state", "neighbors", "twohop", "mprs", "selectors", "routes", "topology", "link", "send", "help", "quit"}
+
+func init() {
+	commands = map[string]command{
+		"step":      {args: "[N]", summary: "Run N ticks, 1 by default", run: (*shell).step},
+		"tick":      {summary: "Show the tick run next", run: (*shell).tick},
+		"state":     {args: "ID", summary: "Show every table of a node", run: inspect((*shell).state)},
+		"neighbors": {args: "ID", summary: "Show a node's symmetric neighbors", run: inspect((*shell).neighbors)},
+		"twohop":    {args: "ID", summary: "Show a node's two-hop neighbors", run: inspect((*shell).twoHop)},
+		"mprs":      {args: "ID", summary: "Show the MPRs a node selected", run: inspect((*shell).mprs)},
+		"selectors": {args: "ID", summary: "Show the nodes which selected a node as an MPR", run: inspect((*shell).selectors)},
+		"routes":    {args: "ID", summary: "Show a node's routing table", run: inspect((*shell).routes)},
+		"topology":  {args: "ID", summary: "Show the links a node learnt of from advertisements", run: inspect((*shell).topology)},
+		"link":      {args: "A [->] B {up | down} [loss=P] [delay=T]", summary: "Change a link from the current tick onward, in both directions unless -> is given", run: (*shell).link},
+		"send":      {args: "SRC DST \"DATA\"", summary: "Have a node send data to another", run: (*shell).send},
+		"help":      {summary: "List the commands", run: (*shell).help},
+		"quit":      {summary: "Stop the shell", run: func(*shell, []string) error { return errQuit }},
+	}
+}
+
+// Run reads commands from in, one per line, and runs them against c until in is exhausted or the quit command is
+// read. Results are written to out, as are errors with commands, which do not stop the shell. An error is returned
+// only if reading or writing fails. c must not be running, and must not be run by anything else while the shell is.
+func Run(in io.Reader, out io.Writer, c *controller.Controller) error {
+	s := &shell{c: c, out: out}
+	scanner := bufio.NewScanner(in)
+	for {
+		if _, err := io.WriteString(out, Prompt); err != nil {
+			return err
+		}
+		if !scanner.Scan() {
+			_, err := io.WriteString(out, "\n")
+			if scanner.Err() != nil {
+				return scanner.Err()
+			}
+			return err
+		}
+
+		err := s.exec(scanner.Text())
+		if errors.Is(err, errQuit) {
+			return nil
+		}
+		if err != nil {
+			if _, err := fmt.Fprintf(out, "error: %s\n", err); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// exec runs a single line of input.
+func (s *shell) exec(line string) error {
+	fields, err := split(line)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	cmd, ok := commands[fields[0]]
+	if !ok {
+		return fmt.Errorf("unknown command: %s: try help", fields[0])
+	}
+	return cmd.run(s, fields[1:])
+}
+
+// split splits a line into fields separated by spaces. Double quoted fields may hold spaces and Go escape sequences.
+func split(line string) ([]string, error) {
+	var fields []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return fields, nil
+		}
+		if line[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, fmt.Errorf("unterminated quoted string: %s", line)
+			}
+			f, _ := strconv.Unquote(quoted)
+			fields = append(fields, f)
+			line = line[len(quoted):]
+			continue
+		}
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			end = len(line)
+		}
+		fields = append(fields, line[:end])
+		line = line[end:]
+	}
+}
+
+func (s *shell) step(args []string) error {
+	n := 1
+	switch len(args) {
+	case 0:
+	case 1:
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			return fmt.Errorf("invalid number of ticks: %s: must be a positive integer", args[0])
+		}
+	default:
+		return usage("step")
+	}
+	for i := 0; i < n; i++ {
+		s.c.Step()
+	}
+	return s.tick(nil)
+}
+
+func (s *shell) tick([]string) error {
+	_, err := fmt.Fprintf(s.out, "tick %d\n", s.c.CurrentTick())
+	return err
+}
+
+// inspect adapts a command showing the state of the node given as its only argument.
+func inspect(show func(s *shell, state controller.NodeState) error) func(s *shell, args []string) error {
+	return func(s *shell, args []string) error {
+		if len(args) != 1 {
+			return errors.New("a single node ID must be given")
+		}
+		id, err := parseID(args[0])
+		if err != nil {
+			return err
+		}
+		state, err := s.c.Inspect(id)
+		if err != nil {
+			return err
+		}
+		return show(s, state)
+	}
+}
+
+// WriteState writes every table of a node, as the state command shows them.
+func WriteState(w io.Writer, state controller.NodeState) error {
+	return (&shell{out: w}).state(state)
+}
+
+func (s *shell) state(state controller.NodeState) error {
+	sections := []struct {
+		name string
+		show func(s *shell, state controller.NodeState) error
+	}{
+		{"neighbors", (*shell).neighbors},
+		{"two-hop neighbors", (*shell).twoHop},
+		{"mprs", (*shell).mprs},
+		{"mpr selectors", (*shell).selectors},
+		{"topology", (*shell).topology},
+		{"routes", (*shell).routes},
+	}
+	if state.Down {
+		_, err := fmt.Fprintf(s.out, "node %d is down\n", state.ID)
+		return err
+	}
+	if _, err := fmt.Fprintf(s.out, "node %d after %d ticks\n", state.ID, state.Tick); err != nil {
+		return err
+	}
+	for _, sec := range sections {
+		if _, err := fmt.Fprintf(s.out, "%s:\n", sec.name); err != nil {
+			return err
+		}
+		if err := sec.show(s, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *shell) neighbors(state controller.NodeState) error {
+	return s.ids(state.Neighbors)
+}
+
+func (s *shell) mprs(state controller.NodeState) error {
+	return s.ids(state.MPRs)
+}
+
+func (s *shell) selectors(state controller.NodeState) error {
+	return s.ids(state.MPRSelectors)
+}
+
+// ids writes a list of nodes on a single line.
+func (s *shell) ids(ids []message.NodeID) error {
+	if len(ids) == 0 {
+		return s.none()
+	}
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.Itoa(int(id))
+	}
+	_, err := fmt.Fprintln(s.out, strings.Join(strs, " "))
+	return err
+}
+
+func (s *shell) twoHop(state controller.NodeState) error {
+	if len(state.TwoHopNeighbors) == 0 {
+		return s.none()
+	}
+	tw := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tVIA")
+	for _, n := range state.TwoHopNeighbors {
+		fmt.Fprintf(tw, "%d\t%d\n", n.ID, n.Via)
+	}
+	return tw.Flush()
+}
+
+func (s *shell) topology(state controller.NodeState) error {
+	if len(state.Topology) == 0 {
+		return s.none()
+	}
+	for _, l := range state.Topology {
+		if _, err := fmt.Fprintf(s.out, "%d -> %d\n", l.From, l.To); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *shell) routes(state controller.NodeState) error {
+	if len(state.Routes) == 0 {
+		return s.none()
+	}
+	tw := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DESTINATION\tNEXT HOP\tDISTANCE")
+	for _, r := range state.Routes {
+		fmt.Fprintf(tw, "%d\t%d\t%d\n", r.Destination, r.NextHop, r.Distance)
+	}
+	return tw.Flush()
+}
+
+func (s *shell) none() error {
+	_, err := fmt.Fprintln(s.out, "none")
+	return err
+}
+
+// link changes a link by parsing it as a line of a topology file at the current tick, so that it takes the same
+// attributes.
+func (s *shell) link(args []string) error {
+	if len(args) < 3 {
+		return usage("link")
+	}
+	bidir := true
+	if args[1] == "->" {
+		bidir = false
+		args = append(args[:1:1], args[2:]...)
+	}
+	if len(args) < 3 {
+		return usage("link")
+	}
+	to := args[1]
+	if bidir {
+		to = "<-> " + to
+	}
+	line := fmt.Sprintf("%d %s %s %s %s", s.c.CurrentTick(), strings.ToUpper(args[2]), args[0], to, strings.Join(args[3:], " "))
+	states, err := topology.ParseLine(line)
+	if err != nil {
+		return err
+	}
+	for _, ls := range states {
+		s.c.SetLink(ls.From, ls.To, ls.Status == topology.UP, ls.Attrs)
+	}
+	for _, ls := range states {
+		if _, err := fmt.Fprintln(s.out, ls.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *shell) send(args []string) error {
+	if len(args) != 3 {
+		return usage("send")
+	}
+	src, err := parseID(args[0])
+	if err != nil {
+		return err
+	}
+	dst, err := parseID(args[1])
+	if err != nil {
+		return err
+	}
+	return s.c.Send(src, dst, args[2])
+}
+
+func (s *shell) help([]string) error {
+	tw := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', 0)
+	for _, name := range order {
+		c := commands[name]
+		fmt.Fprintf(tw, "%s\t%s\n", strings.TrimSpace(name+" "+c.args), c.summary)
+	}
+	return tw.Flush()
+}
+
+// usage returns an error describing the arguments of the named command.
+func usage(name string) error {
+	return fmt.Errorf("usage: %s %s", name, commands[name].args)
+}
+
+// parseID parses a node ID.
+func parseID(s string) (message.NodeID, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid node ID: %s: must be a non-negative integer", s)
+	}
+	return message.NodeID(id), nil
+}
@@ -0,0 +1,174 @@
+package repl
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim"
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "step",
+			in:   "tick\nstep\nstep 9\n",
+			want: "> tick 0\n> tick 1\n> tick 10\n> \n",
+		},
+		{
+			name: "inspect",
+			in:   "step 30\nneighbors 1\nmprs 0\nselectors 1\nroutes 0\n",
+			want: "> tick 30\n" +
+				"> 0 2\n" +
+				"> 1\n" +
+				"> 0 2\n" +
+				"> DESTINATION  NEXT HOP  DISTANCE\n" +
+				"1            1         1\n" +
+				"2            1         2\n" +
+				"> \n",
+		},
+		{
+			name: "link down",
+			in:   "step 30\nlink 1 2 down\nstep 40\nroutes 0\ntwohop 0\n",
+			want: "> tick 30\n" +
+				"> 30 DOWN 1 2\n30 DOWN 2 1\n" +
+				"> tick 70\n" +
+				"> DESTINATION  NEXT HOP  DISTANCE\n" +
+				"1            1         1\n" +
+				"> none\n" +
+				"> \n",
+		},
+		{
+			name: "link one way",
+			in:   "link 1 -> 2 up loss=0.5\n",
+			want: "> 0 UP 1 2 loss=0.5\n> \n",
+		},
+		{
+			name: "quit",
+			in:   "quit\nstep\n",
+			want: "> ",
+		},
+		{
+			name: "errors",
+			in:   "bogus\nstep x\nroutes\nroutes 9\nlink 0 1 sideways\nsend 0 9 \"hi\nsend 0 1\n",
+			want: "> error: unknown command: bogus: try help\n" +
+				"> error: invalid number of ticks: x: must be a positive integer\n" +
+				"> error: a single node ID must be given\n" +
+				"> error: node 9 does not exist\n" +
+				"> error: parse link state: invalid Status: 'SIDEWAYS': must be {UP | DOWN}\n" +
+				"> error: unterminated quoted string: \"hi\n" +
+				"> error: usage: send SRC DST \"DATA\"\n" +
+				"> \n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &olsrsim.Scenario{
+				TopologyLines: []string{"0 UP 0 <-> 1", "0 UP 1 <-> 2"},
+				Nodes:         []olsrsim.ScenarioNode{{ID: 0}, {ID: 1}, {ID: 2}},
+			}
+			c, err := s.Controller()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := Run(strings.NewReader(tt.in), &out, c); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("Run() got =\n%s\nwant =\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_send(t *testing.T) {
+	s := &olsrsim.Scenario{
+		TopologyLines: []string{"0 UP 0 <-> 1"},
+		Nodes:         []olsrsim.ScenarioNode{{ID: 0}, {ID: 1}},
+	}
+	c, err := s.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := "step 20\nsend 0 1 \"hello there\"\nstep 5\n"
+	if err := Run(strings.NewReader(in), &bytes.Buffer{}, c); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if m := c.Metrics(); m.DataOriginated != 1 || m.DataDelivered != 1 {
+		t.Errorf("Run() originated %d and delivered %d data, want 1 and 1", m.DataOriginated, m.DataDelivered)
+	}
+}
+
+func TestWriteState(t *testing.T) {
+	tests := []struct {
+		name  string
+		state controller.NodeState
+		want  string
+	}{
+		{
+			name: "running",
+			state: controller.NodeState{
+				ID:        3,
+				Tick:      41,
+				Neighbors: []message.NodeID{2, 4},
+				Routes:    []controller.Route{{Destination: 2, NextHop: 2, Distance: 1}},
+			},
+			want: "node 3 after 41 ticks\n" +
+				"neighbors:\n2 4\n" +
+				"two-hop neighbors:\nnone\n" +
+				"mprs:\nnone\n" +
+				"mpr selectors:\nnone\n" +
+				"topology:\nnone\n" +
+				"routes:\nDESTINATION  NEXT HOP  DISTANCE\n2            2         1\n",
+		},
+		{
+			name:  "down",
+			state: controller.NodeState{ID: 3, Tick: 41, Down: true},
+			want:  "node 3 is down\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := WriteState(&out, tt.state); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("WriteState() got =\n%s\nwant =\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_split(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "", want: nil},
+		{line: "  step\t 3 ", want: []string{"step", "3"}},
+		{line: `send 2 5 "hi there"`, want: []string{"send", "2", "5", "hi there"}},
+		{line: `send 2 5 "say \"hi\"\n"`, want: []string{"send", "2", "5", "say \"hi\"\n"}},
+		{line: `send 2 5 "hi`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := split(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("split() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package olsrsim
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/trace"
+)
+
+// Replay reconstructs the state of every node of a traced run at the end of a tick, without simulating the network:
+// each node is recreated, and handed the envelopes it received and the data it originated, in the order the trace
+// records them, so that it runs exactly as it did. Nodes are created as they join the network and dropped as they
+// leave it. Nodes going DOWN stop running, and are recreated once they come back UP. States are returned in the order
+// of the trace's nodes, for the nodes in the network at the end of the tick.
+//
+// Nodes are recreated with the protocol parameters of the trace's scenario. Traces without a scenario are replayed
+// with the default parameters of their protocol, which only reconstructs their state if those were used. An error is
+// returned if the trace ends before the tick.
+func Replay(t *trace.Trace, tick int) ([]controller.NodeState, error) {
+	r, err := newReplayer(t)
+	if err != nil {
+		return nil, err
+	}
+	if last := lastTick(t); last < tick {
+		return nil, fmt.Errorf("replay: the trace ends at tick %d, before tick %d", last, tick)
+	}
+	if err := r.advance(tick); err != nil {
+		return nil, err
+	}
+	return r.states(), nil
+}
+
+// lastTick returns the tick of the last event of a trace, or -1 if it has none.
+func lastTick(t *trace.Trace) int {
+	if len(t.Events) == 0 {
+		return -1
+	}
+	return t.Events[len(t.Events)-1].Tick
+}
+
+// replayer recreates the nodes of a traced run, and runs them through the trace one tick at a time.
+type replayer struct {
+	t       *trace.Trace
+	factory controller.Factory
+
+	// routers holds the Router of every node in the network.
+	routers map[message.NodeID]controller.Router
+
+	// down holds the nodes which are DOWN, along with the sequence numbers they had when they went DOWN.
+	down map[message.NodeID]map[string]int
+
+	// next is the index of the next event to replay.
+	next int
+
+	// ran is the number of ticks every node has been ticked for. Nodes are ticked once every input of a tick has
+	// been handed to them, which is once the trace moves on to a later tick.
+	ran int
+}
+
+// newReplayer recreates the nodes of t, as they were before its first tick: every node, but those which join the
+// network later.
+func newReplayer(t *trace.Trace) (*replayer, error) {
+	s := &Scenario{Protocol: t.Protocol}
+	if len(t.Scenario) > 0 {
+		if err := json.Unmarshal(t.Scenario, s); err != nil {
+			return nil, fmt.Errorf("replay: invalid scenario: %w", err)
+		}
+	}
+	factory, err := s.Factory()
+	if err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+
+	r := &replayer{
+		t:       t,
+		factory: factory,
+		routers: make(map[message.NodeID]controller.Router, len(t.Nodes)),
+		down:    make(map[message.NodeID]map[string]int),
+	}
+	late := make(map[message.NodeID]bool)
+	for _, e := range t.Events {
+		if e.Kind == controller.EventJoin {
+			late[e.Node] = true
+		}
+	}
+	for _, id := range t.Nodes {
+		if late[id] {
+			continue
+		}
+		router, err := r.create(id)
+		if err != nil {
+			return nil, err
+		}
+		r.routers[id] = router
+	}
+	return r, nil
+}
+
+// create recreates the Router of a node, which originates no data of its own: the trace records the data it did.
+func (r *replayer) create(id message.NodeID) (controller.Router, error) {
+	router, err := r.factory(controller.NodeConfig{ID: id, Message: controller.NodeMessage{Sent: true}})
+	if err != nil {
+		return nil, fmt.Errorf("replay: node %d: %w", id, err)
+	}
+	return router, nil
+}
+
+// advance replays the trace up to the end of tick, which must not be earlier than the tick last advanced to. An error
+// is returned if a node cannot be recreated as it joins or restarts.
+func (r *replayer) advance(tick int) error {
+	for ; r.next < len(r.t.Events); r.next++ {
+		e := r.t.Events[r.next]
+		if e.Tick > tick {
+			break
+		}
+		r.runUntil(e.Tick)
+		switch e.Kind {
+		case controller.EventJoin:
+			router, err := r.create(e.Node)
+			if err != nil {
+				return err
+			}
+			r.routers[e.Node] = router
+			continue
+		case controller.EventLeave:
+			delete(r.routers, e.Node)
+			delete(r.down, e.Node)
+			continue
+		}
+		router, ok := r.routers[e.Node]
+		if !ok {
+			continue
+		}
+		switch e.Kind {
+		case controller.EventReceive:
+			router.Receive(*e.Envelope)
+		case controller.EventOriginate:
+			router.Send(e.Originated.Destination, e.Originated.Data)
+		case controller.EventNode:
+			if err := r.setNode(e.Node, router, e.NodeChange); err != nil {
+				return err
+			}
+		}
+	}
+	r.runUntil(tick + 1)
+	return nil
+}
+
+// setNode brings a node DOWN, or back UP with a new Router, as the Controller did.
+func (r *replayer) setNode(id message.NodeID, router controller.Router, change *controller.NodeChange) error {
+	if !change.Up {
+		var seqs map[string]int
+		if p, ok := router.(controller.Persister); ok {
+			seqs = p.Sequences()
+		}
+		r.down[id] = seqs
+		return nil
+	}
+	restarted, err := r.create(id)
+	if err != nil {
+		return err
+	}
+	if p, ok := restarted.(controller.Persister); ok && change.Persist && r.down[id] != nil {
+		p.RestoreSequences(r.down[id])
+	}
+	r.routers[id] = restarted
+	delete(r.down, id)
+	return nil
+}
+
+// runUntil ticks every node until they have run for end ticks.
+func (r *replayer) runUntil(end int) {
+	for ; r.ran < end; r.ran++ {
+		for _, id := range r.t.Nodes {
+			router, in := r.routers[id]
+			if _, down := r.down[id]; in && !down {
+				router.Tick()
+			}
+		}
+	}
+}
+
+// states returns the state of every node in the network, in the order of the trace's nodes.
+func (r *replayer) states() []controller.NodeState {
+	states := make([]controller.NodeState, 0, len(r.t.Nodes))
+	for _, id := range r.t.Nodes {
+		router, in := r.routers[id]
+		if !in {
+			continue
+		}
+		if _, down := r.down[id]; down {
+			states = append(states, controller.NodeState{ID: id, Down: true})
+		} else if i, ok := router.(controller.Inspector); ok {
+			states = append(states, i.Inspect())
+		} else {
+			states = append(states, controller.NodeState{ID: id, Tick: r.ran, Routes: router.Routes()})
+		}
+	}
+	return states
+}
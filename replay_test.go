@@ -0,0 +1,103 @@
+package olsrsim
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/kprusa/olsrsim/aodv"
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/olsr"
+	"github.com/kprusa/olsrsim/trace"
+)
+
+// recordTrace runs s, tracing it with a snapshot of every node at every tick.
+func recordTrace(t *testing.T, s *Scenario) *trace.Trace {
+	t.Helper()
+	c, err := s.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := s.TraceHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	w, err := trace.NewWriter(&b, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Observe(w)
+	c.SetTickDuration(0)
+	c.SetSnapshotInterval(1)
+
+	// Data is also handed to a node between ticks, as the REPL and HTTP API do.
+	for i := 0; i < s.Duration(); i++ {
+		if i == 40 {
+			if err := c.Send(4, 0, "late"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		c.Step()
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := trace.Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+func TestReplay(t *testing.T) {
+	for _, protocol := range Protocols {
+		t.Run(protocol, func(t *testing.T) {
+			s := &Scenario{
+				Protocol: protocol,
+				Ticks:    70,
+				TopologyLines: []string{
+					"0 UP 0 <-> 1",
+					"0 UP 1 <-> 2 loss=0.3",
+					"0 UP 2 <-> 3",
+					"0 UP 3 <-> 4",
+					"0 UP 4 <-> 5",
+					"0-35 UP 1 <-> 3",
+					// Restarted nodes must be replayed with the sequence numbers they restarted with.
+					"20-30 NODE DOWN 2",
+					"40-45 NODE DOWN 3 PERSIST",
+					"50 UP 0 <-> 4",
+				},
+				// Nodes joining and leaving the network must be replayed as they do.
+				Nodes:   []ScenarioNode{{ID: 0}, {ID: 1}, {ID: 2}, {ID: 5, Join: 15, Leave: 55}, {ID: 3}, {ID: 4}},
+				Traffic: []Traffic{{Source: 0, Destination: 4, Message: "hello", Delay: 25}},
+				// Parameters other than the defaults must be replayed too.
+				Params: olsr.Params{HelloInterval: 3},
+				AODV:   aodv.Params{HelloInterval: 3},
+			}
+			tr := recordTrace(t, s)
+
+			snapshots := make(map[int][]controller.NodeState)
+			for _, e := range tr.Events {
+				if e.Kind == controller.EventState {
+					snapshots[e.Tick] = append(snapshots[e.Tick], *e.State)
+				}
+			}
+			for tick := 0; tick < s.Duration(); tick += 3 {
+				got, err := Replay(tr, tick)
+				if err != nil {
+					t.Fatalf("Replay() error = %v", err)
+				}
+				// Snapshots went through JSON, which does not tell nil and empty tables apart.
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(snapshots[tick])
+				if !bytes.Equal(gotJSON, wantJSON) {
+					t.Fatalf("Replay() at tick %d = %s, want %s", tick, gotJSON, wantJSON)
+				}
+			}
+			if _, err := Replay(tr, s.Duration()); err == nil {
+				t.Errorf("Replay() error = nil after the end of the trace, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package olsrsim
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/trace"
+)
+
+// WriteReport writes a table of the metrics of one or more runs side-by-side, so that protocols can be compared on the
+// same scenario. Each run is a column, headed by its name.
+func WriteReport(w io.Writer, names []string, metrics []controller.Metrics) error {
+	if len(names) != len(metrics) {
+		return fmt.Errorf("report: %d names given for %d runs", len(names), len(metrics))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\t"+strings.Join(names, "\t"))
+	for _, r := range metricRows(metrics) {
+		cells := []string{r.label}
+		for _, m := range metrics {
+			cells = append(cells, fmt.Sprintf(r.format, r.value(m)))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// metricRow is a row of a report, holding a single metric of each run.
+type metricRow struct {
+	label  string
+	value  func(m controller.Metrics) float64
+	format string
+}
+
+// metricRows returns the rows of a report of metrics, with a row for each type of message sent in any run.
+func metricRows(metrics []controller.Metrics) []metricRow {
+	types := make(map[string]bool)
+	for _, m := range metrics {
+		for t := range m.Sent {
+			types[t] = true
+		}
+	}
+	sorted := make([]string, 0, len(types))
+	for t := range types {
+		sorted = append(sorted, t)
+	}
+	sort.Strings(sorted)
+
+	count := func(label string, value func(m controller.Metrics) int) metricRow {
+		return metricRow{label: label, value: func(m controller.Metrics) float64 { return float64(value(m)) }, format: "%.0f"}
+	}
+	rows := []metricRow{count("ticks", func(m controller.Metrics) int { return m.Ticks })}
+	for _, t := range sorted {
+		t := t
+		rows = append(rows, count(t+" sent", func(m controller.Metrics) int { return m.Sent[t] }))
+	}
+	return append(rows,
+		count("control sent", controller.Metrics.ControlSent),
+		count("total sent", controller.Metrics.TotalSent),
+		count("data originated", func(m controller.Metrics) int { return m.DataOriginated }),
+		count("data delivered", func(m controller.Metrics) int { return m.DataDelivered }),
+		metricRow{label: "delivery ratio", value: func(m controller.Metrics) float64 { return 100 * m.DeliveryRatio() }, format: "%.1f%%"},
+		metricRow{label: "mean latency", value: controller.Metrics.MeanLatency, format: "%.1f"},
+	)
+}
+
+// WriteNodeReport writes a table of the traffic of each node of a traced run, one row per node.
+func WriteNodeReport(w io.Writer, stats []trace.NodeStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "node\tsent\treceived\tlost\tdata sent\tdata received")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\n", s.ID, s.Sent, s.Received, s.Lost, s.DataSent, s.DataReceived)
+	}
+	return tw.Flush()
+}
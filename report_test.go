@@ -0,0 +1,58 @@
+package olsrsim
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/trace"
+)
+
+func TestWriteReport(t *testing.T) {
+	metrics := []controller.Metrics{
+		{Ticks: 10, Sent: map[string]int{"HELLO": 4, "DATA": 2}, DataOriginated: 2, DataDelivered: 2, Latency: 3},
+		{Ticks: 10, Sent: map[string]int{"RREQ": 1, "RREP": 1, "DATA": 1}, DataOriginated: 2, DataDelivered: 1, Latency: 4},
+	}
+	want := `                 olsr    aodv
+ticks            10      10
+DATA sent        2       1
+HELLO sent       4       0
+RREP sent        0       1
+RREQ sent        0       1
+control sent     4       2
+total sent       6       3
+data originated  2       2
+data delivered   2       1
+delivery ratio   100.0%  50.0%
+mean latency     1.5     4.0
+`
+	var b bytes.Buffer
+	if err := WriteReport(&b, []string{"olsr", "aodv"}, metrics); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("WriteReport() got =\n%s\nwant =\n%s", got, want)
+	}
+
+	if err := WriteReport(&b, []string{"olsr"}, metrics); err == nil {
+		t.Errorf("WriteReport() error = nil, want error")
+	}
+}
+
+func TestWriteNodeReport(t *testing.T) {
+	stats := []trace.NodeStats{
+		{ID: 0, Sent: 12, Received: 10, DataSent: 1},
+		{ID: 1, Sent: 11, Received: 13, Lost: 2, DataReceived: 1},
+	}
+	want := `node  sent  received  lost  data sent  data received
+0     12    10        0     1          0
+1     11    13        2     0          1
+`
+	var b bytes.Buffer
+	if err := WriteNodeReport(&b, stats); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("WriteNodeReport() got =\n%s\nwant =\n%s", got, want)
+	}
+}
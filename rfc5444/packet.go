@@ -0,0 +1,496 @@
+// Package rfc5444 encodes and decodes packets in the generalized MANET packet/message format of RFC 5444.
+//
+// Addresses are node IDs, encoded as 4 octets. Packets never carry a packet sequence number or packet TLVs, but
+// messages carry every optional header field. Decoding accepts every feature of the format, including address
+// compression and multivalue TLVs, and expands multivalue and multi-index address TLVs to one TLV per address.
+package rfc5444
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/kprusa/olsrsim/message"
+)
+
+// AddressLength is the length, in octets, of every address.
+const AddressLength = 4
+
+// Packet is a packet holding any number of messages.
+type Packet struct {
+	Messages []Message
+}
+
+// Message is a message of a Packet.
+type Message struct {
+	Type uint8
+
+	// Originator is the node which originated the message.
+	Originator message.NodeID
+
+	// HopLimit is the number of further hops the message may travel.
+	HopLimit uint8
+
+	// HopCount is the number of hops the message has travelled.
+	HopCount uint8
+
+	SequenceNumber uint16
+
+	TLVs          []TLV
+	AddressBlocks []AddressBlock
+}
+
+// TLV is a type-length-value element of a message.
+type TLV struct {
+	Type    uint8
+	TypeExt uint8
+	Value   []byte
+}
+
+// AddressBlock is a list of addresses, along with TLVs describing them.
+type AddressBlock struct {
+	Addresses []message.NodeID
+	TLVs      []AddressTLV
+}
+
+// AddressTLV is a TLV which applies to the addresses of an AddressBlock from IndexStart to IndexStop, inclusive.
+type AddressTLV struct {
+	TLV
+	IndexStart int
+	IndexStop  int
+}
+
+// TLV returns the first TLV of the message of the given type.
+func (m *Message) TLV(typ uint8) (TLV, bool) {
+	for _, t := range m.TLVs {
+		if t.Type == typ {
+			return t, true
+		}
+	}
+	return TLV{}, false
+}
+
+// AddressTLVs returns the TLVs of the given type which apply to the address at index.
+func (b *AddressBlock) AddressTLVs(index int, typ uint8) []TLV {
+	var tlvs []TLV
+	for _, t := range b.TLVs {
+		if t.Type == typ && t.IndexStart <= index && index <= t.IndexStop {
+			tlvs = append(tlvs, t.TLV)
+		}
+	}
+	return tlvs
+}
+
+// Flags of the packet, message, TLV and address block headers.
+const (
+	pktHasSeqNum = 0x8
+	pktHasTLV    = 0x4
+
+	msgHasOrig     = 0x8
+	msgHasHopLimit = 0x4
+	msgHasHopCount = 0x2
+	msgHasSeqNum   = 0x1
+
+	tlvHasTypeExt     = 0x80
+	tlvHasSingleIndex = 0x40
+	tlvHasMultiIndex  = 0x20
+	tlvHasValue       = 0x10
+	tlvHasExtLen      = 0x08
+	tlvIsMultiValue   = 0x04
+
+	addrHasHead         = 0x80
+	addrHasFullTail     = 0x40
+	addrHasZeroTail     = 0x20
+	addrHasSinglePrelen = 0x10
+	addrHasMultiPrelen  = 0x08
+)
+
+// maxAddressesPerBlock is the most addresses an address block may hold.
+const maxAddressesPerBlock = 255
+
+// ErrMalformed is returned when a packet cannot be decoded.
+var ErrMalformed = errors.New("rfc5444: malformed packet")
+
+// MarshalBinary encodes the packet.
+func (p Packet) MarshalBinary() ([]byte, error) {
+	// Version 0, with no packet sequence number or packet TLVs.
+	out := []byte{0}
+	for i := range p.Messages {
+		msg, err := p.Messages[i].marshal()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, msg...)
+	}
+	return out, nil
+}
+
+func (m *Message) marshal() ([]byte, error) {
+	out := []byte{m.Type, (msgHasOrig|msgHasHopLimit|msgHasHopCount|msgHasSeqNum)<<4 | (AddressLength - 1), 0, 0}
+	out, err := appendAddress(out, m.Originator)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, m.HopLimit, m.HopCount)
+	out = appendUint16(out, m.SequenceNumber)
+
+	tlvs, err := marshalTLVs(m.TLVs, nil)
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, tlvs...)
+
+	for _, b := range m.AddressBlocks {
+		if len(b.Addresses) == 0 || len(b.Addresses) > maxAddressesPerBlock {
+			return nil, fmt.Errorf("rfc5444: address block must hold between 1 and %d addresses: %d", maxAddressesPerBlock, len(b.Addresses))
+		}
+		out = append(out, byte(len(b.Addresses)), 0)
+		for _, a := range b.Addresses {
+			if out, err = appendAddress(out, a); err != nil {
+				return nil, err
+			}
+		}
+		tlvs, err := marshalTLVs(nil, &b)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, tlvs...)
+	}
+
+	if len(out) > 0xffff {
+		return nil, fmt.Errorf("rfc5444: message too large: %d octets", len(out))
+	}
+	binary.BigEndian.PutUint16(out[2:], uint16(len(out)))
+	return out, nil
+}
+
+// marshalTLVs encodes a TLV block holding either tlvs, or the TLVs of block.
+func marshalTLVs(tlvs []TLV, block *AddressBlock) ([]byte, error) {
+	var out []byte
+	add := func(t TLV, flags byte, index []byte) error {
+		if t.TypeExt != 0 {
+			flags |= tlvHasTypeExt
+		}
+		if len(t.Value) > 0 {
+			flags |= tlvHasValue
+			if len(t.Value) > 0xff {
+				flags |= tlvHasExtLen
+			}
+		}
+		if len(t.Value) > 0xffff {
+			return fmt.Errorf("rfc5444: tlv value too large: %d octets", len(t.Value))
+		}
+		out = append(out, t.Type, flags)
+		if flags&tlvHasTypeExt != 0 {
+			out = append(out, t.TypeExt)
+		}
+		out = append(out, index...)
+		if flags&tlvHasExtLen != 0 {
+			out = appendUint16(out, uint16(len(t.Value)))
+		} else if flags&tlvHasValue != 0 {
+			out = append(out, byte(len(t.Value)))
+		}
+		out = append(out, t.Value...)
+		return nil
+	}
+
+	for _, t := range tlvs {
+		if err := add(t, 0, nil); err != nil {
+			return nil, err
+		}
+	}
+	if block != nil {
+		last := len(block.Addresses) - 1
+		for _, t := range block.TLVs {
+			if t.IndexStart < 0 || t.IndexStop > last || t.IndexStart > t.IndexStop {
+				return nil, fmt.Errorf("rfc5444: tlv index range %d-%d is outside of the address block", t.IndexStart, t.IndexStop)
+			}
+			var err error
+			switch {
+			case t.IndexStart == 0 && t.IndexStop == last:
+				err = add(t.TLV, 0, nil)
+			case t.IndexStart == t.IndexStop:
+				err = add(t.TLV, tlvHasSingleIndex, []byte{byte(t.IndexStart)})
+			default:
+				err = add(t.TLV, tlvHasMultiIndex, []byte{byte(t.IndexStart), byte(t.IndexStop)})
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(out) > 0xffff {
+		return nil, fmt.Errorf("rfc5444: tlv block too large: %d octets", len(out))
+	}
+	return append(appendUint16(nil, uint16(len(out))), out...), nil
+}
+
+func appendUint16(out []byte, v uint16) []byte {
+	return append(out, byte(v>>8), byte(v))
+}
+
+func appendAddress(out []byte, id message.NodeID) ([]byte, error) {
+	if uint64(id) > 0xffffffff {
+		return nil, fmt.Errorf("rfc5444: address %d does not fit in %d octets", id, AddressLength)
+	}
+	return append(out, byte(id>>24), byte(id>>16), byte(id>>8), byte(id)), nil
+}
+
+// UnmarshalBinary decodes a packet. ErrMalformed is returned if data is not a valid packet.
+func (p *Packet) UnmarshalBinary(data []byte) error {
+	r := &reader{data: data}
+	header := r.byte()
+	if header>>4 != 0 {
+		return fmt.Errorf("%w: unsupported version %d", ErrMalformed, header>>4)
+	}
+	if header&pktHasSeqNum != 0 {
+		r.skip(2)
+	}
+	if header&pktHasTLV != 0 {
+		if _, err := r.tlvs(); err != nil {
+			return err
+		}
+	}
+
+	p.Messages = nil
+	for r.err == nil && r.remaining() > 0 {
+		m, err := r.message()
+		if err != nil {
+			return err
+		}
+		p.Messages = append(p.Messages, m)
+	}
+	return r.err
+}
+
+// reader decodes a packet, recording the first error encountered.
+type reader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (r *reader) remaining() int {
+	return len(r.data) - r.off
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.remaining() < n {
+		r.err = fmt.Errorf("%w: truncated at octet %d", ErrMalformed, r.off)
+		return nil
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *reader) skip(n int) {
+	r.next(n)
+}
+
+func (r *reader) byte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) uint16() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *reader) message() (Message, error) {
+	start := r.off
+	m := Message{Type: r.byte()}
+	flags := r.byte()
+	addrLen := int(flags&0xf) + 1
+	size := int(r.uint16())
+	if r.err != nil {
+		return m, r.err
+	}
+	if size < r.off-start || start+size > len(r.data) {
+		return m, fmt.Errorf("%w: message size %d at octet %d", ErrMalformed, size, start)
+	}
+
+	// Decode the message from its own reader, so that it cannot overrun its size.
+	mr := &reader{data: r.data[:start+size], off: r.off}
+	r.off = start + size
+
+	if flags>>4&msgHasOrig != 0 {
+		m.Originator = mr.address(addrLen)
+	}
+	if flags>>4&msgHasHopLimit != 0 {
+		m.HopLimit = mr.byte()
+	}
+	if flags>>4&msgHasHopCount != 0 {
+		m.HopCount = mr.byte()
+	}
+	if flags>>4&msgHasSeqNum != 0 {
+		m.SequenceNumber = mr.uint16()
+	}
+	tlvs, err := mr.tlvs()
+	if err != nil {
+		return m, err
+	}
+	for _, t := range tlvs {
+		if t.indexed {
+			return m, fmt.Errorf("%w: message tlv %d has an index", ErrMalformed, t.Type)
+		}
+		m.TLVs = append(m.TLVs, t.TLV)
+	}
+
+	for mr.err == nil && mr.remaining() > 0 {
+		b, err := mr.addressBlock(addrLen)
+		if err != nil {
+			return m, err
+		}
+		m.AddressBlocks = append(m.AddressBlocks, b)
+	}
+	return m, mr.err
+}
+
+// address decodes an address of length octets, which must fit in AddressLength octets.
+func (r *reader) address(length int) message.NodeID {
+	b := r.next(length)
+	var id message.NodeID
+	for i, o := range b {
+		if i < len(b)-AddressLength && o != 0 {
+			r.err = fmt.Errorf("%w: address %x does not fit in %d octets", ErrMalformed, b, AddressLength)
+			return 0
+		}
+		id = id<<8 | message.NodeID(o)
+	}
+	return id
+}
+
+func (r *reader) addressBlock(addrLen int) (AddressBlock, error) {
+	num := int(r.byte())
+	flags := r.byte()
+	if r.err != nil {
+		return AddressBlock{}, r.err
+	}
+	if num == 0 {
+		return AddressBlock{}, fmt.Errorf("%w: empty address block", ErrMalformed)
+	}
+
+	var head, tail []byte
+	if flags&addrHasHead != 0 {
+		head = r.next(int(r.byte()))
+	}
+	switch {
+	case flags&addrHasFullTail != 0 && flags&addrHasZeroTail != 0:
+		return AddressBlock{}, fmt.Errorf("%w: address block has both a full and a zero tail", ErrMalformed)
+	case flags&addrHasFullTail != 0:
+		tail = r.next(int(r.byte()))
+	case flags&addrHasZeroTail != 0:
+		tail = make([]byte, r.byte())
+	}
+	midLen := addrLen - len(head) - len(tail)
+	if midLen < 0 {
+		return AddressBlock{}, fmt.Errorf("%w: address head and tail are longer than an address", ErrMalformed)
+	}
+
+	b := AddressBlock{}
+	for i := 0; i < num && r.err == nil; i++ {
+		addr := append(append(append([]byte{}, head...), r.next(midLen)...), tail...)
+		ar := &reader{data: addr}
+		b.Addresses = append(b.Addresses, ar.address(addrLen))
+		if r.err == nil && ar.err != nil {
+			return b, ar.err
+		}
+	}
+	switch {
+	case flags&addrHasSinglePrelen != 0:
+		r.skip(1)
+	case flags&addrHasMultiPrelen != 0:
+		r.skip(num)
+	}
+
+	tlvs, err := r.tlvs()
+	if err != nil {
+		return b, err
+	}
+	for _, t := range tlvs {
+		start, stop := 0, num-1
+		if t.indexed {
+			start, stop = t.indexStart, t.indexStop
+		}
+		if start > stop || stop >= num {
+			return b, fmt.Errorf("%w: tlv index range %d-%d is outside of the address block", ErrMalformed, start, stop)
+		}
+		if !t.multiValue {
+			b.TLVs = append(b.TLVs, AddressTLV{TLV: t.TLV, IndexStart: start, IndexStop: stop})
+			continue
+		}
+		count := stop - start + 1
+		if len(t.Value)%count != 0 {
+			return b, fmt.Errorf("%w: multivalue tlv length %d is not divisible by %d", ErrMalformed, len(t.Value), count)
+		}
+		size := len(t.Value) / count
+		for i := 0; i < count; i++ {
+			v := TLV{Type: t.Type, TypeExt: t.TypeExt, Value: t.Value[i*size : (i+1)*size]}
+			b.TLVs = append(b.TLVs, AddressTLV{TLV: v, IndexStart: start + i, IndexStop: start + i})
+		}
+	}
+	return b, r.err
+}
+
+// decodedTLV is a TLV along with the header fields which only apply to address TLVs.
+type decodedTLV struct {
+	TLV
+	indexed    bool
+	indexStart int
+	indexStop  int
+	multiValue bool
+}
+
+func (r *reader) tlvs() ([]decodedTLV, error) {
+	length := int(r.uint16())
+	block := &reader{data: r.next(length)}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	var tlvs []decodedTLV
+	for block.err == nil && block.remaining() > 0 {
+		t := decodedTLV{}
+		t.Type = block.byte()
+		flags := block.byte()
+		if flags&tlvHasTypeExt != 0 {
+			t.TypeExt = block.byte()
+		}
+		switch {
+		case flags&tlvHasSingleIndex != 0 && flags&tlvHasMultiIndex != 0:
+			return nil, fmt.Errorf("%w: tlv has both a single and multiple indices", ErrMalformed)
+		case flags&tlvHasSingleIndex != 0:
+			t.indexed = true
+			t.indexStart = int(block.byte())
+			t.indexStop = t.indexStart
+		case flags&tlvHasMultiIndex != 0:
+			t.indexed = true
+			t.indexStart = int(block.byte())
+			t.indexStop = int(block.byte())
+		}
+		if flags&tlvHasValue != 0 {
+			var n int
+			if flags&tlvHasExtLen != 0 {
+				n = int(block.uint16())
+			} else {
+				n = int(block.byte())
+			}
+			t.Value = block.next(n)
+			t.multiValue = flags&tlvIsMultiValue != 0
+		}
+		tlvs = append(tlvs, t)
+	}
+	return tlvs, block.err
+}
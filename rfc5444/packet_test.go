@@ -0,0 +1,292 @@
+package rfc5444
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kprusa/olsrsim/message"
+)
+
+func TestPacket_MarshalBinary(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet Packet
+	}{
+		{
+			name:   "empty",
+			packet: Packet{},
+		},
+		{
+			name: "message without tlvs",
+			packet: Packet{Messages: []Message{
+				{Type: 0, Originator: 3, HopLimit: 1, SequenceNumber: 9},
+			}},
+		},
+		{
+			name: "tlvs and address blocks",
+			packet: Packet{Messages: []Message{
+				{
+					Type:           1,
+					Originator:     0x01020304,
+					HopLimit:       255,
+					HopCount:       2,
+					SequenceNumber: 0xbeef,
+					TLVs: []TLV{
+						{Type: 1, Value: []byte{0x50}},
+						{Type: 22, TypeExt: 1, Value: []byte{0, 4}},
+					},
+					AddressBlocks: []AddressBlock{
+						{
+							Addresses: []message.NodeID{1, 2, 3},
+							TLVs: []AddressTLV{
+								{TLV: TLV{Type: 9, Value: []byte{1}}, IndexStart: 0, IndexStop: 2},
+								{TLV: TLV{Type: 8, Value: []byte{3}}, IndexStart: 1, IndexStop: 1},
+								{TLV: TLV{Type: 7, TypeExt: 0, Value: []byte{0x20, 0xff}}, IndexStart: 1, IndexStop: 2},
+								{TLV: TLV{Type: 2}, IndexStart: 0, IndexStop: 0},
+							},
+						},
+						{Addresses: []message.NodeID{7}},
+					},
+				},
+				{Type: 0, Originator: 4},
+			}},
+		},
+		{
+			name: "extended value length",
+			packet: Packet{Messages: []Message{
+				{TLVs: []TLV{{Type: 1, Value: make([]byte, 300)}}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.packet.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error = %v", err)
+			}
+			var got Packet
+			if err := got.UnmarshalBinary(data); err != nil {
+				t.Fatalf("UnmarshalBinary() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.packet) {
+				t.Errorf("UnmarshalBinary() = %+v, want %+v", got, tt.packet)
+			}
+		})
+	}
+}
+
+func TestPacket_MarshalBinary_invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet Packet
+	}{
+		{
+			name:   "empty address block",
+			packet: Packet{Messages: []Message{{AddressBlocks: []AddressBlock{{}}}}},
+		},
+		{
+			name:   "address too long",
+			packet: Packet{Messages: []Message{{Originator: 1 << 32}}},
+		},
+		{
+			name: "index out of range",
+			packet: Packet{Messages: []Message{{AddressBlocks: []AddressBlock{{
+				Addresses: []message.NodeID{1},
+				TLVs:      []AddressTLV{{TLV: TLV{Type: 1}, IndexStart: 0, IndexStop: 1}},
+			}}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.packet.MarshalBinary(); err == nil {
+				t.Errorf("MarshalBinary() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestPacket_UnmarshalBinary(t *testing.T) {
+	// A message with an originator and sequence number only, holding one message TLV and one address block. The
+	// address block shares a head of 0x0000 and a zero tail of one octet, and a multivalue TLV gives each address its
+	// own value.
+	compressed := []byte{
+		0x00,
+		0x01, 0x93, 0x00, 0x1f,
+		0x00, 0x00, 0x00, 0x05,
+		0x00, 0x07,
+		0x00, 0x04, 0x01, 0x10, 0x01, 0x2a,
+		0x02, 0xa0, 0x02, 0x00, 0x00, 0x01, 0x01, 0x02,
+		0x00, 0x05, 0x03, 0x14, 0x02, 0x01, 0x02,
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Packet
+		wantErr bool
+	}{
+		{
+			name: "compressed addresses and multivalue tlv",
+			data: compressed,
+			want: Packet{Messages: []Message{{
+				Type:           1,
+				Originator:     5,
+				SequenceNumber: 7,
+				TLVs:           []TLV{{Type: 1, Value: []byte{0x2a}}},
+				AddressBlocks: []AddressBlock{{
+					Addresses: []message.NodeID{0x100, 0x200},
+					TLVs: []AddressTLV{
+						{TLV: TLV{Type: 3, Value: []byte{1}}, IndexStart: 0, IndexStop: 0},
+						{TLV: TLV{Type: 3, Value: []byte{2}}, IndexStart: 1, IndexStop: 1},
+					},
+				}},
+			}}},
+		},
+		{
+			name: "packet sequence number",
+			data: []byte{0x08, 0x12, 0x34},
+			want: Packet{},
+		},
+		{
+			name: "long address",
+			data: []byte{0x00, 0x01, 0x87, 0x00, 0x0e, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00},
+			want: Packet{Messages: []Message{{Type: 1, Originator: 5}}},
+		},
+		{
+			name:    "address too long for a node ID",
+			data:    []byte{0x00, 0x01, 0x87, 0x00, 0x0e, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00},
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			data:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported version",
+			data:    []byte{0x10},
+			wantErr: true,
+		},
+		{
+			name:    "truncated",
+			data:    compressed[:len(compressed)-1],
+			wantErr: true,
+		},
+		{
+			name:    "message size shorter than its header",
+			data:    []byte{0x00, 0x01, 0x93, 0x00, 0x02},
+			wantErr: true,
+		},
+		{
+			name:    "empty address block",
+			data:    []byte{0x00, 0x01, 0x03, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			wantErr: true,
+		},
+		{
+			name: "index out of range",
+			data: []byte{
+				0x00,
+				0x01, 0x03, 0x00, 0x11,
+				0x00, 0x00,
+				0x01, 0x00, 0x00, 0x00, 0x00, 0x01,
+				0x00, 0x03, 0x03, 0x40, 0x01,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Packet
+			err := got.UnmarshalBinary(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalBinary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrMalformed) {
+					t.Errorf("UnmarshalBinary() error = %v, want %v", err, ErrMalformed)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalBinary() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzPacket_UnmarshalBinary(f *testing.F) {
+	seed, err := Packet{Messages: []Message{{
+		Type:           1,
+		Originator:     2,
+		HopLimit:       255,
+		SequenceNumber: 3,
+		TLVs:           []TLV{{Type: 1, Value: []byte{0x50}}},
+		AddressBlocks: []AddressBlock{{
+			Addresses: []message.NodeID{1, 4},
+			TLVs:      []AddressTLV{{TLV: TLV{Type: 2, Value: []byte{1}}, IndexStart: 0, IndexStop: 1}},
+		}},
+	}}}.MarshalBinary()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(seed)
+	f.Add([]byte{
+		0x00,
+		0x01, 0x93, 0x00, 0x1f,
+		0x00, 0x00, 0x00, 0x05,
+		0x00, 0x07,
+		0x00, 0x04, 0x01, 0x10, 0x01, 0x2a,
+		0x02, 0xa0, 0x02, 0x00, 0x00, 0x01, 0x01, 0x02,
+		0x00, 0x05, 0x03, 0x14, 0x02, 0x01, 0x02,
+	})
+	f.Add([]byte{0x08, 0x12, 0x34})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var p Packet
+		if err := p.UnmarshalBinary(data); err != nil {
+			if !errors.Is(err, ErrMalformed) {
+				t.Fatalf("UnmarshalBinary() error = %v, want %v", err, ErrMalformed)
+			}
+			return
+		}
+		// Whatever decodes encodes back to the same packet, unless it does not fit the format of encoded packets.
+		// Packets are compared once encoded, as empty values may decode to nil or empty slices.
+		encoded, err := p.MarshalBinary()
+		if err != nil {
+			return
+		}
+		var again Packet
+		if err := again.UnmarshalBinary(encoded); err != nil {
+			t.Fatalf("UnmarshalBinary() error = %v, decoding the encoding of %+v", err, p)
+		}
+		reencoded, err := again.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error = %v, encoding %+v", err, again)
+		}
+		if !bytes.Equal(reencoded, encoded) {
+			t.Fatalf("UnmarshalBinary() = %+v, want %+v", again, p)
+		}
+	})
+}
+
+func TestEncodeTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		ticks int
+		want  int
+	}{
+		{name: "zero", ticks: 0, want: 1},
+		{name: "one", ticks: 1, want: 1},
+		{name: "exact", ticks: 15, want: 15},
+		{name: "rounded up", ticks: 17, want: 18},
+		{name: "large", ticks: 1000, want: 1024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DecodeTime(EncodeTime(tt.ticks)); got != tt.want {
+				t.Errorf("DecodeTime(EncodeTime(%d)) = %d, want %d", tt.ticks, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package rfc5444
+
+import (
+	"math"
+)
+
+// timeUnits is the number of units of the constant C of RFC 5497 in a tick, taking a tick as a second.
+const timeUnits = 1024
+
+// EncodeTime encodes a duration of ticks as the 8 bit time value of RFC 5497, rounding up to the next representable
+// value. Durations longer than can be represented are encoded as the longest representable duration.
+func EncodeTime(ticks int) byte {
+	if ticks <= 0 {
+		return 0
+	}
+	t := float64(ticks) * timeUnits
+	b := int(math.Floor(math.Log2(t)))
+	a := int(math.Ceil(8 * (t/math.Exp2(float64(b)) - 1)))
+	if a == 8 {
+		b++
+		a = 0
+	}
+	if b > 31 {
+		return 0xff
+	}
+	return byte(8*b + a)
+}
+
+// DecodeTime decodes the 8 bit time value of RFC 5497 as a duration of ticks, rounding up to the next tick.
+func DecodeTime(v byte) int {
+	a, b := float64(v&0x7), float64(v>>3)
+	return int(math.Ceil((1 + a/8) * math.Exp2(b) / timeUnits))
+}
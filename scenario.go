@@ -0,0 +1,467 @@
+package olsrsim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kprusa/olsrsim/aodv"
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/dsdv"
+	"github.com/kprusa/olsrsim/flooding"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/olsr"
+	"github.com/kprusa/olsrsim/olsrv2"
+	"github.com/kprusa/olsrsim/topology"
+	"github.com/kprusa/olsrsim/trace"
+)
+
+// Scenario is a declarative description of a simulation: its topology, nodes, traffic and protocol parameters.
+// Scenarios are read from JSON files, so that a run can be shared and reviewed as a single artifact.
+type Scenario struct {
+	// Name describes the scenario.
+	Name string `json:"name,omitempty"`
+
+	// TickMillis is the tick duration in milliseconds. Defaults to 1000.
+	TickMillis int `json:"tickMillis,omitempty"`
+
+	// Ticks is the number of ticks to run the simulation for. Defaults to 120.
+	Ticks int `json:"ticks,omitempty"`
+
+	// Seed seeds the random source deciding which messages are lost on lossy links. Defaults to 1.
+	Seed *int64 `json:"seed,omitempty"`
+
+	// Protocol is the routing protocol run by every node: one of Protocols. Defaults to OLSR.
+	Protocol string `json:"protocol,omitempty"`
+
+	// Params are the OLSR protocol parameters of every node, unless overridden per node.
+	// Unset parameters take their values from olsr.DefaultParams.
+	Params olsr.Params `json:"params"`
+
+	// AODV are the AODV protocol parameters of every node.
+	// Unset parameters take their values from aodv.DefaultParams.
+	AODV aodv.Params `json:"aodv"`
+
+	// OLSRv2 are the OLSRv2 protocol parameters of every node.
+	// Unset parameters take their values from olsrv2.DefaultParams.
+	OLSRv2 olsrv2.Params `json:"olsrv2"`
+
+	// DSDV are the DSDV protocol parameters of every node.
+	// Unset parameters take their values from dsdv.DefaultParams.
+	DSDV dsdv.Params `json:"dsdv"`
+
+	// Loss, if set, replaces the loss of every link of the topology: the probability, within [0, 1], of a message
+	// sent over it being lost.
+	Loss *float64 `json:"loss,omitempty"`
+
+	// TopologyFile is the path of a topology file, relative to the scenario file.
+	TopologyFile string `json:"topologyFile,omitempty"`
+
+	// TopologyLines holds the lines of a topology file, for scenarios that embed their topology.
+	TopologyLines []string `json:"topology,omitempty"`
+
+	// Nodes holds every node taking part in the simulation.
+	Nodes []ScenarioNode `json:"nodes"`
+
+	// Traffic holds the messages sent by nodes. Each node may send at most one message.
+	Traffic []Traffic `json:"traffic,omitempty"`
+
+	// Splits divide the network into partitions, one after the other.
+	Splits []ScenarioSplit `json:"splits,omitempty"`
+
+	// Logs creates the logs of each node. Nodes are not logged if it is nil.
+	Logs controller.Logs `json:"-"`
+
+	// dir is the directory relative paths are resolved against.
+	dir string
+}
+
+// Routing protocols a Scenario may run.
+const (
+	OLSR     = "olsr"
+	OLSRv2   = "olsrv2"
+	AODV     = "aodv"
+	DSDV     = "dsdv"
+	Flooding = "flooding"
+)
+
+// Protocols holds the name of every routing protocol a Scenario may run.
+var Protocols = []string{OLSR, OLSRv2, AODV, DSDV, Flooding}
+
+// ScenarioNode is a node taking part in a Scenario.
+type ScenarioNode struct {
+	ID message.NodeID `json:"id"`
+
+	// Params override the scenario's OLSR protocol parameters for this node.
+	Params olsr.Params `json:"params"`
+
+	// Join is the tick the node joins the network at. Defaults to 0.
+	Join int `json:"join,omitempty"`
+
+	// Leave, if positive, is the tick the node leaves the network at, for good.
+	Leave int `json:"leave,omitempty"`
+}
+
+// Traffic is a message sent by a node during a Scenario.
+type Traffic struct {
+	Source      message.NodeID `json:"source"`
+	Destination message.NodeID `json:"destination"`
+	Message     string         `json:"message"`
+
+	// Delay is the tick the message is first attempted to be sent at.
+	Delay int `json:"delay"`
+}
+
+// ScenarioSplit divides the network of a Scenario into named partitions at a tick, with every link between nodes of
+// different partitions DOWN, until they merge back.
+type ScenarioSplit struct {
+	Tick int `json:"tick"`
+
+	// Merge, if positive, is the tick the partitions merge back at. They never do otherwise.
+	Merge int `json:"merge,omitempty"`
+
+	// Partitions holds the nodes of each partition, by name. Nodes which belong to no partition keep their links.
+	Partitions map[string][]message.NodeID `json:"partitions"`
+}
+
+// split returns the topology.Split of the network.
+func (s ScenarioSplit) split() topology.Split {
+	return topology.Split{Time: s.Tick, Merge: s.Merge, Partitions: s.Partitions}
+}
+
+// NewScenario creates a Scenario from a topology file and node configurations, as read by
+// controller.ReadNodeConfiguration. The topology file path is resolved relative to the working directory.
+// An error is returned if the node configurations are inconsistent.
+func NewScenario(topologyFile string, configs []controller.NodeConfig) (*Scenario, error) {
+	s := &Scenario{TopologyFile: topologyFile}
+	s.configure(configs)
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid node configuration: %w", err)
+	}
+	return s, nil
+}
+
+// configure adds a node, and its traffic, for each node configuration.
+func (s *Scenario) configure(configs []controller.NodeConfig) {
+	for _, c := range configs {
+		s.Nodes = append(s.Nodes, ScenarioNode{ID: c.ID, Join: c.Join, Leave: c.Leave})
+		if !c.Message.Sent {
+			s.Traffic = append(s.Traffic, Traffic{
+				Source:      c.ID,
+				Destination: c.Message.Destination,
+				Message:     c.Message.Message,
+				Delay:       c.Message.Delay,
+			})
+		}
+	}
+}
+
+// LoadScenario reads a Scenario from a JSON file. Paths within the scenario are resolved relative to the file.
+func LoadScenario(path string) (*Scenario, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s, err := ReadScenario(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.dir = filepath.Dir(path)
+	return s, nil
+}
+
+// ReadScenario reads a Scenario from JSON. Paths within the scenario are resolved relative to the working directory.
+func ReadScenario(in io.Reader) (*Scenario, error) {
+	s := &Scenario{}
+	d := json.NewDecoder(in)
+	d.DisallowUnknownFields()
+	if err := d.Decode(s); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	return s, nil
+}
+
+// validate checks the scenario is internally consistent.
+func (s *Scenario) validate() error {
+	if s.TickMillis < 0 || s.Ticks < 0 {
+		return errors.New("tickMillis and ticks must not be negative")
+	}
+	if s.Loss != nil && (*s.Loss < 0 || *s.Loss > 1) {
+		return fmt.Errorf("loss must be within [0, 1]: %g", *s.Loss)
+	}
+	if (s.TopologyFile == "") == (s.TopologyLines == nil) {
+		return errors.New("exactly one of topologyFile and topology must be given")
+	}
+	if len(s.Nodes) == 0 {
+		return errors.New("at least one node must be given")
+	}
+	if _, err := s.Factory(); err != nil {
+		return err
+	}
+
+	nodes := make(map[message.NodeID]bool)
+	configs := make(map[message.NodeID]controller.NodeConfig)
+	for _, n := range s.Nodes {
+		if nodes[n.ID] {
+			return fmt.Errorf("node %d is given more than once", n.ID)
+		}
+		nodes[n.ID] = true
+		configs[n.ID] = controller.NodeConfig{ID: n.ID, Join: n.Join, Leave: n.Leave}
+		if n.Join < 0 || n.Leave < 0 || (n.Leave > 0 && n.Leave <= n.Join) {
+			return fmt.Errorf("node %d must leave after it joins: join %d, leave %d", n.ID, n.Join, n.Leave)
+		}
+		if err := olsr.DefaultParams().Merge(s.Params).Merge(n.Params).Validate(); err != nil {
+			return fmt.Errorf("node %d: %w", n.ID, err)
+		}
+	}
+	sending := make(map[message.NodeID]bool)
+	for _, t := range s.Traffic {
+		if !nodes[t.Source] {
+			return fmt.Errorf("traffic source %d is not a node", t.Source)
+		}
+		if !nodes[t.Destination] {
+			return fmt.Errorf("traffic destination %d of node %d is not a node", t.Destination, t.Source)
+		}
+		if sending[t.Source] {
+			return fmt.Errorf("node %d sends more than one message", t.Source)
+		}
+		sending[t.Source] = true
+		if t.Delay < 0 {
+			return fmt.Errorf("traffic from node %d must not have a negative delay", t.Source)
+		}
+		if !configs[t.Source].Present(t.Delay) {
+			return fmt.Errorf("node %d sends its message at tick %d, while it is not in the network", t.Source, t.Delay)
+		}
+	}
+	for i, sp := range s.Splits {
+		if err := sp.split().Validate(); err != nil {
+			return err
+		}
+		for name, ids := range sp.Partitions {
+			for _, id := range ids {
+				if !nodes[id] {
+					return fmt.Errorf("split at %d: node %d of %s is not a node", sp.Tick, id, name)
+				}
+			}
+		}
+		if i > 0 {
+			if prev := s.Splits[i-1]; prev.Merge <= 0 || sp.Tick < prev.Merge {
+				return fmt.Errorf("split at %d: the split at %d must merge before it", sp.Tick, prev.Tick)
+			}
+		}
+	}
+	return nil
+}
+
+// TickDuration is the duration of each tick of the simulation.
+func (s *Scenario) TickDuration() time.Duration {
+	if s.TickMillis == 0 {
+		return time.Second
+	}
+	return time.Millisecond * time.Duration(s.TickMillis)
+}
+
+// Duration is the number of ticks to run the simulation for.
+func (s *Scenario) Duration() int {
+	if s.Ticks == 0 {
+		return 120
+	}
+	return s.Ticks
+}
+
+// RandomSeed is the seed of the random source deciding which messages are lost on lossy links.
+func (s *Scenario) RandomSeed() int64 {
+	if s.Seed == nil {
+		return 1
+	}
+	return *s.Seed
+}
+
+// Topology parses the scenario's topology, replacing the loss of every link if the scenario's Loss is set, and
+// splitting the network as the scenario's Splits do.
+func (s *Scenario) Topology() (*topology.Topology, error) {
+	n, err := s.readTopology()
+	if err != nil {
+		return nil, err
+	}
+	if s.Loss != nil {
+		n.SetLoss(*s.Loss)
+	}
+	for _, sp := range s.Splits {
+		n.Split(sp.split())
+	}
+	return n, nil
+}
+
+// readTopology parses the scenario's topology, as given.
+func (s *Scenario) readTopology() (*topology.Topology, error) {
+	if s.TopologyLines != nil {
+		return topology.Read(strings.NewReader(strings.Join(s.TopologyLines, "\n")))
+	}
+
+	path := s.TopologyFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.dir, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	n, err := topology.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return n, nil
+}
+
+// NodeConfigs creates the configuration of each node in the scenario.
+// Nodes which send no traffic are configured with a message that is already Sent.
+func (s *Scenario) NodeConfigs() []controller.NodeConfig {
+	traffic := make(map[message.NodeID]Traffic)
+	for _, t := range s.Traffic {
+		traffic[t.Source] = t
+	}
+
+	configs := make([]controller.NodeConfig, 0, len(s.Nodes))
+	for _, n := range s.Nodes {
+		msg := controller.NodeMessage{Sent: true}
+		if t, in := traffic[n.ID]; in {
+			msg = controller.NodeMessage{
+				Message:     t.Message,
+				Delay:       t.Delay,
+				Destination: t.Destination,
+			}
+		}
+		configs = append(configs, controller.NodeConfig{ID: n.ID, Message: msg, Join: n.Join, Leave: n.Leave})
+	}
+	return configs
+}
+
+// Factory creates the nodes of the scenario, running the scenario's protocol with its parameters. OLSR nodes also
+// use their own overrides. An error is returned if the protocol is unknown.
+func (s *Scenario) Factory() (controller.Factory, error) {
+	switch s.Protocol {
+	case "", OLSR:
+		overrides := make(map[message.NodeID]olsr.Params)
+		for _, n := range s.Nodes {
+			overrides[n.ID] = n.Params
+		}
+		return olsr.Protocol(olsr.DefaultParams().Merge(s.Params), overrides), nil
+	case OLSRv2:
+		p := olsrv2.DefaultParams().Merge(s.OLSRv2)
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		return olsrv2.Protocol(p), nil
+	case AODV:
+		p := aodv.DefaultParams().Merge(s.AODV)
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		return aodv.Protocol(p), nil
+	case DSDV:
+		p := dsdv.DefaultParams().Merge(s.DSDV)
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		return dsdv.Protocol(p), nil
+	case Flooding:
+		return flooding.Protocol(), nil
+	default:
+		return nil, fmt.Errorf("unknown protocol: '%s': must be one of %s", s.Protocol, strings.Join(Protocols, ", "))
+	}
+}
+
+// Controller creates a Controller, with initialized nodes, for the scenario.
+func (s *Scenario) Controller() (*controller.Controller, error) {
+	nwt, err := s.Topology()
+	if err != nil {
+		return nil, err
+	}
+	factory, err := s.Factory()
+	if err != nil {
+		return nil, err
+	}
+	c := controller.New(nwt, s.TickDuration(), factory)
+	c.Seed(s.RandomSeed())
+	c.SetLogs(s.Logs)
+	if err := c.Initialize(s.NodeConfigs()); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// TraceHeader returns the header of a trace of the scenario's run, which records the scenario so that the trace can
+// be replayed.
+func (s *Scenario) TraceHeader() (trace.Header, error) {
+	sc, err := json.Marshal(s)
+	if err != nil {
+		return trace.Header{}, err
+	}
+	h := trace.Header{Name: s.Name, Protocol: s.Protocol, Seed: s.RandomSeed(), Ticks: s.Duration(), Scenario: sc}
+	for _, n := range s.Nodes {
+		h.Nodes = append(h.Nodes, n.ID)
+	}
+	return h, nil
+}
+
+// Result is the outcome of running a Scenario.
+type Result struct {
+	// States holds a snapshot of each OLSR node's tables at the end of the run, in the order the nodes were given, so
+	// that States[i] is that of Nodes[i]. Nodes not in the network at the end of the run, having yet to join or having
+	// left, have an empty State. It is empty for other protocols.
+	States []olsr.State
+
+	// Routes holds each node's routes at the end of the run, in the order the nodes were given. Nodes not in the
+	// network at the end of the run have none.
+	Routes [][]controller.Route
+
+	// Metrics summarize the traffic of the run.
+	Metrics controller.Metrics
+
+	// Splits measure how routes reacted to each of the scenario's Splits.
+	Splits []SplitMetrics
+}
+
+// Run runs the scenario to completion.
+func (s *Scenario) Run() (*Result, error) {
+	c, err := s.Controller()
+	if err != nil {
+		return nil, err
+	}
+	splits := NewSplitRecorder(c, s.Splits)
+	c.Start(s.Duration())
+
+	routers := make(map[message.NodeID]controller.Router)
+	for _, router := range c.Routers() {
+		routers[router.ID()] = router
+	}
+	r := &Result{Metrics: c.Metrics(), Splits: splits.Metrics()}
+	for _, n := range s.Nodes {
+		router, in := routers[n.ID]
+		var routes []controller.Route
+		if in {
+			routes = router.Routes()
+		}
+		r.Routes = append(r.Routes, routes)
+		if s.Protocol == "" || s.Protocol == OLSR {
+			state := olsr.State{ID: n.ID}
+			if node, ok := router.(*olsr.Node); ok {
+				state = node.State()
+			}
+			r.States = append(r.States, state)
+		}
+	}
+	return r, nil
+}
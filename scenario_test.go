@@ -0,0 +1,323 @@
+package olsrsim
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kprusa/olsrsim/aodv"
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/dsdv"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/olsr"
+	"github.com/kprusa/olsrsim/olsrv2"
+)
+
+func TestLoadScenario(t *testing.T) {
+	s, err := LoadScenario("./testdata/test_scenario.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Topology(); err != nil {
+		t.Errorf("Topology() error = %v", err)
+	}
+
+	configs, err := controller.ReadNodeConfiguration(getTestData("./testdata/test_node_config.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.NodeConfigs(); !reflect.DeepEqual(got, configs) {
+		t.Errorf("NodeConfigs() got = %v, want %v", got, configs)
+	}
+}
+
+func TestReadScenario(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    *Scenario
+		wantErr bool
+	}{
+		{
+			name: "minimal",
+			in:   `{"topology": ["0 UP 0 <-> 1"], "nodes": [{"id": 0}, {"id": 1}]}`,
+			want: &Scenario{
+				TopologyLines: []string{"0 UP 0 <-> 1"},
+				Nodes:         []ScenarioNode{{ID: 0}, {ID: 1}},
+			},
+		},
+		{
+			name:    "unknown field",
+			in:      `{"topology": [], "nodes": [{"id": 0}], "hello": 3}`,
+			wantErr: true,
+		},
+		{
+			name:    "no topology",
+			in:      `{"nodes": [{"id": 0}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "two topologies",
+			in:      `{"topologyFile": "t.txt", "topology": [], "nodes": [{"id": 0}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "no nodes",
+			in:      `{"topology": []}`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate node",
+			in:      `{"topology": [], "nodes": [{"id": 0}, {"id": 0}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown traffic source",
+			in:      `{"topology": [], "nodes": [{"id": 0}], "traffic": [{"source": 1, "destination": 0}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown traffic destination",
+			in:      `{"topology": [], "nodes": [{"id": 0}], "traffic": [{"source": 0, "destination": 1}]}`,
+			wantErr: true,
+		},
+		{
+			name: "aodv",
+			in:   `{"protocol": "aodv", "aodv": {"helloInterval": 2}, "topology": [], "nodes": [{"id": 0}]}`,
+			want: &Scenario{
+				Protocol:      AODV,
+				AODV:          aodv.Params{HelloInterval: 2},
+				TopologyLines: []string{},
+				Nodes:         []ScenarioNode{{ID: 0}},
+			},
+		},
+		{
+			name:    "unknown protocol",
+			in:      `{"protocol": "babel", "topology": [], "nodes": [{"id": 0}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid aodv params",
+			in:      `{"protocol": "aodv", "aodv": {"netDiameter": -1}, "topology": [], "nodes": [{"id": 0}]}`,
+			wantErr: true,
+		},
+		{
+			name: "olsrv2",
+			in:   `{"protocol": "olsrv2", "olsrv2": {"routingWillingness": 7}, "topology": [], "nodes": [{"id": 0}]}`,
+			want: &Scenario{
+				Protocol:      OLSRv2,
+				OLSRv2:        olsrv2.Params{RoutingWillingness: 7},
+				TopologyLines: []string{},
+				Nodes:         []ScenarioNode{{ID: 0}},
+			},
+		},
+		{
+			name:    "invalid olsrv2 params",
+			in:      `{"protocol": "olsrv2", "olsrv2": {"floodingWillingness": 8}, "topology": [], "nodes": [{"id": 0}]}`,
+			wantErr: true,
+		},
+		{
+			name: "dsdv",
+			in:   `{"protocol": "dsdv", "dsdv": {"updateInterval": 4}, "topology": [], "nodes": [{"id": 0}]}`,
+			want: &Scenario{
+				Protocol:      DSDV,
+				DSDV:          dsdv.Params{UpdateInterval: 4},
+				TopologyLines: []string{},
+				Nodes:         []ScenarioNode{{ID: 0}},
+			},
+		},
+		{
+			name:    "invalid dsdv params",
+			in:      `{"protocol": "dsdv", "dsdv": {"holdTime": 5}, "topology": [], "nodes": [{"id": 0}]}`,
+			wantErr: true,
+		},
+		{
+			name: "flooding",
+			in:   `{"protocol": "flooding", "topology": [], "nodes": [{"id": 0}]}`,
+			want: &Scenario{
+				Protocol:      Flooding,
+				TopologyLines: []string{},
+				Nodes:         []ScenarioNode{{ID: 0}},
+			},
+		},
+		{
+			name: "late node",
+			in:   `{"topology": [], "nodes": [{"id": 0}, {"id": 1, "join": 40, "leave": 90}]}`,
+			want: &Scenario{
+				TopologyLines: []string{},
+				Nodes:         []ScenarioNode{{ID: 0}, {ID: 1, Join: 40, Leave: 90}},
+			},
+		},
+		{
+			name: "split",
+			in:   `{"topology": [], "nodes": [{"id": 0}, {"id": 1}], "splits": [{"tick": 10, "merge": 20, "partitions": {"a": [0], "b": [1]}}]}`,
+			want: &Scenario{
+				TopologyLines: []string{},
+				Nodes:         []ScenarioNode{{ID: 0}, {ID: 1}},
+				Splits:        []ScenarioSplit{{Tick: 10, Merge: 20, Partitions: map[string][]message.NodeID{"a": {0}, "b": {1}}}},
+			},
+		},
+		{
+			name:    "split of unknown node",
+			in:      `{"topology": [], "nodes": [{"id": 0}], "splits": [{"tick": 10, "partitions": {"a": [0], "b": [1]}}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid split",
+			in:      `{"topology": [], "nodes": [{"id": 0}, {"id": 1}], "splits": [{"tick": 10, "merge": 5, "partitions": {"a": [0], "b": [1]}}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "overlapping splits",
+			in:      `{"topology": [], "nodes": [{"id": 0}, {"id": 1}], "splits": [{"tick": 10, "merge": 30, "partitions": {"a": [0], "b": [1]}}, {"tick": 20, "partitions": {"a": [0], "b": [1]}}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "message due before joining",
+			in:      `{"topology": [], "nodes": [{"id": 0, "join": 20}, {"id": 1}], "traffic": [{"source": 0, "destination": 1, "delay": 10}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "message due after leaving",
+			in:      `{"topology": [], "nodes": [{"id": 0, "leave": 20}, {"id": 1}], "traffic": [{"source": 0, "destination": 1, "delay": 20}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "leaves before joining",
+			in:      `{"topology": [], "nodes": [{"id": 0, "join": 40, "leave": 40}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "several messages from one node",
+			in:      `{"topology": [], "nodes": [{"id": 0}, {"id": 1}], "traffic": [{"source": 0, "destination": 1}, {"source": 0, "destination": 1}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadScenario(strings.NewReader(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadScenario() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadScenario() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScenario_defaults(t *testing.T) {
+	s := &Scenario{}
+	if got := s.TickDuration(); got != time.Second {
+		t.Errorf("TickDuration() = %v, want %v", got, time.Second)
+	}
+	if got := s.Duration(); got != 120 {
+		t.Errorf("Duration() = %v, want %v", got, 120)
+	}
+	if got := s.RandomSeed(); got != 1 {
+		t.Errorf("RandomSeed() = %v, want %v", got, 1)
+	}
+}
+
+func TestScenario_Run(t *testing.T) {
+	s := &Scenario{
+		Ticks:         60,
+		TickMillis:    1,
+		TopologyLines: []string{"0 UP 0 <-> 1", "0 UP 1 <-> 2", "0 UP 2 <-> 3"},
+		// Node 2 joins after the end of the run, and node 3 leaves before it.
+		Nodes: []ScenarioNode{{ID: 0}, {ID: 1}, {ID: 2, Join: 100}, {ID: 3, Leave: 30}},
+	}
+	r, err := s.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.States) != len(s.Nodes) || len(r.Routes) != len(s.Nodes) {
+		t.Fatalf("Run() got %d states and %d routes, want %d of each", len(r.States), len(r.Routes), len(s.Nodes))
+	}
+	for i, n := range s.Nodes {
+		if r.States[i].ID != n.ID {
+			t.Errorf("States[%d] is of node %d, want node %d", i, r.States[i].ID, n.ID)
+		}
+	}
+	wantRoutes := [][]controller.Route{
+		{{Destination: 1, NextHop: 1, Distance: 1}},
+		{{Destination: 0, NextHop: 0, Distance: 1}},
+		nil,
+		nil,
+	}
+	if !reflect.DeepEqual(r.Routes, wantRoutes) {
+		t.Errorf("Run() routes = %v, want %v", r.Routes, wantRoutes)
+	}
+	if got := r.States[2]; !reflect.DeepEqual(got, olsr.State{ID: 2}) {
+		t.Errorf("States[2] of a node yet to join = %+v, want an empty State", got)
+	}
+
+	s.Protocol = AODV
+	if r, err = s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.States) != 0 || len(r.Routes) != len(s.Nodes) {
+		t.Errorf("Run() of AODV got %d states and %d routes, want none and %d", len(r.States), len(r.Routes), len(s.Nodes))
+	}
+}
+
+func TestScenario_NodeConfigs(t *testing.T) {
+	s := &Scenario{
+		Nodes:   []ScenarioNode{{ID: 0}, {ID: 1, Params: olsr.Params{TCInterval: 4}, Join: 10, Leave: 20}},
+		Traffic: []Traffic{{Source: 1, Destination: 0, Message: "hi", Delay: 12}},
+	}
+	want := []controller.NodeConfig{
+		{ID: 0, Message: controller.NodeMessage{Sent: true}},
+		{ID: 1, Message: controller.NodeMessage{Message: "hi", Delay: 12, Destination: 0}, Join: 10, Leave: 20},
+	}
+	if got := s.NodeConfigs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("NodeConfigs() got = %v, want %v", got, want)
+	}
+}
+
+func TestReadScenario_invalidParams(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{
+			name: "scenario params",
+			in:   `{"topology": [], "nodes": [{"id": 0}], "params": {"helloInterval": 20}}`,
+		},
+		{
+			name: "node override",
+			in:   `{"topology": [], "nodes": [{"id": 0}, {"id": 1, "params": {"topologyHoldTime": 10}}]}`,
+		},
+		{
+			name: "loss",
+			in:   `{"topology": [], "nodes": [{"id": 0}], "loss": 1.5}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadScenario(strings.NewReader(tt.in)); err == nil {
+				t.Errorf("ReadScenario() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestNewScenario(t *testing.T) {
+	configs, err := controller.ReadNodeConfiguration(getTestData("./testdata/test_node_config.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewScenario("./testdata/test_topology.txt", configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.NodeConfigs(); !reflect.DeepEqual(got, configs) {
+		t.Errorf("NodeConfigs() got = %v, want %v", got, configs)
+	}
+
+	if _, err := NewScenario("./testdata/test_topology.txt", append(configs, configs[0])); err == nil {
+		t.Errorf("NewScenario() error = nil for a duplicate node, want error")
+	}
+}
@@ -0,0 +1,515 @@
+// Package server exposes a running simulation over HTTP, so that it may be controlled and followed live by other
+// tools, such as dashboards.
+//
+// A Server runs the simulation itself, pausing it between ticks as asked, and serves a JSON API:
+//
+//	GET  /api/status        the current tick, the length of the run, and whether it is paused or done
+//	GET  /api/metrics       the traffic of the run so far
+//	GET  /api/nodes         the tables of every node
+//	GET  /api/nodes/{id}    the tables of a node
+//	POST /api/pause         pause the run before its next tick
+//	POST /api/resume        resume a paused run
+//	POST /api/step?n=N      run N ticks of a paused run, 1 by default
+//	POST /api/links         change a link: {"from": 0, "to": 1, "up": false, "bidirectional": true, "loss": 0,
+//	                        "delay": 0}
+//	POST /api/send          have a node send data: {"source": 2, "destination": 5, "data": "hi"}
+//	GET  /api/events        a stream of the run's events, as server-sent events
+//
+// Nodes are described by controller.NodeState, and events by controller.Event, encoded as in a trace. Errors are
+// returned as {"error": "..."} with a 4xx status.
+//
+// The server has no authentication, and must only be listened for on a loopback address. So that web pages cannot
+// reach it from a browser, requests must be for a loopback host, which DNS rebinding cannot fake, and POST requests
+// must be of Content-Type application/json, which browsers only send cross-origin once the server allows it.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/topology"
+)
+
+// eventBuffer is the number of events buffered for each stream. A stream which falls further behind is closed,
+// rather than slowing the simulation down.
+const eventBuffer = 1024
+
+// Server runs a simulation, and serves an HTTP API to control and inspect it.
+type Server struct {
+	// mu guards c, and the state of the run. The simulation only runs while holding it, so that requests see the
+	// nodes between ticks.
+	mu     sync.Mutex
+	resume *sync.Cond
+	c      *controller.Controller
+	ticks  int
+	paused bool
+	done   bool
+
+	tickDuration time.Duration
+
+	// streamsMu guards streams. Events are published while mu is held, so streams have their own lock.
+	streamsMu sync.Mutex
+	streams   map[chan []byte]struct{}
+}
+
+// Status describes the progress of a run.
+type Status struct {
+	// Tick is the tick run next.
+	Tick int `json:"tick"`
+
+	// Ticks is the number of ticks the run lasts.
+	Ticks  int  `json:"ticks"`
+	Paused bool `json:"paused"`
+	Done   bool `json:"done"`
+}
+
+// LinkChange changes the state of a link from the current tick onward.
+type LinkChange struct {
+	From message.NodeID `json:"from"`
+	To   message.NodeID `json:"to"`
+	Up   bool           `json:"up"`
+
+	// Bidirectional also changes the link from To to From.
+	Bidirectional bool `json:"bidirectional"`
+
+	// Loss and Delay are the attributes of a link which is brought up.
+	Loss  float64 `json:"loss"`
+	Delay int     `json:"delay"`
+}
+
+// Data is data handed to a node to send.
+type Data struct {
+	Source      message.NodeID `json:"source"`
+	Destination message.NodeID `json:"destination"`
+	Data        string         `json:"data"`
+}
+
+// New creates a Server running c for the given number of ticks, paced by the tick duration of c, and starting paused
+// if asked. c must be initialized, and must not be used by anything but the Server.
+func New(c *controller.Controller, ticks int, paused bool) *Server {
+	s := &Server{
+		c:            c,
+		ticks:        ticks,
+		paused:       paused,
+		tickDuration: c.TickDuration(),
+		streams:      make(map[chan []byte]struct{}),
+	}
+	s.resume = sync.NewCond(&s.mu)
+	c.Observe(s)
+	return s
+}
+
+// Run runs the simulation until every tick has been run or it is stopped, waiting while it is paused. The logs of the
+// nodes are then closed, and every event stream ends.
+func (s *Server) Run() {
+	var pace <-chan time.Time
+	if s.tickDuration > 0 {
+		ticker := time.NewTicker(s.tickDuration)
+		defer ticker.Stop()
+		pace = ticker.C
+	}
+
+	for {
+		s.mu.Lock()
+		for s.paused && !s.over() {
+			s.resume.Wait()
+		}
+		if s.over() {
+			break
+		}
+		s.c.Step()
+		s.mu.Unlock()
+		if pace != nil {
+			<-pace
+		}
+	}
+	s.done = true
+	s.c.Close()
+	s.mu.Unlock()
+	s.closeStreams()
+	log.Println("done.")
+}
+
+// over reports whether every tick has been run, or the simulation was stopped. s.mu must be held.
+func (s *Server) over() bool {
+	return s.c.CurrentTick() >= s.ticks || s.c.Stopped()
+}
+
+// Event publishes e to every event stream. It is called by the Controller.
+func (s *Server) Event(e controller.Event) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("server: could not encode %s event at tick %d: %s", e.Kind, e.Tick, err)
+		return
+	}
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	for ch := range s.streams {
+		select {
+		case ch <- b:
+		default:
+			// The stream fell behind.
+			delete(s.streams, ch)
+			close(ch)
+		}
+	}
+}
+
+// closeStreams ends every event stream.
+func (s *Server) closeStreams() {
+	s.streamsMu.Lock()
+	defer s.streamsMu.Unlock()
+	for ch := range s.streams {
+		delete(s.streams, ch)
+		close(ch)
+	}
+}
+
+// Handler returns the handler serving the API. Requests for a host other than a loopback address are refused.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/status", get(s.status))
+	mux.HandleFunc("/api/metrics", get(s.metrics))
+	mux.HandleFunc("/api/nodes", get(s.nodes))
+	mux.HandleFunc("/api/nodes/", get(s.node))
+	mux.HandleFunc("/api/pause", post(s.pause))
+	mux.HandleFunc("/api/resume", post(s.resumeRun))
+	mux.HandleFunc("/api/step", post(s.step))
+	mux.HandleFunc("/api/links", post(s.link))
+	mux.HandleFunc("/api/send", post(s.send))
+	mux.HandleFunc("/api/events", s.events)
+	return loopbackOnly(mux)
+}
+
+// loopbackOnly adapts h to refuse requests whose Host header is not a loopback address, such as those of a page whose
+// domain was rebound to a loopback address.
+func loopbackOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = strings.Trim(r.Host, "[]")
+		}
+		if !isLoopback(host) {
+			err := httpError{status: http.StatusForbidden, err: fmt.Errorf("host %q is not a loopback address", r.Host)}
+			respond(w, r, nil, err)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// httpError is an error with the status it is returned with.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e httpError) Error() string {
+	return e.err.Error()
+}
+
+// badRequest returns an error with status 400.
+func badRequest(format string, a ...any) error {
+	return httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, a...)}
+}
+
+// errDone is returned by requests changing a run which is over.
+var errDone = httpError{status: http.StatusConflict, err: errors.New("the run is over")}
+
+// handlerFunc handles a request, returning the value to encode as its response.
+type handlerFunc func(r *http.Request) (any, error)
+
+// get adapts h to only handle GET requests.
+func get(h handlerFunc) http.HandlerFunc {
+	return serve(http.MethodGet, h)
+}
+
+// post adapts h to only handle POST requests.
+func post(h handlerFunc) http.HandlerFunc {
+	return serve(http.MethodPost, h)
+}
+
+// serve adapts h to an http.HandlerFunc handling requests of the given method, which encodes its response, or error,
+// as JSON. POST requests must be of Content-Type application/json, even those without a body.
+func serve(method string, h handlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var v any
+		var err error
+		switch {
+		case r.Method != method:
+			w.Header().Set("Allow", method)
+			err = httpError{status: http.StatusMethodNotAllowed, err: fmt.Errorf("method %s is not allowed", r.Method)}
+		case method == http.MethodPost && !isJSON(r):
+			err = httpError{
+				status: http.StatusUnsupportedMediaType,
+				err:    fmt.Errorf("content type %q is not allowed: must be application/json", r.Header.Get("Content-Type")),
+			}
+		default:
+			v, err = h(r)
+		}
+		respond(w, r, v, err)
+	}
+}
+
+// isJSON reports whether the Content-Type of r is application/json.
+func isJSON(r *http.Request) bool {
+	t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && t == "application/json"
+}
+
+// respond encodes v as the JSON response to r, or err if it is not nil.
+func respond(w http.ResponseWriter, r *http.Request, v any, err error) {
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusInternalServerError
+		var he httpError
+		if errors.As(err, &he) {
+			status = he.status
+		}
+		v = struct {
+			Error string `json:"error"`
+		}{err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("server: could not write response to %s %s: %s", r.Method, r.URL.Path, err)
+	}
+}
+
+func (s *Server) status(*http.Request) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statusLocked(), nil
+}
+
+// statusLocked returns the status of the run. s.mu must be held.
+func (s *Server) statusLocked() Status {
+	return Status{Tick: s.c.CurrentTick(), Ticks: s.ticks, Paused: s.paused, Done: s.done}
+}
+
+func (s *Server) metrics(*http.Request) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Metrics(), nil
+}
+
+func (s *Server) nodes(*http.Request) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states := make([]controller.NodeState, 0, len(s.c.Routers()))
+	for _, r := range s.c.Routers() {
+		state, err := s.c.Inspect(r.ID())
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (s *Server) node(r *http.Request) (any, error) {
+	raw := strings.TrimPrefix(r.URL.Path, "/api/nodes/")
+	id, err := strconv.Atoi(raw)
+	if err != nil || id < 0 {
+		return nil, badRequest("invalid node ID: %q: must be a non-negative integer", raw)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.c.Inspect(message.NodeID(id))
+	if err != nil {
+		return nil, httpError{status: http.StatusNotFound, err: err}
+	}
+	return state, nil
+}
+
+func (s *Server) pause(*http.Request) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return nil, errDone
+	}
+	s.paused = true
+	return s.statusLocked(), nil
+}
+
+func (s *Server) resumeRun(*http.Request) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return nil, errDone
+	}
+	s.paused = false
+	s.resume.Broadcast()
+	return s.statusLocked(), nil
+}
+
+func (s *Server) step(r *http.Request) (any, error) {
+	n := 1
+	if raw := r.URL.Query().Get("n"); raw != "" {
+		var err error
+		if n, err = strconv.Atoi(raw); err != nil || n < 1 {
+			return nil, badRequest("invalid number of ticks: %q: must be a positive integer", raw)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return nil, errDone
+	}
+	if !s.paused {
+		return nil, httpError{status: http.StatusConflict, err: errors.New("the run must be paused to be stepped")}
+	}
+	for i := 0; i < n && !s.over(); i++ {
+		s.c.Step()
+	}
+	if s.over() {
+		// Let Run finish the run.
+		s.resume.Broadcast()
+	}
+	return s.statusLocked(), nil
+}
+
+func (s *Server) link(r *http.Request) (any, error) {
+	var lc LinkChange
+	if err := decode(r, &lc); err != nil {
+		return nil, err
+	}
+	if lc.Loss < 0 || lc.Loss > 1 {
+		return nil, badRequest("invalid loss: %g: must be within [0, 1]", lc.Loss)
+	}
+	if lc.Delay < 0 {
+		return nil, badRequest("invalid delay: %d: must not be negative", lc.Delay)
+	}
+	attrs := topology.LinkAttributes{Loss: lc.Loss, Delay: lc.Delay}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return nil, errDone
+	}
+	s.c.SetLink(lc.From, lc.To, lc.Up, attrs)
+	if lc.Bidirectional {
+		s.c.SetLink(lc.To, lc.From, lc.Up, attrs)
+	}
+	return s.statusLocked(), nil
+}
+
+func (s *Server) send(r *http.Request) (any, error) {
+	var d Data
+	if err := decode(r, &d); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return nil, errDone
+	}
+	if err := s.c.Send(d.Source, d.Destination, d.Data); err != nil {
+		return nil, httpError{status: http.StatusNotFound, err: err}
+	}
+	return s.statusLocked(), nil
+}
+
+// decode decodes the JSON body of a request into v, rejecting unknown fields.
+func decode(r *http.Request, v any) error {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		return badRequest("invalid request body: %s", err)
+	}
+	return nil
+}
+
+// events streams the events of the run, from the time of the request, as server-sent events. The stream ends with a
+// done event once the run is over.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		serve(http.MethodGet, nil)(w, r)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan []byte, eventBuffer)
+	s.mu.Lock()
+	done := s.done
+	if !done {
+		s.streamsMu.Lock()
+		s.streams[ch] = struct{}{}
+		s.streamsMu.Unlock()
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	defer func() {
+		s.streamsMu.Lock()
+		if _, ok := s.streams[ch]; ok {
+			delete(s.streams, ch)
+			close(ch)
+		}
+		s.streamsMu.Unlock()
+	}()
+
+	for !done {
+		select {
+		case b, ok := <-ch:
+			if !ok {
+				// The stream either fell behind, and ends abruptly so that the client reconnects, or the run is over.
+				s.mu.Lock()
+				done = s.done
+				s.mu.Unlock()
+				if !done {
+					return
+				}
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", b); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+	fmt.Fprint(w, "event: done\ndata: {}\n\n")
+	flusher.Flush()
+}
+
+// CheckLoopback returns an error unless addr, of the form host:port, is for a loopback address.
+func CheckLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if !isLoopback(host) {
+		return fmt.Errorf("%s is not a loopback address: the server must only be reachable from this host", host)
+	}
+	return nil
+}
+
+// isLoopback reports whether host is localhost, or a loopback IP address.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,266 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim"
+	"github.com/kprusa/olsrsim/controller"
+)
+
+// newServer creates a paused Server running OLSR on the line 0-1-2 for the given number of ticks.
+func newServer(t *testing.T, ticks int) *Server {
+	t.Helper()
+	s := &olsrsim.Scenario{
+		TopologyLines: []string{"0 UP 0 <-> 1", "0 UP 1 <-> 2"},
+		Nodes:         []olsrsim.ScenarioNode{{ID: 0}, {ID: 1}, {ID: 2}},
+	}
+	c, err := s.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetTickDuration(0)
+	return New(c, ticks, true)
+}
+
+func TestServer_Handler(t *testing.T) {
+	s := newServer(t, 100)
+	h := s.Handler()
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+
+		// host is the Host of the request, localhost:8080 if empty, and contentType the Content-Type of a POST
+		// request, application/json if empty.
+		host        string
+		contentType string
+
+		wantStatus int
+		want       string
+	}{
+		{
+			name: "status", method: http.MethodGet, target: "/api/status",
+			wantStatus: http.StatusOK, want: `{"tick":0,"ticks":100,"paused":true,"done":false}`,
+		},
+		{
+			name: "step", method: http.MethodPost, target: "/api/step?n=30",
+			wantStatus: http.StatusOK, want: `{"tick":30,"ticks":100,"paused":true,"done":false}`,
+		},
+		{
+			name: "node", method: http.MethodGet, target: "/api/nodes/0",
+			wantStatus: http.StatusOK,
+			want:       `{"id":0,"tick":30,"neighbors":[1],"twoHopNeighbors":[{"id":2,"via":1}],"mprs":[1],"topology":[{"from":1,"to":2}],"routes":[{"destination":1,"nextHop":1,"distance":1},{"destination":2,"nextHop":1,"distance":2}]}`,
+		},
+		{
+			name: "link", method: http.MethodPost, target: "/api/links", body: `{"from":1,"to":2,"up":false,"bidirectional":true}`,
+			wantStatus: http.StatusOK, want: `{"tick":30,"ticks":100,"paused":true,"done":false}`,
+		},
+		{
+			name: "send", method: http.MethodPost, target: "/api/send", body: `{"source":0,"destination":1,"data":"hi"}`,
+			wantStatus: http.StatusOK, want: `{"tick":30,"ticks":100,"paused":true,"done":false}`,
+		},
+		{
+			name: "step after link down", method: http.MethodPost, target: "/api/step?n=40",
+			wantStatus: http.StatusOK, want: `{"tick":70,"ticks":100,"paused":true,"done":false}`,
+		},
+		{
+			name: "node after link down", method: http.MethodGet, target: "/api/nodes/0",
+			wantStatus: http.StatusOK,
+			want:       `{"id":0,"tick":70,"neighbors":[1],"routes":[{"destination":1,"nextHop":1,"distance":1}]}`,
+		},
+		{
+			name: "unknown node", method: http.MethodGet, target: "/api/nodes/9",
+			wantStatus: http.StatusNotFound, want: `{"error":"node 9 does not exist"}`,
+		},
+		{
+			name: "invalid node", method: http.MethodGet, target: "/api/nodes/x",
+			wantStatus: http.StatusBadRequest, want: `{"error":"invalid node ID: \"x\": must be a non-negative integer"}`,
+		},
+		{
+			name: "send from unknown node", method: http.MethodPost, target: "/api/send", body: `{"source":9,"destination":1,"data":"hi"}`,
+			wantStatus: http.StatusNotFound, want: `{"error":"node 9 does not exist"}`,
+		},
+		{
+			name: "invalid link", method: http.MethodPost, target: "/api/links", body: `{"from":1,"to":2,"up":true,"loss":2}`,
+			wantStatus: http.StatusBadRequest, want: `{"error":"invalid loss: 2: must be within [0, 1]"}`,
+		},
+		{
+			name: "unknown field", method: http.MethodPost, target: "/api/send", body: `{"src":0}`,
+			wantStatus: http.StatusBadRequest, want: `{"error":"invalid request body: json: unknown field \"src\""}`,
+		},
+		{
+			name: "text body", method: http.MethodPost, target: "/api/links", body: `{"from":0,"to":1,"up":false}`,
+			contentType: "text/plain",
+			wantStatus:  http.StatusUnsupportedMediaType,
+			want:        `{"error":"content type \"text/plain\" is not allowed: must be application/json"}`,
+		},
+		{
+			name: "form without body", method: http.MethodPost, target: "/api/pause",
+			contentType: "application/x-www-form-urlencoded",
+			wantStatus:  http.StatusUnsupportedMediaType,
+			want:        `{"error":"content type \"application/x-www-form-urlencoded\" is not allowed: must be application/json"}`,
+		},
+		{
+			name: "json with charset", method: http.MethodPost, target: "/api/pause", contentType: "application/json; charset=utf-8",
+			wantStatus: http.StatusOK, want: `{"tick":70,"ticks":100,"paused":true,"done":false}`,
+		},
+		{
+			name: "rebound host", method: http.MethodGet, target: "/api/nodes", host: "attacker.example:8080",
+			wantStatus: http.StatusForbidden, want: `{"error":"host \"attacker.example:8080\" is not a loopback address"}`,
+		},
+		{
+			name: "rebound host sending", method: http.MethodPost, target: "/api/send", body: `{"source":0,"destination":1}`,
+			host:       "attacker.example",
+			wantStatus: http.StatusForbidden, want: `{"error":"host \"attacker.example\" is not a loopback address"}`,
+		},
+		{
+			name: "rebound host streaming", method: http.MethodGet, target: "/api/events", host: "attacker.example:8080",
+			wantStatus: http.StatusForbidden, want: `{"error":"host \"attacker.example:8080\" is not a loopback address"}`,
+		},
+		{
+			name: "loopback IP host", method: http.MethodGet, target: "/api/status", host: "[::1]:8080",
+			wantStatus: http.StatusOK, want: `{"tick":70,"ticks":100,"paused":true,"done":false}`,
+		},
+		{
+			name: "wrong method", method: http.MethodGet, target: "/api/pause",
+			wantStatus: http.StatusMethodNotAllowed, want: `{"error":"method GET is not allowed"}`,
+		},
+		{
+			name: "resume", method: http.MethodPost, target: "/api/resume",
+			wantStatus: http.StatusOK, want: `{"tick":70,"ticks":100,"paused":false,"done":false}`,
+		},
+		{
+			name: "step while running", method: http.MethodPost, target: "/api/step",
+			wantStatus: http.StatusConflict, want: `{"error":"the run must be paused to be stepped"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			r.Host = "localhost:8080"
+			if tt.host != "" {
+				r.Host = tt.host
+			}
+			if tt.method == http.MethodPost {
+				r.Header.Set("Content-Type", "application/json")
+				if tt.contentType != "" {
+					r.Header.Set("Content-Type", tt.contentType)
+				}
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("%s %s status = %d, want %d", tt.method, tt.target, w.Code, tt.wantStatus)
+			}
+			if got := strings.TrimSpace(w.Body.String()); got != tt.want {
+				t.Errorf("%s %s got = %s, want %s", tt.method, tt.target, got, tt.want)
+			}
+		})
+	}
+
+	var m controller.Metrics
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/metrics", nil)
+	r.Host = "localhost:8080"
+	h.ServeHTTP(w, r)
+	if err := json.NewDecoder(w.Body).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.DataOriginated != 1 || m.DataDelivered != 1 {
+		t.Errorf("GET /api/metrics originated %d and delivered %d data, want 1 and 1", m.DataOriginated, m.DataDelivered)
+	}
+}
+
+func TestServer_Run(t *testing.T) {
+	s := newServer(t, 20)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("GET /api/events Content-Type = %s, want text/event-stream", ct)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		s.Run()
+		close(finished)
+	}()
+	resp2, err := http.Post(ts.URL+"/api/resume", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp2.Body.Close()
+
+	var ticks []int
+	var last string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		last = line
+		data := strings.TrimPrefix(line, "data: ")
+		if data == line {
+			continue
+		}
+		var e controller.Event
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			t.Fatalf("invalid event %s: %v", data, err)
+		}
+		if e.Kind == controller.EventTick {
+			ticks = append(ticks, e.Tick)
+		}
+	}
+	<-finished
+
+	if len(ticks) != 20 || ticks[0] != 0 || ticks[19] != 19 {
+		t.Errorf("GET /api/events streamed ticks %v, want 0 to 19", ticks)
+	}
+	if last != "data: {}" {
+		t.Errorf("GET /api/events ended with %q, want a done event", last)
+	}
+
+	status, _ := s.status(nil)
+	if want := (Status{Tick: 20, Ticks: 20, Done: true}); !reflect.DeepEqual(status, want) {
+		t.Errorf("status after Run() = %+v, want %+v", status, want)
+	}
+	if _, err := s.send(httptest.NewRequest(http.MethodPost, "/api/send", strings.NewReader(`{"source":0,"destination":1}`))); err != errDone {
+		t.Errorf("send after Run() error = %v, want %v", err, errDone)
+	}
+}
+
+func TestCheckLoopback(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{addr: "localhost:8080"},
+		{addr: "127.0.0.1:8080"},
+		{addr: "[::1]:8080"},
+		{addr: ":8080", wantErr: true},
+		{addr: "0.0.0.0:8080", wantErr: true},
+		{addr: "192.168.1.2:8080", wantErr: true},
+		{addr: "example.com:80", wantErr: true},
+		{addr: "localhost", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if err := CheckLoopback(tt.addr); (err != nil) != tt.wantErr {
+				t.Errorf("CheckLoopback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
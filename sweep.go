@@ -0,0 +1,268 @@
+package olsrsim
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/kprusa/olsrsim/aodv"
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/dsdv"
+	"github.com/kprusa/olsrsim/olsr"
+	"github.com/kprusa/olsrsim/olsrv2"
+)
+
+// Sweep runs a scenario once for every combination of parameter values, so that protocols can be tuned and compared
+// over a range of conditions. An empty range leaves the scenario's own value, or default, in place.
+type Sweep struct {
+	// Base is the scenario every run starts from. It may be nil if Generator is set.
+	Base *Scenario
+
+	// Protocols are the routing protocols run.
+	Protocols []string
+
+	// HelloIntervals are the HELLO intervals of OLSR, OLSRv2 and AODV nodes. They do not apply to other protocols.
+	HelloIntervals []int
+
+	// HoldTimes are the neighbor hold times of OLSR and OLSRv2 nodes, and the route hold times of DSDV nodes. They
+	// do not apply to other protocols.
+	HoldTimes []int
+
+	// Nodes are the numbers of nodes of topologies created by Generator, which replace the topology, nodes and
+	// traffic of Base.
+	Nodes []int
+
+	// Generator creates the topology of each run when Nodes are given. Its seed is replaced by that of the run.
+	Generator *GeneratorConfig
+
+	// Losses replace the loss of every link.
+	Losses []float64
+
+	// Seeds seed the random source deciding which messages are lost, and the generator.
+	Seeds []int64
+
+	// Workers is the number of runs simulated at once. Zero uses every CPU.
+	Workers int
+}
+
+// SweepPoint is a combination of parameter values run by a Sweep. Zero values, and nil Loss and Seed, are left to
+// the scenario.
+type SweepPoint struct {
+	Protocol      string
+	HelloInterval int
+	HoldTime      int
+	Nodes         int
+	Loss          *float64
+	Seed          *int64
+}
+
+// SweepResult is the outcome of running a single SweepPoint.
+type SweepResult struct {
+	SweepPoint
+
+	// Metrics summarize the traffic of the run, unless Err is set.
+	Metrics controller.Metrics
+
+	// Err is set if the parameter values are invalid for the scenario.
+	Err error
+}
+
+// Points returns every combination of parameter values, varying the last parameter (the seed) fastest.
+func (s *Sweep) Points() []SweepPoint {
+	points := []SweepPoint{{}}
+	points = expand(points, s.Protocols, func(p *SweepPoint, v string) { p.Protocol = v })
+	points = expand(points, s.HelloIntervals, func(p *SweepPoint, v int) { p.HelloInterval = v })
+	points = expand(points, s.HoldTimes, func(p *SweepPoint, v int) { p.HoldTime = v })
+	points = expand(points, s.Nodes, func(p *SweepPoint, v int) { p.Nodes = v })
+	points = expand(points, s.Losses, func(p *SweepPoint, v float64) { p.Loss = &v })
+	points = expand(points, s.Seeds, func(p *SweepPoint, v int64) { p.Seed = &v })
+	return points
+}
+
+// expand returns a copy of each point for each value, set by set. The points are returned unchanged if there are no
+// values.
+func expand[V any](points []SweepPoint, values []V, set func(p *SweepPoint, v V)) []SweepPoint {
+	if len(values) == 0 {
+		return points
+	}
+	expanded := make([]SweepPoint, 0, len(points)*len(values))
+	for _, p := range points {
+		for _, v := range values {
+			set(&p, v)
+			expanded = append(expanded, p)
+		}
+	}
+	return expanded
+}
+
+// Run simulates every point of the sweep, as fast as possible, using up to Workers goroutines. Results are returned
+// in the order of Points, and are the same however many workers are used. An error is returned if the sweep itself is
+// invalid; points with invalid parameters are reported in their results.
+func (s *Sweep) Run() ([]SweepResult, error) {
+	if s.Base == nil && s.Generator == nil {
+		return nil, errors.New("sweep: a base scenario or a generator must be given")
+	}
+	if len(s.Nodes) > 0 {
+		if s.Generator == nil {
+			return nil, errors.New("sweep: node counts require a generator")
+		}
+		if s.Generator.Shape == Grid {
+			return nil, errors.New("sweep: node counts cannot be applied to grid topologies, which are sized by rows and columns")
+		}
+	}
+	if s.Base == nil && len(s.Nodes) == 0 {
+		return nil, errors.New("sweep: node counts must be given to generate topologies without a base scenario")
+	}
+
+	points := s.Points()
+	results := make([]SweepResult, len(points))
+	workers := s.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = s.run(points[i])
+			}
+		}()
+	}
+	for i := range points {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results, nil
+}
+
+// run simulates a single point of the sweep.
+func (s *Sweep) run(p SweepPoint) SweepResult {
+	r := SweepResult{SweepPoint: p}
+	sc, err := s.scenario(p)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	c, err := sc.Controller()
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	c.SetTickDuration(0)
+	c.Start(sc.Duration())
+	r.Metrics = c.Metrics()
+	return r
+}
+
+// scenario creates the scenario of a point, returning an error if the point's parameters are invalid.
+func (s *Sweep) scenario(p SweepPoint) (*Scenario, error) {
+	sc := &Scenario{}
+	if s.Base != nil {
+		*sc = *s.Base
+	}
+	sc.Logs = nil
+
+	if p.Nodes > 0 {
+		cfg := *s.Generator
+		cfg.Nodes = p.Nodes
+		if p.Seed != nil {
+			cfg.Seed = *p.Seed
+		}
+		g, err := Generate(cfg)
+		if err != nil {
+			return nil, err
+		}
+		gs := g.Scenario()
+		sc.TopologyFile, sc.TopologyLines = "", gs.TopologyLines
+		sc.Nodes, sc.Traffic = gs.Nodes, gs.Traffic
+	}
+	if p.Protocol != "" {
+		sc.Protocol = p.Protocol
+	}
+	switch sc.Protocol {
+	case "", OLSR:
+		sc.Params = sc.Params.Merge(olsr.Params{HelloInterval: p.HelloInterval, NeighborHoldTime: p.HoldTime})
+	case OLSRv2:
+		sc.OLSRv2 = sc.OLSRv2.Merge(olsrv2.Params{HelloInterval: p.HelloInterval, NeighborHoldTime: p.HoldTime})
+	case AODV:
+		sc.AODV = sc.AODV.Merge(aodv.Params{HelloInterval: p.HelloInterval})
+	case DSDV:
+		sc.DSDV = sc.DSDV.Merge(dsdv.Params{HoldTime: p.HoldTime})
+	}
+	if p.Loss != nil {
+		sc.Loss = p.Loss
+	}
+	if p.Seed != nil {
+		sc.Seed = p.Seed
+	}
+	if err := sc.validate(); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// WriteSweepCSV writes a row of metrics for each result, headed by the names of the columns. Parameters left to the
+// scenario are empty, as are the metrics of runs with invalid parameters, whose error is given instead.
+func WriteSweepCSV(w io.Writer, results []SweepResult) error {
+	cw := csv.NewWriter(w)
+	header := []string{
+		"protocol", "hello interval", "hold time", "nodes", "loss", "seed",
+		"ticks", "control sent", "total sent", "data originated", "data delivered", "delivery ratio", "mean latency",
+		"error",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	optional := func(v int) string {
+		if v == 0 {
+			return ""
+		}
+		return strconv.Itoa(v)
+	}
+	for _, r := range results {
+		row := []string{
+			r.Protocol,
+			optional(r.HelloInterval),
+			optional(r.HoldTime),
+			optional(r.Nodes),
+			"",
+			"",
+		}
+		if r.Loss != nil {
+			row[4] = strconv.FormatFloat(*r.Loss, 'g', -1, 64)
+		}
+		if r.Seed != nil {
+			row[5] = strconv.FormatInt(*r.Seed, 10)
+		}
+		if r.Err != nil {
+			row = append(row, "", "", "", "", "", "", "", r.Err.Error())
+		} else {
+			m := r.Metrics
+			row = append(row,
+				strconv.Itoa(m.Ticks),
+				strconv.Itoa(m.ControlSent()),
+				strconv.Itoa(m.TotalSent()),
+				strconv.Itoa(m.DataOriginated),
+				strconv.Itoa(m.DataDelivered),
+				fmt.Sprintf("%.4f", m.DeliveryRatio()),
+				fmt.Sprintf("%.4f", m.MeanLatency()),
+				"",
+			)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
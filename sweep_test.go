@@ -0,0 +1,183 @@
+package olsrsim
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim/controller"
+)
+
+// seed returns a pointer to v.
+func seed(v int64) *int64 {
+	return &v
+}
+
+// loss returns a pointer to v.
+func loss(v float64) *float64 {
+	return &v
+}
+
+func TestSweep_Points(t *testing.T) {
+	s := &Sweep{Protocols: []string{OLSR, AODV}, HelloIntervals: []int{2, 4}, Seeds: []int64{1, 2}}
+	want := []SweepPoint{
+		{Protocol: OLSR, HelloInterval: 2, Seed: seed(1)},
+		{Protocol: OLSR, HelloInterval: 2, Seed: seed(2)},
+		{Protocol: OLSR, HelloInterval: 4, Seed: seed(1)},
+		{Protocol: OLSR, HelloInterval: 4, Seed: seed(2)},
+		{Protocol: AODV, HelloInterval: 2, Seed: seed(1)},
+		{Protocol: AODV, HelloInterval: 2, Seed: seed(2)},
+		{Protocol: AODV, HelloInterval: 4, Seed: seed(1)},
+		{Protocol: AODV, HelloInterval: 4, Seed: seed(2)},
+	}
+	if got := s.Points(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Points() = %v, want %v", got, want)
+	}
+	if got := (&Sweep{}).Points(); !reflect.DeepEqual(got, []SweepPoint{{}}) {
+		t.Errorf("Points() of an empty sweep = %v, want a single point", got)
+	}
+}
+
+func TestSweep_Run(t *testing.T) {
+	base := &Scenario{
+		Ticks:         40,
+		TopologyLines: []string{"0 UP 0 <-> 1", "0 UP 1 <-> 2"},
+		Nodes:         []ScenarioNode{{ID: 0}, {ID: 1}, {ID: 2}},
+		Traffic:       []Traffic{{Source: 0, Destination: 2, Message: "hello", Delay: 20}},
+	}
+	sweep := func(workers int) []SweepResult {
+		s := &Sweep{
+			Base:           base,
+			Protocols:      []string{OLSR, DSDV},
+			HelloIntervals: []int{2, 20},
+			Losses:         []float64{0.2},
+			Seeds:          []int64{1, 2, 3},
+			Workers:        workers,
+		}
+		results, err := s.Run()
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		return results
+	}
+
+	serial := sweep(1)
+	if len(serial) != 12 {
+		t.Fatalf("Run() returned %d results, want 12", len(serial))
+	}
+	for _, r := range serial {
+		// A HELLO interval of 20 is not less than the default OLSR neighbor hold time.
+		if wantErr := r.Protocol == OLSR && r.HelloInterval == 20; (r.Err != nil) != wantErr {
+			t.Errorf("Run() %+v error = %v, want error %v", r.SweepPoint, r.Err, wantErr)
+		}
+		if r.Err == nil && r.Metrics.Ticks != 40 {
+			t.Errorf("Run() %+v ran %d ticks, want 40", r.SweepPoint, r.Metrics.Ticks)
+		}
+	}
+	if parallel := sweep(4); !reflect.DeepEqual(parallel, serial) {
+		t.Errorf("Run() with 4 workers differs from a single worker")
+	}
+}
+
+func TestSweep_Run_zero(t *testing.T) {
+	base := &Scenario{
+		Ticks:         40,
+		Seed:          seed(3),
+		TopologyLines: []string{"0 UP 0 <-> 1 loss=0.5", "0 UP 1 <-> 2 loss=0.5"},
+		Nodes:         []ScenarioNode{{ID: 0}, {ID: 1}, {ID: 2}},
+		Traffic:       []Traffic{{Source: 0, Destination: 2, Message: "hello", Delay: 20}},
+	}
+	lossless := *base
+	lossless.TopologyLines = []string{"0 UP 0 <-> 1", "0 UP 1 <-> 2"}
+	want, err := (&Sweep{Base: &lossless}).Run()
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// A loss and seed of 0 replace those of the scenario, as any other value does.
+	s := &Sweep{Base: base, Losses: []float64{0, 0.5}, Seeds: []int64{0, 1}}
+	results, err := s.Run()
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	wantPoints := []SweepPoint{
+		{Loss: loss(0), Seed: seed(0)},
+		{Loss: loss(0), Seed: seed(1)},
+		{Loss: loss(0.5), Seed: seed(0)},
+		{Loss: loss(0.5), Seed: seed(1)},
+	}
+	if got := s.Points(); !reflect.DeepEqual(got, wantPoints) {
+		t.Fatalf("Points() = %v, want %v", got, wantPoints)
+	}
+	for _, r := range results[:2] {
+		if r.Err != nil || !reflect.DeepEqual(r.Metrics, want[0].Metrics) {
+			t.Errorf("Run() %+v got = %+v, %v, want the metrics of a lossless run %+v", r.SweepPoint, r.Metrics, r.Err, want[0].Metrics)
+		}
+	}
+	sc, err := s.scenario(wantPoints[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sc.RandomSeed(); got != 0 {
+		t.Errorf("scenario() seed = %d, want 0", got)
+	}
+
+	var b bytes.Buffer
+	if err := WriteSweepCSV(&b, results[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Split(b.String(), "\n")[1], ",,,,0,0,"; !strings.HasPrefix(got, want) {
+		t.Errorf("WriteSweepCSV() row = %q, want a prefix of %q", got, want)
+	}
+}
+
+func TestSweep_Run_generated(t *testing.T) {
+	s := &Sweep{
+		Protocols: []string{Flooding},
+		Nodes:     []int{4, 6},
+		Generator: &GeneratorConfig{Shape: Ring, MessageDelay: 5},
+		Seeds:     []int64{1},
+	}
+	results, err := s.Run()
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			t.Fatalf("Run() %+v error = %v", r.SweepPoint, r.Err)
+		}
+		if r.Metrics.DataOriginated != r.Nodes {
+			t.Errorf("Run() %+v originated %d data, want %d", r.SweepPoint, r.Metrics.DataOriginated, r.Nodes)
+		}
+	}
+
+	s.Generator.Shape = Grid
+	if _, err := s.Run(); err == nil {
+		t.Errorf("Run() error = nil for node counts of a grid, want error")
+	}
+}
+
+func TestWriteSweepCSV(t *testing.T) {
+	results := []SweepResult{
+		{
+			SweepPoint: SweepPoint{Protocol: OLSR, HelloInterval: 2, Loss: loss(0.1), Seed: seed(1)},
+			Metrics:    controller.Metrics{Ticks: 10, Sent: map[string]int{"HELLO": 6, "DATA": 2}, DataOriginated: 2, DataDelivered: 1, Latency: 3},
+		},
+		{
+			SweepPoint: SweepPoint{Protocol: OLSR, HelloInterval: 20, Seed: seed(1)},
+			Err:        errors.New("invalid params"),
+		},
+	}
+	want := "protocol,hello interval,hold time,nodes,loss,seed,ticks,control sent,total sent,data originated,data delivered,delivery ratio,mean latency,error\n" +
+		"olsr,2,,,0.1,1,10,6,8,2,1,0.5000,3.0000,\n" +
+		"olsr,20,,,,1,,,,,,,,invalid params\n"
+	var b bytes.Buffer
+	if err := WriteSweepCSV(&b, results); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("WriteSweepCSV() got =\n%s\nwant =\n%s", got, want)
+	}
+}
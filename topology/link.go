@@ -0,0 +1,251 @@
+package topology
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kprusa/olsrsim/message"
+)
+
+// LinkStatus represents whether a link is available or not.
+type LinkStatus string
+
+const (
+	// UP represents a link that is available.
+	UP LinkStatus = "UP"
+
+	// DOWN represents a link that is unavailable.
+	DOWN = "DOWN"
+)
+
+// LinkState represents a link's state at a given moment in time.
+type LinkState struct {
+	// Time is the moment in time, inclusive, this state becomes valid.
+	Time int
+
+	// Status is the status of the link.
+	Status LinkStatus
+
+	// From is the source Node ID.
+	From message.NodeID
+
+	// To is the destination Node ID.
+	To message.NodeID
+
+	// Attrs are the properties of the link while it is UP.
+	Attrs LinkAttributes
+}
+
+// LinkAttributes are the properties of a link while it is UP.
+type LinkAttributes struct {
+	// Loss is the probability, within [0, 1], of a message sent over the link being lost.
+	Loss float64
+
+	// Delay is the number of additional ticks a message takes to traverse the link.
+	Delay int
+}
+
+func (a LinkAttributes) String() string {
+	var attrs []string
+	if a.Loss > 0 {
+		attrs = append(attrs, "loss="+strconv.FormatFloat(a.Loss, 'g', -1, 64))
+	}
+	if a.Delay > 0 {
+		attrs = append(attrs, "delay="+strconv.Itoa(a.Delay))
+	}
+	return strings.Join(attrs, " ")
+}
+
+func (l *LinkState) String() string {
+	s := fmt.Sprintf("%d %s %d %d", l.Time, l.Status, l.From, l.To)
+	if attrs := l.Attrs.String(); attrs != "" {
+		s += " " + attrs
+	}
+	return s
+}
+
+// ParseLine parses a line of a topology file into the link states it describes.
+// Lines have the form: {TIME | START-END} {UP | DOWN} {FROM} {TO | <-> TO} [BIDIR] [loss={P}] [delay={TICKS}]
+//
+// Everything following a '#' is a comment, and blank lines describe no link states. A time range implies the
+// opposite transition at its end, and '<->' or BIDIR describe both directions of the link.
+func ParseLine(line string) ([]LinkState, error) {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	if len(fields) < 4 {
+		return nil, ErrParseLinkState{msg: "must be of the form: '{TIME} {UP | DOWN} {LABEL} {LABEL}'"}
+	}
+
+	// Separate the time range, which parseLinkState does not understand.
+	rawTime, end, err := splitRange(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	bidir := false
+	ids := fields[2:]
+	if len(ids) >= 3 && ids[1] == "<->" {
+		bidir = true
+		ids = append([]string{ids[0]}, ids[2:]...)
+	}
+	rest := ids[2:]
+	ids = ids[:2]
+
+	var attrs LinkAttributes
+	for _, f := range rest {
+		key, value, found := strings.Cut(f, "=")
+		switch {
+		case f == "BIDIR":
+			bidir = true
+		case found && key == "loss":
+			loss, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(loss) || loss < 0 || loss > 1 {
+				return nil, ErrParseLinkState{msg: fmt.Sprintf("invalid loss: '%s': must be within [0, 1]", value)}
+			}
+			attrs.Loss = loss
+		case found && key == "delay":
+			delay, err := strconv.Atoi(value)
+			if err != nil || delay < 0 {
+				return nil, ErrParseLinkState{msg: fmt.Sprintf("invalid delay: '%s': must be a non-negative integer", value)}
+			}
+			attrs.Delay = delay
+		default:
+			return nil, ErrParseLinkState{msg: fmt.Sprintf("invalid attribute: '%s': must be {BIDIR | loss={P} | delay={TICKS}}", f)}
+		}
+	}
+
+	ls, err := parseLinkState(strings.Join([]string{rawTime, fields[1], ids[0], ids[1]}, " "))
+	if err != nil {
+		return nil, err
+	}
+	ls.Attrs = attrs
+
+	states := []LinkState{*ls}
+	if end >= 0 {
+		if end <= ls.Time {
+			return nil, ErrParseLinkState{msg: fmt.Sprintf("range end must be after its start: '%s'", fields[0])}
+		}
+		opposite := *ls
+		opposite.Time = end
+		if ls.Status == UP {
+			opposite.Status = DOWN
+			opposite.Attrs = LinkAttributes{}
+		} else {
+			opposite.Status = UP
+		}
+		states = append(states, opposite)
+	}
+	if bidir {
+		for _, s := range states {
+			s.From, s.To = s.To, s.From
+			states = append(states, s)
+		}
+	}
+	return states, nil
+}
+
+// splitRange splits a time, or time range, into its start and its end. The end is -1 if it is not a range.
+func splitRange(raw string) (string, int, error) {
+	i := strings.IndexByte(raw[1:], '-')
+	if i < 0 {
+		return raw, -1, nil
+	}
+	rawEnd := raw[i+2:]
+	end, err := strconv.Atoi(rawEnd)
+	if err != nil {
+		return "", 0, ErrParseLinkState{msg: fmt.Sprintf("range end is not an integer: '%s'", rawEnd)}
+	}
+	return raw[:i+1], end, nil
+}
+
+func parseLinkState(state string) (*LinkState, error) {
+	ls := &LinkState{}
+
+	// Basic validation
+	splitState := strings.Split(state, " ")
+	if len(splitState) != 4 {
+		return nil, ErrParseLinkState{msg: "must be of the form: '{TIME} {UP | DOWN} {LABEL} {LABEL}'"}
+	}
+
+	// Parse time
+	time, err := strconv.Atoi(splitState[0])
+	if err != nil {
+		return nil, ErrParseLinkState{msg: fmt.Sprintf("time is not an integer: '%s'", splitState[0])}
+	}
+	if time < 0 {
+		return nil, ErrParseLinkState{msg: fmt.Sprintf("time must be greater than 0: '%s'", splitState[0])}
+	}
+	ls.Time = time
+
+	// Parse status
+	switch LinkStatus(splitState[1]) {
+	case UP:
+		ls.Status = UP
+	case DOWN:
+		ls.Status = DOWN
+	default:
+		return nil, ErrParseLinkState{msg: fmt.Sprintf("invalid Status: '%s': must be {UP | DOWN}", splitState[1])}
+	}
+
+	// Parse labels
+	lre := regexp.MustCompile(`^\d+$`)
+	if !lre.Match([]byte(splitState[2])) {
+		return nil, ErrParseLinkState{msg: fmt.Sprintf("invalid ID: '%s': must be '^[0-9]+$'", splitState[2])}
+	}
+	if !lre.Match([]byte(splitState[3])) {
+		return nil, ErrParseLinkState{msg: fmt.Sprintf("invalid ID: '%s': must be '^[0-9]+$'", splitState[3])}
+	}
+
+	// The regex ensures each label is made of digits, but it may still be out of range.
+	from, err := strconv.Atoi(splitState[2])
+	if err != nil {
+		return nil, ErrParseLinkState{msg: fmt.Sprintf("invalid ID: '%s': out of range", splitState[2])}
+	}
+	ls.From = message.NodeID(from)
+
+	to, err := strconv.Atoi(splitState[3])
+	if err != nil {
+		return nil, ErrParseLinkState{msg: fmt.Sprintf("invalid ID: '%s': out of range", splitState[3])}
+	}
+	ls.To = message.NodeID(to)
+
+	return ls, nil
+}
+
+type Link struct {
+	// fromNode is the source Node ID.
+	fromNode message.NodeID
+
+	// toNode is the destination Node ID.
+	toNode message.NodeID
+
+	states []LinkState
+}
+
+// isUp determines whether the link is available at the given time.
+func (l *Link) isUp(time int) bool {
+	_, up := l.attributes(time)
+	return up
+}
+
+// attributes returns the link's attributes at the given time, and whether the link is available.
+func (l *Link) attributes(time int) (LinkAttributes, bool) {
+	var current *LinkState
+	for i, state := range l.states {
+		if time >= state.Time {
+			current = &l.states[i]
+		}
+	}
+	if current == nil || current.Status != UP {
+		return LinkAttributes{}, false
+	}
+	return current.Attrs, true
+}
@@ -0,0 +1,418 @@
+package topology
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim/message"
+)
+
+func TestLinkState_String(t *testing.T) {
+	type fields struct {
+		time     int
+		status   LinkStatus
+		fromNode message.NodeID
+		toNode   message.NodeID
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		want   string
+	}{
+		{
+			name: "valid",
+			fields: fields{
+				time:     10,
+				status:   UP,
+				fromNode: 0,
+				toNode:   1,
+			},
+			want: "10 UP 0 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &LinkState{
+				Time:   tt.fields.time,
+				Status: tt.fields.status,
+				From:   tt.fields.fromNode,
+				To:     tt.fields.toNode,
+			}
+			if got := l.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLink_isUp(t *testing.T) {
+	type fields struct {
+		fromNode message.NodeID
+		toNode   message.NodeID
+		states   []LinkState
+	}
+	type args struct {
+		time int
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		want   bool
+	}{
+		{
+			name: "no states",
+			fields: fields{
+				fromNode: 0,
+				toNode:   1,
+				states:   []LinkState{},
+			},
+			args: args{time: 0},
+			want: false,
+		},
+		{
+			name: "is up inclusive",
+			fields: fields{
+				fromNode: 0,
+				toNode:   1,
+				states: []LinkState{
+					{
+						Time:   1,
+						Status: UP,
+						From:   0,
+						To:     1,
+					},
+				},
+			},
+			args: args{time: 1},
+			want: true,
+		},
+		{
+			name: "up then down",
+			fields: fields{
+				fromNode: 0,
+				toNode:   1,
+				states: []LinkState{
+					{
+						Time:   1,
+						Status: UP,
+						From:   0,
+						To:     1,
+					},
+					{
+						Time:   3,
+						Status: DOWN,
+						From:   0,
+						To:     1,
+					},
+				},
+			},
+			args: args{time: 4},
+			want: false,
+		},
+		{
+			name: "down then up",
+			fields: fields{
+				fromNode: 0,
+				toNode:   1,
+				states: []LinkState{
+					{
+						Time:   1,
+						Status: DOWN,
+						From:   0,
+						To:     1,
+					},
+					{
+						Time:   3,
+						Status: UP,
+						From:   0,
+						To:     1,
+					},
+				},
+			},
+			args: args{time: 4},
+			want: true,
+		},
+		{
+			name: "between states",
+			fields: fields{
+				fromNode: 0,
+				toNode:   1,
+				states: []LinkState{
+					{
+						Time:   1,
+						Status: DOWN,
+						From:   0,
+						To:     1,
+					},
+					{
+						Time:   3,
+						Status: UP,
+						From:   0,
+						To:     1,
+					},
+				},
+			},
+			args: args{time: 2},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Link{
+				fromNode: tt.fields.fromNode,
+				toNode:   tt.fields.toNode,
+				states:   tt.fields.states,
+			}
+			if got := l.isUp(tt.args.time); got != tt.want {
+				t.Errorf("isUp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseLinkState(t *testing.T) {
+	type args struct {
+		state string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *LinkState
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			args: args{state: "10 UP 0 1"},
+			want: &LinkState{
+				Time:   10,
+				Status: UP,
+				From:   0,
+				To:     1,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "invalid syntax",
+			args:    args{state: "10UP 0 1"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "invalid time",
+			args:    args{state: "x UP 0 1"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "no negative time",
+			args:    args{state: "-1 UP 0 1"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "invalid status",
+			args:    args{state: "1 x 0 1"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "invalid ID",
+			args:    args{state: "1 UP X 1"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "ID out of range",
+			args:    args{state: "0 UP 0 10000000000000000000"},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLinkState(tt.args.state)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseLinkState() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLinkState() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseLine(t *testing.T) {
+	type args struct {
+		line string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []LinkState
+		wantErr bool
+	}{
+		{
+			name: "original syntax",
+			args: args{line: "10 UP 0 1"},
+			want: []LinkState{
+				{Time: 10, Status: UP, From: 0, To: 1},
+			},
+		},
+		{
+			name: "blank",
+			args: args{line: "  \t"},
+			want: nil,
+		},
+		{
+			name: "comment",
+			args: args{line: "# 10 UP 0 1"},
+			want: nil,
+		},
+		{
+			name: "whitespace and trailing comment",
+			args: args{line: " 10\tUP  0 1\r # up"},
+			want: []LinkState{
+				{Time: 10, Status: UP, From: 0, To: 1},
+			},
+		},
+		{
+			name: "bidirectional arrow",
+			args: args{line: "10 DOWN 0 <-> 1"},
+			want: []LinkState{
+				{Time: 10, Status: DOWN, From: 0, To: 1},
+				{Time: 10, Status: DOWN, From: 1, To: 0},
+			},
+		},
+		{
+			name: "bidirectional keyword",
+			args: args{line: "10 UP 0 1 BIDIR"},
+			want: []LinkState{
+				{Time: 10, Status: UP, From: 0, To: 1},
+				{Time: 10, Status: UP, From: 1, To: 0},
+			},
+		},
+		{
+			name: "attributes",
+			args: args{line: "10 UP 0 1 loss=0.5 delay=3"},
+			want: []LinkState{
+				{Time: 10, Status: UP, From: 0, To: 1, Attrs: LinkAttributes{Loss: 0.5, Delay: 3}},
+			},
+		},
+		{
+			name: "up range",
+			args: args{line: "10-20 UP 0 1 delay=1"},
+			want: []LinkState{
+				{Time: 10, Status: UP, From: 0, To: 1, Attrs: LinkAttributes{Delay: 1}},
+				{Time: 20, Status: DOWN, From: 0, To: 1},
+			},
+		},
+		{
+			name: "bidirectional down range",
+			args: args{line: "10-20 DOWN 0 <-> 1"},
+			want: []LinkState{
+				{Time: 10, Status: DOWN, From: 0, To: 1},
+				{Time: 20, Status: UP, From: 0, To: 1},
+				{Time: 10, Status: DOWN, From: 1, To: 0},
+				{Time: 20, Status: UP, From: 1, To: 0},
+			},
+		},
+		{
+			name:    "empty range",
+			args:    args{line: "20-20 UP 0 1"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid range end",
+			args:    args{line: "10-x UP 0 1"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid loss",
+			args:    args{line: "10 UP 0 1 loss=2"},
+			wantErr: true,
+		},
+		{
+			name:    "loss not a number",
+			args:    args{line: "10 UP 0 1 loss=NaN"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown attribute",
+			args:    args{line: "10 UP 0 1 jitter=2"},
+			wantErr: true,
+		},
+		{
+			name:    "missing ID",
+			args:    args{line: "10 UP 0 <->"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLine(tt.args.line)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseLine() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLine() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzParseLinkState(f *testing.F) {
+	for _, seed := range []string{"10 UP 0 1", "0 DOWN 12 3", "10UP 0 1", "-1 UP 0 1", "1 UP X 1", "1 UP 007 1"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, state string) {
+		ls, err := parseLinkState(state)
+		if err != nil {
+			return
+		}
+		// Labels hold the IDs they spell out.
+		fields := strings.Split(state, " ")
+		for i, id := range []message.NodeID{ls.From, ls.To} {
+			label := strings.TrimLeft(fields[2+i], "0")
+			if label == "" {
+				label = "0"
+			}
+			if got := strconv.Itoa(int(id)); got != label {
+				t.Fatalf("parseLinkState(%q) parsed ID %s, want %s", state, got, label)
+			}
+		}
+		again, err := parseLinkState(ls.String())
+		if err != nil {
+			t.Fatalf("parseLinkState(%q) error = %v, parsing the String() of %q", ls.String(), err, state)
+		}
+		if !reflect.DeepEqual(again, ls) {
+			t.Fatalf("parseLinkState(%q) got = %v, want %v", ls.String(), again, ls)
+		}
+	})
+}
+
+func FuzzParseLine(f *testing.F) {
+	for _, seed := range []string{"10 UP 0 1", " 10\tUP  0 1\r # up", "10-20 DOWN 0 <-> 1", "10 UP 0 1 BIDIR loss=0.5 delay=3", "10-x UP 0 1", "10 UP 0 1 loss=NaN"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		states, err := ParseLine(line)
+		if err != nil {
+			return
+		}
+		// Each state is written as a line of its own.
+		for _, ls := range states {
+			again, err := ParseLine(ls.String())
+			if err != nil {
+				t.Fatalf("ParseLine(%q) error = %v, parsing a state of %q", ls.String(), err, line)
+			}
+			if want := []LinkState{ls}; !reflect.DeepEqual(again, want) {
+				t.Fatalf("ParseLine(%q) got = %v, want %v", ls.String(), again, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package topology
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kprusa/olsrsim/message"
+)
+
+// NodeEvent is a node going DOWN, as if it crashed, or coming back UP, as if it restarted, at a moment in time.
+type NodeEvent struct {
+	// Time is the moment in time, inclusive, the node changes state.
+	Time int
+
+	// Status is UP for a node restarting, and DOWN for a node crashing.
+	Status LinkStatus
+
+	Node message.NodeID
+
+	// Persist restarts a node coming UP with the sequence numbers it had when it went DOWN, rather than with none.
+	Persist bool
+}
+
+func (e NodeEvent) String() string {
+	s := fmt.Sprintf("%d NODE %s %d", e.Time, e.Status, e.Node)
+	if e.Persist {
+		s += " PERSIST"
+	}
+	return s
+}
+
+// nodeIDRe matches a node ID.
+var nodeIDRe = regexp.MustCompile(`^\d+$`)
+
+// ParseNodeLine parses a line of a topology file into the node events it describes, reporting whether the line
+// describes nodes at all rather than links. Lines have the form: {TIME | START-END} NODE {UP | DOWN} {ID} [PERSIST]
+//
+// A time range implies the opposite transition at its end. PERSIST keeps the node's sequence numbers across the
+// restart, and only applies to lines bringing the node UP.
+func ParseNodeLine(line string) ([]NodeEvent, bool, error) {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[1] != "NODE" {
+		return nil, false, nil
+	}
+	if len(fields) < 4 || len(fields) > 5 {
+		return nil, true, ErrParseLinkState{msg: "must be of the form: '{TIME} NODE {UP | DOWN} {ID} [PERSIST]'"}
+	}
+
+	rawTime, end, err := splitRange(fields[0])
+	if err != nil {
+		return nil, true, err
+	}
+	start, err := strconv.Atoi(rawTime)
+	if err != nil || start < 0 {
+		return nil, true, ErrParseLinkState{msg: fmt.Sprintf("invalid time: '%s': must be a non-negative integer", rawTime)}
+	}
+	e := NodeEvent{Time: start}
+
+	switch LinkStatus(fields[2]) {
+	case UP:
+		e.Status = UP
+	case DOWN:
+		e.Status = DOWN
+	default:
+		return nil, true, ErrParseLinkState{msg: fmt.Sprintf("invalid Status: '%s': must be {UP | DOWN}", fields[2])}
+	}
+
+	id, err := strconv.Atoi(fields[3])
+	if !nodeIDRe.MatchString(fields[3]) || err != nil {
+		return nil, true, ErrParseLinkState{msg: fmt.Sprintf("invalid ID: '%s': must be '^[0-9]+$'", fields[3])}
+	}
+	e.Node = message.NodeID(id)
+
+	events := []NodeEvent{e}
+	if end >= 0 {
+		if end <= start {
+			return nil, true, ErrParseLinkState{msg: fmt.Sprintf("range end must be after its start: '%s'", fields[0])}
+		}
+		opposite := e
+		opposite.Time = end
+		opposite.Status = UP
+		if e.Status == UP {
+			opposite.Status = DOWN
+		}
+		events = append(events, opposite)
+	}
+
+	if len(fields) == 5 {
+		if fields[4] != "PERSIST" {
+			return nil, true, ErrParseLinkState{msg: fmt.Sprintf("invalid attribute: '%s': must be PERSIST", fields[4])}
+		}
+		persisted := false
+		for i := range events {
+			if events[i].Status == UP {
+				events[i].Persist = true
+				persisted = true
+			}
+		}
+		if !persisted {
+			return nil, true, ErrParseLinkState{msg: "PERSIST only applies to a node coming UP"}
+		}
+	}
+	return events, true, nil
+}
@@ -0,0 +1,114 @@
+package topology
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNodeEvent_String(t *testing.T) {
+	tests := []struct {
+		name string
+		e    NodeEvent
+		want string
+	}{
+		{name: "down", e: NodeEvent{Time: 10, Status: DOWN, Node: 3}, want: "10 NODE DOWN 3"},
+		{name: "persist", e: NodeEvent{Time: 20, Status: UP, Node: 3, Persist: true}, want: "20 NODE UP 3 PERSIST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.e.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNodeLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		want     []NodeEvent
+		wantNode bool
+		wantErr  bool
+	}{
+		{name: "link", line: "10 UP 0 1"},
+		{name: "comment", line: "# 10 NODE DOWN 1"},
+		{
+			name:     "down",
+			line:     " 10\tNODE DOWN 1 # crash",
+			want:     []NodeEvent{{Time: 10, Status: DOWN, Node: 1}},
+			wantNode: true,
+		},
+		{
+			name:     "down range",
+			line:     "10-20 NODE DOWN 1",
+			want:     []NodeEvent{{Time: 10, Status: DOWN, Node: 1}, {Time: 20, Status: UP, Node: 1}},
+			wantNode: true,
+		},
+		{
+			name:     "down range persist",
+			line:     "10-20 NODE DOWN 1 PERSIST",
+			want:     []NodeEvent{{Time: 10, Status: DOWN, Node: 1}, {Time: 20, Status: UP, Node: 1, Persist: true}},
+			wantNode: true,
+		},
+		{
+			name:     "up persist",
+			line:     "20 NODE UP 1 PERSIST",
+			want:     []NodeEvent{{Time: 20, Status: UP, Node: 1, Persist: true}},
+			wantNode: true,
+		},
+		{name: "persist down", line: "10 NODE DOWN 1 PERSIST", wantNode: true, wantErr: true},
+		{name: "bad attribute", line: "10 NODE DOWN 1 KEEP", wantNode: true, wantErr: true},
+		{name: "bad status", line: "10 NODE CRASH 1", wantNode: true, wantErr: true},
+		{name: "bad ID", line: "10 NODE DOWN -1", wantNode: true, wantErr: true},
+		{name: "bad time", line: "x NODE DOWN 1", wantNode: true, wantErr: true},
+		{name: "empty range", line: "10-10 NODE DOWN 1", wantNode: true, wantErr: true},
+		{name: "missing ID", line: "10 NODE DOWN", wantNode: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, node, err := ParseNodeLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseNodeLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if node != tt.wantNode {
+				t.Errorf("ParseNodeLine() node = %v, want %v", node, tt.wantNode)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseNodeLine() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopology_NodeEvents(t *testing.T) {
+	n, err := Read(strings.NewReader("0 UP 0 <-> 1\n10-20 NODE DOWN 1\n30 NODE DOWN 0\n30 DOWN 0 <-> 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		time int
+		want []NodeEvent
+	}{
+		{name: "none", time: 0, want: nil},
+		{name: "down", time: 10, want: []NodeEvent{{Time: 10, Status: DOWN, Node: 1}}},
+		{name: "implied up", time: 20, want: []NodeEvent{{Time: 20, Status: UP, Node: 1}}},
+		{name: "with links", time: 30, want: []NodeEvent{{Time: 30, Status: DOWN, Node: 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.NodeEvents(tt.time); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NodeEvents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if !n.Query(QueryMsg{FromNode: 1, ToNode: 0, AtTime: 15}) {
+		t.Errorf("Query() = false while the node is down, want the link to stay up")
+	}
+
+	if _, err := Read(strings.NewReader("0 UP 0 <-> 1\n10 NODE DOWN 1\n5 UP 1 2\n")); err == nil {
+		t.Errorf("Read() error = nil for node and link lines out of order, want error")
+	}
+}
@@ -0,0 +1,100 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kprusa/olsrsim/message"
+)
+
+// Split divides the network into named partitions at a moment in time: every link between nodes of different
+// partitions is DOWN until the partitions merge back, when links return to the state the topology gives them. Links of
+// nodes which belong to no partition are unaffected.
+type Split struct {
+	// Time is the moment in time, inclusive, the network splits.
+	Time int
+
+	// Merge, if positive, is the moment in time, inclusive, the partitions merge back. They never do otherwise.
+	Merge int
+
+	// Partitions holds the nodes of each partition, by name.
+	Partitions map[string][]message.NodeID
+}
+
+// active reports whether the split is in effect at time.
+func (s Split) active(time int) bool {
+	return time >= s.Time && (s.Merge <= 0 || time < s.Merge)
+}
+
+// partition returns the name of the partition a node belongs to, if any.
+func (s Split) partition(id message.NodeID) (string, bool) {
+	for name, nodes := range s.Partitions {
+		for _, n := range nodes {
+			if n == id {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Separates reports whether two nodes belong to different partitions of the split.
+func (s Split) Separates(a, b message.NodeID) bool {
+	pa, ina := s.partition(a)
+	pb, inb := s.partition(b)
+	return ina && inb && pa != pb
+}
+
+// Validate checks the split has at least two partitions, no node belongs to more than one, and that it merges after
+// it splits.
+func (s Split) Validate() error {
+	if s.Time < 0 || s.Merge < 0 {
+		return fmt.Errorf("split at %d: times must not be negative", s.Time)
+	}
+	if s.Merge > 0 && s.Merge <= s.Time {
+		return fmt.Errorf("split at %d: must merge after it splits, not at %d", s.Time, s.Merge)
+	}
+	if len(s.Partitions) < 2 {
+		return fmt.Errorf("split at %d: at least two partitions must be given", s.Time)
+	}
+	names := make([]string, 0, len(s.Partitions))
+	for name := range s.Partitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	seen := make(map[message.NodeID]string)
+	for _, name := range names {
+		for _, id := range s.Partitions[name] {
+			if prev, in := seen[id]; in {
+				return fmt.Errorf("split at %d: node %d belongs to both %s and %s", s.Time, id, prev, name)
+			}
+			seen[id] = name
+		}
+	}
+	return nil
+}
+
+// Split adds a split of the network. Links are DOWN while any split separates their nodes.
+func (n *Topology) Split(s Split) {
+	n.splits = append(n.splits, s)
+}
+
+// cut reports whether a split separates the nodes of a link at time.
+func (n *Topology) cut(from, to message.NodeID, time int) bool {
+	for _, s := range n.splits {
+		if s.active(time) && s.Separates(from, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitChanges reports whether a split separating the nodes of a link starts or ends at time.
+func (n *Topology) splitChanges(from, to message.NodeID, time int) bool {
+	for _, s := range n.splits {
+		if (time == s.Time || (s.Merge > 0 && time == s.Merge)) && s.Separates(from, to) {
+			return true
+		}
+	}
+	return false
+}
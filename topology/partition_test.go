@@ -0,0 +1,85 @@
+package topology
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim/message"
+)
+
+func TestSplit_Validate(t *testing.T) {
+	halves := map[string][]message.NodeID{"west": {0, 1}, "east": {2, 3}}
+	tests := []struct {
+		name    string
+		split   Split
+		wantErr bool
+	}{
+		{name: "valid", split: Split{Time: 10, Merge: 20, Partitions: halves}},
+		{name: "never merges", split: Split{Time: 10, Partitions: halves}},
+		{name: "merges before splitting", split: Split{Time: 10, Merge: 10, Partitions: halves}, wantErr: true},
+		{name: "negative time", split: Split{Time: -1, Partitions: halves}, wantErr: true},
+		{name: "single partition", split: Split{Time: 10, Partitions: map[string][]message.NodeID{"all": {0, 1}}}, wantErr: true},
+		{
+			name:    "node in two partitions",
+			split:   Split{Time: 10, Partitions: map[string][]message.NodeID{"west": {0, 1}, "east": {1, 2}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.split.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTopology_Split(t *testing.T) {
+	// 2 belongs to no partition, and 0 -> 3 goes DOWN then back UP while the network is split.
+	n, err := Read(strings.NewReader("0 UP 0 <-> 1\n0 UP 0 <-> 2\n0 UP 0 3 delay=1\n0 UP 2 3\n12 DOWN 0 3\n15 UP 0 3 delay=2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Split(Split{Time: 10, Merge: 20, Partitions: map[string][]message.NodeID{"west": {0, 1}, "east": {3}}})
+
+	queries := []struct {
+		name string
+		msg  QueryMsg
+		want bool
+	}{
+		{name: "before split", msg: QueryMsg{FromNode: 0, ToNode: 3, AtTime: 9}, want: true},
+		{name: "split", msg: QueryMsg{FromNode: 0, ToNode: 3, AtTime: 10}, want: false},
+		{name: "set UP while split", msg: QueryMsg{FromNode: 0, ToNode: 3, AtTime: 16}, want: false},
+		{name: "same partition", msg: QueryMsg{FromNode: 0, ToNode: 1, AtTime: 10}, want: true},
+		{name: "no partition", msg: QueryMsg{FromNode: 2, ToNode: 3, AtTime: 10}, want: true},
+		{name: "merged", msg: QueryMsg{FromNode: 0, ToNode: 3, AtTime: 20}, want: true},
+	}
+	for _, tt := range queries {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.Query(tt.msg); got != tt.want {
+				t.Errorf("Query() = %v, want %v", got, tt.want)
+			}
+			if _, got := n.Link(tt.msg); got != tt.want {
+				t.Errorf("Link() up = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	changes := []struct {
+		name string
+		time int
+		want []LinkState
+	}{
+		{name: "split", time: 10, want: []LinkState{{Time: 10, Status: DOWN, From: 0, To: 3}}},
+		{name: "set while split", time: 15, want: nil},
+		{name: "merge", time: 20, want: []LinkState{{Time: 20, Status: UP, From: 0, To: 3, Attrs: LinkAttributes{Delay: 2}}}},
+	}
+	for _, tt := range changes {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.Changes(tt.time); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Changes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+// Package topology models the links of the simulated ad-hoc network, and how they change over time.
+package topology
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/kprusa/olsrsim/message"
+)
+
+// QueryMsg enables the Controller to query the Topology to determine the state of a link at a given moment
+// in time.
+type QueryMsg struct {
+	// FromNode is the source of the link.
+	FromNode message.NodeID
+
+	// ToNode is the destination of the link.
+	ToNode message.NodeID
+
+	// AtTime is the moment in time to check the status of the link.
+	AtTime int
+}
+
+// Topology represents the ad-hoc network topology and is used by the Controller.
+type Topology struct {
+	links map[message.NodeID]map[message.NodeID]Link
+
+	// nodes holds the nodes going DOWN and coming back UP, sorted by time.
+	nodes []NodeEvent
+
+	// splits holds the splits of the network into partitions.
+	splits []Split
+}
+
+// ErrParseLinkState is returned when a line of a topology file cannot be parsed.
+type ErrParseLinkState struct {
+	// line is the 1-based line number of the offending line, or 0 if unknown.
+	line int
+
+	msg string
+}
+
+func (e ErrParseLinkState) Error() string {
+	if e.line > 0 {
+		return fmt.Sprintf("parse link state: line %d: %s", e.line, e.msg)
+	}
+	return fmt.Sprintf("parse link state: %s", e.msg)
+}
+
+// Read parses newline separated link states, and node events, from an io.Reader.
+// Link states should be in the form: {TIME} {UP | DOWN} {FROM} {TO}, sorted by increasing time. See ParseLine for the
+// full syntax, and ParseNodeLine for that of node events.
+func Read(in io.Reader) (*Topology, error) {
+	n := &Topology{}
+	n.links = make(map[message.NodeID]map[message.NodeID]Link)
+
+	states, nodes, err := readLinkStates(in)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the time of every explicit entry, in the order they were described.
+	type entry struct {
+		time, line int
+	}
+	var entries []entry
+	for _, s := range states {
+		if !s.implied {
+			entries = append(entries, entry{time: s.Time, line: s.line})
+		}
+	}
+	for _, e := range nodes {
+		if !e.implied {
+			entries = append(entries, entry{time: e.Time, line: e.line})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].line < entries[j].line
+	})
+	currTime := 0
+	for _, e := range entries {
+		if e.time < currTime {
+			return nil, fmt.Errorf("line %d: entries in input must be sorted by increasing time", e.line)
+		}
+		currTime = e.time
+	}
+
+	for _, s := range sortLinkStates(states) {
+		n.addLinkState(s.LinkState)
+	}
+	for _, e := range nodes {
+		n.nodes = append(n.nodes, e.NodeEvent)
+	}
+	sort.SliceStable(n.nodes, func(i, j int) bool {
+		return n.nodes[i].Time < n.nodes[j].Time
+	})
+
+	return n, nil
+}
+
+// New creates a Topology from link states, which need not be sorted.
+// Link states with the same time take effect in the order they are given.
+func New(states []LinkState) *Topology {
+	n := &Topology{}
+	n.links = make(map[message.NodeID]map[message.NodeID]Link)
+
+	sorted := make([]LinkState, len(states))
+	copy(sorted, states)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time < sorted[j].Time
+	})
+	for _, s := range sorted {
+		n.addLinkState(s)
+	}
+	return n
+}
+
+// numberedLinkState is a LinkState along with the line of the topology file that described it.
+type numberedLinkState struct {
+	LinkState
+
+	// line is the 1-based line number of the describing line.
+	line int
+
+	// implied is set for states that are implied by the end of a time range, rather than explicitly described.
+	implied bool
+}
+
+// numberedNodeEvent is a NodeEvent along with the line of the topology file that described it.
+type numberedNodeEvent struct {
+	NodeEvent
+
+	// line is the 1-based line number of the describing line.
+	line int
+
+	// implied is set for events that are implied by the end of a time range, rather than explicitly described.
+	implied bool
+}
+
+// readLinkStates parses all link states and node events from in, in the order they were described.
+func readLinkStates(in io.Reader) ([]numberedLinkState, []numberedNodeEvent, error) {
+	var states []numberedLinkState
+	var nodes []numberedNodeEvent
+	err := readLines(in, func(num int, line string) error {
+		withLine := func(err error) error {
+			var perr ErrParseLinkState
+			if errors.As(err, &perr) {
+				perr.line = num
+				return perr
+			}
+			return err
+		}
+		events, isNode, err := ParseNodeLine(line)
+		if err != nil {
+			return withLine(err)
+		}
+		if isNode {
+			for _, e := range events {
+				nodes = append(nodes, numberedNodeEvent{NodeEvent: e, line: num, implied: e.Time != events[0].Time})
+			}
+			return nil
+		}
+
+		ls, err := ParseLine(line)
+		if err != nil {
+			return withLine(err)
+		}
+		for _, s := range ls {
+			states = append(states, numberedLinkState{LinkState: s, line: num, implied: s.Time != ls[0].Time})
+		}
+		return nil
+	})
+	return states, nodes, err
+}
+
+// sortLinkStates returns the explicit link states, merged with those implied by time ranges, sorted by time.
+// Link states with the same time remain in the order they were described.
+func sortLinkStates(states []numberedLinkState) []numberedLinkState {
+	sorted := make([]numberedLinkState, len(states))
+	copy(sorted, states)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time < sorted[j].Time
+	})
+	return sorted
+}
+
+// addLinkState adds the new LinkState to the applicable link. If there is not a link, one is created.
+func (n *Topology) addLinkState(ls LinkState) {
+	dsts, in := n.links[ls.From]
+	if !in {
+		dsts = make(map[message.NodeID]Link)
+		n.links[ls.From] = dsts
+	}
+	link, in := dsts[ls.To]
+	if !in {
+		link = Link{fromNode: ls.From, toNode: ls.To}
+	}
+	link.states = append(link.states, ls)
+	dsts[ls.To] = link
+}
+
+// readLines calls fn with each line, and its 1-based line number, read from in.
+func readLines(in io.Reader, fn func(num int, line string) error) error {
+	s := bufio.NewScanner(in)
+	num := 0
+	for s.Scan() {
+		num++
+		if err := fn(num, s.Text()); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// Query enables to Controller to determine the current link-state at a time quantum.
+func (n *Topology) Query(msg QueryMsg) bool {
+	links, in := n.links[msg.FromNode]
+	if !in {
+		return false
+	}
+
+	link, in := links[msg.ToNode]
+	if !in {
+		return false
+	}
+
+	return link.isUp(msg.AtTime) && !n.cut(msg.FromNode, msg.ToNode, msg.AtTime)
+}
+
+// Link enables the Controller to determine the attributes of a link at a time quantum, and whether it is UP.
+func (n *Topology) Link(msg QueryMsg) (LinkAttributes, bool) {
+	link, in := n.links[msg.FromNode][msg.ToNode]
+	if !in || n.cut(msg.FromNode, msg.ToNode, msg.AtTime) {
+		return LinkAttributes{}, false
+	}
+	return link.attributes(msg.AtTime)
+}
+
+// Set changes the state of a link from ls.Time onward, until the next transition already scheduled for the link.
+// Link states with the same time take effect in the order they are set.
+func (n *Topology) Set(ls LinkState) {
+	dsts, in := n.links[ls.From]
+	if !in {
+		dsts = make(map[message.NodeID]Link)
+		n.links[ls.From] = dsts
+	}
+	link, in := dsts[ls.To]
+	if !in {
+		link = Link{fromNode: ls.From, toNode: ls.To}
+	}
+	i := sort.Search(len(link.states), func(i int) bool {
+		return link.states[i].Time > ls.Time
+	})
+	link.states = append(link.states, LinkState{})
+	copy(link.states[i+1:], link.states[i:])
+	link.states[i] = ls
+	dsts[ls.To] = link
+}
+
+// Changes returns the state each link takes at the given time, for the links whose state is set at that time, ordered
+// by source then destination. Links separated by a split are DOWN: they change state as the network splits and merges,
+// if they are UP otherwise, and not as their state is set in the meantime.
+func (n *Topology) Changes(time int) []LinkState {
+	var changes []LinkState
+	for _, from := range sortedIDs(n.links) {
+		dsts := n.links[from]
+		for _, to := range sortedIDs(dsts) {
+			link := dsts[to]
+			var current *LinkState
+			for i, state := range link.states {
+				if state.Time == time {
+					current = &link.states[i]
+				}
+			}
+			cut := n.cut(from, to, time)
+			switch {
+			case n.splitChanges(from, to, time) && cut != n.cut(from, to, time-1):
+				if cut {
+					if link.isUp(time - 1) {
+						changes = append(changes, LinkState{Time: time, Status: DOWN, From: from, To: to})
+					}
+				} else if attrs, up := link.attributes(time); up {
+					changes = append(changes, LinkState{Time: time, Status: UP, From: from, To: to, Attrs: attrs})
+				}
+			case current != nil && !cut:
+				changes = append(changes, *current)
+			}
+		}
+	}
+	return changes
+}
+
+// NodeEvents returns the nodes going DOWN or coming back UP at the given time, in the order they were described.
+func (n *Topology) NodeEvents(time int) []NodeEvent {
+	var events []NodeEvent
+	for _, e := range n.nodes {
+		if e.Time == time {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// sortedIDs returns the keys of m in increasing order.
+func sortedIDs[V any](m map[message.NodeID]V) []message.NodeID {
+	ids := make([]message.NodeID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// SetLoss replaces the loss of every link, while it is UP, with loss.
+func (n *Topology) SetLoss(loss float64) {
+	for _, dsts := range n.links {
+		for _, link := range dsts {
+			for i := range link.states {
+				link.states[i].Attrs.Loss = loss
+			}
+		}
+	}
+}
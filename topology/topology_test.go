@@ -0,0 +1,417 @@
+package topology
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim/message"
+)
+
+func TestNetworkTypology_Query(t *testing.T) {
+	type fields struct {
+		links map[message.NodeID]map[message.NodeID]Link
+	}
+	type args struct {
+		msg QueryMsg
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		want   bool
+	}{
+		{
+			name:   "is up",
+			fields: fields{links: goodTopology().links},
+			args: args{msg: QueryMsg{
+				FromNode: 0,
+				ToNode:   1,
+				AtTime:   10,
+			}},
+			want: true,
+		},
+		{
+			name:   "is down",
+			fields: fields{links: goodTopology().links},
+			args: args{msg: QueryMsg{
+				FromNode: 0,
+				ToNode:   1,
+				AtTime:   20,
+			}},
+			want: false,
+		},
+		{
+			name:   "is up end",
+			fields: fields{links: goodTopology().links},
+			args: args{msg: QueryMsg{
+				FromNode: 2,
+				ToNode:   0,
+				AtTime:   25,
+			}},
+			want: true,
+		},
+		{
+			name:   "ID not in topology",
+			fields: fields{links: goodTopology().links},
+			args: args{msg: QueryMsg{
+				FromNode: 1,
+				ToNode:   0,
+				AtTime:   0,
+			}},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &Topology{
+				links: tt.fields.links,
+			}
+			if got := n.Query(tt.args.msg); got != tt.want {
+				t.Errorf("Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func getTestData(p string) io.ReadCloser {
+	f, err := os.Open(p)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func goodTopologyReadyCloser() io.ReadCloser {
+	return getTestData("../testdata/good_topology.txt")
+}
+
+func badTopologyReadCloser() io.ReadCloser {
+	return getTestData("../testdata/bad_topology_order.txt")
+}
+
+func goodTopology() *Topology {
+	t, err := Read(goodTopologyReadyCloser())
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNewNetworkTypology(t *testing.T) {
+	type args struct {
+		in io.ReadCloser
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *Topology
+		wantErr bool
+	}{
+		{
+			name: "good topology",
+			args: args{in: goodTopologyReadyCloser()},
+			want: &Topology{
+				links: map[message.NodeID]map[message.NodeID]Link{
+					0: {
+						1: {
+							fromNode: 0,
+							toNode:   1,
+							states: []LinkState{
+								{
+									Time:   10,
+									Status: UP,
+									From:   0,
+									To:     1,
+								},
+								{
+									Time:   20,
+									Status: DOWN,
+									From:   0,
+									To:     1,
+								},
+							},
+						},
+						2: {
+							fromNode: 0,
+							toNode:   2,
+							states: []LinkState{
+								{
+									Time:   21,
+									Status: UP,
+									From:   0,
+									To:     2,
+								},
+							},
+						},
+					},
+					1: {
+						0: {
+							fromNode: 1,
+							toNode:   0,
+							states: []LinkState{
+								{
+									Time:   10,
+									Status: UP,
+									From:   1,
+									To:     0,
+								},
+								{
+									Time:   20,
+									Status: DOWN,
+									From:   1,
+									To:     0,
+								},
+							},
+						},
+					},
+					2: {
+						0: {
+							fromNode: 2,
+							toNode:   0,
+							states: []LinkState{
+								{
+									Time:   25,
+									Status: UP,
+									From:   2,
+									To:     0,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "bad topology",
+			args:    args{in: badTopologyReadCloser()},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Read() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewNetworkTypology_lineNumbers(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr string
+	}{
+		{
+			name:    "syntax",
+			in:      "10 UP 0 1\n10 UP 1\n",
+			wantErr: "parse link state: line 2: must be of the form: '{TIME} {UP | DOWN} {LABEL} {LABEL}'",
+		},
+		{
+			name:    "order",
+			in:      "10 UP 0 1\n10 UP 1 0\n9 DOWN 0 1\n",
+			wantErr: "line 3: entries in input must be sorted by increasing time",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.in))
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Read() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNetworkTypology_Link(t *testing.T) {
+	extended, err := Read(getTestData("../testdata/extended_topology.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	crlf, err := Read(getTestData("../testdata/crlf_topology.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		topology  *Topology
+		msg       QueryMsg
+		wantAttrs LinkAttributes
+		wantUp    bool
+	}{
+		{
+			name:     "bidirectional arrow",
+			topology: extended,
+			msg:      QueryMsg{FromNode: 1, ToNode: 0, AtTime: 0},
+			wantUp:   true,
+		},
+		{
+			name:      "lossy",
+			topology:  extended,
+			msg:       QueryMsg{FromNode: 2, ToNode: 1, AtTime: 3},
+			wantAttrs: LinkAttributes{Loss: 0.25},
+			wantUp:    true,
+		},
+		{
+			name:     "before range",
+			topology: extended,
+			msg:      QueryMsg{FromNode: 0, ToNode: 2, AtTime: 4},
+			wantUp:   false,
+		},
+		{
+			name:      "within range",
+			topology:  extended,
+			msg:       QueryMsg{FromNode: 0, ToNode: 2, AtTime: 14},
+			wantAttrs: LinkAttributes{Delay: 2},
+			wantUp:    true,
+		},
+		{
+			name:     "after range",
+			topology: extended,
+			msg:      QueryMsg{FromNode: 0, ToNode: 2, AtTime: 15},
+			wantUp:   false,
+		},
+		{
+			name:     "bidirectional down",
+			topology: extended,
+			msg:      QueryMsg{FromNode: 1, ToNode: 0, AtTime: 20},
+			wantUp:   false,
+		},
+		{
+			name:     "crlf",
+			topology: crlf,
+			msg:      QueryMsg{FromNode: 1, ToNode: 0, AtTime: 10},
+			wantUp:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAttrs, gotUp := tt.topology.Link(tt.msg)
+			if gotAttrs != tt.wantAttrs || gotUp != tt.wantUp {
+				t.Errorf("Link() = %v, %v, want %v, %v", gotAttrs, gotUp, tt.wantAttrs, tt.wantUp)
+			}
+		})
+	}
+}
+
+func TestNewNetworkTypology_ranges(t *testing.T) {
+	// The DOWN implied by the range must not be reported as out of order, and must apply after the explicit UP.
+	n, err := Read(strings.NewReader("0-10 UP 0 1\n5 UP 1 0\n10 UP 0 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.Query(QueryMsg{FromNode: 0, ToNode: 1, AtTime: 12}) {
+		t.Errorf("Query() = false, want true")
+	}
+}
+
+func TestNew(t *testing.T) {
+	// The states are given out of order, and must be sorted to match the good topology.
+	got := New([]LinkState{
+		{Time: 21, Status: UP, From: 0, To: 2},
+		{Time: 10, Status: UP, From: 0, To: 1},
+		{Time: 10, Status: UP, From: 1, To: 0},
+		{Time: 25, Status: UP, From: 2, To: 0},
+		{Time: 20, Status: DOWN, From: 0, To: 1},
+		{Time: 20, Status: DOWN, From: 1, To: 0},
+	})
+	if want := goodTopology(); !reflect.DeepEqual(got, want) {
+		t.Errorf("New() got = %v, want %v", got, want)
+	}
+}
+
+func TestTopology_SetLoss(t *testing.T) {
+	n, err := Read(getTestData("../testdata/extended_topology.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.SetLoss(0.5)
+	for _, msg := range []QueryMsg{{FromNode: 1, ToNode: 0, AtTime: 0}, {FromNode: 2, ToNode: 1, AtTime: 3}, {FromNode: 0, ToNode: 2, AtTime: 14}} {
+		attrs, up := n.Link(msg)
+		if !up || attrs.Loss != 0.5 {
+			t.Errorf("Link(%+v) = %v, %v, want loss 0.5", msg, attrs, up)
+		}
+	}
+	if attrs, _ := n.Link(QueryMsg{FromNode: 0, ToNode: 2, AtTime: 14}); attrs.Delay != 2 {
+		t.Errorf("SetLoss() changed the delay of a link to %d, want 2", attrs.Delay)
+	}
+}
+
+func TestTopology_Set(t *testing.T) {
+	n, err := Read(strings.NewReader("0 UP 0 1\n20 DOWN 0 1\n30 UP 0 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Set(LinkState{Time: 10, Status: DOWN, From: 0, To: 1})
+	n.Set(LinkState{Time: 5, Status: UP, From: 2, To: 0, Attrs: LinkAttributes{Delay: 1}})
+
+	tests := []struct {
+		name string
+		msg  QueryMsg
+		want bool
+	}{
+		{name: "before change", msg: QueryMsg{FromNode: 0, ToNode: 1, AtTime: 9}, want: true},
+		{name: "changed", msg: QueryMsg{FromNode: 0, ToNode: 1, AtTime: 10}, want: false},
+		{name: "until scheduled transition", msg: QueryMsg{FromNode: 0, ToNode: 1, AtTime: 30}, want: true},
+		{name: "new link before", msg: QueryMsg{FromNode: 2, ToNode: 0, AtTime: 4}, want: false},
+		{name: "new link", msg: QueryMsg{FromNode: 2, ToNode: 0, AtTime: 5}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.Query(tt.msg); got != tt.want {
+				t.Errorf("Query() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopology_Changes(t *testing.T) {
+	n, err := Read(strings.NewReader("0 UP 1 <-> 0 loss=0.5\n0-10 UP 0 2\n10 DOWN 1 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Set(LinkState{Time: 10, Status: UP, From: 1, To: 0, Attrs: LinkAttributes{Delay: 2}})
+
+	tests := []struct {
+		name string
+		time int
+		want []LinkState
+	}{
+		{
+			name: "initial",
+			time: 0,
+			want: []LinkState{
+				{Time: 0, Status: UP, From: 0, To: 1, Attrs: LinkAttributes{Loss: 0.5}},
+				{Time: 0, Status: UP, From: 0, To: 2},
+				{Time: 0, Status: UP, From: 1, To: 0, Attrs: LinkAttributes{Loss: 0.5}},
+			},
+		},
+		{
+			name: "last state set wins",
+			time: 10,
+			want: []LinkState{
+				{Time: 10, Status: DOWN, From: 0, To: 2},
+				{Time: 10, Status: UP, From: 1, To: 0, Attrs: LinkAttributes{Delay: 2}},
+			},
+		},
+		{name: "no changes", time: 5, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.Changes(tt.time); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Changes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+// link is a directed link envelopes were delivered along.
+type link struct {
+	from message.NodeID
+	to   message.NodeID
+}
+
+// WriteDOT renders the trace as a Graphviz DOT graph, with an edge for each link envelopes were delivered along
+// between ticks from and to, inclusive. A negative to renders until the end of the trace. Each edge is labelled with
+// the number of envelopes of each type delivered along it, and edges which carried data are highlighted.
+func (t *Trace) WriteDOT(w io.Writer, from, to int) error {
+	nodes := make(map[message.NodeID]bool)
+	for _, id := range t.Nodes {
+		nodes[id] = true
+	}
+	counts := make(map[link]map[string]int)
+	for _, e := range t.Events {
+		if e.Kind != controller.EventReceive || e.Tick < from || (to >= 0 && e.Tick > to) {
+			continue
+		}
+		l := link{from: e.Envelope.From, to: e.Node}
+		nodes[l.from], nodes[l.to] = true, true
+		if counts[l] == nil {
+			counts[l] = make(map[string]int)
+		}
+		counts[l][e.Envelope.Message.Type()]++
+	}
+
+	var b strings.Builder
+	name := t.Name
+	if name == "" {
+		name = t.Protocol
+	}
+	fmt.Fprintf(&b, "digraph %q {\n", name)
+	for _, id := range sortedNodes(nodes) {
+		fmt.Fprintf(&b, "\t%d;\n", id)
+	}
+
+	links := make([]link, 0, len(counts))
+	for l := range counts {
+		links = append(links, l)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].from != links[j].from {
+			return links[i].from < links[j].from
+		}
+		return links[i].to < links[j].to
+	})
+	for _, l := range links {
+		types := make([]string, 0, len(counts[l]))
+		for typ := range counts[l] {
+			types = append(types, typ)
+		}
+		sort.Strings(types)
+		labels := make([]string, 0, len(types))
+		for _, typ := range types {
+			labels = append(labels, fmt.Sprintf("%s %d", typ, counts[l][typ]))
+		}
+		attrs := fmt.Sprintf(`label="%s"`, strings.Join(labels, `\n`))
+		if counts[l]["DATA"] > 0 {
+			attrs += ", color=red, penwidth=2"
+		}
+		fmt.Fprintf(&b, "\t%d -> %d [%s];\n", l.from, l.to, attrs)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// sortedNodes returns the nodes of the set in increasing order.
+func sortedNodes(set map[message.NodeID]bool) []message.NodeID {
+	nodes := make([]message.NodeID, 0, len(set))
+	for id := range set {
+		nodes = append(nodes, id)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i] < nodes[j]
+	})
+	return nodes
+}
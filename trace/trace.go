@@ -0,0 +1,206 @@
+// Package trace records the events of a simulation to a file, and reads them back, so that a run may be analyzed and
+// rendered after the fact.
+//
+// A trace is a stream of JSON values, one per line: a Header describing the run, followed by every controller.Event
+// in the order it happened.
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+// Header describes the run a trace was recorded from.
+type Header struct {
+	// Name describes the run.
+	Name string `json:"name,omitempty"`
+
+	// Protocol is the routing protocol run by every node.
+	Protocol string `json:"protocol"`
+
+	// Seed is the seed of the random source deciding which messages are lost on lossy links.
+	Seed int64 `json:"seed"`
+
+	// Ticks is the number of ticks the run was to last.
+	Ticks int `json:"ticks"`
+
+	// Nodes holds every node taking part in the run.
+	Nodes []message.NodeID `json:"nodes"`
+
+	// Scenario is the scenario run, encoded as a scenario file, so that its nodes may be recreated to replay the
+	// trace. It is empty if the run was not described by a scenario.
+	Scenario json.RawMessage `json:"scenario,omitempty"`
+}
+
+// Writer writes a trace. Writer implements controller.Observer, so that a run may be traced as it happens.
+type Writer struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+
+	// err is the first error writing the trace.
+	err error
+}
+
+// NewWriter creates a Writer, writing the header of the trace to w. The trace must be flushed once the run is over.
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	tw := &Writer{w: bw, enc: json.NewEncoder(bw)}
+	if err := tw.enc.Encode(h); err != nil {
+		return nil, fmt.Errorf("trace: writing header: %w", err)
+	}
+	return tw, nil
+}
+
+// Event writes e to the trace. Once writing fails, further events are discarded, and Flush returns the error.
+func (w *Writer) Event(e controller.Event) {
+	if w.err != nil {
+		return
+	}
+	if err := w.enc.Encode(e); err != nil {
+		w.err = fmt.Errorf("trace: writing %s event at tick %d: %w", e.Kind, e.Tick, err)
+	}
+}
+
+// Flush writes any buffered events, returning the first error writing the trace.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// Trace is a recorded run.
+type Trace struct {
+	Header
+	Events []controller.Event
+}
+
+// Read reads a trace written by a Writer.
+func Read(r io.Reader) (*Trace, error) {
+	d := json.NewDecoder(bufio.NewReader(r))
+	t := &Trace{}
+	if err := d.Decode(&t.Header); err != nil {
+		return nil, fmt.Errorf("trace: reading header: %w", err)
+	}
+	for {
+		var e controller.Event
+		err := d.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			return t, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("trace: reading event %d: %w", len(t.Events)+1, err)
+		}
+		if err := check(e); err != nil {
+			return nil, fmt.Errorf("trace: event %d: %w", len(t.Events)+1, err)
+		}
+		t.Events = append(t.Events, e)
+	}
+}
+
+// check verifies an event holds what its kind requires.
+func check(e controller.Event) error {
+	switch e.Kind {
+	case controller.EventTick, controller.EventJoin, controller.EventLeave:
+	case controller.EventOriginate:
+		if e.Originated == nil {
+			return fmt.Errorf("%s event without data", e.Kind)
+		}
+	case controller.EventSend, controller.EventReceive, controller.EventLost:
+		if e.Envelope == nil {
+			return fmt.Errorf("%s event without envelope", e.Kind)
+		}
+	case controller.EventLink:
+		if e.Link == nil {
+			return fmt.Errorf("%s event without link", e.Kind)
+		}
+	case controller.EventNode:
+		if e.NodeChange == nil {
+			return fmt.Errorf("%s event without node change", e.Kind)
+		}
+	case controller.EventState:
+		if e.State == nil {
+			return fmt.Errorf("%s event without state", e.Kind)
+		}
+	default:
+		return fmt.Errorf("unknown event kind: %s", e.Kind)
+	}
+	return nil
+}
+
+// Metrics summarizes the traffic of the run, exactly as the controller did while it was recorded.
+func (t *Trace) Metrics() controller.Metrics {
+	return controller.ComputeMetrics(t.Events)
+}
+
+// NodeStats summarize the traffic of a single node.
+type NodeStats struct {
+	ID message.NodeID
+
+	// Sent counts the envelopes sent by the node, and Received those delivered to it.
+	Sent     int
+	Received int
+
+	// Lost counts the envelopes to the node which were lost on lossy links.
+	Lost int
+
+	// DataSent counts the DATA envelopes sent by the node, whether it originated or forwarded them.
+	DataSent int
+
+	// DataReceived counts the DATA envelopes delivered to the node as their destination.
+	DataReceived int
+}
+
+// NodeStats summarizes the traffic of every node, sorted by ID. Nodes of the header with no traffic are included.
+func (t *Trace) NodeStats() []NodeStats {
+	stats := make(map[message.NodeID]*NodeStats)
+	node := func(id message.NodeID) *NodeStats {
+		s, in := stats[id]
+		if !in {
+			s = &NodeStats{ID: id}
+			stats[id] = s
+		}
+		return s
+	}
+	for _, id := range t.Nodes {
+		node(id)
+	}
+
+	for _, e := range t.Events {
+		if e.Envelope == nil {
+			continue
+		}
+		dm, isData := e.Envelope.Message.(*message.DataMessage)
+		s := node(e.Node)
+		switch e.Kind {
+		case controller.EventSend:
+			s.Sent++
+			if isData {
+				s.DataSent++
+			}
+		case controller.EventReceive:
+			s.Received++
+			if isData && dm.Destination == e.Node {
+				s.DataReceived++
+			}
+		case controller.EventLost:
+			s.Lost++
+		}
+	}
+
+	sorted := make([]NodeStats, 0, len(stats))
+	for _, s := range stats {
+		sorted = append(sorted, *s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
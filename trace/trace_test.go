@@ -0,0 +1,132 @@
+package trace
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/flooding"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/topology"
+)
+
+// record runs blind flooding over links for ticks, returning the trace of the run and the controller's metrics.
+func record(t *testing.T, links string, ticks int) ([]byte, controller.Metrics) {
+	t.Helper()
+	nwt, err := topology.Read(strings.NewReader(links))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := controller.New(nwt, 0, flooding.Protocol())
+	err = c.Initialize([]controller.NodeConfig{
+		{ID: 0, Message: controller.NodeMessage{Message: "data", Delay: 1, Destination: 2}},
+		{ID: 1, Message: controller.NodeMessage{Sent: true}},
+		{ID: 2, Message: controller.NodeMessage{Sent: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{Protocol: "flooding", Seed: 1, Ticks: ticks, Nodes: []message.NodeID{0, 1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Observe(w)
+	c.Start(ticks)
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	return buf.Bytes(), c.Metrics()
+}
+
+func TestRead(t *testing.T) {
+	data, want := record(t, "0 UP 0 <-> 1\n0 UP 1 <-> 2\n0 UP 1 2 loss=1\n", 5)
+	tr, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	wantHeader := Header{Protocol: "flooding", Seed: 1, Ticks: 5, Nodes: []message.NodeID{0, 1, 2}}
+	if !reflect.DeepEqual(tr.Header, wantHeader) {
+		t.Errorf("Read() header = %+v, want %+v", tr.Header, wantHeader)
+	}
+	if got := tr.Metrics(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Metrics() = %+v, want %+v", got, want)
+	}
+
+	wantStats := []NodeStats{
+		{ID: 0, Sent: 1, Received: 1, DataSent: 1},
+		{ID: 1, Sent: 1, Received: 1, DataSent: 1},
+		{ID: 2, Lost: 1},
+	}
+	if got := tr.NodeStats(); !reflect.DeepEqual(got, wantStats) {
+		t.Errorf("NodeStats() = %+v, want %+v", got, wantStats)
+	}
+}
+
+func TestRead_invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		trace string
+	}{
+		{name: "empty", trace: ""},
+		{name: "invalid event", trace: "{\"protocol\":\"olsr\"}\n{\"tick\":\"0\"}\n"},
+		{name: "unknown kind", trace: "{\"protocol\":\"olsr\"}\n{\"tick\":0,\"kind\":\"other\"}\n"},
+		{name: "send without envelope", trace: "{\"protocol\":\"olsr\"}\n{\"tick\":0,\"kind\":\"send\",\"node\":1}\n"},
+		{name: "link without link", trace: "{\"protocol\":\"olsr\"}\n{\"tick\":0,\"kind\":\"link\",\"node\":1}\n"},
+		{name: "state without state", trace: "{\"protocol\":\"olsr\"}\n{\"tick\":0,\"kind\":\"state\",\"node\":1}\n"},
+		{name: "node without change", trace: "{\"protocol\":\"olsr\"}\n{\"tick\":0,\"kind\":\"node\",\"node\":1}\n"},
+		{name: "unregistered message", trace: "{\"protocol\":\"olsr\"}\n{\"tick\":0,\"kind\":\"send\",\"node\":1,\"envelope\":{\"from\":1,\"to\":2,\"kind\":\"OTHER\",\"message\":{}}}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(strings.NewReader(tt.trace)); err == nil {
+				t.Errorf("Read() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestTrace_WriteDOT(t *testing.T) {
+	data, _ := record(t, "0 UP 0 <-> 1\n0 UP 1 <-> 2\n", 5)
+	tr, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		from int
+		to   int
+		want string
+	}{
+		{
+			name: "whole trace",
+			from: 0,
+			to:   -1,
+			want: "digraph \"flooding\" {\n\t0;\n\t1;\n\t2;\n" +
+				"\t0 -> 1 [label=\"DATA 1\", color=red, penwidth=2];\n" +
+				"\t1 -> 0 [label=\"DATA 1\", color=red, penwidth=2];\n" +
+				"\t1 -> 2 [label=\"DATA 1\", color=red, penwidth=2];\n}\n",
+		},
+		{
+			name: "tick range",
+			from: 2,
+			to:   2,
+			want: "digraph \"flooding\" {\n\t0;\n\t1;\n\t2;\n" +
+				"\t0 -> 1 [label=\"DATA 1\", color=red, penwidth=2];\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := tr.WriteDOT(&b, tt.from, tt.to); err != nil {
+				t.Fatalf("WriteDOT() error = %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("WriteDOT() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
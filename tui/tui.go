@@ -0,0 +1,361 @@
+// Package tui runs a simulation behind a terminal dashboard, showing the progress of the run, the tables of each
+// node, the rate of each type of message, and the latest events, in place of a log of every message.
+//
+// The dashboard is drawn with ANSI escape sequences, and redrawn in place as the run progresses.
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+)
+
+// ANSI escape sequences used to draw the dashboard.
+const (
+	clearScreen = "\x1b[2J"
+	home        = "\x1b[H"
+	clearLine   = "\x1b[K"
+	clearBelow  = "\x1b[J"
+	bold        = "\x1b[1m"
+	reset       = "\x1b[0m"
+)
+
+// refreshInterval is the shortest time between two redraws, so that fast runs are not slowed down by drawing.
+const refreshInterval = 50 * time.Millisecond
+
+// sparks are the characters of a sparkline, from lowest to highest.
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Options configures a Dashboard.
+type Options struct {
+	// Title names the run.
+	Title string
+
+	// Width and Height are the size of the terminal, in characters. Zero values are taken from the COLUMNS and
+	// LINES environment variables, or default to 100 by 32.
+	Width  int
+	Height int
+}
+
+// Dashboard runs a simulation, drawing its progress to a terminal.
+type Dashboard struct {
+	c     *controller.Controller
+	ticks int
+	out   *bufio.Writer
+	opts  Options
+
+	// rates holds the number of messages of each type sent during each of the latest ticks, oldest first.
+	rates map[string][]int
+
+	// events holds the latest events, formatted, oldest first.
+	events []string
+
+	// drawn is the time the dashboard was last drawn.
+	drawn time.Time
+}
+
+// New creates a Dashboard running c for the given number of ticks, drawing to out. c must be initialized, and must
+// not be used by anything but the Dashboard.
+func New(out io.Writer, c *controller.Controller, ticks int, opts Options) *Dashboard {
+	if opts.Width <= 0 {
+		opts.Width = envInt("COLUMNS", 100)
+	}
+	if opts.Height <= 0 {
+		opts.Height = envInt("LINES", 32)
+	}
+	d := &Dashboard{
+		c:     c,
+		ticks: ticks,
+		out:   bufio.NewWriter(out),
+		opts:  opts,
+		rates: make(map[string][]int),
+	}
+	c.Observe(d)
+	return d
+}
+
+// envInt returns the positive integer held by an environment variable, or def.
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// Run runs the simulation, until its end or until it is stopped, paced by the tick duration of the Controller,
+// redrawing the dashboard as it goes. The logs of the nodes are closed once it is over, and the dashboard is drawn a
+// last time. An error is returned if the dashboard cannot be drawn.
+func (d *Dashboard) Run() error {
+	var pace <-chan time.Time
+	if td := d.c.TickDuration(); td > 0 {
+		ticker := time.NewTicker(td)
+		defer ticker.Stop()
+		pace = ticker.C
+	}
+
+	if _, err := d.out.WriteString(clearScreen); err != nil {
+		return err
+	}
+	for i := 0; i < d.ticks && !d.c.Stopped(); i++ {
+		d.c.Step()
+		if time.Since(d.drawn) >= refreshInterval {
+			if err := d.draw(); err != nil {
+				return err
+			}
+		}
+		if pace != nil {
+			<-pace
+		}
+	}
+	d.c.Close()
+	return d.draw()
+}
+
+// Event records e, to be shown when the dashboard is next drawn. It is called by the Controller.
+func (d *Dashboard) Event(e controller.Event) {
+	switch e.Kind {
+	case controller.EventTick:
+		for kind, counts := range d.rates {
+			counts = append(counts, 0)
+			if width := d.sparkWidth(); len(counts) > width {
+				counts = counts[len(counts)-width:]
+			}
+			d.rates[kind] = counts
+		}
+		return
+	case controller.EventSend:
+		kind := e.Envelope.Message.Type()
+		counts, ok := d.rates[kind]
+		if !ok {
+			// Every series covers the same ticks, so that they line up.
+			counts = make([]int, d.seriesLength()+1)
+		}
+		counts[len(counts)-1]++
+		d.rates[kind] = counts
+	case controller.EventState:
+		return
+	}
+
+	d.events = append(d.events, format(e))
+	if max := d.opts.Height; len(d.events) > max {
+		d.events = d.events[len(d.events)-max:]
+	}
+}
+
+// seriesLength returns the number of ticks covered by the rates.
+func (d *Dashboard) seriesLength() int {
+	for _, counts := range d.rates {
+		return len(counts) - 1
+	}
+	return 0
+}
+
+// sparkWidth is the number of ticks shown by each sparkline.
+func (d *Dashboard) sparkWidth() int {
+	if w := d.opts.Width - 24; w > 10 {
+		return w
+	}
+	return 10
+}
+
+// format describes an event on a single line.
+func format(e controller.Event) string {
+	prefix := fmt.Sprintf("[%4d] ", e.Tick)
+	switch e.Kind {
+	case controller.EventOriginate:
+		return prefix + fmt.Sprintf("node %d: handed data for %d: %q", e.Node, e.Originated.Destination, e.Originated.Data)
+	case controller.EventSend:
+		return prefix + fmt.Sprintf("node %d: sent %s to %s", e.Node, e.Envelope.Message.Type(), to(e.Envelope.To))
+	case controller.EventReceive:
+		return prefix + fmt.Sprintf("node %d: received %s from %d", e.Node, e.Envelope.Message.Type(), e.Envelope.From)
+	case controller.EventLost:
+		return prefix + fmt.Sprintf("node %d: lost %s from %d", e.Node, e.Envelope.Message.Type(), e.Envelope.From)
+	case controller.EventLink:
+		status := "DOWN"
+		if e.Link.Up {
+			status = "UP"
+		}
+		return prefix + fmt.Sprintf("link %d -> %d %s", e.Link.From, e.Link.To, status)
+	case controller.EventJoin:
+		return prefix + fmt.Sprintf("node %d: joined", e.Node)
+	case controller.EventLeave:
+		return prefix + fmt.Sprintf("node %d: left", e.Node)
+	case controller.EventNode:
+		if e.NodeChange.Up {
+			return prefix + fmt.Sprintf("node %d: UP", e.Node)
+		}
+		return prefix + fmt.Sprintf("node %d: DOWN", e.Node)
+	}
+	return prefix + string(e.Kind)
+}
+
+// to describes the destination of an envelope.
+func to(id message.NodeID) string {
+	if id == message.Broadcast {
+		return "all"
+	}
+	return strconv.Itoa(int(id))
+}
+
+// draw redraws the whole dashboard.
+func (d *Dashboard) draw() error {
+	d.drawn = time.Now()
+	var lines []string
+	lines = append(lines, d.header()...)
+	lines = append(lines, "")
+	lines = append(lines, d.nodes()...)
+	lines = append(lines, "")
+	lines = append(lines, d.sparklines()...)
+	lines = append(lines, "")
+
+	// The event log takes whatever room is left, showing the latest events.
+	lines = append(lines, bold+"EVENTS"+reset)
+	room := d.opts.Height - len(lines) - 1
+	events := d.events
+	if room < 0 {
+		room = 0
+	}
+	if len(events) > room {
+		events = events[len(events)-room:]
+	}
+	lines = append(lines, events...)
+
+	if _, err := d.out.WriteString(home); err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if _, err := d.out.WriteString(truncate(l, d.opts.Width) + clearLine + "\n"); err != nil {
+			return err
+		}
+	}
+	if _, err := d.out.WriteString(clearBelow); err != nil {
+		return err
+	}
+	return d.out.Flush()
+}
+
+// header describes the progress of the run and its data delivery.
+func (d *Dashboard) header() []string {
+	done := d.c.CurrentTick()
+	bar := 30
+	filled := 0
+	if d.ticks > 0 {
+		filled = done * bar / d.ticks
+	}
+	title := d.opts.Title
+	if title == "" {
+		title = "olsrsim"
+	}
+	m := d.c.Metrics()
+	return []string{
+		fmt.Sprintf("%s%s%s  tick %d/%d [%s%s]", bold, title, reset, done, d.ticks, strings.Repeat("#", filled), strings.Repeat(".", bar-filled)),
+		fmt.Sprintf("data: %d originated, %d delivered (%.0f%%), mean latency %.1f ticks; %d messages sent",
+			m.DataOriginated, m.DataDelivered, 100*m.DeliveryRatio(), m.MeanLatency(), m.TotalSent()),
+	}
+}
+
+// nodes tabulates the size of the tables of each node, with as many nodes as fit in a third of the screen.
+func (d *Dashboard) nodes() []string {
+	lines := []string{bold + fmt.Sprintf("%-6s %9s %6s %7s", "NODE", "NEIGHBORS", "MPRS", "ROUTES") + reset}
+	routers := d.c.Routers()
+	room := d.opts.Height/3 - 1
+	if room < 1 {
+		room = 1
+	}
+	for i, r := range routers {
+		if i == room && len(routers) > room+1 {
+			lines = append(lines, fmt.Sprintf("... %d more", len(routers)-room))
+			break
+		}
+		state, err := d.c.Inspect(r.ID())
+		if err != nil {
+			continue
+		}
+		if state.Down {
+			lines = append(lines, fmt.Sprintf("%-6d %9s", state.ID, "down"))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%-6d %9d %6d %7d", state.ID, len(state.Neighbors), len(state.MPRs), len(state.Routes)))
+	}
+	return lines
+}
+
+// sparklines draws the number of messages of each type sent per tick over the latest ticks.
+func (d *Dashboard) sparklines() []string {
+	lines := []string{bold + fmt.Sprintf("MESSAGES SENT PER TICK, LAST %d TICKS", d.sparkWidth()) + reset}
+	kinds := make([]string, 0, len(d.rates))
+	for kind := range d.rates {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		counts := d.rates[kind]
+		total := 0
+		for _, c := range counts {
+			total += c
+		}
+		mean := 0.0
+		if len(counts) > 0 {
+			mean = float64(total) / float64(len(counts))
+		}
+		lines = append(lines, fmt.Sprintf("%-8s %s %6.2f/tick", kind, sparkline(counts, d.sparkWidth()), mean))
+	}
+	if len(kinds) == 0 {
+		lines = append(lines, "none yet")
+	}
+	return lines
+}
+
+// sparkline draws counts as a line of width characters, scaled to the largest count, and padded on the left so that
+// the latest count is always at the right. Zero counts are blank.
+func sparkline(counts []int, width int) string {
+	if len(counts) > width {
+		counts = counts[len(counts)-width:]
+	}
+	max := 0
+	for _, c := range counts {
+		if c > max {
+			max = c
+		}
+	}
+	var b strings.Builder
+	b.WriteString(strings.Repeat(" ", width-len(counts)))
+	for _, c := range counts {
+		if c == 0 {
+			b.WriteRune(' ')
+			continue
+		}
+		b.WriteRune(sparks[(c*len(sparks)-1)/max])
+	}
+	return b.String()
+}
+
+// truncate shortens a line to width characters, ignoring escape sequences.
+func truncate(line string, width int) string {
+	var b strings.Builder
+	n := 0
+	escape := false
+	for _, r := range line {
+		switch {
+		case escape:
+			escape = r != 'm'
+		case r == '\x1b':
+			escape = true
+		default:
+			if n == width {
+				continue
+			}
+			n++
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
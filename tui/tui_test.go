@@ -0,0 +1,93 @@
+package tui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim"
+)
+
+func TestDashboard_Run(t *testing.T) {
+	s := &olsrsim.Scenario{
+		TopologyLines: []string{"0 UP 0 <-> 1", "0 UP 1 <-> 2", "50 DOWN 1 2"},
+		Nodes:         []olsrsim.ScenarioNode{{ID: 0}, {ID: 1}, {ID: 2}},
+		Traffic:       []olsrsim.Traffic{{Source: 0, Destination: 2, Message: "hello", Delay: 30}},
+	}
+	c, err := s.Controller()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetTickDuration(0)
+	var out bytes.Buffer
+	d := New(&out, c, 60, Options{Title: "line", Width: 80, Height: 30})
+	if err := d.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Only the last frame, drawn once the run is over, is checked.
+	frames := strings.Split(out.String(), home)
+	last := frames[len(frames)-1]
+	for _, want := range []string{
+		"line" + reset + "  tick 60/60 [" + strings.Repeat("#", 30) + "]",
+		"data: 1 originated, 1 delivered (100%)",
+		"0              1      1       2",
+		"1              2      0       2",
+		"HELLO    ",
+		"TC       ",
+		"DATA     ",
+		"[  50] link 1 -> 2 DOWN",
+	} {
+		if !strings.Contains(last, want) {
+			t.Errorf("Run() last frame does not contain %q:\n%s", want, last)
+		}
+	}
+	for i, l := range strings.Split(last, "\n") {
+		if l = strings.TrimSuffix(l, clearLine); len([]rune(truncate(l, 1000))) > 80+len(bold+reset) {
+			t.Errorf("Run() line %d is wider than 80 characters: %q", i, l)
+		}
+	}
+	if lines := strings.Count(last, "\n"); lines > 30 {
+		t.Errorf("Run() drew %d lines, want at most 30", lines)
+	}
+}
+
+func Test_sparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts []int
+		width  int
+		want   string
+	}{
+		{name: "empty", counts: nil, width: 3, want: "   "},
+		{name: "scaled", counts: []int{0, 1, 4, 8}, width: 4, want: " ▁▄█"},
+		{name: "padded", counts: []int{2, 2}, width: 4, want: "  ██"},
+		{name: "latest", counts: []int{9, 1, 2}, width: 2, want: "▄█"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sparkline(tt.counts, tt.width); got != tt.want {
+				t.Errorf("sparkline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_truncate(t *testing.T) {
+	tests := []struct {
+		line  string
+		width int
+		want  string
+	}{
+		{line: "hello", width: 10, want: "hello"},
+		{line: "hello", width: 3, want: "hel"},
+		{line: bold + "hello" + reset + " world", width: 7, want: bold + "hello" + reset + " w"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := truncate(tt.line, tt.width); got != tt.want {
+				t.Errorf("truncate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package olsrsim
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/topology"
+)
+
+// Severity ranks how serious a Problem is.
+type Severity int
+
+const (
+	// Warning is a Problem the simulation tolerates, but which is likely unintended.
+	Warning Severity = iota + 1
+
+	// Error is a Problem that makes the input unusable.
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		return "ok"
+	}
+}
+
+// Problem is an issue found while validating a topology or node configuration file.
+type Problem struct {
+	// File is the name of the file containing the problem.
+	File string
+
+	// Line is the 1-based line number of the problem, or 0 if it is not specific to a line.
+	Line int
+
+	Severity Severity
+	Msg      string
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Severity, p.Msg)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.File, p.Severity, p.Msg)
+}
+
+// ValidationReport holds all problems found by Validate.
+type ValidationReport struct {
+	Problems []Problem
+}
+
+// Severity is the most serious Severity among the report's problems, or 0 if there are none.
+func (r *ValidationReport) Severity() Severity {
+	var s Severity
+	for _, p := range r.Problems {
+		if p.Severity > s {
+			s = p.Severity
+		}
+	}
+	return s
+}
+
+func (r *ValidationReport) add(file string, line int, s Severity, format string, args ...interface{}) {
+	r.Problems = append(r.Problems, Problem{File: file, Line: line, Severity: s, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks a topology, and optionally a node configuration, reporting every problem found.
+// The names are only used to give context to each Problem. If configsIn is nil, only the topology is checked.
+// An error is only returned if an input could not be read.
+func Validate(topologyName string, topologyIn io.Reader, configName string, configsIn io.Reader) (*ValidationReport, error) {
+	r := &ValidationReport{}
+
+	type linkKey struct {
+		from, to message.NodeID
+	}
+	// up holds the current status of each link, and firstUp the line a link first came UP on.
+	up := make(map[linkKey]bool)
+	firstUp := make(map[linkKey]int)
+	// mentioned holds the first line each node appears on.
+	mentioned := make(map[message.NodeID]int)
+
+	// numbered is a LinkState along with the line of the topology file that described it.
+	type numbered struct {
+		topology.LinkState
+		line int
+
+		// implied is set for states implied by the end of a time range.
+		implied bool
+	}
+	// numberedNode is a NodeEvent along with the line of the topology file that described it.
+	type numberedNode struct {
+		topology.NodeEvent
+		line    int
+		implied bool
+	}
+	var states []numbered
+	var nodes []numberedNode
+	// times holds the time of each explicit entry, by line, in the order they were described.
+	type lineTime struct {
+		line, time int
+	}
+	var times []lineTime
+	err := readLines(topologyIn, func(num int, line string) error {
+		events, isNode, err := topology.ParseNodeLine(line)
+		if err != nil {
+			r.add(topologyName, num, Error, "%s", err)
+			return nil
+		}
+		if isNode {
+			for _, e := range events {
+				nodes = append(nodes, numberedNode{NodeEvent: e, line: num, implied: e.Time != events[0].Time})
+			}
+			times = append(times, lineTime{line: num, time: events[0].Time})
+			return nil
+		}
+
+		ls, err := topology.ParseLine(line)
+		if err != nil {
+			r.add(topologyName, num, Error, "%s", err)
+			return nil
+		}
+		for _, s := range ls {
+			states = append(states, numbered{LinkState: s, line: num, implied: s.Time != ls[0].Time})
+		}
+		if len(ls) > 0 {
+			times = append(times, lineTime{line: num, time: ls[0].Time})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	currTime := 0
+	for _, t := range times {
+		if t.time < currTime {
+			r.add(topologyName, t.line, Error, "time %d is before the preceding entry's time %d", t.time, currTime)
+		} else {
+			currTime = t.time
+		}
+	}
+	for _, s := range states {
+		if s.implied {
+			continue
+		}
+		if s.From == s.To {
+			r.add(topologyName, s.line, Error, "self-link on node %d", s.From)
+		}
+		for _, id := range []message.NodeID{s.From, s.To} {
+			if _, in := mentioned[id]; !in {
+				mentioned[id] = s.line
+			}
+		}
+	}
+
+	// Check transitions in the order they take effect, which merges in those implied by time ranges.
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].Time < states[j].Time
+	})
+	for _, s := range states {
+		k := linkKey{from: s.From, to: s.To}
+		isUp := s.Status == topology.UP
+		if up[k] == isUp {
+			r.add(topologyName, s.line, Warning, "redundant transition: link %d -> %d is already %s", k.from, k.to, s.Status)
+		}
+		up[k] = isUp
+		if _, in := firstUp[k]; !in && isUp {
+			firstUp[k] = s.line
+		}
+	}
+
+	// Nodes start UP, and each transition must change their state.
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Time < nodes[j].Time
+	})
+	down := make(map[message.NodeID]bool)
+	for _, e := range nodes {
+		isDown := e.Status == topology.DOWN
+		if down[e.Node] == isDown {
+			r.add(topologyName, e.line, Warning, "redundant transition: node %d is already %s", e.Node, e.Status)
+		}
+		down[e.Node] = isDown
+		if _, in := mentioned[e.Node]; !in && !e.implied {
+			mentioned[e.Node] = e.line
+		}
+	}
+
+	// Report links that are never reciprocated, in the order they first came UP.
+	asymmetric := make([]linkKey, 0)
+	for k := range firstUp {
+		if _, in := firstUp[linkKey{from: k.to, to: k.from}]; !in && k.from != k.to {
+			asymmetric = append(asymmetric, k)
+		}
+	}
+	sort.SliceStable(asymmetric, func(i, j int) bool {
+		return firstUp[asymmetric[i]] < firstUp[asymmetric[j]]
+	})
+	for _, k := range asymmetric {
+		r.add(topologyName, firstUp[k], Warning, "link %d -> %d is never reciprocated by %d -> %d", k.from, k.to, k.to, k.from)
+	}
+
+	if configsIn == nil {
+		r.sort(topologyName)
+		return r, nil
+	}
+
+	configured := make(map[message.NodeID]int)
+	dsts := make(map[message.NodeID]int)
+	err = readLines(configsIn, func(num int, line string) error {
+		c, err := controller.ParseNodeConfig(line)
+		if err != nil {
+			r.add(configName, num, Error, "%s", err)
+			return nil
+		}
+		if prev, in := configured[c.ID]; in {
+			r.add(configName, num, Error, "node %d is already configured on line %d", c.ID, prev)
+			return nil
+		}
+		configured[c.ID] = num
+		if c.Message.Destination == c.ID {
+			r.add(configName, num, Warning, "node %d sends its message to itself", c.ID)
+		}
+		if !c.Present(c.Message.Delay) {
+			r.add(configName, num, Error, "node %d sends its message at tick %d, while it is not in the network", c.ID, c.Message.Delay)
+		}
+		dsts[c.Message.Destination] = num
+		if _, in := mentioned[c.ID]; !in {
+			r.add(configName, num, Warning, "node %d does not appear in the topology", c.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range sortedIDs(mentioned) {
+		if _, in := configured[id]; !in {
+			r.add(topologyName, mentioned[id], Warning, "node %d has no node configuration", id)
+		}
+	}
+	for _, id := range sortedIDs(dsts) {
+		if _, in := configured[id]; !in {
+			r.add(configName, dsts[id], Warning, "destination %d has no node configuration", id)
+		}
+	}
+
+	r.sort(topologyName)
+	return r, nil
+}
+
+// sort orders problems by line, with those of the topology file first.
+func (r *ValidationReport) sort(topologyName string) {
+	sort.SliceStable(r.Problems, func(i, j int) bool {
+		pi, pj := r.Problems[i], r.Problems[j]
+		if (pi.File == topologyName) != (pj.File == topologyName) {
+			return pi.File == topologyName
+		}
+		return pi.Line < pj.Line
+	})
+}
+
+// readLines calls fn with each line, and its 1-based line number, read from in.
+func readLines(in io.Reader, fn func(num int, line string) error) error {
+	s := bufio.NewScanner(in)
+	num := 0
+	for s.Scan() {
+		num++
+		if err := fn(num, s.Text()); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// sortedIDs returns the keys of m in increasing order.
+func sortedIDs[V any](m map[message.NodeID]V) []message.NodeID {
+	ids := make([]message.NodeID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
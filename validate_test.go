@@ -0,0 +1,94 @@
+package olsrsim
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	topologyIn := getTestData("./testdata/lint_topology.txt")
+	defer topologyIn.Close()
+	configsIn := getTestData("./testdata/lint_node_config.txt")
+	defer configsIn.Close()
+
+	got, err := Validate("topology", topologyIn, "nodes", configsIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"topology:3: warning: redundant transition: link 0 -> 1 is already UP",
+		"topology:4: error: self-link on node 2",
+		"topology:4: warning: node 2 has no node configuration",
+		"topology:5: error: time 3 is before the preceding entry's time 5",
+		"topology:5: warning: link 1 -> 2 is never reciprocated by 2 -> 1",
+		"topology:6: error: parse link state: time is not an integer: 'x'",
+		"topology:7: warning: redundant transition: link 0 -> 3 is already DOWN",
+		"topology:7: warning: node 3 has no node configuration",
+		"topology:8: warning: link 4 -> 0 is never reciprocated by 0 -> 4",
+		"topology:10: warning: redundant transition: node 1 is already DOWN",
+		"topology:11: error: parse link state: PERSIST only applies to a node coming UP",
+		"nodes:2: warning: node 1 sends its message to itself",
+		"nodes:3: error: node 1 is already configured on line 2",
+		"nodes:4: warning: node 7 does not appear in the topology",
+		"nodes:5: error: invalid node config: must be of the form: '{SRC} {DST} \"{MSG}\" {DELAY} [join={TICK}] [leave={TICK}]': bad line",
+		"nodes:6: error: node 4 sends its message at tick 30, while it is not in the network",
+		"nodes:7: error: invalid node config: must leave after it joins: 3 0 \"(3 -> 0)\" 30 join=20 leave=20",
+	}
+	var problems []string
+	for _, p := range got.Problems {
+		problems = append(problems, p.String())
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("Validate() got = %s, want %s", strings.Join(problems, "\n"), strings.Join(want, "\n"))
+	}
+	if got.Severity() != Error {
+		t.Errorf("Severity() = %v, want %v", got.Severity(), Error)
+	}
+}
+
+func TestValidationReport_Severity(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology string
+		want     Severity
+	}{
+		{
+			name:     "clean",
+			topology: "10 UP 0 1\n10 UP 1 0\n20 DOWN 0 1\n20 DOWN 1 0\n",
+			want:     0,
+		},
+		{
+			name:     "warnings only",
+			topology: "10 UP 0 1\n",
+			want:     Warning,
+		},
+		{
+			name:     "errors",
+			topology: "10 UP 0 1\n10 UP 1 0\n9 DOWN 0 1\n",
+			want:     Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Validate("topology", strings.NewReader(tt.topology), "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.Severity(); got != tt.want {
+				t.Errorf("Severity() = %v, want %v: %v", got, tt.want, r.Problems)
+			}
+		})
+	}
+}
+
+func getTestData(p string) io.ReadCloser {
+	f, err := os.Open(p)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
@@ -0,0 +1,529 @@
+// Plays back a traced olsrsim run, as served at trace.json by the viz package.
+"use strict";
+
+const svgNS = "http://www.w3.org/2000/svg";
+const width = 1000;
+const height = 700;
+const nodeRadius = 18;
+
+// Colors of well known message types. Others are given one from the palette.
+const kindColors = {
+  HELLO: "#4e79a7",
+  TC: "#f28e2b",
+  DATA: "#e15759",
+  RREQ: "#76b7b2",
+  RREP: "#59a14f",
+  RERR: "#edc948",
+  UPDATE: "#b07aa1",
+  RFC5444: "#9c755f",
+};
+const palette = ["#ff9da7", "#bab0ac", "#8cd17d", "#86bcb6", "#d37295"];
+
+const state = {
+  header: null,
+  nodes: [],
+  positions: new Map(),
+  // byTick holds the events of each tick, by kind.
+  byTick: [],
+  // linkChanges holds the changes of each directed link, "from-to", in tick order.
+  linkChanges: new Map(),
+  // snapshots holds the state events of each node, in tick order.
+  snapshots: new Map(),
+  kinds: new Set(),
+  tick: 0,
+  selected: null,
+  playing: null,
+};
+
+const $ = (id) => document.getElementById(id);
+
+function el(name, attrs, parent) {
+  const e = document.createElementNS(svgNS, name);
+  for (const [k, v] of Object.entries(attrs)) {
+    e.setAttribute(k, v);
+  }
+  if (parent) {
+    parent.appendChild(e);
+  }
+  return e;
+}
+
+function kindColor(kind) {
+  if (!(kind in kindColors)) {
+    kindColors[kind] = palette[Object.keys(kindColors).length % palette.length];
+  }
+  return kindColors[kind];
+}
+
+// load indexes the events of the trace.
+function load(trace) {
+  state.header = trace.header;
+  const nodes = new Set(trace.header.nodes || []);
+  let last = Math.max(0, (trace.header.ticks || 1) - 1);
+  for (const e of trace.events) {
+    last = Math.max(last, e.tick);
+  }
+  state.byTick = Array.from({ length: last + 1 }, () => ({ link: [], send: [], receive: [], lost: [], originate: [] }));
+
+  for (const e of trace.events) {
+    const at = state.byTick[e.tick];
+    switch (e.kind) {
+      case "link": {
+        const key = e.link.from + "-" + e.link.to;
+        if (!state.linkChanges.has(key)) {
+          state.linkChanges.set(key, []);
+        }
+        state.linkChanges.get(key).push({ tick: e.tick, ...e.link });
+        at.link.push(e.link);
+        nodes.add(e.link.from);
+        nodes.add(e.link.to);
+        break;
+      }
+      case "state":
+        if (!state.snapshots.has(e.node)) {
+          state.snapshots.set(e.node, []);
+        }
+        state.snapshots.get(e.node).push(e.state);
+        break;
+      case "send":
+      case "receive":
+      case "lost":
+        at[e.kind].push(e);
+        state.kinds.add(e.envelope.kind);
+        nodes.add(e.node);
+        break;
+      case "originate":
+        at.originate.push(e);
+        break;
+    }
+  }
+  state.nodes = Array.from(nodes).sort((a, b) => a - b);
+  layout();
+}
+
+// layout places the nodes with a force-directed layout of every link which was ever up, starting from a circle so
+// that it is the same every time.
+function layout() {
+  const n = state.nodes.length;
+  const pos = state.nodes.map((id, i) => ({
+    id,
+    x: width / 2 + (width / 3) * Math.cos((2 * Math.PI * i) / n),
+    y: height / 2 + (height / 3) * Math.sin((2 * Math.PI * i) / n),
+  }));
+  const index = new Map(state.nodes.map((id, i) => [id, i]));
+  const edges = [];
+  for (const [key, changes] of state.linkChanges) {
+    if (changes.some((c) => c.up)) {
+      const [from, to] = key.split("-").map(Number);
+      if (from < to || !state.linkChanges.has(to + "-" + from)) {
+        edges.push([index.get(from), index.get(to)]);
+      }
+    }
+  }
+
+  const k = Math.sqrt((width * height) / Math.max(n, 1)) * 0.6;
+  let temperature = width / 10;
+  for (let iter = 0; iter < 300; iter++) {
+    const disp = pos.map(() => ({ x: 0, y: 0 }));
+    for (let i = 0; i < n; i++) {
+      for (let j = i + 1; j < n; j++) {
+        const dx = pos[i].x - pos[j].x;
+        const dy = pos[i].y - pos[j].y;
+        const d = Math.max(Math.hypot(dx, dy), 0.01);
+        const f = (k * k) / d;
+        disp[i].x += (dx / d) * f;
+        disp[i].y += (dy / d) * f;
+        disp[j].x -= (dx / d) * f;
+        disp[j].y -= (dy / d) * f;
+      }
+    }
+    for (const [i, j] of edges) {
+      const dx = pos[i].x - pos[j].x;
+      const dy = pos[i].y - pos[j].y;
+      const d = Math.max(Math.hypot(dx, dy), 0.01);
+      const f = (d * d) / k;
+      disp[i].x -= (dx / d) * f;
+      disp[i].y -= (dy / d) * f;
+      disp[j].x += (dx / d) * f;
+      disp[j].y += (dy / d) * f;
+    }
+    for (let i = 0; i < n; i++) {
+      const d = Math.max(Math.hypot(disp[i].x, disp[i].y), 0.01);
+      pos[i].x += (disp[i].x / d) * Math.min(d, temperature);
+      pos[i].y += (disp[i].y / d) * Math.min(d, temperature);
+    }
+    temperature *= 0.98;
+  }
+
+  // Fit the layout within the view.
+  const margin = nodeRadius * 3;
+  const xs = pos.map((p) => p.x);
+  const ys = pos.map((p) => p.y);
+  const [minX, maxX, minY, maxY] = [Math.min(...xs), Math.max(...xs), Math.min(...ys), Math.max(...ys)];
+  const scale = Math.min((width - 2 * margin) / Math.max(maxX - minX, 1), (height - 2 * margin) / Math.max(maxY - minY, 1));
+  for (const p of pos) {
+    state.positions.set(p.id, {
+      x: margin + (p.x - minX) * scale + (width - 2 * margin - (maxX - minX) * scale) / 2,
+      y: margin + (p.y - minY) * scale + (height - 2 * margin - (maxY - minY) * scale) / 2,
+    });
+  }
+}
+
+// linkAt returns the last change of a directed link at or before tick, if any.
+function linkAt(from, to, tick) {
+  const changes = state.linkChanges.get(from + "-" + to) || [];
+  let current = null;
+  for (const c of changes) {
+    if (c.tick > tick) {
+      break;
+    }
+    current = c;
+  }
+  return current;
+}
+
+// snapshotAt returns the last snapshot of a node at or before tick, if any.
+function snapshotAt(id, tick) {
+  let current = null;
+  for (const s of state.snapshots.get(id) || []) {
+    if (s.tick > tick) {
+      break;
+    }
+    current = s;
+  }
+  return current;
+}
+
+// render draws the network at the current tick.
+function render() {
+  const tick = state.tick;
+  const at = state.byTick[tick];
+  $("tick").textContent = "tick " + tick;
+  $("scrubber").value = tick;
+
+  renderLinks(tick, at);
+  renderMPRs(tick);
+  renderMessages(at);
+  renderNodes(tick);
+  renderDetails(tick, at);
+}
+
+function renderLinks(tick, at) {
+  const g = $("links");
+  g.replaceChildren();
+  // Every link of the initial topology changes state at tick 0, so only later changes are highlighted.
+  const changed = new Map(tick === 0 ? [] : at.link.map((l) => [l.from + "-" + l.to, l.up]));
+  const drawn = new Set();
+  for (const key of state.linkChanges.keys()) {
+    const [from, to] = key.split("-").map(Number);
+    const pair = Math.min(from, to) + "-" + Math.max(from, to);
+    if (drawn.has(pair)) {
+      continue;
+    }
+    drawn.add(pair);
+
+    const forward = linkAt(from, to, tick);
+    const backward = linkAt(to, from, tick);
+    const up = [forward, backward].filter((l) => l && l.up);
+    const change = changed.get(key) ?? changed.get(to + "-" + from);
+    if (up.length === 0 && change === undefined) {
+      continue;
+    }
+
+    const a = state.positions.get(from);
+    const b = state.positions.get(to);
+    let cls = "link";
+    if (change === true) {
+      cls += " changed-up";
+    } else if (change === false) {
+      cls += " changed-down";
+    } else if (up.length === 1) {
+      cls += " oneway";
+    }
+    const line = el("line", { x1: a.x, y1: a.y, x2: b.x, y2: b.y, class: cls }, g);
+    const loss = Math.max(0, ...up.map((l) => l.loss || 0));
+    if (loss > 0) {
+      line.setAttribute("stroke-opacity", 1 - loss * 0.7);
+    }
+    const title = up.map((l) => `${l.from} → ${l.to}` + (l.loss ? ` loss=${l.loss}` : "") + (l.delay ? ` delay=${l.delay}` : ""));
+    el("title", {}, line).textContent = title.length ? title.join("\n") : `${from} ↔ ${to} down`;
+  }
+}
+
+function renderMPRs(tick) {
+  const g = $("mprs");
+  g.replaceChildren();
+  if (!$("show-mprs").checked) {
+    return;
+  }
+  for (const id of state.nodes) {
+    const s = snapshotAt(id, tick);
+    if (!s || !s.mprs) {
+      continue;
+    }
+    const a = state.positions.get(id);
+    for (const mpr of s.mprs) {
+      const b = state.positions.get(mpr);
+      if (!b) {
+        continue;
+      }
+      // Offset the arrow so that it stops at the edge of the MPR, and bend it away from the link.
+      const dx = b.x - a.x;
+      const dy = b.y - a.y;
+      const d = Math.hypot(dx, dy) || 1;
+      const end = { x: b.x - (dx / d) * (nodeRadius + 2), y: b.y - (dy / d) * (nodeRadius + 2) };
+      const mid = { x: (a.x + b.x) / 2 - (dy / d) * 20, y: (a.y + b.y) / 2 + (dx / d) * 20 };
+      el("path", { d: `M ${a.x} ${a.y} Q ${mid.x} ${mid.y} ${end.x} ${end.y}`, class: "mpr" }, g);
+    }
+  }
+}
+
+function renderMessages(at) {
+  const g = $("messages");
+  g.replaceChildren();
+  const control = $("show-control").checked;
+  const duration = 0.8 / Number($("speed").value);
+
+  for (const e of at.send) {
+    if (e.envelope.to !== "*" || (!control && e.envelope.kind !== "DATA")) {
+      continue;
+    }
+    const p = state.positions.get(e.node);
+    const ring = el("circle", { cx: p.x, cy: p.y, r: nodeRadius, class: "broadcast", stroke: kindColor(e.envelope.kind) }, g);
+    el("animate", { attributeName: "r", from: nodeRadius, to: nodeRadius * 4, dur: duration + "s", fill: "freeze" }, ring);
+    el("animate", { attributeName: "stroke-opacity", from: 1, to: 0, dur: duration + "s", fill: "freeze" }, ring);
+  }
+  for (const e of at.receive) {
+    const kind = e.envelope.kind;
+    const a = state.positions.get(e.envelope.from);
+    const b = state.positions.get(e.node);
+    if (!a || !b) {
+      continue;
+    }
+    if (kind === "DATA") {
+      el("line", { x1: a.x, y1: a.y, x2: b.x, y2: b.y, class: "data-hop" }, g);
+    } else if (!control) {
+      continue;
+    }
+    const dot = el("circle", { r: kind === "DATA" ? 8 : 5, fill: kindColor(kind) }, g);
+    el("title", {}, dot).textContent = `${kind} ${e.envelope.from} → ${e.node}`;
+    el("animate", { attributeName: "cx", from: a.x, to: b.x, dur: duration + "s", fill: "freeze" }, dot);
+    el("animate", { attributeName: "cy", from: a.y, to: b.y, dur: duration + "s", fill: "freeze" }, dot);
+  }
+  for (const e of at.lost) {
+    const p = state.positions.get(e.node);
+    const s = nodeRadius * 0.6;
+    const x = p.x + nodeRadius;
+    const y = p.y - nodeRadius;
+    el("line", { x1: x - s, y1: y - s, x2: x + s, y2: y + s, class: "lost" }, g);
+    el("line", { x1: x - s, y1: y + s, x2: x + s, y2: y - s, class: "lost" }, g);
+  }
+  for (const anim of g.querySelectorAll("animate")) {
+    anim.beginElement();
+  }
+}
+
+function renderNodes(tick) {
+  const g = $("nodes");
+  g.replaceChildren();
+  const mprs = new Set();
+  for (const id of state.nodes) {
+    const s = snapshotAt(id, tick);
+    for (const m of (s && s.mprs) || []) {
+      mprs.add(m);
+    }
+  }
+  const selected = state.selected !== null ? snapshotAt(state.selected, tick) : null;
+  const selectedMPRs = new Set((selected && selected.mprs) || []);
+
+  for (const id of state.nodes) {
+    const p = state.positions.get(id);
+    let cls = "node";
+    if (mprs.has(id)) {
+      cls += " is-mpr";
+    }
+    if (id === state.selected) {
+      cls += " selected";
+    } else if (selectedMPRs.has(id)) {
+      cls += " mpr-of-selected";
+    }
+    const node = el("g", { class: cls, transform: `translate(${p.x} ${p.y})` }, g);
+    el("circle", { r: nodeRadius }, node);
+    el("text", {}, node).textContent = id;
+    node.addEventListener("click", () => {
+      state.selected = state.selected === id ? null : id;
+      render();
+    });
+  }
+}
+
+function renderDetails(tick, at) {
+  const aside = $("details");
+  aside.replaceChildren();
+  const add = (tag, text, parent = aside) => {
+    const e = document.createElement(tag);
+    if (text !== undefined) {
+      e.textContent = text;
+    }
+    parent.appendChild(e);
+    return e;
+  };
+  const table = (headings, rows) => {
+    if (rows.length === 0) {
+      add("p", "none").className = "hint";
+      return;
+    }
+    const t = add("table");
+    const head = add("tr", undefined, t);
+    for (const h of headings) {
+      add("th", h, head);
+    }
+    for (const row of rows) {
+      const tr = add("tr", undefined, t);
+      for (const cell of row) {
+        add("td", cell, tr);
+      }
+    }
+  };
+  const list = (ids) => add("p", ids && ids.length ? ids.join(" ") : "none");
+
+  const id = state.selected;
+  if (id === null) {
+    add("p", "Click a node to show its tables.").className = "hint";
+    return;
+  }
+  add("h2", "Node " + id);
+
+  const s = snapshotAt(id, tick);
+  if (!s) {
+    add("p", "The trace has no snapshot of this node's tables at or before this tick. Trace the run with -snapshot to record them.").className = "hint";
+  } else {
+    if (s.tick !== tick) {
+      add("p", "As of tick " + s.tick + ".").className = "hint";
+    }
+    add("h3", "Neighbors");
+    list(s.neighbors);
+    if (s.twoHopNeighbors) {
+      add("h3", "Two-hop neighbors");
+      table(["node", "via"], s.twoHopNeighbors.map((n) => [n.id, n.via]));
+    }
+    if (s.mprs) {
+      add("h3", "MPRs");
+      list(s.mprs);
+    }
+    if (s.mprSelectors) {
+      add("h3", "MPR selectors");
+      list(s.mprSelectors);
+    }
+    if (s.topology) {
+      add("h3", "Topology");
+      table(["from", "to"], s.topology.map((l) => [l.from, l.to]));
+    }
+    add("h3", "Routes");
+    table(["destination", "next hop", "distance"], (s.routes || []).map((r) => [r.destination, r.nextHop, r.distance]));
+  }
+
+  add("h3", "Events this tick");
+  const rows = [];
+  for (const e of at.originate) {
+    if (e.node === id) {
+      rows.push(["originated", "DATA", `→ ${e.originated.destination}: ${e.originated.data}`]);
+    }
+  }
+  for (const e of at.send) {
+    if (e.node === id) {
+      rows.push(["sent", e.envelope.kind, "→ " + e.envelope.to]);
+    }
+  }
+  for (const e of at.receive) {
+    if (e.node === id) {
+      rows.push(["received", e.envelope.kind, "← " + e.envelope.from]);
+    }
+  }
+  for (const e of at.lost) {
+    if (e.node === id) {
+      rows.push(["lost", e.envelope.kind, "← " + e.envelope.from]);
+    }
+  }
+  table(["event", "kind", ""], rows);
+}
+
+function seek(tick) {
+  state.tick = Math.min(Math.max(tick, 0), state.byTick.length - 1);
+  render();
+}
+
+function play() {
+  if (state.playing) {
+    clearInterval(state.playing);
+    state.playing = null;
+    $("play").innerHTML = "&#9654;";
+    return;
+  }
+  if (state.tick >= state.byTick.length - 1) {
+    seek(0);
+  }
+  $("play").innerHTML = "&#10074;&#10074;";
+  state.playing = setInterval(() => {
+    if (state.tick >= state.byTick.length - 1) {
+      play();
+      return;
+    }
+    seek(state.tick + 1);
+  }, 1000 / Number($("speed").value));
+}
+
+function legend() {
+  const div = $("legend");
+  for (const kind of Array.from(state.kinds).sort()) {
+    const span = document.createElement("span");
+    const swatch = document.createElement("span");
+    swatch.className = "swatch";
+    swatch.style.background = kindColor(kind);
+    span.append(swatch, kind);
+    div.appendChild(span);
+  }
+}
+
+async function main() {
+  const resp = await fetch("trace.json");
+  if (!resp.ok) {
+    $("summary").textContent = "Unable to load the trace: " + resp.statusText;
+    return;
+  }
+  load(await resp.json());
+
+  const h = state.header;
+  $("title").textContent = h.name || "olsrsim";
+  $("summary").textContent = `${h.protocol} · ${state.nodes.length} nodes · ${state.byTick.length} ticks · seed ${h.seed}`;
+  $("scrubber").max = state.byTick.length - 1;
+  $("scrubber").addEventListener("input", (e) => seek(Number(e.target.value)));
+  $("back").addEventListener("click", () => seek(state.tick - 1));
+  $("forward").addEventListener("click", () => seek(state.tick + 1));
+  $("play").addEventListener("click", play);
+  $("speed").addEventListener("change", () => {
+    if (state.playing) {
+      play();
+      play();
+    }
+  });
+  $("show-mprs").addEventListener("change", render);
+  $("show-control").addEventListener("change", render);
+  document.addEventListener("keydown", (e) => {
+    if (e.target.tagName === "SELECT" || (e.target.tagName === "INPUT" && e.target.type !== "range")) {
+      return;
+    }
+    if (e.key === "ArrowLeft") {
+      seek(state.tick - 1);
+    } else if (e.key === "ArrowRight") {
+      seek(state.tick + 1);
+    } else if (e.key === " ") {
+      e.preventDefault();
+      play();
+    }
+  });
+  legend();
+  render();
+}
+
+main();
@@ -0,0 +1,46 @@
+// Package viz serves a web page which plays a traced run back: nodes, links going up and down, the messages they
+// exchange, and, for traces with snapshots of node state, the MPRs and tables of each node at every tick.
+//
+// The page is embedded in the binary, and loads the trace from the server as JSON, so that it needs nothing but a
+// browser.
+package viz
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/trace"
+)
+
+//go:embed static
+var static embed.FS
+
+// payload is the trace, as served to the page.
+type payload struct {
+	Header trace.Header       `json:"header"`
+	Events []controller.Event `json:"events"`
+}
+
+// Handler returns a handler serving the page at /, and the trace it plays back at /trace.json.
+func Handler(t *trace.Trace) (http.Handler, error) {
+	b, err := json.Marshal(payload{Header: t.Header, Events: t.Events})
+	if err != nil {
+		return nil, fmt.Errorf("viz: encoding trace: %w", err)
+	}
+	root, err := fs.Sub(static, "static")
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(root)))
+	mux.HandleFunc("/trace.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(b)
+	})
+	return mux, nil
+}
@@ -0,0 +1,65 @@
+package viz
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kprusa/olsrsim/controller"
+	"github.com/kprusa/olsrsim/message"
+	"github.com/kprusa/olsrsim/trace"
+)
+
+func TestHandler(t *testing.T) {
+	tr := &trace.Trace{
+		Header: trace.Header{Protocol: "olsr", Seed: 1, Ticks: 2, Nodes: []message.NodeID{0, 1}},
+		Events: []controller.Event{
+			{Tick: 0, Kind: controller.EventTick},
+			{Tick: 0, Kind: controller.EventLink, Node: 0, Link: &controller.LinkChange{From: 0, To: 1, Up: true}},
+			{Tick: 0, Kind: controller.EventState, Node: 0, State: &controller.NodeState{ID: 0, Neighbors: []message.NodeID{1}}},
+		},
+	}
+	h, err := Handler(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		wantType string
+		contains string
+	}{
+		{path: "/", wantType: "text/html; charset=utf-8", contains: `<script src="app.js">`},
+		{path: "/app.js", wantType: "text/javascript; charset=utf-8", contains: "trace.json"},
+		{path: "/style.css", wantType: "text/css; charset=utf-8", contains: ".node"},
+		{path: "/trace.json", wantType: "application/json", contains: `"kind":"link"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s status = %d, want %d", tt.path, w.Code, http.StatusOK)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("GET %s Content-Type = %s, want %s", tt.path, got, tt.wantType)
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("GET %s does not contain %s", tt.path, tt.contains)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/trace.json", nil))
+	var got payload
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Header, tr.Header) || !reflect.DeepEqual(got.Events, tr.Events) {
+		t.Errorf("GET /trace.json = %+v, want %+v", got, *tr)
+	}
+}